@@ -1,13 +1,730 @@
 package main
 
 import (
+	"cmp"
+	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
 
 	"github.com/k0ff1l/tgcloudbot/internal/config"
+	"github.com/k0ff1l/tgcloudbot/internal/services/admin"
+	"github.com/k0ff1l/tgcloudbot/internal/services/encryption"
+	"github.com/k0ff1l/tgcloudbot/internal/services/file"
+	"github.com/k0ff1l/tgcloudbot/internal/services/metrics"
+	"github.com/k0ff1l/tgcloudbot/internal/services/state"
+	"github.com/k0ff1l/tgcloudbot/internal/services/syncer"
+	"github.com/k0ff1l/tgcloudbot/internal/services/telegram"
+	"github.com/k0ff1l/tgcloudbot/internal/version"
 )
 
 func main() {
-	cfg := config.New()
+	configPath := flag.String("config", os.Getenv("CONFIG_FILE"), "path to the YAML config file")
+	printVersion := flag.Bool("version", false, "print the version and exit")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(),
+			"usage: %s [flags] [delete <path>... | restore <dir> | reconcile [-fix] [-notify] |"+
+				" dead-letters [clear <path>...]]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
 
-	fmt.Println(cfg)
+	if *printVersion {
+		fmt.Fprintln(os.Stdout, "tgcloudbot", version.Get())
+
+		return
+	}
+
+	logger := slog.Default()
+
+	var err error
+
+	switch flag.Arg(0) {
+	case "":
+		err = run(*configPath, logger)
+	case "delete":
+		err = deleteMessages(*configPath, flag.Args()[1:], logger)
+	case "restore":
+		if flag.NArg() != 2 { //nolint:mnd // restore <dir>
+			flag.Usage()
+			os.Exit(2)
+		}
+
+		err = restore(*configPath, flag.Arg(1), logger)
+	case "reconcile":
+		err = reconcile(*configPath, flag.Args()[1:], logger)
+	case "dead-letters":
+		err = deadLetters(*configPath, flag.Args()[1:], logger)
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err != nil {
+		logger.Error("failed", "error", err)
+		os.Exit(1)
+	}
+}
+
+func run(configPath string, logger *slog.Logger) error {
+	cfg, err := config.New(configPath)
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	build := version.Get()
+	logger.Info("starting", "version", build.Version, "commit", build.Commit, "date", build.Date)
+
+	var m *metrics.Metrics
+
+	if cfg.MetricsPort > 0 {
+		m = metrics.New()
+		m.SetBuildInfo(build.Version, build.Commit, build.Date, build.GoVersion)
+	}
+
+	watcher, err := newWatcher(cfg, logger)
+	if err != nil {
+		return err
+	}
+
+	// shared by the uploads and the commands, so that they keep the same flood and rate limits
+	bot, err := newBot(cfg, logger, m)
+	if err != nil {
+		return err
+	}
+
+	syncService, err := newSyncService(ctx, cfg, bot, watcher, logger, m)
+	if err != nil {
+		return err
+	}
+
+	// the chat learns once about every file skipped for its size
+	watcher.OnOversized = syncService.NotifyOversized
+
+	// fail fast instead of on the first upload, a dry run doesn't need a working token
+	if err := ping(syncService); err != nil && !cfg.DryRun {
+		return err
+	}
+
+	if m != nil {
+		serveMetrics(ctx, m, cfg.MetricsPort, syncService, logger)
+	}
+
+	startSyncs(cfg, watcher, syncService, logger)
+
+	hostname, _ := os.Hostname()
+	announcement := syncer.AnnouncementData{Hostname: hostname, Version: build.Version}
+
+	if cfg.AnnounceStartup {
+		err := syncService.AnnounceTemplate(cmp.Or(cfg.StartupMessage, syncer.DefaultStartupMessage), announcement)
+		if err != nil {
+			logger.Error("failed to send the startup message", "error", err)
+		}
+	}
+
+	adminDone := serveAdmin(ctx, cfg, syncService, logger)
+	updatesDone := serveUpdates(ctx, cfg, bot, syncService, logger)
+
+	waitForSignals(ctx, syncService, logger)
+
+	// requests in progress return before the service stops, their syncs were cancelled with ctx
+	<-adminDone
+	<-updatesDone
+	syncService.Stop()
+
+	if cfg.AnnounceShutdown {
+		err := syncService.AnnounceTemplate(cmp.Or(cfg.ShutdownMessage, syncer.DefaultShutdownMessage), announcement)
+		if err != nil {
+			logger.Error("failed to send the shutdown message", "error", err)
+		}
+	}
+
+	return watcher.Close()
+}
+
+// ping checks that the Bot API is reachable with the configured token.
+func ping(syncService *syncer.SyncService) error {
+	err := syncService.Ping()
+
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, telegram.ErrUnauthorized):
+		return fmt.Errorf("the bot token is invalid, check botToken or TELEGRAM_BOT_TOKEN: %w", err)
+	default:
+		return fmt.Errorf("can't reach the Bot API: %w", err)
+	}
+}
+
+// serveMetrics serves m on port until ctx is done, its health check pings the Bot API.
+func serveMetrics(
+	ctx context.Context, m *metrics.Metrics, port int, syncService *syncer.SyncService, logger *slog.Logger,
+) {
+	m.SetHealthCheck(syncService.Ping)
+
+	go func() {
+		if err := m.Serve(ctx, ":"+strconv.Itoa(port)); err != nil {
+			logger.Error("metrics server failed", "error", err)
+		}
+	}()
+}
+
+// newWatcher returns the watcher of the synced directories configured by cfg, with its state restored.
+func newWatcher(cfg *config.Config, logger *slog.Logger) (*file.IWatcher, error) {
+	watcher := file.NewWatcher()
+	watcher.Logger = logger
+	watcher.HashVerification = cfg.HashVerification
+	watcher.Debounce = cfg.Debounce
+	watcher.FollowSymlinks = cfg.FollowSymlinks
+	watcher.IgnoreDefaults = !cfg.DisableDefaultIgnores
+	watcher.MaxDepth = cfg.MaxDepth
+	watcher.ExcludeDirs = cfg.ExcludeDirs
+	watcher.SkipEmptyFiles = cfg.SkipEmptyFiles
+
+	if cfg.MaxFileSize > 0 {
+		watcher.MaxFileSize = cfg.MaxFileSize
+	}
+
+	// larger files are split
+	if cfg.ChunkSize > 0 {
+		watcher.MaxFileSize = 0
+	}
+
+	if cfg.StateFile != "" {
+		store, err := state.NewFileStore[file.FileState](cfg.StateFile)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := watcher.SetStateStore(store); err != nil {
+			return nil, err
+		}
+	}
+
+	return watcher, nil
+}
+
+// startSyncs starts the sync of every configured directory and the periodic summary, the failures are only logged.
+func startSyncs(cfg *config.Config, watcher *file.IWatcher, syncService *syncer.SyncService, logger *slog.Logger) {
+	for _, dir := range cfg.Directories {
+		watchDirectory(cfg, dir, watcher, syncService, logger)
+	}
+
+	if cfg.SummaryInterval > 0 {
+		if err := syncService.StartPeriodicSummary(cfg.SummaryInterval, cfg.SummaryInPlace); err != nil {
+			logger.Error("failed to start summary", "error", err)
+		}
+	}
+}
+
+// watchDirectory adds dir to the watcher and starts its sync, an invalid or unreadable directory is only logged.
+func watchDirectory(
+	cfg *config.Config, dir config.Directory, watcher *file.IWatcher, syncService *syncer.SyncService,
+	logger *slog.Logger,
+) {
+	whitelist, blacklist, err := dir.Filters()
+	if err != nil {
+		logger.Error("invalid directory filters", "dir", dir.Path, "error", err)
+
+		return
+	}
+
+	kind, forceKind, err := syncer.ParseForceKind(dir.ForceKind)
+	if err != nil {
+		logger.Error("invalid directory forceKind", "dir", dir.Path, "error", err)
+
+		return
+	}
+
+	if err := watcher.AddDirWithFilters(dir.Path, whitelist, blacklist); err != nil {
+		logger.Error("failed to watch directory", "dir", dir.Path, "error", err)
+
+		return
+	}
+
+	// only on the first run, files added while stopped are still uploaded
+	if cfg.StartupMode == config.StartupChangesOnly && watcher.TrackedFiles(dir.Path) == 0 {
+		if err := watcher.PrimeState(dir.Path); err != nil {
+			logger.Error("failed to record the existing files", "dir", dir.Path, "error", err)
+		}
+	}
+
+	syncService.SetDirChatID(dir.Path, dir.ChatID)
+	syncService.SetDirProtection(dir.Path, syncer.Protection{ProtectContent: dir.ProtectContent, Spoiler: dir.Spoiler})
+
+	if forceKind {
+		syncService.SetDirKind(dir.Path, kind)
+	}
+
+	if dir.TopicID != 0 {
+		syncService.SetDirTopic(dir.Path, dir.TopicID)
+	}
+
+	if err := syncService.StartContinuousSync(dir.Path, dir.Interval); err != nil {
+		logger.Error("failed to start sync", "dir", dir.Path, "error", err)
+	}
+}
+
+// serveAdmin serves the admin API until ctx is done if enabled, the returned channel is closed once it stopped.
+func serveAdmin(
+	ctx context.Context, cfg *config.Config, syncService *syncer.SyncService, logger *slog.Logger,
+) <-chan struct{} {
+	done := make(chan struct{})
+
+	if cfg.AdminPort <= 0 {
+		close(done)
+
+		return done
+	}
+
+	go func() {
+		defer close(done)
+
+		server := admin.New(syncService, cfg.AdminToken, logger)
+		if err := server.Serve(ctx, ":"+strconv.Itoa(cfg.AdminPort)); err != nil {
+			logger.Error("admin server failed", "error", err)
+		}
+	}()
+
+	return done
+}
+
+// serveUpdates receives the commands until ctx is done if enabled, the returned channel is closed once it stopped.
+func serveUpdates(
+	ctx context.Context, cfg *config.Config, bot *telegram.IBot, syncService *syncer.SyncService,
+	logger *slog.Logger,
+) <-chan struct{} {
+	done := make(chan struct{})
+
+	if cfg.Updates == "" {
+		close(done)
+
+		return done
+	}
+
+	go func() {
+		defer close(done)
+
+		if err := receiveUpdates(ctx, cfg, bot, syncService, logger); err != nil {
+			logger.Error("failed to receive commands", "error", err)
+		}
+	}()
+
+	return done
+}
+
+// waitForSignals syncs now or toggles the pause on the signals of notifySyncNow and notifyTogglePause
+// until ctx is done.
+func waitForSignals(ctx context.Context, syncService *syncer.SyncService, logger *slog.Logger) {
+	syncNow := make(chan os.Signal, 1)
+	notifySyncNow(syncNow)
+
+	togglePause := make(chan os.Signal, 1)
+	notifyTogglePause(togglePause)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-syncNow:
+			logger.Info("syncing now")
+
+			if err := syncService.SyncNow(); err != nil {
+				logger.Error("failed to sync", "error", err)
+			}
+		case <-togglePause:
+			if syncService.Paused() {
+				syncService.Resume()
+			} else {
+				syncService.Pause()
+			}
+		}
+	}
+}
+
+// deleteMessages deletes the messages of the given local files, it needs the index file.
+func deleteMessages(configPath string, paths []string, logger *slog.Logger) error {
+	cfg, err := config.New(configPath)
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+
+	if cfg.IndexFile == "" {
+		return errors.New("delete needs an index file, set indexFile or TELEGRAM_INDEX_FILE")
+	}
+
+	bot, err := newBot(cfg, logger, nil)
+	if err != nil {
+		return err
+	}
+
+	syncService, err := newSyncService(context.Background(), cfg, bot, file.NewWatcher(), logger, nil)
+	if err != nil {
+		return err
+	}
+
+	var failed int
+
+	for _, path := range paths {
+		err := syncService.DeleteFileMessage(path)
+
+		switch {
+		case err == nil:
+			logger.Info("deleted", "file", path)
+		case errors.Is(err, telegram.ErrMessageCantBeDeleted):
+			failed++
+
+			logger.Warn("message can't be deleted, it is probably older than 48 hours", "file", path)
+		default:
+			failed++
+
+			logger.Error("failed to delete", "file", path, "error", err)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d messages not deleted", failed, len(paths))
+	}
+
+	return nil
+}
+
+// restore downloads every indexed file into destDir, it needs the index file.
+func restore(configPath, destDir string, logger *slog.Logger) error {
+	cfg, err := config.New(configPath)
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+
+	if cfg.IndexFile == "" {
+		return errors.New("restore needs an index file, set indexFile or TELEGRAM_INDEX_FILE")
+	}
+
+	bot, err := newBot(cfg, logger, nil)
+	if err != nil {
+		return err
+	}
+
+	syncService, err := newSyncService(context.Background(), cfg, bot, file.NewWatcher(), logger, nil)
+	if err != nil {
+		return err
+	}
+
+	return syncService.Restore(destDir)
+}
+
+// reconcile prints the drift between the configured directories and the index, with -fix it also
+// uploads what is missing and with -notify sends the report to the chat. It needs the index file.
+func reconcile(configPath string, args []string, logger *slog.Logger) error {
+	flags := flag.NewFlagSet("reconcile", flag.ExitOnError)
+	fix := flags.Bool("fix", false, "upload the missing files and prune the entries of gone messages")
+	notify := flags.Bool("notify", false, "send the report to the chat")
+
+	_ = flags.Parse(args) // exits on error
+
+	cfg, err := config.New(configPath)
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+
+	if cfg.IndexFile == "" {
+		return errors.New("reconcile needs an index file, set indexFile or TELEGRAM_INDEX_FILE")
+	}
+
+	watcher := file.NewWatcher()
+	watcher.Logger = logger
+	watcher.FollowSymlinks = cfg.FollowSymlinks
+	watcher.IgnoreDefaults = !cfg.DisableDefaultIgnores
+	watcher.MaxDepth = cfg.MaxDepth
+	watcher.ExcludeDirs = cfg.ExcludeDirs
+	watcher.SkipEmptyFiles = cfg.SkipEmptyFiles
+
+	if cfg.MaxFileSize > 0 {
+		watcher.MaxFileSize = cfg.MaxFileSize
+	}
+
+	bot, err := newBot(cfg, logger, nil)
+	if err != nil {
+		return err
+	}
+
+	syncService, err := newSyncService(context.Background(), cfg, bot, watcher, logger, nil)
+	if err != nil {
+		return err
+	}
+
+	dirs := make([]string, 0, len(cfg.Directories))
+
+	for _, dir := range cfg.Directories {
+		whitelist, blacklist, err := dir.Filters()
+		if err != nil {
+			return fmt.Errorf("directory %s: %w", dir.Path, err)
+		}
+
+		if err := watcher.AddDirWithFilters(dir.Path, whitelist, blacklist); err != nil {
+			return fmt.Errorf("directory %s: %w", dir.Path, err)
+		}
+
+		syncService.SetDirChatID(dir.Path, dir.ChatID)
+		syncService.SetDirTopic(dir.Path, dir.TopicID)
+
+		dirs = append(dirs, dir.Path)
+	}
+
+	report, err := syncService.Reconcile(dirs, *fix)
+
+	fmt.Fprintln(os.Stdout, report.String())
+
+	if *notify {
+		if err := syncService.Announce(report.String()); err != nil {
+			return err
+		}
+	}
+
+	return err
+}
+
+// deadLetters lists the dead-lettered files, or with "clear <path>..." drops them so they are retried.
+// It needs the dead-letter file.
+func deadLetters(configPath string, args []string, logger *slog.Logger) error {
+	cfg, err := config.New(configPath)
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+
+	if cfg.DeadLetterFile == "" {
+		return errors.New("dead-letters needs a dead-letter file, set deadLetterFile or TELEGRAM_DEAD_LETTER_FILE")
+	}
+
+	// clearing forgets the file in the watcher state, so that the next run uploads it again
+	watcher := file.NewWatcher()
+	watcher.Logger = logger
+
+	if cfg.StateFile != "" {
+		store, err := state.NewFileStore[file.FileState](cfg.StateFile)
+		if err != nil {
+			return err
+		}
+
+		if err := watcher.SetStateStore(store); err != nil {
+			return err
+		}
+	}
+
+	bot, err := newBot(cfg, logger, nil)
+	if err != nil {
+		return err
+	}
+
+	syncService, err := newSyncService(context.Background(), cfg, bot, watcher, logger, nil)
+	if err != nil {
+		return err
+	}
+
+	if len(args) == 0 {
+		letters, err := syncService.DeadLetters()
+		if err != nil {
+			return err
+		}
+
+		for path, letter := range letters {
+			fmt.Fprintf(os.Stdout, "%s\t%d attempts since %s\t%s\n",
+				path, letter.Attempts, letter.Since.Format(time.DateTime), letter.Error)
+		}
+
+		return nil
+	}
+
+	if args[0] != "clear" {
+		return fmt.Errorf("unknown dead-letters command %q", args[0])
+	}
+
+	for _, path := range args[1:] {
+		if err := syncService.ClearDeadLetter(path); err != nil {
+			return err
+		}
+
+		logger.Info("cleared", "file", path)
+	}
+
+	return nil
+}
+
+// newSyncService creates the sync service configured by cfg sending through bot, m may be nil.
+// Cancelling ctx stops the service, its uploads in progress and their waits for a flood limit.
+func newSyncService(
+	ctx context.Context, cfg *config.Config, bot telegram.Bot, watcher file.Watcher, logger *slog.Logger,
+	m *metrics.Metrics,
+) (*syncer.SyncService, error) {
+	encryptionKey, err := encryption.LoadKey(cfg.EncryptionKey, cfg.EncryptionKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("load encryption key: %w", err)
+	}
+
+	var classifier syncer.Classifier = syncer.ContentClassifier{}
+	if cfg.DetectByExtension {
+		classifier = syncer.ExtensionClassifier{}
+	}
+
+	syncService := syncer.NewSyncService(bot, watcher,
+		syncer.WithChatID(cfg.ChatID),
+		syncer.WithLogger(logger),
+		syncer.WithContext(ctx),
+		syncer.WithClassifier(classifier),
+	)
+	syncService.SetDryRun(cfg.DryRun, cfg.DryRunKeepState)
+	syncService.SetPreferVoice(cfg.PreferVoice)
+	syncService.SetPhotoLimits(cfg.PhotoMaxSide, cfg.PhotoMaxSize)
+	syncService.SetSiblingThumbnails(cfg.SiblingThumbnails)
+	syncService.SetAudioTagsFromName(cfg.AudioTagsFromName)
+	syncService.SetChatActions(!cfg.DisableChatActions)
+	syncService.SetUploadQueueSize(cfg.UploadQueueSize)
+	syncService.SetBatchDigest(cfg.BatchDigestThreshold, cfg.BatchDigestNoCaptions)
+	syncService.SetJitter(cfg.SyncJitter)
+	syncService.SetChunkSize(cfg.ChunkSize)
+	syncService.SetRenameDetection(cfg.DetectRenames, cfg.RenameEditCaption)
+	syncService.SetMirrorChats(cfg.ChatIDs...)
+	syncService.SetCompression(cfg.Compress)
+	syncService.SetReplyThreads(cfg.ReplyThreads)
+	syncService.SetAlbums(cfg.Albums)
+	syncService.SetDisableNotification(cfg.DisableNotification)
+	syncService.SetEncryptionKey(encryptionKey)
+	syncService.SetEditOnResync(cfg.EditOnResync)
+	syncService.SetReplaceOnResync(cfg.ReplaceOnResync)
+	syncService.SetMetrics(m)
+
+	order, err := syncer.ParseSyncOrder(cfg.SyncOrder)
+	if err != nil {
+		return nil, err
+	}
+
+	syncService.SetSyncOrder(order)
+
+	if cfg.CaptionTemplate != nil {
+		captionTemplate, err := syncer.ParseCaptionTemplate(*cfg.CaptionTemplate)
+		if err != nil {
+			return nil, err
+		}
+
+		syncService.SetCaptionTemplate(captionTemplate)
+	}
+
+	instance, _ := os.Hostname()
+	if cfg.InstanceName != nil {
+		instance = *cfg.InstanceName
+	}
+
+	syncService.SetInstance(instance, cfg.InstanceInPath)
+
+	if cfg.QuietHours != (config.QuietHours{}) {
+		quietHours, err := syncer.ParseQuietHours(cfg.QuietHours.Start, cfg.QuietHours.End)
+		if err != nil {
+			return nil, fmt.Errorf("quiet hours: %w", err)
+		}
+
+		syncService.SetQuietHours(quietHours)
+	}
+
+	if cfg.ErrorAlerts {
+		syncService.SetErrorAlerts(cfg.AlertChatID, cfg.AlertCooldown)
+	}
+
+	syncService.SetMissingDirs(cfg.MissingDirGrace, cfg.ReAddMissingDirs)
+	syncService.SetRetryBudget(cfg.RetryBudget)
+	syncService.SetQuota(cfg.Quota, cfg.QuotaWindow)
+
+	if err := setStateFiles(cfg, syncService); err != nil {
+		return nil, err
+	}
+
+	return syncService, nil
+}
+
+// setStateFiles opens the files the sync service keeps its state in, the unset ones are skipped.
+func setStateFiles(cfg *config.Config, syncService *syncer.SyncService) error {
+	if cfg.IndexFile != "" {
+		if err := syncService.SetIndexFile(cfg.IndexFile); err != nil {
+			return err
+		}
+	}
+
+	if cfg.ChunkProgressFile != "" {
+		if err := syncService.SetChunkProgressFile(cfg.ChunkProgressFile); err != nil {
+			return err
+		}
+	}
+
+	if cfg.DeadLetterFile != "" {
+		if err := syncService.SetDeadLetterFile(cfg.DeadLetterFile); err != nil {
+			return err
+		}
+	}
+
+	if cfg.UsageFile != "" {
+		if err := syncService.SetUsageFile(cfg.UsageFile); err != nil {
+			return err
+		}
+	}
+
+	if cfg.Dedup {
+		if err := syncService.SetDedupFile(cfg.DedupFile); err != nil {
+			return err
+		}
+
+		syncService.SetDedupCacheSize(cfg.DedupCacheSize)
+	}
+
+	return nil
+}
+
+// newBot returns the bot of cfg, m counts its requests if not nil.
+func newBot(cfg *config.Config, logger *slog.Logger, m *metrics.Metrics) (*telegram.IBot, error) {
+	transport, err := telegram.NewTransport(cfg.Proxy)
+	if err != nil {
+		return nil, err
+	}
+
+	var roundTripper http.RoundTripper = transport
+	if m != nil {
+		roundTripper = m.RoundTripper(transport)
+	}
+
+	captionOverflow := telegram.CaptionTruncate
+	if cfg.SplitLongCaptions {
+		captionOverflow = telegram.CaptionSplit
+	}
+
+	botOpts := []telegram.Option{
+		telegram.WithLogger(logger),
+		telegram.WithHTTPClient(&http.Client{Transport: roundTripper}),
+		telegram.WithUploadRateLimit(cfg.UploadRateLimit),
+		telegram.WithProgress(syncer.ProgressLogger(logger)),
+		telegram.WithCaptionOverflow(captionOverflow),
+		telegram.WithMaxFileSize(cfg.MaxFileSize),
+		telegram.WithRetries(cfg.APIRetries),
+	}
+
+	if cfg.APIURL != "" {
+		botOpts = append(botOpts, telegram.WithAPIURL(cfg.APIURL))
+	}
+
+	if cfg.FileURL != "" {
+		botOpts = append(botOpts, telegram.WithFileURL(cfg.FileURL))
+	}
+
+	if cfg.LocalFiles {
+		botOpts = append(botOpts, telegram.WithLocalFiles())
+	}
+
+	return telegram.NewBot(cfg.BotToken, botOpts...), nil
 }
@@ -0,0 +1,11 @@
+//go:build !unix
+
+package main
+
+import "os"
+
+// notifySyncNow does nothing, there is no SIGUSR1.
+func notifySyncNow(chan<- os.Signal) {}
+
+// notifyTogglePause does nothing, there is no SIGUSR2.
+func notifyTogglePause(chan<- os.Signal) {}
@@ -0,0 +1,19 @@
+//go:build unix
+
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// notifySyncNow relays SIGUSR1 to ch, e.g. `kill -USR1 <pid>` syncs all directories right away.
+func notifySyncNow(ch chan<- os.Signal) {
+	signal.Notify(ch, syscall.SIGUSR1)
+}
+
+// notifyTogglePause relays SIGUSR2 to ch, e.g. `kill -USR2 <pid>` pauses the sync or resumes it.
+func notifyTogglePause(ch chan<- os.Signal) {
+	signal.Notify(ch, syscall.SIGUSR2)
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/k0ff1l/tgcloudbot/internal/config"
+	"github.com/k0ff1l/tgcloudbot/internal/services/commands"
+	"github.com/k0ff1l/tgcloudbot/internal/services/telegram"
+)
+
+const (
+	webhookReadHeaderTimeout = 5 * time.Second
+	webhookShutdownTimeout   = 5 * time.Second
+)
+
+// allowedUpdates are the update types the commands need.
+//
+//nolint:gochecknoglobals // read-only list
+var allowedUpdates = []string{"message", "channel_post"}
+
+// receiveUpdates answers the commands of the chats of cfg by polling or a webhook, see config.Updates,
+// until ctx is done. bot is the one of the uploads, so that the replies and polls wait for the flood
+// and rate limits hit by them and the reverse.
+func receiveUpdates(
+	ctx context.Context, cfg *config.Config, bot *telegram.IBot, service commands.Service, logger *slog.Logger,
+) error {
+	// commands of groups may name the bot, "/status@name"
+	var username string
+	if me, err := bot.GetMe(ctx); err != nil {
+		logger.Warn("failed to get the bot username, commands to other bots are answered too", "error", err)
+	} else {
+		username = me.Username
+	}
+
+	dispatcher := telegram.NewDispatcher(username)
+	commands.New(service, bot, commandChats(cfg), logger).Register(dispatcher)
+
+	// a command runs while the next updates are received, e.g. /status during a long /sync,
+	// the ones in progress are waited for before returning
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	dispatch := func(ctx context.Context, update telegram.Update) {
+		wg.Go(func() { dispatcher.Dispatch(ctx, update) })
+	}
+
+	if cfg.Updates == config.UpdatesWebhook {
+		return serveWebhook(ctx, cfg, bot, dispatch)
+	}
+
+	// getUpdates fails while a webhook is set, e.g. by an earlier run in webhook mode
+	if err := bot.DeleteWebhook(ctx, false); err != nil {
+		return fmt.Errorf("delete webhook: %w", err)
+	}
+
+	err := telegram.NewPoller(bot, dispatch, telegram.PollAllowedUpdates(allowedUpdates...)).Run(ctx)
+	if errors.Is(err, context.Canceled) {
+		return nil
+	}
+
+	return fmt.Errorf("poll updates: %w", err)
+}
+
+// serveWebhook sets the webhook of cfg and serves it until ctx is done, passing the updates to dispatch.
+// The webhook is kept on shutdown, Telegram holds the updates until the next start.
+func serveWebhook(
+	ctx context.Context, cfg *config.Config, bot *telegram.IBot, dispatch func(context.Context, telegram.Update),
+) error {
+	err := bot.SetWebhook(ctx, cfg.WebhookURL,
+		telegram.SecretToken(cfg.WebhookSecret), telegram.AllowedUpdates(allowedUpdates...))
+	if err != nil {
+		return fmt.Errorf("set webhook: %w", err)
+	}
+
+	// validated by config.New
+	u, _ := url.Parse(cfg.WebhookURL)
+
+	// Telegram retries updates not answered quickly, e.g. while /sync runs
+	handler := telegram.NewWebhookHandler(cfg.WebhookSecret, func(update telegram.Update) {
+		dispatch(ctx, update)
+	})
+
+	mux := http.NewServeMux()
+	mux.Handle(cmp.Or(u.Path, "/"), handler)
+
+	srv := &http.Server{Addr: cfg.WebhookListen, Handler: mux, ReadHeaderTimeout: webhookReadHeaderTimeout}
+
+	errCh := make(chan error, 1)
+
+	go func() {
+		errCh <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		return fmt.Errorf("webhook server: %w", err)
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), webhookShutdownTimeout)
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("shutdown webhook server: %w", err)
+	}
+
+	if err := <-errCh; !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("webhook server: %w", err)
+	}
+
+	return nil
+}
+
+// commandChats returns the chats allowed to send commands, the chats the files are sent to.
+func commandChats(cfg *config.Config) []string {
+	chats := append([]string{cfg.ChatID}, cfg.ChatIDs...)
+
+	for _, dir := range cfg.Directories {
+		chats = append(chats, dir.ChatID)
+	}
+
+	return chats
+}
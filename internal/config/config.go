@@ -1,13 +1,771 @@
 package config
 
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	defaultSyncInterval = 10 * time.Second
+	// defaultMaxFileSize is the upload limit of the public Bot API, localMaxFileSize the one of a local server.
+	defaultMaxFileSize = 50 << 20
+	localMaxFileSize   = 2000 << 20
+	defaultAPIRetries  = 3
+)
+
+// The values of StartupMode.
+const (
+	StartupFull        = "full"
+	StartupChangesOnly = "changes-only"
+)
+
+// The values of Updates.
+const (
+	UpdatesPolling = "polling"
+	UpdatesWebhook = "webhook"
+)
+
+// webhookSecretPattern [https://core.telegram.org/bots/api#setwebhook]
+var webhookSecretPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,256}$`)
+
 type Config struct {
-	BotToken string
+	BotToken string `yaml:"botToken"`
+	ChatID   string `yaml:"chatId"`
+	// ChatIDs are more chats every file is sent to as well, the first one is the chat if ChatID is empty.
+	ChatIDs []string `yaml:"chatIds"`
+
+	// APIURL and FileURL point the bot at a local Bot API server, e.g. "http://localhost:8081/bot",
+	// FileURL defaults to the "/file/bot" path of the APIURL server.
+	APIURL  string `yaml:"apiUrl"`
+	FileURL string `yaml:"fileUrl"`
+	// LocalFiles sends files to the APIURL server by their file:// path instead of uploading them,
+	// the server must run with --local and see the watched directories at the same paths.
+	LocalFiles bool `yaml:"localFiles"`
+
+	SyncInterval time.Duration `yaml:"syncInterval"`
+	// SyncJitter spreads the syncs of the directories by a random part of up to that fraction of their interval,
+	// from 0 (disabled) to 1.
+	SyncJitter float64 `yaml:"syncJitter"`
+
+	// Proxy is the http://, https:// or socks5:// proxy to reach the Bot API through,
+	// empty falls back to HTTPS_PROXY.
+	Proxy string `yaml:"proxy"`
+
+	// Whitelist and Blacklist are the default path regexps of directories that don't set their own.
+	Whitelist []string `yaml:"whitelist"`
+	Blacklist []string `yaml:"blacklist"`
+
+	Directories []Directory `yaml:"directories"`
+
+	// ForceKind is the default of Directory.ForceKind.
+	ForceKind string `yaml:"forceKind"`
+	// DetectByExtension classifies files by extension only instead of sniffing their content.
+	DetectByExtension bool `yaml:"detectByExtension"`
+	// PreferVoice sends .ogg/.opus audio as voice messages.
+	PreferVoice bool `yaml:"preferVoice"`
+	// PhotoMaxSide and PhotoMaxSize send images wider or higher than that many pixels, or larger than that
+	// many bytes, as documents so that Telegram keeps the original. 0 disables either.
+	PhotoMaxSide int   `yaml:"photoMaxSide"`
+	PhotoMaxSize int64 `yaml:"photoMaxSize"`
+	// SiblingThumbnails sends "name.jpg" as the preview of the video or document "name.ext".
+	SiblingThumbnails bool `yaml:"siblingThumbnails"`
+	// AudioTagsFromName sends the performer and title of audio files from names like "Performer - Title.mp3".
+	AudioTagsFromName bool `yaml:"audioTagsFromName"`
+
+	// HashVerification re-uploads a touched file only when its content changed.
+	HashVerification bool `yaml:"hashVerification"`
+
+	// Debounce holds back further changes of a file for this long after it was synced, 0 disables it.
+	// With HashVerification a change of the content is synced at once.
+	Debounce time.Duration `yaml:"debounce"`
+
+	// FollowSymlinks descends into symlinked directories of the watched ones.
+	FollowSymlinks bool `yaml:"followSymlinks"`
+
+	// DisableDefaultIgnores also syncs hidden files and the temporary, swap and lock files
+	// that are skipped by default.
+	DisableDefaultIgnores bool `yaml:"disableDefaultIgnores"`
+
+	// MaxDepth is how many directory levels of a watched directory are scanned, 0 means no limit.
+	MaxDepth int `yaml:"maxDepth"`
+	// ExcludeDirs are name patterns of subdirectories that are not scanned, e.g. "node_modules".
+	ExcludeDirs []string `yaml:"excludeDirs"`
+
+	// MaxFileSize skips larger files, 0 keeps the limit of the Bot API: 50MB, or 2000MB with an APIURL server.
+	// Without APIURL it can't be over 50MB.
+	// Without chunking, the chat of a skipped file is told about it once.
+	MaxFileSize int64 `yaml:"maxFileSize"`
+	// SkipEmptyFiles skips zero-byte files until they get content.
+	SkipEmptyFiles bool `yaml:"skipEmptyFiles"`
+
+	// ChunkSize uploads files larger than it in chunks of that size instead of skipping them, 0 disables it.
+	// It must stay below MaxFileSize, and at 20MB at most for the chunks to be restorable without APIURL.
+	// ChunkProgressFile keeps the chunks uploaded so far across restarts.
+	ChunkSize         int64  `yaml:"chunkSize"`
+	ChunkProgressFile string `yaml:"chunkProgressFile"`
+
+	// StartupMode is what the first sync of a directory uploads: StartupFull (default) all its files,
+	// StartupChangesOnly only the files added or changed after the start. A directory with files
+	// recorded in the StateFile of an earlier run is never primed.
+	StartupMode string `yaml:"startupMode"`
+
+	// SyncOrder is the upload order within a sync batch: "path" (default), "newest" or "smallest".
+	SyncOrder string `yaml:"syncOrder"`
+
+	// UploadQueueSize is how many files may be queued or uploading at once, 0 keeps the default of 64.
+	// Syncs wait while it is full.
+	UploadQueueSize int `yaml:"uploadQueueSize"`
+
+	// UploadRateLimit caps the upload speed in bytes per second, 0 means unlimited.
+	UploadRateLimit int64 `yaml:"uploadRateLimit"`
+
+	// APIRetries sends a Bot API request failed by a network or server error again up to that many times,
+	// with a growing delay, before the upload counts as failed. It defaults to 3, 0 disables it.
+	APIRetries int `yaml:"apiRetries"`
+
+	// BatchDigestThreshold announces a sync batch of at least that many files, e.g. the first scan of a full
+	// directory, with one message listing them and uploads them one at a time, without captions if
+	// BatchDigestNoCaptions is set. 0 disables it.
+	BatchDigestThreshold  int  `yaml:"batchDigestThreshold"`
+	BatchDigestNoCaptions bool `yaml:"batchDigestNoCaptions"`
+
+	// AnnounceStartup and AnnounceShutdown send StartupMessage and ShutdownMessage to the chat on every start
+	// and stop. The messages are text/templates with the variables .Hostname and .Version, they default to
+	// "Bot {{.Version}} started" and "Bot {{.Version}} stopped".
+	AnnounceStartup  bool   `yaml:"announceStartup"`
+	AnnounceShutdown bool   `yaml:"announceShutdown"`
+	StartupMessage   string `yaml:"startupMessage"`
+	ShutdownMessage  string `yaml:"shutdownMessage"`
+
+	// SummaryInterval is how often a summary of the sync activity is sent to the chat, 0 disables it.
+	SummaryInterval time.Duration `yaml:"summaryInterval"`
+	// SummaryInPlace edits the first summary message instead of sending a new one every interval.
+	SummaryInPlace bool `yaml:"summaryInPlace"`
+
+	// IndexFile persists which message every local file was uploaded as, empty keeps it in memory only.
+	IndexFile string `yaml:"indexFile"`
+	// StateFile persists the state of the watched files, so that a restart doesn't upload them again.
+	// Empty keeps it in memory only.
+	StateFile string `yaml:"stateFile"`
+	// Dedup sends a file with the content of an already uploaded one by its file_id instead of uploading it again.
+	// DedupFile persists the content hashes of the uploads, empty keeps them in memory only.
+	// DedupCacheSize keeps that many recently used hashes in memory in front of the file, 0 disables the cache.
+	Dedup          bool   `yaml:"dedup"`
+	DedupFile      string `yaml:"dedupFile"`
+	DedupCacheSize int    `yaml:"dedupCacheSize"`
+	// DetectRenames keeps a renamed or moved file from being uploaded again, its index entry follows it.
+	// RenameEditCaption also updates the caption of its message to the new path.
+	DetectRenames     bool `yaml:"detectRenames"`
+	RenameEditCaption bool `yaml:"renameEditCaption"`
+	// EditOnResync updates the caption of the message of a modified file instead of uploading it again.
+	EditOnResync bool `yaml:"editOnResync"`
+	// ReplaceOnResync uploads a modified file into its message instead of posting a new one,
+	// EditOnResync takes precedence.
+	ReplaceOnResync bool `yaml:"replaceOnResync"`
+
+	// CaptionTemplate is the text/template of the file captions with the variables .Name, .RelPath, .Size,
+	// .ModTime and .Hash. Unset keeps the default "File: {{.RelPath}}", empty sends no caption.
+	CaptionTemplate *string `yaml:"captionTemplate"`
+
+	// InstanceName tells apart the machines that back up into the same chat, it is prepended to the captions
+	// as "[name]" and recorded in the index. Unset defaults to the hostname, empty disables it.
+	// InstanceInPath also puts it in front of the indexed relative paths, restores get a directory per machine.
+	InstanceName   *string `yaml:"instanceName"`
+	InstanceInPath bool    `yaml:"instanceInPath"`
+
+	// SplitLongCaptions sends the part of a caption over 1024 characters as a reply message instead of truncating it.
+	SplitLongCaptions bool `yaml:"splitLongCaptions"`
+
+	// ReplyThreads posts a header message per directory and sync batch and sends the files as replies to it.
+	ReplyThreads bool `yaml:"replyThreads"`
+	// Albums sends the new photos and videos of a directory found in one tick as albums of up to 10 files.
+	Albums bool `yaml:"albums"`
+
+	// ProtectContent and Spoiler are the defaults of the directories, see Directory.
+	ProtectContent bool `yaml:"protectContent"`
+	Spoiler        bool `yaml:"spoiler"`
+
+	// DisableNotification sends all messages silently.
+	DisableNotification bool `yaml:"disableNotification"`
+	// DisableChatActions hides the "sending file..." status the chat shows during uploads.
+	DisableChatActions bool `yaml:"disableChatActions"`
+	// QuietHours sends messages silently during a daily period of the local time.
+	QuietHours QuietHours `yaml:"quietHours"`
+
+	// Compress gzips compressible documents before upload.
+	Compress bool `yaml:"compress"`
+
+	// EncryptionKey is a base64 AES key (16, 24 or 32 bytes), EncryptionKeyFile a file holding it.
+	// Files are uploaded unencrypted when neither is set.
+	EncryptionKey     string `yaml:"encryptionKey"`
+	EncryptionKeyFile string `yaml:"encryptionKeyFile"`
+
+	// ErrorAlerts sends errors that persist to AlertChatID, the chat if empty, at most once per AlertCooldown
+	// (default 1h) for the same error of a file or directory.
+	ErrorAlerts   bool          `yaml:"errorAlerts"`
+	AlertChatID   string        `yaml:"alertChatId"`
+	AlertCooldown time.Duration `yaml:"alertCooldown"`
+
+	// A watched directory that disappears is logged and alerted once and synced again when it is back.
+	// MissingDirGrace removes it once it has been missing that long, 0 keeps it. ReAddMissingDirs still
+	// checks for a removed directory and adds it again when it reappears, e.g. a network mount.
+	MissingDirGrace  time.Duration `yaml:"missingDirGrace"`
+	ReAddMissingDirs bool          `yaml:"reAddMissingDirs"`
+
+	// RetryBudget gives up on a file after that many failed attempts in a row until it changes, 0 retries forever.
+	// DeadLetterFile keeps the given up files across restarts, empty keeps them in memory only.
+	RetryBudget    int    `yaml:"retryBudget"`
+	DeadLetterFile string `yaml:"deadLetterFile"`
+
+	// Quota caps the bytes uploaded per QuotaWindow (default 24h), 0 disables it.
+	// UsageFile keeps the uploaded bytes and the window across restarts, empty keeps them in memory only.
+	Quota       int64         `yaml:"quota"`
+	QuotaWindow time.Duration `yaml:"quotaWindow"`
+	UsageFile   string        `yaml:"usageFile"`
+
+	// DryRun logs what would be synced without uploading anything.
+	DryRun bool `yaml:"dryRun"`
+	// DryRunKeepState leaves the watcher state untouched during a dry run.
+	DryRunKeepState bool `yaml:"dryRunKeepState"`
+
+	// MetricsPort is the port of the Prometheus /metrics endpoint, 0 disables it.
+	MetricsPort int `yaml:"metricsPort"`
+	// AdminPort is the port of the HTTP admin API, 0 disables it. AdminToken is the bearer token
+	// every request needs, it must be set to enable the API.
+	AdminPort  int    `yaml:"adminPort"`
+	AdminToken string `yaml:"adminToken"`
+
+	// Updates is how the bot receives the commands of its chats, e.g. /status: UpdatesPolling, UpdatesWebhook
+	// or empty to ignore them. WebhookURL is the public HTTPS url Telegram posts the updates to,
+	// WebhookListen the local address serving it, e.g. ":8443" behind a reverse proxy.
+	// WebhookSecret is sent with every update to authenticate it.
+	Updates       string `yaml:"updates"`
+	WebhookURL    string `yaml:"webhookUrl"`
+	WebhookListen string `yaml:"webhookListen"`
+	WebhookSecret string `yaml:"webhookSecret"`
+}
+
+// QuietHours is a daily period as "15:04" times, End before Start spans midnight, e.g. 22:00-07:00.
+type QuietHours struct {
+	Start string `yaml:"start"`
+	End   string `yaml:"end"`
 }
 
-func New() *Config {
-	// YAML config parse
+// Directory is a watched directory with its own settings.
+type Directory struct {
+	Path string `yaml:"path"`
+	// Interval defaults to Config.SyncInterval
+	Interval  time.Duration `yaml:"interval"`
+	ChatID    string        `yaml:"chatId"`
+	Whitelist []string      `yaml:"whitelist"`
+	Blacklist []string      `yaml:"blacklist"`
+	// ProtectContent keeps the files from being forwarded and saved, Spoiler blurs photos and videos.
+	// Either is enabled when set here or in Config.
+	ProtectContent bool `yaml:"protectContent"`
+	Spoiler        bool `yaml:"spoiler"`
+	// ForceKind sends all files as "document", "photo", "audio", "video" or "animation" instead of detecting
+	// the kind, "auto" (default) detects it. Documents keep photos from being recompressed.
+	ForceKind string `yaml:"forceKind"`
+	// TopicID is the forum topic (message_thread_id) of ChatID the files go to, 0 is the general topic.
+	TopicID int64 `yaml:"topicId"`
+}
+
+// Filters compiles the whitelist and blacklist of the directory.
+func (d Directory) Filters() (whitelist, blacklist []*regexp.Regexp, err error) {
+	if whitelist, err = compileAll(d.Whitelist); err != nil {
+		return nil, nil, fmt.Errorf("directory %s whitelist: %w", d.Path, err)
+	}
+
+	if blacklist, err = compileAll(d.Blacklist); err != nil {
+		return nil, nil, fmt.Errorf("directory %s blacklist: %w", d.Path, err)
+	}
+
+	return whitelist, blacklist, nil
+}
 
+// New loads the YAML config file at path (if not empty) and applies environment variables on top of it.
+func New(path string) (*Config, error) {
 	// godotenv parse for credentials (sensitive information)
 
-	return &Config{}
+	cfg := &Config{
+		SyncInterval: defaultSyncInterval,
+		APIRetries:   defaultAPIRetries,
+	}
+
+	if path != "" {
+		if err := cfg.loadFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := cfg.loadEnv(); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.resolveAPIURLs(); err != nil {
+		return nil, err
+	}
+
+	// a local Bot API server accepts larger files
+	if cfg.APIURL != "" && cfg.MaxFileSize == 0 {
+		cfg.MaxFileSize = localMaxFileSize
+	}
+
+	validators := []func() error{
+		cfg.validateUploads,
+		cfg.validateLimits,
+		cfg.validateUpdates,
+		cfg.validateTemplates,
+		cfg.validateSettings,
+		cfg.resolveDirectories,
+	}
+
+	for _, validate := range validators {
+		if err := validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	return cfg, nil
+}
+
+// validateUploads checks the limits of the uploaded files.
+func (c *Config) validateUploads() error {
+	// the public Bot API rejects larger files, they would fail on every sync
+	if c.APIURL == "" && c.MaxFileSize > defaultMaxFileSize {
+		return fmt.Errorf("maxFileSize %d is over the %d bytes upload limit of the Bot API, larger files need apiUrl",
+			c.MaxFileSize, defaultMaxFileSize)
+	}
+
+	uploadLimit := c.MaxFileSize
+	if uploadLimit <= 0 {
+		uploadLimit = defaultMaxFileSize
+	}
+
+	if c.PhotoMaxSide < 0 || c.PhotoMaxSize < 0 {
+		return fmt.Errorf("invalid photo limits %d px, %d bytes", c.PhotoMaxSide, c.PhotoMaxSize)
+	}
+
+	if c.ChunkSize < 0 || c.ChunkSize >= uploadLimit {
+		return fmt.Errorf("invalid chunkSize %d, it must be below the upload limit of %d bytes",
+			c.ChunkSize, uploadLimit)
+	}
+
+	return nil
+}
+
+// validateLimits checks the counts, durations and fractions that must not be negative.
+func (c *Config) validateLimits() error {
+	if c.Quota < 0 || c.QuotaWindow < 0 {
+		return fmt.Errorf("invalid quota %d per %s", c.Quota, c.QuotaWindow)
+	}
+
+	if c.MissingDirGrace < 0 {
+		return fmt.Errorf("invalid missingDirGrace %s", c.MissingDirGrace)
+	}
+
+	if c.APIRetries < 0 {
+		return fmt.Errorf("invalid apiRetries %d", c.APIRetries)
+	}
+
+	if c.DedupCacheSize < 0 {
+		return fmt.Errorf("invalid dedupCacheSize %d", c.DedupCacheSize)
+	}
+
+	if c.BatchDigestThreshold < 0 {
+		return fmt.Errorf("invalid batchDigestThreshold %d", c.BatchDigestThreshold)
+	}
+
+	if c.SyncJitter < 0 || c.SyncJitter > 1 {
+		return fmt.Errorf("invalid syncJitter %v, want a fraction from 0 to 1", c.SyncJitter)
+	}
+
+	return nil
+}
+
+// validateTemplates parses the text/templates of the messages and the captions.
+func (c *Config) validateTemplates() error {
+	messages := map[string]string{"startupMessage": c.StartupMessage, "shutdownMessage": c.ShutdownMessage}
+	for name, text := range messages {
+		if _, err := template.New(name).Parse(text); err != nil {
+			return fmt.Errorf("invalid %s: %w", name, err)
+		}
+	}
+
+	if c.CaptionTemplate != nil {
+		if _, err := template.New("caption").Parse(*c.CaptionTemplate); err != nil {
+			return fmt.Errorf("invalid captionTemplate: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// validateSettings checks the settings with a fixed set or form of values.
+func (c *Config) validateSettings() error {
+	if c.AdminPort > 0 && c.AdminToken == "" {
+		return fmt.Errorf("adminPort %d needs an adminToken, the admin API is never served without one", c.AdminPort)
+	}
+
+	// the name becomes a directory of the restored files
+	if c.InstanceName != nil && *c.InstanceName != "" && !isPathElement(*c.InstanceName) {
+		return fmt.Errorf("invalid instanceName %q, it must not contain a path separator", *c.InstanceName)
+	}
+
+	switch c.StartupMode {
+	case "", StartupFull, StartupChangesOnly:
+	default:
+		return fmt.Errorf("invalid startupMode %q, want %q or %q", c.StartupMode, StartupFull, StartupChangesOnly)
+	}
+
+	for _, pattern := range c.ExcludeDirs {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid excludeDirs pattern %q: %w", pattern, err)
+		}
+	}
+
+	return nil
+}
+
+// resolveDirectories fills the unset settings of the directories with the global ones and validates them.
+func (c *Config) resolveDirectories() error {
+	if c.ChatID == "" && len(c.ChatIDs) > 0 {
+		c.ChatID, c.ChatIDs = c.ChatIDs[0], c.ChatIDs[1:]
+	}
+
+	for i := range c.Directories {
+		dir := &c.Directories[i]
+
+		if dir.Interval <= 0 {
+			dir.Interval = c.SyncInterval
+		}
+
+		if dir.ChatID == "" {
+			dir.ChatID = c.ChatID
+		}
+
+		if dir.Whitelist == nil {
+			dir.Whitelist = c.Whitelist
+		}
+
+		if dir.Blacklist == nil {
+			dir.Blacklist = c.Blacklist
+		}
+
+		if dir.ForceKind == "" {
+			dir.ForceKind = c.ForceKind
+		}
+
+		dir.ProtectContent = dir.ProtectContent || c.ProtectContent
+		dir.Spoiler = dir.Spoiler || c.Spoiler
+
+		if dir.TopicID < 0 {
+			return fmt.Errorf("directory %s: invalid topic id %d", dir.Path, dir.TopicID)
+		}
+
+		if _, _, err := dir.Filters(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// resolveAPIURLs validates APIURL and FileURL and derives FileURL from APIURL if not set.
+func (c *Config) resolveAPIURLs() error {
+	if c.APIURL == "" {
+		if c.LocalFiles {
+			return errors.New("localFiles needs apiUrl, the local Bot API server reading the files")
+		}
+
+		if c.FileURL != "" {
+			return validateURL("file url", c.FileURL)
+		}
+
+		return nil
+	}
+
+	if err := validateURL("api url", c.APIURL); err != nil {
+		return err
+	}
+
+	if c.FileURL == "" {
+		u, _ := url.Parse(c.APIURL)
+		u.Path = strings.TrimSuffix(strings.TrimSuffix(u.Path, "/"), "/bot") + "/file/bot"
+		c.FileURL = u.String()
+	}
+
+	return validateURL("file url", c.FileURL)
+}
+
+func (c *Config) validateUpdates() error {
+	switch c.Updates {
+	case "", UpdatesPolling:
+		return nil
+	case UpdatesWebhook:
+	default:
+		return fmt.Errorf("invalid updates %q, want %q or %q", c.Updates, UpdatesPolling, UpdatesWebhook)
+	}
+
+	if u, err := url.Parse(c.WebhookURL); err != nil || u.Scheme != "https" || u.Host == "" {
+		return fmt.Errorf("invalid webhookUrl %q: expected https://host/...", c.WebhookURL)
+	}
+
+	if c.WebhookListen == "" {
+		return errors.New("the webhook needs webhookListen, the local address serving webhookUrl")
+	}
+
+	if c.WebhookSecret != "" && !webhookSecretPattern.MatchString(c.WebhookSecret) {
+		return errors.New("invalid webhookSecret: 1-256 characters of A-Z, a-z, 0-9, _ and -")
+	}
+
+	return nil
+}
+
+func validateURL(name, s string) error {
+	u, err := url.Parse(s)
+	if err != nil {
+		return fmt.Errorf("invalid %s: %w", name, err)
+	}
+
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid %s %q: expected http(s)://host/...", name, s)
+	}
+
+	return nil
+}
+
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read config file: %w", err)
+	}
+
+	if err := yaml.Unmarshal(data, c); err != nil {
+		return fmt.Errorf("parse config file %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// loadEnv overrides the values of the variables that are set, a number or duration that doesn't parse
+// is an error.
+func (c *Config) loadEnv() error {
+	envString(&c.BotToken, "TELEGRAM_BOT_TOKEN")
+	envString(&c.ChatID, "TELEGRAM_CHAT_ID")
+	envList(&c.ChatIDs, "TELEGRAM_CHAT_IDS")
+	envString(&c.APIURL, "TELEGRAM_API_URL")
+	envString(&c.FileURL, "TELEGRAM_FILE_URL")
+	envBool(&c.LocalFiles, "TELEGRAM_LOCAL_FILES")
+	envString(&c.Proxy, "TELEGRAM_PROXY")
+	envList(&c.Whitelist, "WHITELIST_REGEXP")
+	envList(&c.Blacklist, "BLACKLIST_REGEXP")
+	envString(&c.ForceKind, "TELEGRAM_FORCE_KIND")
+	envBool(&c.DetectByExtension, "TELEGRAM_DETECT_BY_EXTENSION")
+	envBool(&c.PreferVoice, "TELEGRAM_PREFER_VOICE")
+	envBool(&c.SiblingThumbnails, "TELEGRAM_SIBLING_THUMBNAILS")
+	envBool(&c.AudioTagsFromName, "TELEGRAM_AUDIO_TAGS_FROM_NAME")
+	envBool(&c.HashVerification, "TELEGRAM_HASH_VERIFICATION")
+	envBool(&c.FollowSymlinks, "TELEGRAM_FOLLOW_SYMLINKS")
+	envBool(&c.DisableDefaultIgnores, "TELEGRAM_DISABLE_DEFAULT_IGNORES")
+	envList(&c.ExcludeDirs, "TELEGRAM_EXCLUDE_DIRS")
+	envBool(&c.SkipEmptyFiles, "TELEGRAM_SKIP_EMPTY_FILES")
+	envString(&c.ChunkProgressFile, "TELEGRAM_CHUNK_PROGRESS_FILE")
+	envString(&c.SyncOrder, "TELEGRAM_SYNC_ORDER")
+	envString(&c.StartupMode, "TELEGRAM_STARTUP_MODE")
+	envBool(&c.BatchDigestNoCaptions, "TELEGRAM_BATCH_DIGEST_NO_CAPTIONS")
+	envBool(&c.AnnounceStartup, "TELEGRAM_ANNOUNCE_STARTUP")
+	envBool(&c.AnnounceShutdown, "TELEGRAM_ANNOUNCE_SHUTDOWN")
+	envString(&c.StartupMessage, "TELEGRAM_STARTUP_MESSAGE")
+	envString(&c.ShutdownMessage, "TELEGRAM_SHUTDOWN_MESSAGE")
+	envBool(&c.SummaryInPlace, "TELEGRAM_SUMMARY_IN_PLACE")
+	envString(&c.IndexFile, "TELEGRAM_INDEX_FILE")
+	envBool(&c.Dedup, "TELEGRAM_DEDUP")
+	envBool(&c.DetectRenames, "TELEGRAM_DETECT_RENAMES")
+	envBool(&c.RenameEditCaption, "TELEGRAM_RENAME_EDIT_CAPTION")
+	envString(&c.DedupFile, "TELEGRAM_DEDUP_FILE")
+	envString(&c.StateFile, "TELEGRAM_STATE_FILE")
+	envBool(&c.EditOnResync, "TELEGRAM_EDIT_ON_RESYNC")
+	envBool(&c.ReplaceOnResync, "TELEGRAM_REPLACE_ON_RESYNC")
+	envBool(&c.SplitLongCaptions, "TELEGRAM_SPLIT_LONG_CAPTIONS")
+	envOptionalString(&c.CaptionTemplate, "TELEGRAM_CAPTION_TEMPLATE")
+	envOptionalString(&c.InstanceName, "TELEGRAM_INSTANCE_NAME")
+	envBool(&c.InstanceInPath, "TELEGRAM_INSTANCE_IN_PATH")
+	envBool(&c.ReplyThreads, "TELEGRAM_REPLY_THREADS")
+	envBool(&c.Albums, "TELEGRAM_ALBUMS")
+	envBool(&c.ErrorAlerts, "TELEGRAM_ERROR_ALERTS")
+	envString(&c.AlertChatID, "TELEGRAM_ALERT_CHAT_ID")
+	envBool(&c.ReAddMissingDirs, "TELEGRAM_READD_MISSING_DIRS")
+	envString(&c.DeadLetterFile, "TELEGRAM_DEAD_LETTER_FILE")
+	envString(&c.UsageFile, "TELEGRAM_USAGE_FILE")
+	envBool(&c.ProtectContent, "TELEGRAM_PROTECT_CONTENT")
+	envBool(&c.Spoiler, "TELEGRAM_SPOILER")
+	envBool(&c.DisableNotification, "TELEGRAM_DISABLE_NOTIFICATION")
+	envBool(&c.DisableChatActions, "TELEGRAM_DISABLE_CHAT_ACTIONS")
+	envQuietHours(&c.QuietHours, "TELEGRAM_QUIET_HOURS")
+	envBool(&c.Compress, "TELEGRAM_COMPRESS")
+	envString(&c.EncryptionKey, "TELEGRAM_ENCRYPTION_KEY")
+	envString(&c.EncryptionKeyFile, "TELEGRAM_ENCRYPTION_KEY_FILE")
+	envBool(&c.DryRun, "TELEGRAM_DRY_RUN")
+	envBool(&c.DryRunKeepState, "TELEGRAM_DRY_RUN_KEEP_STATE")
+	envString(&c.AdminToken, "TELEGRAM_ADMIN_TOKEN")
+	envString(&c.Updates, "TELEGRAM_UPDATES")
+	envString(&c.WebhookURL, "TELEGRAM_WEBHOOK_URL")
+	envString(&c.WebhookListen, "TELEGRAM_WEBHOOK_LISTEN")
+	envString(&c.WebhookSecret, "TELEGRAM_WEBHOOK_SECRET")
+
+	var dirs []string
+
+	envList(&dirs, "TELEGRAM_WATCH_DIRS")
+
+	if dirs != nil {
+		c.Directories = c.Directories[:0]
+		for _, dir := range dirs {
+			c.Directories = append(c.Directories, parseDirectory(dir))
+		}
+	}
+
+	return errors.Join(
+		envDuration(&c.SyncInterval, "TELEGRAM_SYNC_INTERVAL"),
+		envFloat(&c.SyncJitter, "TELEGRAM_SYNC_JITTER"),
+		envInt(&c.PhotoMaxSide, "TELEGRAM_PHOTO_MAX_SIDE"),
+		envInt64(&c.PhotoMaxSize, "TELEGRAM_PHOTO_MAX_SIZE"),
+		envDuration(&c.Debounce, "TELEGRAM_DEBOUNCE"),
+		envInt(&c.MaxDepth, "TELEGRAM_MAX_DEPTH"),
+		envInt64(&c.MaxFileSize, "TELEGRAM_MAX_FILE_SIZE"),
+		envInt64(&c.ChunkSize, "TELEGRAM_CHUNK_SIZE"),
+		envInt(&c.UploadQueueSize, "TELEGRAM_UPLOAD_QUEUE_SIZE"),
+		envInt64(&c.UploadRateLimit, "TELEGRAM_UPLOAD_RATE_LIMIT"),
+		envInt(&c.APIRetries, "TELEGRAM_API_RETRIES"),
+		envInt(&c.BatchDigestThreshold, "TELEGRAM_BATCH_DIGEST_THRESHOLD"),
+		envDuration(&c.SummaryInterval, "TELEGRAM_SUMMARY_INTERVAL"),
+		envInt(&c.DedupCacheSize, "TELEGRAM_DEDUP_CACHE_SIZE"),
+		envDuration(&c.AlertCooldown, "TELEGRAM_ALERT_COOLDOWN"),
+		envDuration(&c.MissingDirGrace, "TELEGRAM_MISSING_DIR_GRACE"),
+		envInt(&c.RetryBudget, "TELEGRAM_RETRY_BUDGET"),
+		envInt64(&c.Quota, "TELEGRAM_QUOTA"),
+		envDuration(&c.QuotaWindow, "TELEGRAM_QUOTA_WINDOW"),
+		envInt(&c.MetricsPort, "TELEGRAM_METRICS_PORT"),
+		envInt(&c.AdminPort, "TELEGRAM_ADMIN_PORT"),
+	)
+}
+
+// parseDirectory parses the "path[@interval]" syntax of TELEGRAM_WATCH_DIRS,
+// a suffix that is not a valid duration is kept as a part of the path.
+func parseDirectory(s string) Directory {
+	if i := strings.LastIndexByte(s, '@'); i > 0 {
+		if interval, err := time.ParseDuration(s[i+1:]); err == nil && interval > 0 {
+			return Directory{Path: s[:i], Interval: interval}
+		}
+	}
+
+	return Directory{Path: s}
+}
+
+func envString(dst *string, key string) {
+	if v, ok := os.LookupEnv(key); ok {
+		*dst = v
+	}
+}
+
+// isPathElement reports whether name is a single element of a path.
+func isPathElement(name string) bool {
+	return name != "." && name != ".." && !strings.ContainsAny(name, `/\`)
+}
+
+// envOptionalString sets dst also to an empty value, unlike an unset variable.
+func envOptionalString(dst **string, key string) {
+	if v, ok := os.LookupEnv(key); ok {
+		*dst = &v
+	}
+}
+
+func envBool(dst *bool, key string) {
+	if v, err := strconv.ParseBool(os.Getenv(key)); err == nil {
+		*dst = v
+	}
+}
+
+func envInt(dst *int, key string) error {
+	return envParse(dst, key, strconv.Atoi)
+}
+
+func envInt64(dst *int64, key string) error {
+	return envParse(dst, key, func(v string) (int64, error) { return strconv.ParseInt(v, 10, 64) })
+}
+
+func envFloat(dst *float64, key string) error {
+	return envParse(dst, key, func(v string) (float64, error) { return strconv.ParseFloat(v, 64) })
+}
+
+func envDuration(dst *time.Duration, key string) error {
+	return envParse(dst, key, time.ParseDuration)
+}
+
+// envParse sets dst to the value of the variable parsed by parse, an unset or empty variable is skipped.
+func envParse[T any](dst *T, key string, parse func(string) (T, error)) error {
+	v := os.Getenv(key)
+	if v == "" {
+		return nil
+	}
+
+	parsed, err := parse(v)
+	if err != nil {
+		return fmt.Errorf("invalid %s %q: %w", key, v, err)
+	}
+
+	*dst = parsed
+
+	return nil
+}
+
+// envQuietHours parses the "start-end" syntax, e.g. "22:00-07:00".
+func envQuietHours(dst *QuietHours, key string) {
+	if start, end, ok := strings.Cut(os.Getenv(key), "-"); ok {
+		*dst = QuietHours{Start: strings.TrimSpace(start), End: strings.TrimSpace(end)}
+	}
+}
+
+func envList(dst *[]string, key string) {
+	if v, ok := os.LookupEnv(key); ok {
+		*dst = splitList(v)
+	}
+}
+
+// splitList splits a comma-separated value, dropping empty items.
+func splitList(s string) []string {
+	items := []string{}
+
+	for item := range strings.SplitSeq(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+func compileAll(patterns []string) ([]*regexp.Regexp, error) {
+	regexps := make([]*regexp.Regexp, 0, len(patterns))
+
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regexp %q: %w", pattern, err)
+		}
+
+		regexps = append(regexps, re)
+	}
+
+	return regexps, nil
 }
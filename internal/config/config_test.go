@@ -0,0 +1,347 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewFromFileWithEnvOverride(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+
+	data := `
+botToken: file-token
+chatId: "@file"
+syncInterval: 30s
+whitelist: ['\.jpg$']
+directories:
+  - path: /srv/inbox
+    interval: 2s
+    chatId: "@inbox"
+    topicId: 12
+    whitelist: ['\.png$', '\.jpg$']
+  - path: /srv/archive
+    blacklist: ['\.tmp$']
+`
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("TELEGRAM_BOT_TOKEN", "env-token")
+
+	cfg, err := New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.BotToken != "env-token" || cfg.ChatID != "@file" || cfg.SyncInterval != 30*time.Second {
+		t.Errorf("unexpected config: %+v", cfg)
+	}
+
+	if len(cfg.Directories) != 2 {
+		t.Fatalf("expected 2 directories, got %+v", cfg.Directories)
+	}
+
+	inbox, archive := cfg.Directories[0], cfg.Directories[1]
+
+	if inbox.Interval != 2*time.Second || inbox.ChatID != "@inbox" || inbox.TopicID != 12 || len(inbox.Whitelist) != 2 {
+		t.Errorf("unexpected inbox: %+v", inbox)
+	}
+
+	if archive.ChatID != "@file" || archive.Interval != 30*time.Second || len(archive.Whitelist) != 1 || len(archive.Blacklist) != 1 {
+		t.Errorf("archive must inherit the defaults: %+v", archive)
+	}
+}
+
+func TestNewEnvOnly(t *testing.T) {
+	t.Setenv("TELEGRAM_WATCH_DIRS", "/a, /b")
+	t.Setenv("TELEGRAM_CHAT_ID", "@chat")
+
+	cfg, err := New("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(cfg.Directories) != 2 || cfg.Directories[1].Path != "/b" || cfg.Directories[1].ChatID != "@chat" {
+		t.Errorf("unexpected directories: %+v", cfg.Directories)
+	}
+
+	if cfg.APIRetries != defaultAPIRetries {
+		t.Errorf("expected the default of %d retries, got %d", defaultAPIRetries, cfg.APIRetries)
+	}
+}
+
+func TestNewInvalidRegexp(t *testing.T) {
+	t.Setenv("TELEGRAM_WATCH_DIRS", "/a")
+	t.Setenv("WHITELIST_REGEXP", "(")
+
+	if _, err := New(""); err == nil {
+		t.Fatal("expected an error for an invalid regexp")
+	}
+}
+
+func TestNewEnvDirectoryIntervals(t *testing.T) {
+	t.Setenv("TELEGRAM_WATCH_DIRS", "/inbox@2s,/archive@5m,/mail@home")
+	t.Setenv("TELEGRAM_SYNC_INTERVAL", "1m")
+
+	cfg, err := New("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Directory{
+		{Path: "/inbox", Interval: 2 * time.Second},
+		{Path: "/archive", Interval: 5 * time.Minute},
+		{Path: "/mail@home", Interval: time.Minute},
+	}
+
+	if len(cfg.Directories) != len(want) {
+		t.Fatalf("unexpected directories: %+v", cfg.Directories)
+	}
+
+	for i, dir := range cfg.Directories {
+		if dir.Path != want[i].Path || dir.Interval != want[i].Interval {
+			t.Errorf("directory %d = %s@%s, want %s@%s", i, dir.Path, dir.Interval, want[i].Path, want[i].Interval)
+		}
+	}
+}
+
+func TestAPIURL(t *testing.T) {
+	t.Setenv("TELEGRAM_API_URL", "http://localhost:8081/bot")
+
+	cfg, err := New("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.FileURL != "http://localhost:8081/file/bot" {
+		t.Errorf("unexpected file url %q", cfg.FileURL)
+	}
+
+	if cfg.MaxFileSize != localMaxFileSize {
+		t.Errorf("expected the upload limit of a local server, got %d", cfg.MaxFileSize)
+	}
+
+	t.Setenv("TELEGRAM_API_URL", "localhost:8081")
+
+	if _, err := New(""); err == nil {
+		t.Error("expected an error for an url without scheme")
+	}
+
+	t.Setenv("TELEGRAM_API_URL", "")
+	t.Setenv("TELEGRAM_LOCAL_FILES", "true")
+
+	if _, err := New(""); err == nil {
+		t.Error("expected an error for local files without a local server")
+	}
+}
+
+func TestNewMaxFileSize(t *testing.T) {
+	t.Setenv("TELEGRAM_MAX_FILE_SIZE", strconv.Itoa(100<<20))
+
+	if _, err := New(""); err == nil {
+		t.Error("expected an error for a limit over the one of the Bot API")
+	}
+
+	t.Setenv("TELEGRAM_API_URL", "http://localhost:8081/bot")
+
+	cfg, err := New("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.MaxFileSize != 100<<20 {
+		t.Errorf("expected the limit to be kept with a local server, got %d", cfg.MaxFileSize)
+	}
+}
+
+func TestNewInvalidEnvNumbers(t *testing.T) {
+	for key, value := range map[string]string{
+		"TELEGRAM_MAX_DEPTH":      "three",
+		"TELEGRAM_QUOTA":          "1GB",
+		"TELEGRAM_SYNC_JITTER":    "10%",
+		"TELEGRAM_SYNC_INTERVAL":  "10",
+		"TELEGRAM_DEBOUNCE":       "5 minutes",
+		"TELEGRAM_MAX_FILE_SIZE":  "20MB",
+		"TELEGRAM_API_RETRIES":    "-",
+		"TELEGRAM_ALERT_COOLDOWN": "1d",
+	} {
+		t.Run(key, func(t *testing.T) {
+			t.Setenv(key, value)
+
+			if _, err := New(""); err == nil || !strings.Contains(err.Error(), key) {
+				t.Errorf("expected an error naming %s, got %v", key, err)
+			}
+		})
+	}
+}
+
+func TestNewEnvZero(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("debounce: 5s\nmissingDirGrace: 1m\napiRetries: 5\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// 0 disables the settings of the file
+	t.Setenv("TELEGRAM_DEBOUNCE", "0")
+	t.Setenv("TELEGRAM_MISSING_DIR_GRACE", "0s")
+	t.Setenv("TELEGRAM_API_RETRIES", "0")
+	t.Setenv("TELEGRAM_MAX_DEPTH", "")
+
+	cfg, err := New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Debounce != 0 || cfg.MissingDirGrace != 0 || cfg.APIRetries != 0 {
+		t.Errorf("expected the settings to be disabled, got debounce %s, grace %s, retries %d",
+			cfg.Debounce, cfg.MissingDirGrace, cfg.APIRetries)
+	}
+}
+
+func TestNewWhitelistFromEnv(t *testing.T) {
+	t.Setenv("TELEGRAM_WATCH_DIRS", "/a")
+	t.Setenv("WHITELIST_REGEXP", `\.jpg$,\.png$`)
+
+	cfg, err := New("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	whitelist, _, err := cfg.Directories[0].Filters()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(whitelist) != 2 || whitelist[0].String() != `\.jpg$` || whitelist[1].String() != `\.png$` {
+		t.Errorf("expected one regexp per listed pattern, got %v", whitelist)
+	}
+}
+
+func TestNewExcludeDirs(t *testing.T) {
+	t.Setenv("TELEGRAM_EXCLUDE_DIRS", "node_modules,.git")
+	t.Setenv("TELEGRAM_MAX_DEPTH", "3")
+
+	cfg, err := New("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.MaxDepth != 3 || len(cfg.ExcludeDirs) != 2 || cfg.ExcludeDirs[1] != ".git" {
+		t.Errorf("unexpected config: depth %d, excludes %v", cfg.MaxDepth, cfg.ExcludeDirs)
+	}
+
+	t.Setenv("TELEGRAM_EXCLUDE_DIRS", "[")
+
+	if _, err := New(""); err == nil {
+		t.Error("expected an error for an invalid pattern")
+	}
+}
+
+func TestNewStartupMode(t *testing.T) {
+	t.Setenv("TELEGRAM_STARTUP_MODE", StartupChangesOnly)
+
+	cfg, err := New("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.StartupMode != StartupChangesOnly {
+		t.Errorf("unexpected startup mode %q", cfg.StartupMode)
+	}
+
+	t.Setenv("TELEGRAM_STARTUP_MODE", "changes")
+
+	if _, err := New(""); err == nil {
+		t.Error("expected an error for an unknown startup mode")
+	}
+}
+
+func TestNewCaptionTemplate(t *testing.T) {
+	if cfg, err := New(""); err != nil || cfg.CaptionTemplate != nil {
+		t.Fatalf("expected no caption template, got %v, %v", cfg, err)
+	}
+
+	t.Setenv("TELEGRAM_CAPTION_TEMPLATE", "")
+
+	cfg, err := New("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.CaptionTemplate == nil || *cfg.CaptionTemplate != "" {
+		t.Errorf("expected an empty caption template, got %v", cfg.CaptionTemplate)
+	}
+
+	t.Setenv("TELEGRAM_CAPTION_TEMPLATE", "{{.Name")
+
+	if _, err := New(""); err == nil {
+		t.Error("expected an error for an invalid template")
+	}
+}
+
+func TestNewAdminNeedsToken(t *testing.T) {
+	t.Setenv("TELEGRAM_ADMIN_PORT", "8090")
+
+	if _, err := New(""); err == nil {
+		t.Error("expected an error for an admin port without token")
+	}
+
+	t.Setenv("TELEGRAM_ADMIN_TOKEN", "secret")
+
+	if cfg, err := New(""); err != nil || cfg.AdminPort != 8090 || cfg.AdminToken != "secret" {
+		t.Errorf("unexpected config %+v, %v", cfg, err)
+	}
+}
+
+func TestNewInstanceName(t *testing.T) {
+	if cfg, err := New(""); err != nil || cfg.InstanceName != nil {
+		t.Fatalf("expected no instance name, got %v, %v", cfg, err)
+	}
+
+	t.Setenv("TELEGRAM_INSTANCE_NAME", "")
+
+	if cfg, err := New(""); err != nil || cfg.InstanceName == nil || *cfg.InstanceName != "" {
+		t.Errorf("expected an empty instance name, got %v, %v", cfg, err)
+	}
+
+	for _, name := range []string{"..", "a/b", `a\b`} {
+		t.Setenv("TELEGRAM_INSTANCE_NAME", name)
+
+		if _, err := New(""); err == nil {
+			t.Errorf("expected an error for the instance name %q", name)
+		}
+	}
+}
+
+func TestNewUpdates(t *testing.T) {
+	t.Setenv("TELEGRAM_UPDATES", "webhook")
+
+	if _, err := New(""); err == nil {
+		t.Error("expected the webhook to need a url")
+	}
+
+	t.Setenv("TELEGRAM_WEBHOOK_URL", "https://bot.example.com/hook")
+	t.Setenv("TELEGRAM_WEBHOOK_LISTEN", ":8443")
+
+	if cfg, err := New(""); err != nil || cfg.Updates != UpdatesWebhook {
+		t.Fatalf("expected webhook mode, got %v, %v", cfg, err)
+	}
+
+	for env, value := range map[string]string{
+		"TELEGRAM_WEBHOOK_URL":    "http://bot.example.com/hook",
+		"TELEGRAM_WEBHOOK_SECRET": "not a valid token",
+		"TELEGRAM_UPDATES":        "push",
+	} {
+		t.Run(env, func(t *testing.T) {
+			t.Setenv(env, value)
+
+			if _, err := New(""); err == nil {
+				t.Errorf("expected an error for %s=%q", env, value)
+			}
+		})
+	}
+}
@@ -0,0 +1,231 @@
+// Package admin serves a local HTTP API to control the sync service, e.g. from a larger system.
+package admin
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/k0ff1l/tgcloudbot/internal/services/syncer"
+)
+
+const (
+	readHeaderTimeout = 5 * time.Second
+	shutdownTimeout   = 5 * time.Second
+
+	// maxBodySize limits the JSON bodies of the requests
+	maxBodySize = 64 << 10
+)
+
+var _ Service = (*syncer.SyncService)(nil)
+
+// Service is the part of the sync service the API controls.
+type Service interface {
+	Status() syncer.Status
+	Files() (map[string]syncer.IndexEntry, error)
+	SyncNow() error
+	ForceSync(filePath string) error
+	Pause()
+	Resume()
+}
+
+// Server is the admin API:
+//
+//	GET  /status     the watched directories, counters and the paused state
+//	GET  /files      the index, the message of every uploaded file by path
+//	POST /sync       syncs all directories now and returns once done
+//	POST /sync/file  uploads {"path": "..."} again, it must be in a watched directory
+//	POST /pause      pauses the sync
+//	POST /resume     resumes it
+//
+// Every request needs the header "Authorization: Bearer <token>". Errors are answered as {"error": "..."}.
+type Server struct {
+	service Service
+	token   string
+	logger  *slog.Logger
+}
+
+// New returns the API of service protected by token, a nil logger uses slog.Default.
+func New(service Service, token string, logger *slog.Logger) *Server {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	return &Server{service: service, token: token, logger: logger}
+}
+
+// Handler returns the routes of the API behind the token check.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /status", s.status)
+	mux.HandleFunc("GET /files", s.files)
+	mux.HandleFunc("POST /sync", s.syncNow)
+	mux.HandleFunc("POST /sync/file", s.syncFile)
+	mux.HandleFunc("POST /pause", s.pause)
+	mux.HandleFunc("POST /resume", s.resume)
+
+	return s.authorize(mux)
+}
+
+// Serve exposes the API on addr until ctx is done, then shuts it down gracefully.
+func (s *Server) Serve(ctx context.Context, addr string) error {
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: readHeaderTimeout,
+	}
+
+	errCh := make(chan error, 1)
+
+	go func() {
+		errCh <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		return fmt.Errorf("admin server: %w", err)
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), shutdownTimeout)
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("shutdown admin server: %w", err)
+	}
+
+	if err := <-errCh; !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("admin server: %w", err)
+	}
+
+	return nil
+}
+
+// authorize answers 401 to requests without the bearer token.
+func (s *Server) authorize(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, http.StatusUnauthorized, errors.New("missing or invalid bearer token"))
+
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) status(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, s.service.Status())
+}
+
+func (s *Server) files(w http.ResponseWriter, _ *http.Request) {
+	files, err := s.service.Files()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+
+		return
+	}
+
+	writeJSON(w, http.StatusOK, files)
+}
+
+func (s *Server) syncNow(w http.ResponseWriter, _ *http.Request) {
+	if err := s.service.SyncNow(); err != nil {
+		writeError(w, statusOf(err), err)
+
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result{Status: "synced"})
+}
+
+// syncFileRequest is the body of POST /sync/file.
+type syncFileRequest struct {
+	Path string `json:"path"`
+}
+
+func (s *Server) syncFile(w http.ResponseWriter, r *http.Request) {
+	var req syncFileRequest
+
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodySize)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid body: %w", err))
+
+		return
+	}
+
+	path := filepath.Clean(req.Path)
+
+	// the API must not upload arbitrary files of the host
+	if !filepath.IsAbs(path) || !s.inWatchedDir(path) {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("%q is not a file in a watched directory", req.Path))
+
+		return
+	}
+
+	if err := s.service.ForceSync(path); err != nil {
+		writeError(w, statusOf(err), err)
+
+		return
+	}
+
+	s.logger.Info("file synced by the admin API", "file", path)
+	writeJSON(w, http.StatusOK, result{Status: "synced"})
+}
+
+func (s *Server) pause(w http.ResponseWriter, _ *http.Request) {
+	s.service.Pause()
+	writeJSON(w, http.StatusOK, result{Status: "paused"})
+}
+
+func (s *Server) resume(w http.ResponseWriter, _ *http.Request) {
+	s.service.Resume()
+	writeJSON(w, http.StatusOK, result{Status: "resumed"})
+}
+
+// inWatchedDir reports whether path is below a directory with a sync loop.
+func (s *Server) inWatchedDir(path string) bool {
+	for _, dir := range s.service.Status().Directories {
+		if strings.HasPrefix(path, dir.Path+string(filepath.Separator)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// result is the answer of the actions.
+type result struct {
+	Status string `json:"status"`
+}
+
+// statusOf returns the HTTP status of a failed action.
+func statusOf(err error) int {
+	switch {
+	case errors.Is(err, syncer.ErrServiceStopped):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, fs.ErrNotExist):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, map[string]string{"error": err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,149 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/k0ff1l/tgcloudbot/internal/services/file"
+	"github.com/k0ff1l/tgcloudbot/internal/services/syncer"
+	"github.com/k0ff1l/tgcloudbot/internal/services/telegram/telegramtest"
+)
+
+const token = "secret"
+
+// newAPI returns the API of a sync service watching a new directory, it never ticks on its own.
+func newAPI(t *testing.T) (http.Handler, *syncer.SyncService, *telegramtest.FakeClient, string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	bot := telegramtest.NewFakeClient()
+	s := syncer.NewSyncService(bot, file.NewWatcher(), syncer.WithChatID("chat"))
+
+	if err := s.StartContinuousSync(dir, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(s.Stop)
+
+	return New(s, token, nil).Handler(), s, bot, dir
+}
+
+func do(t *testing.T, h http.Handler, method, path, body string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	return rec
+}
+
+func TestUnauthorized(t *testing.T) {
+	h, _, _, _ := newAPI(t)
+
+	for _, header := range []string{"", "Bearer wrong", token} {
+		req := httptest.NewRequest(http.MethodGet, "/status", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("Authorization %q: got %d, want 401", header, rec.Code)
+		}
+	}
+}
+
+func TestStatusAndPause(t *testing.T) {
+	h, s, _, dir := newAPI(t)
+
+	if rec := do(t, h, http.MethodPost, "/pause", ""); rec.Code != http.StatusOK || !s.Paused() {
+		t.Fatalf("pause: %d %s", rec.Code, rec.Body)
+	}
+
+	rec := do(t, h, http.MethodGet, "/status", "")
+
+	var status syncer.Status
+	if err := json.NewDecoder(rec.Body).Decode(&status); err != nil {
+		t.Fatal(err)
+	}
+
+	if !status.Paused || len(status.Directories) != 1 || status.Directories[0].Path != dir ||
+		status.Directories[0].ChatID != "chat" {
+		t.Errorf("unexpected status %+v", status)
+	}
+
+	if rec := do(t, h, http.MethodPost, "/resume", ""); rec.Code != http.StatusOK || s.Paused() {
+		t.Errorf("resume: %d %s", rec.Code, rec.Body)
+	}
+
+	if rec := do(t, h, http.MethodGet, "/pause", ""); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET /pause: got %d, want 405", rec.Code)
+	}
+}
+
+func TestSyncAndFiles(t *testing.T) {
+	h, _, bot, dir := newAPI(t)
+
+	path := filepath.Join(dir, "a.txt")
+	if err := os.WriteFile(path, []byte("a"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if rec := do(t, h, http.MethodPost, "/sync", ""); rec.Code != http.StatusOK {
+		t.Fatalf("sync: %d %s", rec.Code, rec.Body)
+	}
+
+	if uploads := bot.CallsTo("SendDocument"); len(uploads) != 1 {
+		t.Fatalf("expected the file to be synced, got %+v", uploads)
+	}
+
+	// unchanged, only uploaded again when forced
+	body, _ := json.Marshal(syncFileRequest{Path: path})
+	if rec := do(t, h, http.MethodPost, "/sync/file", string(body)); rec.Code != http.StatusOK {
+		t.Fatalf("sync/file: %d %s", rec.Code, rec.Body)
+	}
+
+	if uploads := bot.CallsTo("SendDocument"); len(uploads) != 2 {
+		t.Errorf("expected the file to be uploaded again, got %+v", uploads)
+	}
+
+	var files map[string]syncer.IndexEntry
+	if err := json.NewDecoder(do(t, h, http.MethodGet, "/files", "").Body).Decode(&files); err != nil {
+		t.Fatal(err)
+	}
+
+	if entry, ok := files[path]; !ok || entry.ChatID != "chat" {
+		t.Errorf("expected the file in the index, got %+v", files)
+	}
+}
+
+func TestSyncFileRejected(t *testing.T) {
+	h, _, bot, dir := newAPI(t)
+
+	for body, want := range map[string]int{
+		`{"path": "/etc/passwd"}`:                                    http.StatusBadRequest,
+		`{"path": "` + filepath.Join(dir, "..", "escape.txt") + `"}`: http.StatusBadRequest,
+		`{"path": "relative.txt"}`:                                   http.StatusBadRequest,
+		`not json`:                                                   http.StatusBadRequest,
+		`{"path": "` + filepath.Join(dir, "missing.txt") + `"}`:      http.StatusNotFound,
+	} {
+		if rec := do(t, h, http.MethodPost, "/sync/file", body); rec.Code != want {
+			t.Errorf("%s: got %d %s, want %d", body, rec.Code, rec.Body, want)
+		}
+	}
+
+	if uploads := bot.CallsTo("SendDocument"); len(uploads) != 0 {
+		t.Errorf("nothing must be uploaded, got %+v", uploads)
+	}
+}
@@ -0,0 +1,141 @@
+// Package commands answers the bot commands sent to the chats of the sync service,
+// the updates are received by a telegram.Poller or a telegram.WebhookHandler.
+package commands
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"github.com/k0ff1l/tgcloudbot/internal/services/syncer"
+	"github.com/k0ff1l/tgcloudbot/internal/services/telegram"
+)
+
+var _ Service = (*syncer.SyncService)(nil)
+
+// Service is the part of the sync service the commands control.
+type Service interface {
+	Status() syncer.Status
+	SyncNow() error
+	Pause()
+	Resume()
+}
+
+// Commands answers the commands of the chats allowed to control the service:
+//
+//	/status  the watched directories, the queue and the counters
+//	/sync    syncs all directories now and answers once done
+//	/pause   pauses the sync
+//	/resume  resumes it
+//
+// Commands from other chats are ignored, anyone can message a bot.
+type Commands struct {
+	service Service
+	bot     telegram.Bot
+	chats   map[string]bool
+	logger  *slog.Logger
+}
+
+// New returns the commands of service answered with bot in chatIDs, numeric ids or "@channelname".
+// A nil logger uses slog.Default.
+func New(service Service, bot telegram.Bot, chatIDs []string, logger *slog.Logger) *Commands {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	chats := make(map[string]bool, len(chatIDs))
+	for _, chatID := range chatIDs {
+		if chatID != "" {
+			chats[chatID] = true
+		}
+	}
+
+	return &Commands{service: service, bot: bot, chats: chats, logger: logger}
+}
+
+// Register sets the handlers of the commands in d.
+func (c *Commands) Register(d *telegram.Dispatcher) {
+	d.HandleCommand("status", c.allowed(c.status))
+	d.HandleCommand("sync", c.allowed(c.syncNow))
+	d.HandleCommand("pause", c.allowed(c.pause))
+	d.HandleCommand("resume", c.allowed(c.resume))
+}
+
+// allowed runs handle with the configured id of the chat of the command, if it may control the service.
+func (c *Commands) allowed(handle func(ctx context.Context, chatID string)) telegram.CommandHandler {
+	return func(ctx context.Context, msg *telegram.Message, _ string) {
+		chatID, ok := c.chatOf(msg)
+		if !ok {
+			c.logger.Warn("ignored command from an unknown chat", "chat", msg.Chat.ID, "text", msg.Text)
+
+			return
+		}
+
+		handle(ctx, chatID)
+	}
+}
+
+// chatOf returns the id the chat of msg is configured with, it reports false for other chats.
+func (c *Commands) chatOf(msg *telegram.Message) (string, bool) {
+	if chatID := strconv.FormatInt(msg.Chat.ID, 10); c.chats[chatID] {
+		return chatID, true
+	}
+
+	if msg.Chat.Username != "" && c.chats["@"+msg.Chat.Username] {
+		return "@" + msg.Chat.Username, true
+	}
+
+	return "", false
+}
+
+func (c *Commands) status(ctx context.Context, chatID string) {
+	c.reply(ctx, chatID, statusText(c.service.Status()))
+}
+
+func (c *Commands) syncNow(ctx context.Context, chatID string) {
+	if err := c.service.SyncNow(); err != nil {
+		c.reply(ctx, chatID, "Sync failed: "+err.Error())
+
+		return
+	}
+
+	c.reply(ctx, chatID, "Synced.")
+}
+
+func (c *Commands) pause(ctx context.Context, chatID string) {
+	c.service.Pause()
+	c.reply(ctx, chatID, "Paused.")
+}
+
+func (c *Commands) resume(ctx context.Context, chatID string) {
+	c.service.Resume()
+	c.reply(ctx, chatID, "Resumed.")
+}
+
+func (c *Commands) reply(ctx context.Context, chatID, text string) {
+	if _, err := c.bot.SendMessage(ctx, chatID, text); err != nil {
+		c.logger.Error("failed to answer command", "chat", chatID, "error", err)
+	}
+}
+
+// statusText renders status like the periodic summary.
+func statusText(status syncer.Status) string {
+	var b strings.Builder
+
+	state := "running"
+	if status.Paused {
+		state = "paused"
+	}
+
+	fmt.Fprintf(&b, "Sync is %s, %d files queued.\n", state, status.QueueLength)
+	fmt.Fprintf(&b, "files uploaded: %d\nbytes transferred: %d\nerrors: %d\n",
+		status.FilesUploaded, status.BytesUploaded, status.Errors)
+
+	for _, dir := range status.Directories {
+		fmt.Fprintf(&b, "\n%s: %d files tracked", dir.Path, dir.TrackedFiles)
+	}
+
+	return strings.TrimSuffix(b.String(), "\n")
+}
@@ -0,0 +1,79 @@
+package commands
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/k0ff1l/tgcloudbot/internal/services/syncer"
+	"github.com/k0ff1l/tgcloudbot/internal/services/telegram"
+	"github.com/k0ff1l/tgcloudbot/internal/services/telegram/telegramtest"
+)
+
+type fakeService struct {
+	paused  bool
+	syncErr error
+}
+
+func (f *fakeService) Status() syncer.Status {
+	return syncer.Status{
+		Paused:        f.paused,
+		Directories:   []syncer.DirStatus{{Path: "/data", ChatID: "-100", TrackedFiles: 3}},
+		FilesUploaded: 2,
+	}
+}
+
+func (f *fakeService) SyncNow() error { return f.syncErr }
+func (f *fakeService) Pause()         { f.paused = true }
+func (f *fakeService) Resume()        { f.paused = false }
+
+func command(chatID int64, username, text string) telegram.Update {
+	return telegram.Update{Message: &telegram.Message{Chat: telegram.Chat{ID: chatID, Username: username}, Text: text}}
+}
+
+func TestCommands(t *testing.T) {
+	service := &fakeService{}
+	bot := telegramtest.NewFakeClient()
+
+	d := telegram.NewDispatcher("")
+	New(service, bot, []string{"-100", "@backups"}, nil).Register(d)
+
+	d.Dispatch(t.Context(), command(-100, "", "/pause"))
+
+	if !service.paused {
+		t.Error("expected /pause to pause the service")
+	}
+
+	d.Dispatch(t.Context(), command(-200, "backups", "/status"))
+
+	sent := bot.CallsTo("SendMessage")
+	if len(sent) != 2 || sent[0].ChatID != "-100" || sent[1].ChatID != "@backups" {
+		t.Fatalf("expected the answers in the chats of the commands, got %+v", sent)
+	}
+
+	if text := sent[1].Text; !strings.Contains(text, "paused") || !strings.Contains(text, "/data: 3 files") {
+		t.Errorf("unexpected status %q", text)
+	}
+
+	service.syncErr = errors.New("service stopped")
+	d.Dispatch(t.Context(), command(-100, "", "/sync"))
+
+	if sent := bot.CallsTo("SendMessage"); len(sent) != 3 || !strings.Contains(sent[2].Text, "service stopped") {
+		t.Errorf("expected the sync error as the answer, got %+v", sent)
+	}
+}
+
+func TestCommandsFromUnknownChats(t *testing.T) {
+	service := &fakeService{}
+	bot := telegramtest.NewFakeClient()
+
+	d := telegram.NewDispatcher("")
+	New(service, bot, []string{"-100", ""}, nil).Register(d)
+
+	d.Dispatch(t.Context(), command(42, "", "/pause"))
+	d.Dispatch(t.Context(), command(43, "stranger", "/status"))
+
+	if service.paused || len(bot.Calls()) != 0 {
+		t.Errorf("commands of other chats must be ignored, got %+v", bot.Calls())
+	}
+}
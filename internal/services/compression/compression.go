@@ -0,0 +1,214 @@
+package compression
+
+import (
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// compressedExts are formats that are already compressed, gzip would only waste time on them.
+//
+//nolint:gochecknoglobals // read-only lookup table
+var compressedExts = map[string]bool{
+	".jpg": true, ".jpeg": true, ".png": true, ".gif": true, ".webp": true, ".heic": true,
+	".mp4": true, ".mkv": true, ".mov": true, ".webm": true, ".avi": true,
+	".mp3": true, ".m4a": true, ".ogg": true, ".opus": true, ".flac": true,
+	".zip": true, ".gz": true, ".tgz": true, ".bz2": true, ".xz": true, ".zst": true, ".7z": true, ".rar": true,
+	".docx": true, ".xlsx": true, ".pptx": true, ".odt": true, ".epub": true, ".jar": true, ".apk": true,
+}
+
+// IsCompressible reports whether gzip is likely to shrink the file.
+func IsCompressible(path string) bool {
+	return !compressedExts[strings.ToLower(filepath.Ext(path))]
+}
+
+// GzipFile compresses srcPath into dstDir/<name>.gz and returns its path.
+func GzipFile(srcPath, dstDir string) (string, error) {
+	src, err := os.Open(srcPath)
+	if err != nil {
+		return "", fmt.Errorf("open %s: %w", srcPath, err)
+	}
+	defer src.Close()
+
+	dstPath := filepath.Join(dstDir, filepath.Base(srcPath)+".gz")
+
+	dst, err := os.Create(dstPath)
+	if err != nil {
+		return "", fmt.Errorf("create %s: %w", dstPath, err)
+	}
+
+	zw := gzip.NewWriter(dst)
+	zw.Name = filepath.Base(srcPath)
+
+	if _, err := io.Copy(zw, src); err != nil {
+		_ = dst.Close()
+
+		return "", fmt.Errorf("compress %s: %w", srcPath, err)
+	}
+
+	if err := zw.Close(); err != nil {
+		_ = dst.Close()
+
+		return "", fmt.Errorf("compress %s: %w", srcPath, err)
+	}
+
+	if err := dst.Close(); err != nil {
+		return "", fmt.Errorf("close %s: %w", dstPath, err)
+	}
+
+	return dstPath, nil
+}
+
+// GunzipFile decompresses a file made by GzipFile into dstDir under its original name.
+func GunzipFile(srcPath, dstDir string) (string, error) {
+	src, err := os.Open(srcPath)
+	if err != nil {
+		return "", fmt.Errorf("open %s: %w", srcPath, err)
+	}
+	defer src.Close()
+
+	zr, err := gzip.NewReader(src)
+	if err != nil {
+		return "", fmt.Errorf("read gzip header of %s: %w", srcPath, err)
+	}
+	defer zr.Close()
+
+	name := filepath.Base(zr.Name)
+	if zr.Name == "" {
+		name = strings.TrimSuffix(filepath.Base(srcPath), ".gz")
+	}
+
+	dstPath := filepath.Join(dstDir, name)
+
+	if err := writeFrom(dstPath, zr); err != nil {
+		return "", err
+	}
+
+	return dstPath, nil
+}
+
+// ZipDir bundles every regular file under dir into dstDir/<dir name>.zip
+// with paths relative to dir and returns the archive path.
+func ZipDir(dir, dstDir string) (string, error) {
+	dir = filepath.Clean(dir)
+	dstPath := filepath.Join(dstDir, filepath.Base(dir)+".zip")
+
+	dst, err := os.Create(dstPath)
+	if err != nil {
+		return "", fmt.Errorf("create %s: %w", dstPath, err)
+	}
+
+	zw := zip.NewWriter(dst)
+
+	err = filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !d.Type().IsRegular() || path == dstPath {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		return addToZip(zw, path, filepath.ToSlash(rel))
+	})
+	if err != nil {
+		_ = dst.Close()
+
+		return "", fmt.Errorf("zip %s: %w", dir, err)
+	}
+
+	if err := zw.Close(); err != nil {
+		_ = dst.Close()
+
+		return "", fmt.Errorf("zip %s: %w", dir, err)
+	}
+
+	if err := dst.Close(); err != nil {
+		return "", fmt.Errorf("close %s: %w", dstPath, err)
+	}
+
+	return dstPath, nil
+}
+
+// Unzip extracts an archive made by ZipDir into dstDir.
+func Unzip(srcPath, dstDir string) error {
+	zr, err := zip.OpenReader(srcPath)
+	if err != nil {
+		return fmt.Errorf("open %s: %w", srcPath, err)
+	}
+	defer zr.Close()
+
+	for _, f := range zr.File {
+		if err := extract(f, dstDir); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func addToZip(zw *zip.Writer, path, name string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	w, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate})
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(w, src)
+
+	return err
+}
+
+func extract(f *zip.File, dstDir string) error {
+	// zip slip: names are relative paths that must stay inside dstDir
+	dstPath := filepath.Join(dstDir, filepath.FromSlash(f.Name))
+	if !strings.HasPrefix(dstPath, filepath.Clean(dstDir)+string(filepath.Separator)) {
+		return fmt.Errorf("invalid path %q in archive", f.Name)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dstPath), 0o750); err != nil {
+		return fmt.Errorf("create directory for %s: %w", dstPath, err)
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("open %s in archive: %w", f.Name, err)
+	}
+	defer rc.Close()
+
+	return writeFrom(dstPath, rc)
+}
+
+func writeFrom(dstPath string, r io.Reader) error {
+	dst, err := os.Create(dstPath)
+	if err != nil {
+		return fmt.Errorf("create %s: %w", dstPath, err)
+	}
+
+	//nolint:gosec // archives made by this package, size is bounded by the upload limit
+	if _, err := io.Copy(dst, r); err != nil {
+		_ = dst.Close()
+
+		return fmt.Errorf("write %s: %w", dstPath, err)
+	}
+
+	if err := dst.Close(); err != nil {
+		return fmt.Errorf("close %s: %w", dstPath, err)
+	}
+
+	return nil
+}
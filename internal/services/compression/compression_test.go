@@ -0,0 +1,79 @@
+package compression
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGzipRoundTrip(t *testing.T) {
+	content := bytes.Repeat([]byte("log line\n"), 1000)
+	src := filepath.Join(t.TempDir(), "app.log")
+
+	if err := os.WriteFile(src, content, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	gzPath, err := GzipFile(src, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if filepath.Base(gzPath) != "app.log.gz" {
+		t.Errorf("unexpected archive name %s", gzPath)
+	}
+
+	if info, _ := os.Stat(gzPath); info.Size() >= int64(len(content)) {
+		t.Errorf("compressed size %d is not smaller than %d", info.Size(), len(content))
+	}
+
+	restored, err := GunzipFile(gzPath, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, _ := os.ReadFile(restored); !bytes.Equal(got, content) || filepath.Base(restored) != "app.log" {
+		t.Errorf("round trip failed: %s", restored)
+	}
+}
+
+func TestZipDirRoundTrip(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "notes")
+	files := map[string]string{"a.txt": "a", "sub/b.txt": "b"}
+
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	zipPath, err := ZipDir(dir, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dst := t.TempDir()
+	if err := Unzip(zipPath, dst); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, content := range files {
+		if got, err := os.ReadFile(filepath.Join(dst, name)); err != nil || string(got) != content {
+			t.Errorf("%s: got %q, %v", name, got, err)
+		}
+	}
+}
+
+func TestIsCompressible(t *testing.T) {
+	for path, want := range map[string]bool{"a.txt": true, "a.csv": true, "a.JPG": false, "a.mp4": false, "a.zip": false} {
+		if got := IsCompressible(path); got != want {
+			t.Errorf("IsCompressible(%s) = %v, want %v", path, got, want)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package encryption
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// An encrypted file is magic | nonce | AES-GCM(name length (uint16) | original name | content),
+// the magic is authenticated as additional data.
+const magic = "TGCB1"
+
+var (
+	ErrInvalidKey   = errors.New("encryption key must be 16, 24 or 32 bytes")
+	ErrNotEncrypted = errors.New("file is not encrypted")
+)
+
+// LoadKey decodes a base64 key, or reads it from keyFile (raw or base64) when b64Key is empty.
+// It returns nil when neither is set.
+func LoadKey(b64Key, keyFile string) ([]byte, error) {
+	if b64Key == "" && keyFile == "" {
+		return nil, nil //nolint:nilnil // encryption is disabled
+	}
+
+	var key []byte
+
+	if b64Key != "" {
+		decoded, err := base64.StdEncoding.DecodeString(b64Key)
+		if err != nil {
+			return nil, fmt.Errorf("decode encryption key: %w", err)
+		}
+
+		key = decoded
+	} else {
+		data, err := os.ReadFile(keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("read encryption key file: %w", err)
+		}
+
+		key = data
+		if decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data))); err == nil {
+			key = decoded
+		}
+	}
+
+	if _, err := aes.NewCipher(key); err != nil {
+		return nil, ErrInvalidKey
+	}
+
+	return key, nil
+}
+
+// EncryptFile encrypts srcPath into a new temporary file in dstDir (os.TempDir if empty)
+// and returns its path, the caller removes it.
+func EncryptFile(key []byte, srcPath, dstDir string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	content, err := os.ReadFile(srcPath)
+	if err != nil {
+		return "", fmt.Errorf("read %s: %w", srcPath, err)
+	}
+
+	name := filepath.Base(srcPath)
+	if len(name) > math.MaxUint16 {
+		return "", fmt.Errorf("file name %s is too long", name)
+	}
+
+	plain := make([]byte, 0, 2+len(name)+len(content))
+	plain = binary.BigEndian.AppendUint16(plain, uint16(len(name)))
+	plain = append(plain, name...)
+	plain = append(plain, content...)
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("generate nonce: %w", err)
+	}
+
+	out := make([]byte, 0, len(magic)+len(nonce)+len(plain)+gcm.Overhead())
+	out = append(out, magic...)
+	out = append(out, nonce...)
+	out = gcm.Seal(out, nonce, plain, []byte(magic))
+
+	return writeTemp(dstDir, "*.enc", out)
+}
+
+// DecryptFile decrypts a file made by EncryptFile into dstDir under its original name
+// and returns the path of the decrypted file.
+func DecryptFile(key []byte, srcPath, dstDir string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := os.ReadFile(srcPath)
+	if err != nil {
+		return "", fmt.Errorf("read %s: %w", srcPath, err)
+	}
+
+	if len(data) < len(magic)+gcm.NonceSize() || !bytes.HasPrefix(data, []byte(magic)) {
+		return "", ErrNotEncrypted
+	}
+
+	nonce := data[len(magic) : len(magic)+gcm.NonceSize()]
+
+	plain, err := gcm.Open(nil, nonce, data[len(magic)+gcm.NonceSize():], []byte(magic))
+	if err != nil {
+		return "", fmt.Errorf("decrypt %s: %w", srcPath, err)
+	}
+
+	if len(plain) < 2 {
+		return "", fmt.Errorf("decrypt %s: truncated header", srcPath)
+	}
+
+	nameLen := int(binary.BigEndian.Uint16(plain))
+	if len(plain) < 2+nameLen {
+		return "", fmt.Errorf("decrypt %s: truncated header", srcPath)
+	}
+
+	// the name comes from the sender, never let it escape dstDir
+	name := filepath.Base(string(plain[2 : 2+nameLen]))
+	if name == "." || name == ".." || name == string(filepath.Separator) {
+		return "", fmt.Errorf("decrypt %s: invalid file name %q", srcPath, name)
+	}
+
+	dstPath := filepath.Join(dstDir, name)
+
+	if err := os.WriteFile(dstPath, plain[2+nameLen:], 0o600); err != nil {
+		return "", fmt.Errorf("write %s: %w", dstPath, err)
+	}
+
+	return dstPath, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, ErrInvalidKey
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("create gcm: %w", err)
+	}
+
+	return gcm, nil
+}
+
+func writeTemp(dir, pattern string, data []byte) (string, error) {
+	f, err := os.CreateTemp(dir, pattern)
+	if err != nil {
+		return "", fmt.Errorf("create temp file: %w", err)
+	}
+
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+
+		return "", fmt.Errorf("write temp file: %w", err)
+	}
+
+	if err := f.Close(); err != nil {
+		_ = os.Remove(f.Name())
+
+		return "", fmt.Errorf("close temp file: %w", err)
+	}
+
+	return f.Name(), nil
+}
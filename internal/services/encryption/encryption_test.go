@@ -0,0 +1,78 @@
+package encryption
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEncryptDecryptRoundTrip(t *testing.T) {
+	key := bytes.Repeat([]byte{7}, 32)
+	src := filepath.Join(t.TempDir(), "secret.txt")
+
+	if err := os.WriteFile(src, []byte("top secret"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	encPath, err := EncryptFile(key, src, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	enc, _ := os.ReadFile(encPath)
+	if bytes.Contains(enc, []byte("top secret")) || bytes.Contains(enc, []byte("secret.txt")) {
+		t.Fatal("encrypted file leaks the content or the name")
+	}
+
+	dstDir := t.TempDir()
+
+	dstPath, err := DecryptFile(key, encPath, dstDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if dstPath != filepath.Join(dstDir, "secret.txt") {
+		t.Errorf("unexpected restored path %s", dstPath)
+	}
+
+	if got, _ := os.ReadFile(dstPath); string(got) != "top secret" {
+		t.Errorf("unexpected content %q", got)
+	}
+
+	if _, err := DecryptFile(bytes.Repeat([]byte{8}, 32), encPath, dstDir); err == nil {
+		t.Error("decrypting with a wrong key must fail")
+	}
+
+	if _, err := DecryptFile(key, src, dstDir); !errors.Is(err, ErrNotEncrypted) {
+		t.Errorf("expected ErrNotEncrypted, got %v", err)
+	}
+}
+
+func TestLoadKey(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, 32)
+	b64 := base64.StdEncoding.EncodeToString(key)
+
+	if got, err := LoadKey(b64, ""); err != nil || !bytes.Equal(got, key) {
+		t.Errorf("LoadKey(base64) = %v, %v", got, err)
+	}
+
+	keyFile := filepath.Join(t.TempDir(), "key")
+	if err := os.WriteFile(keyFile, []byte(b64+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, err := LoadKey("", keyFile); err != nil || !bytes.Equal(got, key) {
+		t.Errorf("LoadKey(file) = %v, %v", got, err)
+	}
+
+	if got, err := LoadKey("", ""); err != nil || got != nil {
+		t.Errorf("no key must mean no encryption, got %v, %v", got, err)
+	}
+
+	if _, err := LoadKey(base64.StdEncoding.EncodeToString([]byte("short")), ""); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("expected ErrInvalidKey, got %v", err)
+	}
+}
@@ -1,69 +1,788 @@
 package file
 
 import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"maps"
 	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
 	"time"
+
+	"github.com/k0ff1l/tgcloudbot/internal/services/state"
 )
 
-// limitations : 20 MB per or 50 MB
+// DefaultMaxFileSize is the upload limit of the public Bot API.
+const DefaultMaxFileSize = 50 << 20
 
 var _ Watcher = (*IWatcher)(nil)
 
+var (
+	errNotWatched = errors.New("directory is not watched")
+
+	// ErrWatcherClosed is returned by the methods of a closed watcher.
+	ErrWatcherClosed = errors.New("watcher is closed")
+	// ErrPartialScan is returned with the updated files when some paths of a directory could not be read,
+	// e.g. for missing permissions. It wraps the errors of these paths.
+	ErrPartialScan = errors.New("some paths could not be read")
+)
+
 type Watcher interface {
 	AddFile(path string) error
 	AddDir(path string) error
+	AddDirWithFilters(path string, whitelist, blacklist []*regexp.Regexp) error
+	RemoveDir(path string)
 	GetUpdatedFiles() ([]string, error)
+	GetUpdatedFilesIn(dir string) ([]string, error)
+	PeekUpdatedFilesIn(dir string) ([]string, error)
+	TrackedFiles(dir string) int
+	ListFiles(dir string) ([]string, error)
+	Forget(path string)
+	Close() error
+}
+
+type watchedFile struct {
+	size    int64
+	modTime time.Time
+	// hash is the hex sha256 of the content, set only with HashVerification
+	hash     string
+	lastSync time.Time
+}
+
+// FileState is the recorded state of a synced file, as kept in the state store.
+type FileState struct {
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+	Hash    string    `json:"hash,omitempty"`
+}
+
+func (f *watchedFile) state() FileState {
+	return FileState{Size: f.size, ModTime: f.modTime, Hash: f.hash}
+}
+
+// watchedDir keeps the path filters of a directory.
+// A file is watched when it matches any whitelist regexp (or the whitelist is empty)
+// and none of the blacklist ones.
+type watchedDir struct {
+	whitelist []*regexp.Regexp
+	blacklist []*regexp.Regexp
 }
 
 type IWatcher struct {
-	fileUpdates chan string
+	// HashVerification reports a file only when its content hash changes,
+	// size and modtime are still used as a pre-filter.
+	HashVerification bool
+
+	// Debounce is the cooldown after a file is reported during which its further changes are held back,
+	// they are reported once the cooldown is over. 0 disables it.
+	// With HashVerification a change of the content is reported at once, it bypasses the cooldown.
+	Debounce time.Duration
+
+	// FollowSymlinks descends into symlinked directories and stats symlinked files through their target,
+	// every real directory is scanned once so symlink cycles terminate.
+	FollowSymlinks bool
+
+	// MaxFileSize excludes larger files from the updates, 0 means no limit.
+	MaxFileSize int64
+
+	// OnOversized is called without the lock for a file excluded by MaxFileSize, e.g. to tell the chat about it.
+	// It is called once per file and again only after the file was below the limit in between.
+	OnOversized func(path string, size, limit int64)
+
+	// SkipEmptyFiles excludes zero-byte files, e.g. just created ones, they are reported once they have content.
+	// Named pipes, sockets and devices are always excluded.
+	SkipEmptyFiles bool
+
+	// MaxDepth is how many directory levels of a watched directory are scanned,
+	// 1 scans only the files directly in it. 0 means no limit.
+	MaxDepth int
+
+	// ExcludeDirs are filepath.Match patterns of directory names that are not descended into,
+	// e.g. "node_modules" or ".git".
+	ExcludeDirs []string
+
+	// IgnoreDefaults excludes hidden, temporary, swap and lock files of watched directories, see isIgnored.
+	// It is enabled by NewWatcher and applies on top of the directory filters.
+	IgnoreDefaults bool
+
+	// Logger gets the failures to save the state and the skipped files, slog.Default unless replaced.
+	Logger *slog.Logger
+
+	mu          sync.Mutex
+	watchedDirs map[string]*watchedDir
+	// removedDirs keep the filters of the directories dropped by RemoveDir for AddDir
+	removedDirs  map[string]*watchedDir
+	watchedFiles map[string]*watchedFile
+	// singleFiles are the files added with AddFile
+	singleFiles map[string]bool
+	// oversized keeps the files skipped for their size, so that they are logged only once,
+	// newOversized the sizes of those not yet passed to OnOversized
+	oversized    map[string]bool
+	newOversized map[string]int64
+	// store persists watchedFiles, nil keeps them in memory only
+	store  state.Store[FileState]
+	closed bool
 }
 
 func NewWatcher() *IWatcher {
 	return &IWatcher{
-		fileUpdates: make(chan string),
+		MaxFileSize:    DefaultMaxFileSize,
+		IgnoreDefaults: true,
+		Logger:         slog.Default(),
+		watchedDirs:    make(map[string]*watchedDir),
+		removedDirs:    make(map[string]*watchedDir),
+		watchedFiles:   make(map[string]*watchedFile),
+		singleFiles:    make(map[string]bool),
+		oversized:      make(map[string]bool),
+		newOversized:   make(map[string]int64),
 	}
 }
 
+// SetStateStore loads the recorded files from store and saves every change there,
+// so that files synced before a restart are not reported again.
+func (w *IWatcher) SetStateStore(store state.Store[FileState]) error {
+	files, err := store.List()
+	if err != nil {
+		return fmt.Errorf("load watcher state: %w", err)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for path, f := range files {
+		w.watchedFiles[path] = &watchedFile{size: f.Size, modTime: f.ModTime, hash: f.Hash}
+	}
+
+	w.store = store
+
+	return nil
+}
+
+// Close stops watching and releases the state store if it is an io.Closer,
+// the other methods return ErrWatcherClosed afterwards. It is safe to call more than once.
+func (w *IWatcher) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return nil
+	}
+
+	w.closed = true
+
+	clear(w.watchedDirs)
+	clear(w.singleFiles)
+
+	if closer, ok := w.store.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			return fmt.Errorf("close watcher state: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// AddFile polls a single file with GetUpdatedFiles, it is reported once it changes.
 func (w *IWatcher) AddFile(path string) error {
 	return w.watchFile(path)
 }
 
 func (w *IWatcher) AddDir(path string) error {
-	return w.watchDir(path)
+	return w.watchDir(path, nil)
 }
 
+// RemoveDir stops watching the directory, e.g. after it was deleted. The recorded files are kept and
+// AddDir restores its filters, so that a directory that comes back only reports what changed meanwhile.
+func (w *IWatcher) RemoveDir(path string) {
+	path = filepath.Clean(path)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if filters, ok := w.watchedDirs[path]; ok {
+		w.removedDirs[path] = filters
+		delete(w.watchedDirs, path)
+	}
+}
+
+// AddDirWithFilters watches the directory syncing only the paths passing the filters,
+// the filters of an already watched directory are replaced.
+func (w *IWatcher) AddDirWithFilters(path string, whitelist, blacklist []*regexp.Regexp) error {
+	return w.watchDir(path, &watchedDir{whitelist: whitelist, blacklist: blacklist})
+}
+
+// GetUpdatedFiles returns new or modified files of all watched directories
+// and the changed files added with AddFile, and records them as synced.
+// The errors of the directories that could not be scanned, fully or in part, are joined.
 func (w *IWatcher) GetUpdatedFiles() ([]string, error) {
-	files := make([]string, 1)
-	files[0] = "README.md"
+	w.mu.Lock()
+	dirs, closed := slices.Sorted(maps.Keys(w.watchedDirs)), w.closed
+	w.mu.Unlock()
+
+	if closed {
+		return nil, ErrWatcherClosed
+	}
+
+	var (
+		updated []string
+		errs    []error
+	)
+
+	for _, dir := range dirs {
+		files, err := w.GetUpdatedFilesIn(dir)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", dir, err))
+		}
+
+		updated = append(updated, files...)
+	}
+
+	updated = append(updated, w.recordChanges(w.changedSingleFiles(), true)...)
+
+	return updated, errors.Join(errs...)
+}
+
+// GetUpdatedFilesIn returns new or modified files under dir and records them as synced.
+// With ErrPartialScan the files that could be read are returned as well.
+func (w *IWatcher) GetUpdatedFilesIn(dir string) ([]string, error) {
+	return w.updatedFilesIn(dir, true)
+}
+
+// PeekUpdatedFilesIn is GetUpdatedFilesIn without recording anything,
+// the same files are reported again by the next call.
+func (w *IWatcher) PeekUpdatedFilesIn(dir string) ([]string, error) {
+	return w.updatedFilesIn(dir, false)
+}
+
+// PrimeState records the current files under dir as synced without reporting them,
+// so that only the files added or changed later are reported.
+func (w *IWatcher) PrimeState(dir string) error {
+	_, err := w.updatedFilesIn(dir, true)
+
+	return err
+}
+
+// TrackedFiles returns the number of files under dir that have been recorded as synced.
+func (w *IWatcher) TrackedFiles(dir string) int {
+	prefix := filepath.Clean(dir) + string(filepath.Separator)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	n := 0
+
+	for path := range w.watchedFiles {
+		if strings.HasPrefix(path, prefix) {
+			n++
+		}
+	}
+
+	return n
+}
+
+// ListFiles returns the sorted paths of all files under dir that pass its filters, synced or not.
+// Like GetUpdatedFilesIn it returns the readable files with ErrPartialScan.
+func (w *IWatcher) ListFiles(dir string) ([]string, error) {
+	files, err := w.scanFiles(filepath.Clean(dir))
+	if files == nil {
+		return nil, err
+	}
+
+	return slices.Sorted(maps.Keys(files)), err
+}
+
+// Forget drops the recorded state of the file, so that it is reported again by the next call,
+// e.g. after its upload failed.
+func (w *IWatcher) Forget(path string) {
+	path = filepath.Clean(path)
+
+	w.mu.Lock()
+	delete(w.watchedFiles, path)
+	store := w.store
+	w.mu.Unlock()
+
+	if store != nil {
+		if err := store.Delete(path); err != nil {
+			w.Logger.Error("failed to save watcher state", "file", path, "error", err)
+		}
+	}
+}
+
+func (w *IWatcher) updatedFilesIn(dir string, record bool) ([]string, error) {
+	dir = filepath.Clean(dir)
+
+	changed, err := w.changedFiles(dir)
+	if changed == nil {
+		return nil, err
+	}
 
-	return files, nil
+	return w.recordChanges(changed, record), err
 }
 
+// recordChanges drops the files whose content hash is unchanged (with HashVerification)
+// and records the others as synced if record is set. It returns the sorted paths of the changed files.
+func (w *IWatcher) recordChanges(changed map[string]os.FileInfo, record bool) []string {
+	// hashing is done without the lock, large files may take a while
+	hashes := make(map[string]string, len(changed))
+
+	if w.HashVerification {
+		for path := range changed {
+			hash, err := hashFile(path)
+			if err != nil {
+				delete(changed, path)
+
+				continue
+			}
+
+			hashes[path] = hash
+		}
+	}
+
+	var updated []string
+
+	recorded := make(map[string]FileState)
+	now := time.Now()
+
+	w.mu.Lock()
+
+	for path, info := range changed {
+		prev, ok := w.watchedFiles[path]
+		if ok && w.HashVerification && prev.hash == hashes[path] {
+			// touched, but the content is the same
+			if record {
+				prev.size = info.Size()
+				prev.modTime = info.ModTime()
+				recorded[path] = prev.state()
+			}
+
+			continue
+		}
+
+		updated = append(updated, path)
+
+		if record {
+			f := &watchedFile{
+				size:     info.Size(),
+				modTime:  info.ModTime(),
+				hash:     hashes[path],
+				lastSync: now,
+			}
+			w.watchedFiles[path] = f
+			recorded[path] = f.state()
+		}
+	}
+
+	store := w.store
+	w.mu.Unlock()
+
+	if store != nil {
+		if err := store.PutAll(recorded); err != nil {
+			w.Logger.Error("failed to save watcher state", "error", err)
+		}
+	}
+
+	// walk order
+	slices.Sort(updated)
+
+	return updated
+}
+
+// changedFiles returns files under dir whose size or modtime differ from the recorded ones,
+// a cheap pre-filter before hashing. The directory is walked without the lock,
+// it is taken only to compare with the recorded state.
+func (w *IWatcher) changedFiles(dir string) (map[string]os.FileInfo, error) {
+	files, err := w.scanFiles(dir)
+	if files == nil {
+		return nil, err
+	}
+
+	w.mu.Lock()
+
+	for path, info := range files {
+		if !w.isChanged(path, info) {
+			delete(files, path)
+		}
+	}
+
+	w.mu.Unlock()
+	w.notifyOversized()
+
+	return files, err
+}
+
+// scanFiles returns the files under the watched dir that pass its filters.
+func (w *IWatcher) scanFiles(dir string) (map[string]os.FileInfo, error) {
+	w.mu.Lock()
+	watched, ok := w.watchedDirs[dir]
+	ignoreDefaults, skipEmpty, closed := w.IgnoreDefaults, w.SkipEmptyFiles, w.closed
+	opts := scanOptions{followSymlinks: w.FollowSymlinks, maxDepth: w.MaxDepth, excludeDirs: w.ExcludeDirs}
+	w.mu.Unlock()
+
+	if closed {
+		return nil, ErrWatcherClosed
+	}
+
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", dir, errNotWatched)
+	}
+
+	// watched is not modified, AddDirWithFilters replaces it
+	files, err := scanDirectory(dir, opts)
+	if files == nil {
+		return nil, err
+	}
+
+	for path, info := range files {
+		if ignoreDefaults && isIgnored(dir, path) || !watched.isWhitelisted(path) || watched.isBlacklisted(path) ||
+			isSpecial(path, info.Mode()) || skipEmpty && info.Size() == 0 {
+			delete(files, path)
+		}
+	}
+
+	return files, err
+}
+
+// isSpecial reports whether the file at path of mode can't be uploaded: named pipes, sockets and devices.
+// Without FollowSymlinks the scan sees links themselves, they are uploaded as their target and kept only
+// if it is a regular file. A link to a pipe would block the upload, one to a directory or a broken one fails it.
+func isSpecial(path string, mode fs.FileMode) bool {
+	if mode&fs.ModeSymlink == 0 {
+		return !mode.IsRegular()
+	}
+
+	info, err := os.Stat(path)
+
+	return err != nil || !info.Mode().IsRegular()
+}
+
+// changedSingleFiles returns the files added with AddFile that changed since they were recorded.
+func (w *IWatcher) changedSingleFiles() map[string]os.FileInfo {
+	w.mu.Lock()
+	paths, skipEmpty := slices.Collect(maps.Keys(w.singleFiles)), w.SkipEmptyFiles
+	w.mu.Unlock()
+
+	files := make(map[string]os.FileInfo, len(paths))
+
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil || !info.Mode().IsRegular() || skipEmpty && info.Size() == 0 {
+			continue
+		}
+
+		files[path] = info
+	}
+
+	w.mu.Lock()
+
+	for path, info := range files {
+		if !w.isChanged(path, info) {
+			delete(files, path)
+		}
+	}
+
+	w.mu.Unlock()
+	w.notifyOversized()
+
+	return files
+}
+
+// notifyOversized passes the files newly skipped for their size to OnOversized.
+func (w *IWatcher) notifyOversized() {
+	w.mu.Lock()
+	files, limit, onOversized := w.newOversized, w.MaxFileSize, w.OnOversized
+	w.newOversized = make(map[string]int64)
+	w.mu.Unlock()
+
+	if onOversized == nil {
+		return
+	}
+
+	for _, path := range slices.Sorted(maps.Keys(files)) {
+		onOversized(path, files[path], limit)
+	}
+}
+
+// isChanged reports whether the file differs from its recorded size or modtime and may be reported now,
+// the caller holds mu.
+func (w *IWatcher) isChanged(path string, info os.FileInfo) bool {
+	if w.MaxFileSize > 0 && info.Size() > w.MaxFileSize {
+		if !w.oversized[path] {
+			w.oversized[path] = true
+			w.newOversized[path] = info.Size()
+			w.Logger.Warn("skipping file above the size limit", "file", path, "size", info.Size(),
+				"limit", w.MaxFileSize)
+		}
+
+		return false
+	}
+
+	delete(w.oversized, path)
+
+	prev, ok := w.watchedFiles[path]
+	if !ok {
+		return true
+	}
+
+	if prev.size == info.Size() && prev.modTime.Equal(info.ModTime()) {
+		return false
+	}
+
+	// the content is compared by recordChanges, a new one is reported even while cooling down
+	if w.HashVerification {
+		return true
+	}
+
+	// still cooling down, the state is not recorded so the change is reported later
+	return w.Debounce <= 0 || time.Since(prev.lastSync) >= w.Debounce
+}
+
+// watchFile adds a single file, its current state is recorded so that only later changes are reported.
 func (w *IWatcher) watchFile(filePath string) error {
-	initialStat, err := os.Stat(filePath)
+	info, err := os.Stat(filePath)
 	if err != nil {
 		return err
 	}
 
-	// TODO: go func () ... for {-> channel}
-	for {
-		stat, err := os.Stat(filePath)
-		if err != nil {
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("%s is not a regular file", filePath)
+	}
+
+	filePath = filepath.Clean(filePath)
+
+	var hash string
+
+	if w.HashVerification {
+		if hash, err = hashFile(filePath); err != nil {
 			return err
 		}
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return ErrWatcherClosed
+	}
+
+	w.singleFiles[filePath] = true
+
+	if _, ok := w.watchedFiles[filePath]; ok {
+		return nil
+	}
+
+	f := &watchedFile{size: info.Size(), modTime: info.ModTime(), hash: hash}
+	w.watchedFiles[filePath] = f
+
+	if w.store != nil {
+		return w.store.Put(filePath, f.state())
+	}
 
-		if stat.Size() != initialStat.Size() || stat.ModTime() != initialStat.ModTime() {
-			break
+	return nil
+}
+
+// watchDir adds the directory, nil filters keep the ones of an already watched directory.
+func (w *IWatcher) watchDir(dirPath string, filters *watchedDir) error {
+	stat, err := os.Stat(dirPath)
+	if err != nil {
+		return err
+	}
+
+	if !stat.IsDir() {
+		return fmt.Errorf("%s is not a directory", dirPath)
+	}
+
+	dirPath = filepath.Clean(dirPath)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return ErrWatcherClosed
+	}
+
+	if filters == nil {
+		if _, ok := w.watchedDirs[dirPath]; ok {
+			return nil
 		}
 
-		time.Sleep(1 * time.Second)
+		filters = cmp.Or(w.removedDirs[dirPath], &watchedDir{})
 	}
 
+	delete(w.removedDirs, dirPath)
+	w.watchedDirs[dirPath] = filters
+
 	return nil
 }
 
-func (w *IWatcher) watchDir(dirPath string) error {
-	return errors.New("TODO")
+func (d *watchedDir) isWhitelisted(path string) bool {
+	if len(d.whitelist) == 0 {
+		return true
+	}
+
+	for _, re := range d.whitelist {
+		if re.MatchString(path) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (d *watchedDir) isBlacklisted(path string) bool {
+	for _, re := range d.blacklist {
+		if re.MatchString(path) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// scanOptions control which directories scanDirectory descends into.
+type scanOptions struct {
+	followSymlinks bool
+	maxDepth       int
+	excludeDirs    []string
+}
+
+// skipDir reports whether the directory at path below root is not scanned, see IWatcher.MaxDepth
+// and IWatcher.ExcludeDirs.
+func (o scanOptions) skipDir(root, path string) bool {
+	if path == root {
+		return false
+	}
+
+	if o.maxDepth > 0 {
+		rel, err := filepath.Rel(root, path)
+		if err == nil && strings.Count(rel, string(filepath.Separator))+1 >= o.maxDepth {
+			return true
+		}
+	}
+
+	name := filepath.Base(path)
+
+	return slices.ContainsFunc(o.excludeDirs, func(pattern string) bool {
+		matched, _ := filepath.Match(pattern, name) // bad patterns never match
+
+		return matched
+	})
+}
+
+// scanDirectory returns all files under dirPath. The paths that could not be read are skipped
+// and returned in an ErrPartialScan with the other files, files removed during the scan are ignored.
+func scanDirectory(dirPath string, opts scanOptions) (map[string]os.FileInfo, error) {
+	if _, err := os.Stat(dirPath); err != nil {
+		return nil, err
+	}
+
+	files := make(map[string]os.FileInfo)
+
+	var errs []error
+
+	if opts.followSymlinks {
+		scanFollowingSymlinks(dirPath, dirPath, opts, files, make(map[string]bool), &errs)
+
+		return files, scanError(errs)
+	}
+
+	_ = filepath.Walk(dirPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if !errors.Is(err, fs.ErrNotExist) {
+				errs = append(errs, err)
+			}
+
+			return nil
+		}
+
+		if info.IsDir() {
+			if opts.skipDir(dirPath, path) {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		files[path] = info
+
+		return nil
+	})
+
+	return files, scanError(errs)
+}
+
+// scanError wraps the errors of the unreadable paths of a scan into ErrPartialScan, nil if there are none.
+func scanError(errs []error) error {
+	if len(errs) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("%w: %w", ErrPartialScan, errors.Join(errs...))
+}
+
+// scanFollowingSymlinks adds the files under dirPath of the watched root to files, visited holds the real paths
+// of the directories scanned so far. Broken links are skipped, the errors of unreadable entries are added to errs.
+func scanFollowingSymlinks(
+	root, dirPath string, opts scanOptions, files map[string]os.FileInfo, visited map[string]bool, errs *[]error,
+) {
+	realPath, err := filepath.EvalSymlinks(dirPath)
+	if err != nil || visited[realPath] {
+		return
+	}
+
+	visited[realPath] = true
+
+	entries, err := os.ReadDir(dirPath)
+	if err != nil {
+		*errs = append(*errs, err)
+
+		return
+	}
+
+	for _, entry := range entries {
+		path := filepath.Join(dirPath, entry.Name())
+
+		// os.Stat follows the link
+		info, err := os.Stat(path)
+		if err != nil {
+			if !errors.Is(err, fs.ErrNotExist) {
+				*errs = append(*errs, err)
+			}
+
+			continue
+		}
+
+		if info.IsDir() {
+			if !opts.skipDir(root, path) {
+				scanFollowingSymlinks(root, path, opts, files, visited, errs)
+			}
+
+			continue
+		}
+
+		files[path] = info
+	}
+}
+
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("hash %s: %w", path, err)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
 }
@@ -1 +1,800 @@
 package file
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/k0ff1l/tgcloudbot/internal/services/state"
+)
+
+func TestPeekUpdatedFilesKeepsState(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	w := NewWatcher()
+	if err := w.AddDir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	for range 2 {
+		files, err := w.PeekUpdatedFilesIn(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(files) != 1 {
+			t.Fatalf("peek must keep reporting the file, got %v", files)
+		}
+	}
+
+	files, err := w.GetUpdatedFilesIn(dir)
+	if err != nil || len(files) != 1 {
+		t.Fatalf("expected the file once, got %v, %v", files, err)
+	}
+
+	files, err = w.GetUpdatedFilesIn(dir)
+	if err != nil || len(files) != 0 {
+		t.Fatalf("expected no updates after recording, got %v, %v", files, err)
+	}
+}
+
+func TestHashVerificationSkipsTouchedFiles(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.txt")
+
+	if err := os.WriteFile(path, []byte("same"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	w := NewWatcher()
+	w.HashVerification = true
+
+	if err := w.AddDir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	if files, _ := w.GetUpdatedFilesIn(dir); len(files) != 1 {
+		t.Fatalf("expected the new file, got %v", files)
+	}
+
+	touched := time.Now().Add(time.Hour)
+	if err := os.Chtimes(path, touched, touched); err != nil {
+		t.Fatal(err)
+	}
+
+	if files, _ := w.GetUpdatedFilesIn(dir); len(files) != 0 {
+		t.Fatalf("touched file with the same content must not be reported, got %v", files)
+	}
+
+	if err := os.WriteFile(path, []byte("diff"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if files, _ := w.GetUpdatedFilesIn(dir); len(files) != 1 {
+		t.Fatalf("changed content must be reported, got %v", files)
+	}
+}
+
+func TestAddDirWithFilters(t *testing.T) {
+	dir := t.TempDir()
+
+	for _, name := range []string{"a.jpg", "b.png", "c.txt", "d.tmp.jpg"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	w := NewWatcher()
+
+	err := w.AddDirWithFilters(dir,
+		[]*regexp.Regexp{regexp.MustCompile(`\.jpg$`), regexp.MustCompile(`\.png$`)},
+		[]*regexp.Regexp{regexp.MustCompile(`\.tmp\.`)},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := w.GetUpdatedFilesIn(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{filepath.Join(dir, "a.jpg"), filepath.Join(dir, "b.png")}
+	if !slices.Equal(files, want) {
+		t.Errorf("got %v, want %v", files, want)
+	}
+}
+
+func TestMaxFileSize(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "big.bin")
+
+	if err := os.WriteFile(path, make([]byte, 10), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var notices []string
+
+	w := NewWatcher()
+	w.MaxFileSize = 5
+	w.OnOversized = func(path string, size, limit int64) {
+		notices = append(notices, fmt.Sprintf("%s %d/%d", filepath.Base(path), size, limit))
+	}
+
+	if err := w.AddDir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	for range 2 {
+		if files, _ := w.GetUpdatedFilesIn(dir); len(files) != 0 {
+			t.Fatalf("oversized file must be skipped, got %v", files)
+		}
+	}
+
+	if !slices.Equal(notices, []string{"big.bin 10/5"}) {
+		t.Errorf("expected one notice of the oversized file, got %v", notices)
+	}
+
+	if err := os.WriteFile(path, []byte("ok"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if files, _ := w.GetUpdatedFilesIn(dir); len(files) != 1 {
+		t.Fatalf("file back under the limit must be reported, got %v", files)
+	}
+
+	// noticed again once it grows over the limit again
+	if err := os.WriteFile(path, make([]byte, 20), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	_, _ = w.GetUpdatedFilesIn(dir)
+
+	if len(notices) != 2 || notices[1] != "big.bin 20/5" {
+		t.Errorf("expected a second notice, got %v", notices)
+	}
+}
+
+func TestDebounce(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+
+	w := NewWatcher()
+	w.Debounce = time.Hour
+
+	if err := w.AddDir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	uploads := 0
+
+	for i := range 5 {
+		if err := os.WriteFile(path, []byte(strings.Repeat("x", i+1)), 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		files, err := w.GetUpdatedFilesIn(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		uploads += len(files)
+	}
+
+	if uploads != 1 {
+		t.Fatalf("expected one upload within the cooldown, got %d", uploads)
+	}
+
+	// the held back change is reported after the cooldown
+	w.mu.Lock()
+	w.watchedFiles[path].lastSync = time.Now().Add(-2 * time.Hour)
+	w.mu.Unlock()
+
+	if files, _ := w.GetUpdatedFilesIn(dir); len(files) != 1 {
+		t.Errorf("expected the last change after the cooldown, got %v", files)
+	}
+}
+
+func TestDebounceWithHashVerification(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "notes.txt")
+
+	w := NewWatcher()
+	w.Debounce = time.Hour
+	w.HashVerification = true
+
+	if err := w.AddDir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	uploads := 0
+
+	for i := range 3 {
+		if err := os.WriteFile(path, []byte(strings.Repeat("x", i+1)), 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		files, err := w.GetUpdatedFilesIn(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		uploads += len(files)
+	}
+
+	if uploads != 3 {
+		t.Fatalf("expected every change of the content within the cooldown, got %d uploads", uploads)
+	}
+
+	// a touch keeps the content
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+
+	if files, _ := w.GetUpdatedFilesIn(dir); len(files) != 0 {
+		t.Errorf("expected a touch not to be reported, got %v", files)
+	}
+}
+
+func TestFollowSymlinks(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "watched")
+	target := filepath.Join(root, "target")
+
+	for _, d := range []string{dir, target} {
+		if err := os.Mkdir(d, 0o700); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := os.WriteFile(filepath.Join(target, "a.txt"), []byte("hello"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// watched/linked -> target, target/loop -> watched
+	if err := os.Symlink(target, filepath.Join(dir, "linked")); err != nil {
+		t.Skip("symlinks not supported:", err)
+	}
+
+	if err := os.Symlink(dir, filepath.Join(target, "loop")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Symlink(filepath.Join(target, "a.txt"), filepath.Join(dir, "b.txt")); err != nil {
+		t.Fatal(err)
+	}
+
+	w := NewWatcher()
+	w.FollowSymlinks = true
+
+	if err := w.AddDir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := w.GetUpdatedFilesIn(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{filepath.Join(dir, "b.txt"), filepath.Join(dir, "linked", "a.txt")}
+	if !slices.Equal(files, want) {
+		t.Fatalf("expected %v, got %v", want, files)
+	}
+
+	if size := w.watchedFiles[filepath.Join(dir, "b.txt")].size; size != 5 {
+		t.Errorf("symlinked file must be stat'd through its target, got size %d", size)
+	}
+}
+
+func TestAddFileDoesNotBlock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notes.txt")
+	if err := os.WriteFile(path, []byte("v1"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	w := NewWatcher()
+
+	done := make(chan error, 1)
+
+	go func() { done <- w.AddFile(path) }()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("AddFile blocked")
+	}
+
+	if files, _ := w.GetUpdatedFiles(); len(files) != 0 {
+		t.Fatalf("unchanged file must not be reported, got %v", files)
+	}
+
+	if err := os.WriteFile(path, []byte("v2 longer"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if files, _ := w.GetUpdatedFiles(); !slices.Equal(files, []string{path}) {
+		t.Fatalf("expected the changed file, got %v", files)
+	}
+
+	if files, _ := w.GetUpdatedFiles(); len(files) != 0 {
+		t.Errorf("file must be reported once, got %v", files)
+	}
+}
+
+func TestWatchedDirFilters(t *testing.T) {
+	multi := &watchedDir{
+		whitelist: []*regexp.Regexp{regexp.MustCompile(`\.jpg$`), regexp.MustCompile(`\.png$`)},
+		blacklist: []*regexp.Regexp{regexp.MustCompile(`^tmp/`), regexp.MustCompile(`\.part$`)},
+	}
+
+	cases := []struct {
+		dir  *watchedDir
+		path string
+		want bool
+	}{
+		{multi, "a.jpg", true},
+		{multi, "b.png", true},
+		{multi, "c.txt", false},
+		{multi, "tmp/d.jpg", false},
+		{multi, "e.png.part", false},
+		// an empty whitelist allows everything
+		{&watchedDir{}, "c.txt", true},
+	}
+
+	for _, tc := range cases {
+		if got := tc.dir.isWhitelisted(tc.path) && !tc.dir.isBlacklisted(tc.path); got != tc.want {
+			t.Errorf("%s: got %v, want %v", tc.path, got, tc.want)
+		}
+	}
+}
+
+func TestAddDirDuringScan(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, 10, 50)
+
+	w := NewWatcher()
+	if err := w.AddDir(root); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan []string)
+
+	go func() {
+		files, _ := w.GetUpdatedFilesIn(root)
+		done <- files
+	}()
+
+	// replaces the filters of the directory being scanned
+	if err := w.AddDirWithFilters(root, []*regexp.Regexp{regexp.MustCompile(`\.none$`)}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if files := <-done; len(files) != 0 && len(files) != 500 {
+		t.Errorf("the scan must use one set of filters, got %d files", len(files))
+	}
+
+	if files, err := w.GetUpdatedFilesIn(root); err != nil || len(files) != 0 {
+		t.Errorf("expected the new filters to apply, got %d files, %v", len(files), err)
+	}
+}
+
+// BenchmarkAddDirDuringScan measures how long AddDir waits for the lock while a large tree is scanned.
+func BenchmarkAddDirDuringScan(b *testing.B) {
+	root := b.TempDir()
+	writeTree(b, root, 50, 200)
+
+	other := b.TempDir()
+
+	w := NewWatcher()
+	if err := w.AddDir(root); err != nil {
+		b.Fatal(err)
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+
+	scanning := make(chan struct{})
+
+	go func() {
+		close(scanning)
+
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				_, _ = w.PeekUpdatedFilesIn(root)
+			}
+		}
+	}()
+
+	<-scanning
+
+	for b.Loop() {
+		if err := w.AddDir(other); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// writeTree creates dirs directories of files files each under root.
+func writeTree(tb testing.TB, root string, dirs, files int) {
+	tb.Helper()
+
+	for i := range dirs {
+		dir := filepath.Join(root, fmt.Sprintf("d%03d", i))
+		if err := os.Mkdir(dir, 0o700); err != nil {
+			tb.Fatal(err)
+		}
+
+		for j := range files {
+			if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("f%03d.txt", j)), nil, 0o600); err != nil {
+				tb.Fatal(err)
+			}
+		}
+	}
+}
+
+func TestIgnoreDefaults(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, ".git"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{
+		"a.txt", ".DS_Store", "file.txt.swp", "movie.mkv.crdownload", "~$report.docx", "notes.txt~", ".git/HEAD",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	w := NewWatcher()
+
+	// the defaults apply even to what the whitelist accepts
+	if err := w.AddDirWithFilters(dir, []*regexp.Regexp{regexp.MustCompile(`.`)}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := w.PeekUpdatedFilesIn(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{filepath.Join(dir, "a.txt")}; !slices.Equal(files, want) {
+		t.Errorf("got %v, want %v", files, want)
+	}
+
+	w.IgnoreDefaults = false
+
+	if files, _ := w.PeekUpdatedFilesIn(dir); len(files) != 7 {
+		t.Errorf("expected every file without the defaults, got %v", files)
+	}
+}
+
+func TestIgnoredInHiddenWatchedDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), ".config")
+
+	if isIgnored(dir, filepath.Join(dir, "app.yaml")) {
+		t.Error("only the path below the watched directory counts")
+	}
+}
+
+func TestStateStoreSurvivesRestart(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	statePath := filepath.Join(t.TempDir(), "state.json")
+
+	newWatcher := func() *IWatcher {
+		t.Helper()
+
+		store, err := state.NewFileStore[FileState](statePath)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		w := NewWatcher()
+		if err := w.SetStateStore(store); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := w.AddDir(dir); err != nil {
+			t.Fatal(err)
+		}
+
+		return w
+	}
+
+	if files, _ := newWatcher().GetUpdatedFilesIn(dir); len(files) != 1 {
+		t.Fatalf("expected the file on the first run, got %v", files)
+	}
+
+	w := newWatcher()
+	if files, _ := w.GetUpdatedFilesIn(dir); len(files) != 0 {
+		t.Errorf("the synced file must not be reported after a restart, got %v", files)
+	}
+
+	w.Forget(filepath.Join(dir, "a.txt"))
+
+	if files, _ := newWatcher().GetUpdatedFilesIn(dir); len(files) != 1 {
+		t.Errorf("a forgotten file must be reported after a restart, got %v", files)
+	}
+}
+
+func TestClose(t *testing.T) {
+	dir := t.TempDir()
+
+	w := NewWatcher()
+
+	// a failed AddDir leaves nothing to release
+	if err := w.AddDir(filepath.Join(dir, "missing")); err == nil {
+		t.Fatal("expected an error for a missing directory")
+	}
+
+	if err := w.AddDir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	for range 2 {
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := w.GetUpdatedFilesIn(dir); !errors.Is(err, ErrWatcherClosed) {
+		t.Errorf("expected ErrWatcherClosed, got %v", err)
+	}
+
+	if err := w.AddDir(dir); !errors.Is(err, ErrWatcherClosed) {
+		t.Errorf("expected ErrWatcherClosed, got %v", err)
+	}
+}
+
+func TestMaxDepthAndExcludeDirs(t *testing.T) {
+	dir := t.TempDir()
+
+	for _, name := range []string{"a.txt", "sub/b.txt", "sub/deep/c.txt", ".git/HEAD", "web/node_modules/x/index.js"} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(path, []byte(name), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, followSymlinks := range []bool{false, true} {
+		w := NewWatcher()
+		w.IgnoreDefaults = false
+		w.FollowSymlinks = followSymlinks
+		w.ExcludeDirs = []string{".git", "node_*"}
+
+		if err := w.AddDir(dir); err != nil {
+			t.Fatal(err)
+		}
+
+		files, err := w.PeekUpdatedFilesIn(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := []string{
+			filepath.Join(dir, "a.txt"), filepath.Join(dir, "sub", "b.txt"), filepath.Join(dir, "sub", "deep", "c.txt"),
+		}
+		if !slices.Equal(files, want) {
+			t.Errorf("followSymlinks=%v: got %v, want %v", followSymlinks, files, want)
+		}
+
+		w.MaxDepth = 2
+
+		files, _ = w.PeekUpdatedFilesIn(dir)
+		if !slices.Equal(files, want[:2]) {
+			t.Errorf("followSymlinks=%v, depth 2: got %v, want %v", followSymlinks, files, want[:2])
+		}
+	}
+}
+
+func TestPrimeState(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "old.txt"), []byte("old"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// full: the existing files are reported by the first scan
+	full := NewWatcher()
+	if err := full.AddDir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	if files, err := full.GetUpdatedFilesIn(dir); err != nil || len(files) != 1 {
+		t.Fatalf("expected the existing file, got %v, %v", files, err)
+	}
+
+	// changes only: they are recorded without being reported
+	changesOnly := NewWatcher()
+	if err := changesOnly.AddDir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := changesOnly.PrimeState(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := changesOnly.TrackedFiles(dir); n != 1 {
+		t.Errorf("expected the existing file to be tracked, got %d", n)
+	}
+
+	newPath := filepath.Join(dir, "new.txt")
+	if err := os.WriteFile(newPath, []byte("new"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := changesOnly.GetUpdatedFilesIn(dir)
+	if err != nil || !slices.Equal(files, []string{newPath}) {
+		t.Errorf("expected only the new file, got %v, %v", files, err)
+	}
+
+	if err := changesOnly.PrimeState(filepath.Join(dir, "missing")); !errors.Is(err, errNotWatched) {
+		t.Errorf("expected errNotWatched, got %v", err)
+	}
+}
+
+func TestListFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"b.txt", "a.txt", "skip.tmp"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	w := NewWatcher()
+	if err := w.AddDirWithFilters(dir, nil, []*regexp.Regexp{regexp.MustCompile(`\.tmp$`)}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.txt")}
+
+	// listing doesn't record the files, synced ones are listed as well
+	for range 2 {
+		files, err := w.ListFiles(dir)
+		if err != nil || !slices.Equal(files, want) {
+			t.Fatalf("expected %v, got %v, %v", want, files, err)
+		}
+
+		if _, err := w.GetUpdatedFilesIn(dir); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestSkipEmptyFiles(t *testing.T) {
+	dir := t.TempDir()
+
+	empty := filepath.Join(dir, "empty.txt")
+	if err := os.WriteFile(empty, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	w := NewWatcher()
+	w.SkipEmptyFiles = true
+
+	if err := w.AddDir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	if files, err := w.GetUpdatedFilesIn(dir); err != nil || len(files) != 0 {
+		t.Fatalf("expected the empty file to be skipped, got %v, %v", files, err)
+	}
+
+	// reported once it has content
+	if err := os.WriteFile(empty, []byte("content"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if files, err := w.GetUpdatedFilesIn(dir); err != nil || !slices.Equal(files, []string{empty}) {
+		t.Errorf("expected the file with content, got %v, %v", files, err)
+	}
+
+	// without the option an empty file is reported like any other
+	other := t.TempDir()
+	if err := os.WriteFile(filepath.Join(other, "empty.txt"), nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	all := NewWatcher()
+	if err := all.AddDir(other); err != nil {
+		t.Fatal(err)
+	}
+
+	if files, err := all.GetUpdatedFilesIn(other); err != nil || len(files) != 1 {
+		t.Errorf("expected the empty file, got %v, %v", files, err)
+	}
+}
+
+func TestUnreadableSubdirectory(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("root reads any directory")
+	}
+
+	dir := t.TempDir()
+	readable := filepath.Join(dir, "a.txt")
+
+	if err := os.WriteFile(readable, []byte("a"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	locked := filepath.Join(dir, "locked")
+	if err := os.Mkdir(locked, 0o000); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { _ = os.Chmod(locked, 0o700) })
+
+	w := NewWatcher()
+	if err := w.AddDir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := w.GetUpdatedFiles()
+	if !errors.Is(err, ErrPartialScan) || !errors.Is(err, os.ErrPermission) {
+		t.Errorf("expected a partial scan error, got %v", err)
+	}
+
+	if !slices.Equal(files, []string{readable}) {
+		t.Errorf("expected the readable file, got %v", files)
+	}
+}
+
+func TestRemoveDir(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.txt", "b.log"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	w := NewWatcher()
+	if err := w.AddDirWithFilters(dir, []*regexp.Regexp{regexp.MustCompile(`\.txt$`)}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if files, err := w.GetUpdatedFilesIn(dir); err != nil || len(files) != 1 {
+		t.Fatalf("expected a.txt, got %v, %v", files, err)
+	}
+
+	w.RemoveDir(dir)
+
+	if _, err := w.GetUpdatedFilesIn(dir); err == nil {
+		t.Error("expected an error for a removed directory")
+	}
+
+	// added again with its filters and recorded files
+	if err := os.WriteFile(filepath.Join(dir, "c.txt"), []byte("c"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := w.AddDir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	if files, err := w.GetUpdatedFilesIn(dir); err != nil || !slices.Equal(files, []string{filepath.Join(dir, "c.txt")}) {
+		t.Errorf("expected only the new file passing the filters, got %v, %v", files, err)
+	}
+}
@@ -0,0 +1,46 @@
+//go:build unix
+
+package file
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"syscall"
+	"testing"
+)
+
+func TestSpecialFilesAreSkipped(t *testing.T) {
+	dir := t.TempDir()
+	if err := syscall.Mkfifo(filepath.Join(dir, "pipe"), 0o600); err != nil {
+		t.Skip("can't create a named pipe:", err)
+	}
+
+	regular := filepath.Join(dir, "a.txt")
+	if err := os.WriteFile(regular, []byte("a"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// a link is uploaded as its target
+	link := filepath.Join(dir, "link.txt")
+	if err := os.Symlink(regular, link); err != nil {
+		t.Fatal(err)
+	}
+
+	// unless the target is special too or a directory
+	for name, target := range map[string]string{"pipe-link": "pipe", "dir-link": t.TempDir()} {
+		if err := os.Symlink(target, filepath.Join(dir, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	w := NewWatcher()
+	if err := w.AddDir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := w.GetUpdatedFilesIn(dir)
+	if err != nil || !slices.Equal(files, []string{regular, link}) {
+		t.Errorf("expected the named pipe and the links to it and a directory to be skipped, got %v, %v", files, err)
+	}
+}
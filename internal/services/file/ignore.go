@@ -0,0 +1,49 @@
+package file
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// ignoredSuffixes are the temporary, swap and partial download files of editors and browsers.
+//
+//nolint:gochecknoglobals // read-only lookup table
+var ignoredSuffixes = []string{
+	"~", ".swp", ".swo", ".swx", ".tmp", ".temp",
+	".part", ".partial", ".crdownload", ".download",
+}
+
+// ignoredNames are the files the OS leaves in directories.
+//
+//nolint:gochecknoglobals // read-only lookup table
+var ignoredNames = []string{"Thumbs.db", "desktop.ini"}
+
+// isIgnored reports whether the file at path under the watched dir is hidden or temporary:
+// a dotfile or a file in a dot directory below dir, an editor swap or lock file or a partial download.
+func isIgnored(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+
+	// covers .DS_Store, .git/ and the .#file and .~lock.file# locks
+	for part := range strings.SplitSeq(rel, string(filepath.Separator)) {
+		if strings.HasPrefix(part, ".") {
+			return true
+		}
+	}
+
+	name := filepath.Base(path)
+
+	// ~$file.docx is an Office lock
+	if strings.HasPrefix(name, "~$") || slices.Contains(ignoredNames, name) {
+		return true
+	}
+
+	lower := strings.ToLower(name)
+
+	return slices.ContainsFunc(ignoredSuffixes, func(suffix string) bool {
+		return strings.HasSuffix(lower, suffix)
+	})
+}
@@ -0,0 +1,220 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const (
+	namespace = "tgcloudbot"
+
+	readHeaderTimeout = 5 * time.Second
+	shutdownTimeout   = 5 * time.Second
+)
+
+// Metrics holds the sync statistics, all methods are no-ops on a nil *Metrics.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	filesSynced    prometheus.Counter
+	uploadBytes    prometheus.Counter
+	apiErrors      *prometheus.CounterVec
+	uploadDuration prometheus.Histogram
+	trackedFiles   *prometheus.GaugeVec
+	queueDepth     prometheus.Gauge
+	buildInfo      *prometheus.GaugeVec
+
+	// healthCheck backs /healthz, nil means no health endpoint
+	healthCheck func() error
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		filesSynced: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "files_synced_total",
+			Help:      "Number of files uploaded to Telegram.",
+		}),
+		uploadBytes: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "upload_bytes_total",
+			Help:      "Number of file bytes uploaded to Telegram.",
+		}),
+		apiErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "api_errors_total",
+			Help:      "Number of failed Bot API requests by method and error type.",
+		}, []string{"method", "type"}),
+		uploadDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "upload_duration_seconds",
+			Help:      "Duration of file uploads.",
+			Buckets:   prometheus.ExponentialBuckets(0.1, 2, 12),
+		}),
+		trackedFiles: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "tracked_files",
+			Help:      "Number of files tracked by the watcher per directory.",
+		}, []string{"dir"}),
+		queueDepth: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "upload_queue_depth",
+			Help:      "Number of files queued or being uploaded.",
+		}),
+		buildInfo: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "build_info",
+			Help:      "Always 1, the labels describe the running build.",
+		}, []string{"version", "commit", "date", "goversion"}),
+	}
+
+	m.registry.MustRegister(
+		m.filesSynced, m.uploadBytes, m.apiErrors, m.uploadDuration, m.trackedFiles, m.queueDepth, m.buildInfo,
+	)
+
+	return m
+}
+
+// FileSynced records a successful upload.
+func (m *Metrics) FileSynced(size int64, duration time.Duration) {
+	if m == nil {
+		return
+	}
+
+	m.filesSynced.Inc()
+	m.uploadBytes.Add(float64(size))
+	m.uploadDuration.Observe(duration.Seconds())
+}
+
+// APIError records a failed Bot API request, errType is e.g. an HTTP status code or "network".
+func (m *Metrics) APIError(method, errType string) {
+	if m == nil {
+		return
+	}
+
+	m.apiErrors.WithLabelValues(method, errType).Inc()
+}
+
+// SetTrackedFiles sets the number of files tracked in dir.
+func (m *Metrics) SetTrackedFiles(dir string, n int) {
+	if m == nil {
+		return
+	}
+
+	m.trackedFiles.WithLabelValues(dir).Set(float64(n))
+}
+
+// SetUploadQueueDepth sets the number of files queued or being uploaded.
+func (m *Metrics) SetUploadQueueDepth(n int) {
+	if m == nil {
+		return
+	}
+
+	m.queueDepth.Set(float64(n))
+}
+
+// SetBuildInfo sets the labels of the build_info gauge.
+func (m *Metrics) SetBuildInfo(version, commit, date, goVersion string) {
+	if m == nil {
+		return
+	}
+
+	m.buildInfo.Reset()
+	m.buildInfo.WithLabelValues(version, commit, date, goVersion).Set(1)
+}
+
+// SetHealthCheck serves /healthz with Serve: 200 when check succeeds, 503 otherwise.
+// It must be called before Serve.
+func (m *Metrics) SetHealthCheck(check func() error) {
+	m.healthCheck = check
+}
+
+// HealthHandler answers 200 when the health check succeeds and 503 with the error otherwise.
+func (m *Metrics) HealthHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if err := m.healthCheck(); err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+
+			return
+		}
+
+		_, _ = w.Write([]byte("ok\n"))
+	})
+}
+
+// Handler serves the metrics in the Prometheus text format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// RoundTripper counts failed Bot API requests, the method is the last element of the URL path.
+func (m *Metrics) RoundTripper(next http.RoundTripper) http.RoundTripper {
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		resp, err := next.RoundTrip(req)
+
+		switch {
+		case err != nil:
+			m.APIError(path.Base(req.URL.Path), "network")
+		case resp.StatusCode >= http.StatusBadRequest:
+			m.APIError(path.Base(req.URL.Path), strconv.Itoa(resp.StatusCode))
+		}
+
+		return resp, err
+	})
+}
+
+// Serve exposes /metrics (and /healthz, see SetHealthCheck) on addr until ctx is done.
+func (m *Metrics) Serve(ctx context.Context, addr string) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", m.Handler())
+
+	if m.healthCheck != nil {
+		mux.Handle("/healthz", m.HealthHandler())
+	}
+
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: readHeaderTimeout,
+	}
+
+	errCh := make(chan error, 1)
+
+	go func() {
+		errCh <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		return fmt.Errorf("metrics server: %w", err)
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), shutdownTimeout)
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("shutdown metrics server: %w", err)
+	}
+
+	if err := <-errCh; !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("metrics server: %w", err)
+	}
+
+	return nil
+}
+
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
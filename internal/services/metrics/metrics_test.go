@@ -0,0 +1,86 @@
+package metrics
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetricsExposition(t *testing.T) {
+	m := New()
+	m.FileSynced(1024, 2*time.Second)
+	m.SetTrackedFiles("/srv", 3)
+	m.SetUploadQueueDepth(2)
+	m.SetBuildInfo("v1.2.3", "abc1234", "2024-01-02", "go1.25.4")
+
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer api.Close()
+
+	client := &http.Client{Transport: m.RoundTripper(http.DefaultTransport)}
+
+	resp, err := client.Post(api.URL+"/bottoken/sendDocument", "text/plain", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	body, _ := io.ReadAll(rec.Body)
+
+	for _, want := range []string{
+		"tgcloudbot_files_synced_total 1",
+		"tgcloudbot_upload_bytes_total 1024",
+		`tgcloudbot_api_errors_total{method="sendDocument",type="429"} 1`,
+		"tgcloudbot_upload_duration_seconds_count 1",
+		`tgcloudbot_tracked_files{dir="/srv"} 3`,
+		"tgcloudbot_upload_queue_depth 2",
+		`tgcloudbot_build_info{commit="abc1234",date="2024-01-02",goversion="go1.25.4",version="v1.2.3"} 1`,
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("missing %q in:\n%s", want, body)
+		}
+	}
+}
+
+func TestNilMetrics(t *testing.T) {
+	var m *Metrics
+
+	m.FileSynced(1, time.Second)
+	m.APIError("sendMessage", "400")
+	m.SetTrackedFiles("/srv", 1)
+	m.SetUploadQueueDepth(1)
+	m.SetBuildInfo("v1.2.3", "abc1234", "2024-01-02", "go1.25.4")
+}
+
+func TestHealthHandler(t *testing.T) {
+	m := New()
+
+	var healthErr error
+
+	m.SetHealthCheck(func() error { return healthErr })
+
+	for _, tt := range []struct {
+		err  error
+		want int
+	}{
+		{nil, http.StatusOK},
+		{errors.New("unauthorized"), http.StatusServiceUnavailable},
+	} {
+		healthErr = tt.err
+
+		rec := httptest.NewRecorder()
+		m.HealthHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+		if rec.Code != tt.want {
+			t.Errorf("health check error %v: status %d, want %d", tt.err, rec.Code, tt.want)
+		}
+	}
+}
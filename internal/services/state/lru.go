@@ -0,0 +1,154 @@
+package state
+
+import (
+	"container/list"
+	"sync"
+)
+
+var _ Store[struct{}] = (*LRUStore[struct{}])(nil)
+
+// LRUStore caches the most recently used values of another store in memory, e.g. so that a store
+// slower than FileStore isn't consulted for every file. Writes go through to the store, Get falls
+// through to it on a miss. Keys missing from the store aren't cached.
+type LRUStore[V any] struct {
+	store Store[V]
+	size  int
+
+	mu sync.Mutex
+	// order holds the cached keys, the most recently used first
+	order *list.List
+	items map[string]*list.Element
+	// writes counts the changes, a value read from the store during one isn't cached
+	writes uint64
+}
+
+type lruItem[V any] struct {
+	key   string
+	value V
+}
+
+// NewLRUStore returns store with a cache of at most size values, at least one.
+func NewLRUStore[V any](store Store[V], size int) *LRUStore[V] {
+	return &LRUStore[V]{store: store, size: max(size, 1), order: list.New(), items: make(map[string]*list.Element)}
+}
+
+func (s *LRUStore[V]) Get(key string) (V, bool, error) {
+	s.mu.Lock()
+
+	if e, ok := s.items[key]; ok {
+		s.order.MoveToFront(e)
+		value := e.Value.(*lruItem[V]).value
+		s.mu.Unlock()
+
+		return value, true, nil
+	}
+
+	writes := s.writes
+	s.mu.Unlock()
+
+	value, ok, err := s.store.Get(key)
+	if err != nil || !ok {
+		return value, ok, err
+	}
+
+	s.mu.Lock()
+
+	if s.writes == writes {
+		s.add(key, value)
+	}
+
+	s.mu.Unlock()
+
+	return value, true, nil
+}
+
+func (s *LRUStore[V]) Put(key string, value V) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.writes++
+
+	if err := s.store.Put(key, value); err != nil {
+		s.remove(key)
+
+		return err
+	}
+
+	s.add(key, value)
+
+	return nil
+}
+
+func (s *LRUStore[V]) PutAll(values map[string]V) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.writes++
+
+	// only the cached keys are updated, the others are read on demand
+	for key, value := range values {
+		if e, ok := s.items[key]; ok {
+			e.Value.(*lruItem[V]).value = value
+		}
+	}
+
+	if err := s.store.PutAll(values); err != nil {
+		for key := range values {
+			s.remove(key)
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+func (s *LRUStore[V]) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.writes++
+	s.remove(key)
+
+	return s.store.Delete(key)
+}
+
+// List returns all values of the store, it doesn't use the cache.
+func (s *LRUStore[V]) List() (map[string]V, error) {
+	return s.store.List()
+}
+
+// Len returns the number of cached values.
+func (s *LRUStore[V]) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.order.Len()
+}
+
+// add caches value as the most recently used one and evicts the least recently used over the size,
+// the caller holds mu.
+func (s *LRUStore[V]) add(key string, value V) {
+	if e, ok := s.items[key]; ok {
+		e.Value.(*lruItem[V]).value = value
+		s.order.MoveToFront(e)
+
+		return
+	}
+
+	s.items[key] = s.order.PushFront(&lruItem[V]{key: key, value: value})
+
+	if s.order.Len() > s.size {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.items, oldest.Value.(*lruItem[V]).key)
+	}
+}
+
+// remove drops key from the cache, the caller holds mu.
+func (s *LRUStore[V]) remove(key string) {
+	if e, ok := s.items[key]; ok {
+		s.order.Remove(e)
+		delete(s.items, key)
+	}
+}
@@ -0,0 +1,153 @@
+package state
+
+import (
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// countingStore counts the reads that reach the store.
+type countingStore struct {
+	*FileStore[entry]
+
+	gets atomic.Int64
+}
+
+func (s *countingStore) Get(key string) (entry, bool, error) {
+	s.gets.Add(1)
+
+	return s.FileStore.Get(key)
+}
+
+func newCountingStore(t *testing.T) *countingStore {
+	t.Helper()
+
+	store, err := NewFileStore[entry]("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &countingStore{FileStore: store}
+}
+
+func TestLRUStoreHits(t *testing.T) {
+	store := newCountingStore(t)
+	_ = store.Put("/a", entry{MessageID: 1})
+
+	s := NewLRUStore[entry](store, 2)
+
+	for range 3 {
+		if e, ok, err := s.Get("/a"); err != nil || !ok || e.MessageID != 1 {
+			t.Fatalf("Get = %v, %v, %v", e, ok, err)
+		}
+	}
+
+	if n := store.gets.Load(); n != 1 {
+		t.Errorf("expected only the first Get to reach the store, got %d", n)
+	}
+
+	// written through and cached
+	if err := s.Put("/b", entry{MessageID: 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	if e, _, _ := store.FileStore.Get("/b"); e.MessageID != 2 {
+		t.Error("Put must write through to the store")
+	}
+
+	if e, _, _ := s.Get("/b"); e.MessageID != 2 || store.gets.Load() != 1 {
+		t.Error("a put value must be served from the cache")
+	}
+
+	// misses aren't cached
+	for range 2 {
+		if _, ok, _ := s.Get("/missing"); ok {
+			t.Error("unexpected value for a missing key")
+		}
+	}
+
+	if n := store.gets.Load(); n != 3 {
+		t.Errorf("expected every miss to reach the store, got %d reads", n)
+	}
+
+	if err := s.Delete("/a"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok, _ := s.Get("/a"); ok {
+		t.Error("a deleted key must not be served from the cache")
+	}
+}
+
+func TestLRUStoreEviction(t *testing.T) {
+	store := newCountingStore(t)
+	s := NewLRUStore[entry](store, 2)
+
+	for i, key := range []string{"/a", "/b", "/c"} {
+		if err := s.Put(key, entry{MessageID: int64(i)}); err != nil {
+			t.Fatal(err)
+		}
+
+		// /a is used again before /c is added, /b is the least recently used then
+		if key == "/b" {
+			_, _, _ = s.Get("/a")
+		}
+	}
+
+	if n := s.Len(); n != 2 {
+		t.Errorf("expected 2 cached values, got %d", n)
+	}
+
+	for _, key := range []string{"/a", "/c"} {
+		_, _, _ = s.Get(key)
+	}
+
+	if n := store.gets.Load(); n != 0 {
+		t.Errorf("recently used values must be cached, %d reads reached the store", n)
+	}
+
+	if e, ok, _ := s.Get("/b"); !ok || e.MessageID != 1 || store.gets.Load() != 1 {
+		t.Errorf("the evicted value must be read from the store, got %v, %v", e, ok)
+	}
+}
+
+func TestLRUStoreConcurrentAccess(t *testing.T) {
+	store := newCountingStore(t)
+	s := NewLRUStore[entry](store, 8)
+
+	var wg sync.WaitGroup
+
+	for w := range 8 {
+		wg.Go(func() {
+			for i := range 200 {
+				key := "/" + strconv.Itoa(i%16)
+
+				if i%3 == w%3 {
+					_ = s.Put(key, entry{MessageID: int64(i % 16)})
+
+					continue
+				}
+
+				// every key only ever holds its own number
+				if e, ok, err := s.Get(key); err != nil || ok && e.MessageID != int64(i%16) {
+					t.Errorf("Get(%s) = %v, %v, %v", key, e, ok, err)
+				}
+			}
+		})
+	}
+
+	wg.Wait()
+
+	if n := s.Len(); n > 8 {
+		t.Errorf("cache over its size: %d", n)
+	}
+
+	if err := s.Put("/0", entry{MessageID: 100}); err != nil {
+		t.Fatal(err)
+	}
+
+	if e, _, _ := s.Get("/0"); e.MessageID != 100 {
+		t.Errorf("expected the last written value, got %v", e)
+	}
+}
@@ -0,0 +1,144 @@
+// Package state stores the sync state, e.g. which message a local file was uploaded as, keyed by path.
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+var _ Store[struct{}] = (*FileStore[struct{}])(nil)
+
+// Store is a key-value store of V keyed by local paths. Implementations are safe for concurrent use
+// and every method is applied atomically, FileStore is the default one.
+type Store[V any] interface {
+	Get(key string) (V, bool, error)
+	Put(key string, value V) error
+	// PutAll stores all values at once, e.g. after a scan
+	PutAll(values map[string]V) error
+	Delete(key string) error
+	List() (map[string]V, error)
+}
+
+// FileStore keeps the values in memory and saves them as a JSON object to a file after every change,
+// atomically through a temp file and a rename.
+type FileStore[V any] struct {
+	mu      sync.Mutex
+	path    string
+	entries map[string]V
+}
+
+// NewFileStore loads the store saved at path, a missing file is an empty store.
+// An empty path keeps the values in memory only.
+func NewFileStore[V any](path string) (*FileStore[V], error) {
+	s := &FileStore[V]{path: path, entries: make(map[string]V)}
+
+	if path == "" {
+		return s, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("read state: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &s.entries); err != nil {
+		return nil, fmt.Errorf("parse state %s: %w", path, err)
+	}
+
+	return s, nil
+}
+
+func (s *FileStore[V]) Get(key string) (V, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	value, ok := s.entries[key]
+
+	return value, ok, nil
+}
+
+func (s *FileStore[V]) Put(key string, value V) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries[key] = value
+
+	return s.save()
+}
+
+func (s *FileStore[V]) PutAll(values map[string]V) error {
+	if len(values) == 0 {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	maps.Copy(s.entries, values)
+
+	return s.save()
+}
+
+func (s *FileStore[V]) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.entries[key]; !ok {
+		return nil
+	}
+
+	delete(s.entries, key)
+
+	return s.save()
+}
+
+// List returns a copy of all values.
+func (s *FileStore[V]) List() (map[string]V, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return maps.Clone(s.entries), nil
+}
+
+// save writes the entries atomically through a temp file, the caller holds mu.
+func (s *FileStore[V]) save() error {
+	if s.path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(s.entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal state: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".state-*")
+	if err != nil {
+		return fmt.Errorf("create state: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+
+		return fmt.Errorf("write state: %w", err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write state: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("save state: %w", err)
+	}
+
+	return nil
+}
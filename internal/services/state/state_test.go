@@ -0,0 +1,78 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type entry struct {
+	MessageID int64 `json:"message_id"`
+}
+
+func TestFileStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+
+	s, err := NewFileStore[entry](path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok, _ := s.Get("/a"); ok {
+		t.Fatal("new store must be empty")
+	}
+
+	if err := s.Put("/a", entry{MessageID: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.PutAll(map[string]entry{"/b": {MessageID: 2}, "/c": {MessageID: 3}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Delete("/c"); err != nil {
+		t.Fatal(err)
+	}
+
+	// reload from the file
+	s, err = NewFileStore[entry](path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	all, _ := s.List()
+	if len(all) != 2 || all["/a"].MessageID != 1 || all["/b"].MessageID != 2 {
+		t.Errorf("unexpected entries %+v", all)
+	}
+
+	// no temp files are left behind
+	if files, _ := os.ReadDir(filepath.Dir(path)); len(files) != 1 {
+		t.Errorf("expected only the state file, got %d files", len(files))
+	}
+}
+
+func TestFileStoreInMemory(t *testing.T) {
+	s, err := NewFileStore[entry]("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Put("/a", entry{MessageID: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, ok, _ := s.Get("/a"); !ok || got.MessageID != 1 {
+		t.Errorf("got %+v, %t", got, ok)
+	}
+}
+
+func TestFileStoreInvalidFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	if err := os.WriteFile(path, []byte("{"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewFileStore[entry](path); err == nil {
+		t.Error("expected an error for a corrupt state file")
+	}
+}
@@ -0,0 +1,72 @@
+package syncer
+
+import (
+	"time"
+
+	"github.com/k0ff1l/tgcloudbot/internal/services/telegram"
+)
+
+// chatActionInterval repeats the upload action before it expires after 5 seconds.
+const chatActionInterval = 4 * time.Second
+
+// SetChatActions shows an "uploading ..." status in the chat while a file is uploaded.
+func (s *SyncService) SetChatActions(enabled bool) {
+	s.chatActions = enabled
+}
+
+// showUploadAction sends the upload action of kind to chatID now and then every chatActionInterval
+// until the returned func is called, which returns once no more action is sent.
+func (s *SyncService) showUploadAction(chatID string, kind SendKind) func() {
+	if !s.chatActions {
+		return func() {}
+	}
+
+	action := uploadAction(kind)
+	s.sendChatAction(chatID, action)
+
+	done, stopped := make(chan struct{}), make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+
+		ticker := time.NewTicker(chatActionInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-s.ctx.Done():
+				return
+			case <-ticker.C:
+				s.sendChatAction(chatID, action)
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+// sendChatAction shows action in chatID, it is only cosmetic so a failure is just logged.
+func (s *SyncService) sendChatAction(chatID, action string) {
+	if err := s.bot.SendChatAction(s.ctx, chatID, action); err != nil {
+		s.logger.Debug("failed to send chat action", "chat", chatID, "action", action, "error", err)
+	}
+}
+
+// uploadAction returns the chat action shown while a file of kind is uploaded.
+func uploadAction(kind SendKind) string {
+	switch kind {
+	case KindPhoto:
+		return telegram.ActionUploadPhoto
+	case KindVideo:
+		return telegram.ActionUploadVideo
+	case KindAudio, KindVoice:
+		return telegram.ActionUploadVoice
+	default:
+		return telegram.ActionUploadDocument
+	}
+}
@@ -0,0 +1,27 @@
+package syncer
+
+import (
+	"testing"
+
+	"github.com/k0ff1l/tgcloudbot/internal/services/file"
+	"github.com/k0ff1l/tgcloudbot/internal/services/telegram"
+	"github.com/k0ff1l/tgcloudbot/internal/services/telegram/telegramtest"
+)
+
+func TestUploadAction(t *testing.T) {
+	dir := t.TempDir()
+	path := writeFile(t, dir, "a.png", []byte("png"))
+
+	bot := telegramtest.NewFakeClient()
+	s := NewSyncService(bot, file.NewWatcher(), WithChatID("chat"), WithClassifier(ExtensionClassifier{}))
+	s.SetChatActions(true)
+
+	if err := s.SyncFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	calls := bot.Calls()
+	if len(calls) != 2 || calls[0].Method != "SendChatAction" || calls[0].Text != telegram.ActionUploadPhoto {
+		t.Errorf("expected the upload_photo action before the upload, got %+v", calls)
+	}
+}
@@ -0,0 +1,225 @@
+package syncer
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/k0ff1l/tgcloudbot/internal/services/telegram"
+)
+
+// maxAlbumSize [https://core.telegram.org/bots/api#sendmediagroup]
+const maxAlbumSize = 10
+
+// albumItem is a file of an album, prepared like syncFile does before the upload.
+type albumItem struct {
+	path    string
+	caption string
+	size    int64
+	entry   IndexEntry
+}
+
+// SetAlbums sends the new photos and videos of a directory found in one tick as albums of up to 10 files,
+// captioned with the captions of their files, instead of one message per file.
+// Files uploaded before, known to the dedup index, chunked or encrypted are still sent one by one,
+// as are batches sent as a digest and all files while renames are detected, see SetRenameDetection.
+func (s *SyncService) SetAlbums(enabled bool) {
+	s.albums = enabled
+}
+
+// sendAlbums sends the photos and videos of files as albums to chatID, as replies to replyTo if not 0.
+// It returns the other files, to be uploaded one by one, with the files of albums that failed.
+func (s *SyncService) sendAlbums(chatID, root string, files []string, replyTo int64) []string {
+	if !s.albums || s.dryRun || s.encryptionKey != nil || s.detectRenames {
+		return files
+	}
+
+	var (
+		items []albumItem
+		rest  []string
+	)
+
+	for _, path := range files {
+		item, ok := s.albumItem(chatID, root, path)
+		if !ok {
+			rest = append(rest, path)
+
+			continue
+		}
+
+		items = append(items, item)
+	}
+
+	// a single file left is no album
+	for len(items) > 1 {
+		// forgotten with the rest of the batch
+		if s.paused.Load() || !s.enqueue() {
+			break
+		}
+
+		n := min(len(items), maxAlbumSize)
+		album := items[:n]
+		items = items[n:]
+
+		err := s.sendAlbum(chatID, root, album, replyTo)
+		s.dequeue()
+
+		if err != nil {
+			s.logger.Warn("failed to send album, uploading its files one by one", "dir", root, "error", err)
+
+			for _, item := range album {
+				rest = append(rest, item.path)
+			}
+		}
+	}
+
+	for _, item := range items {
+		rest = append(rest, item.path)
+	}
+
+	return rest
+}
+
+// albumItem prepares filePath for an album, it reports false when the file is sent on its own.
+// Errors are left to syncFile to report.
+func (s *SyncService) albumItem(chatID, root, filePath string) (albumItem, bool) {
+	if !telegram.IsAlbumMedia(filePath) || s.deadLettered(filePath) {
+		return albumItem{}, false
+	}
+
+	if kind, err := s.sendKind(root, filePath); err != nil || (kind != KindPhoto && kind != KindVideo) {
+		return albumItem{}, false
+	}
+
+	// an update edits or replaces its message
+	if _, ok := s.indexEntry(filePath); ok {
+		return albumItem{}, false
+	}
+
+	info, err := os.Stat(filePath)
+	if err != nil || (s.chunkSize > 0 && info.Size() > s.chunkSize) {
+		return albumItem{}, false
+	}
+
+	relPath := relativePath(root, filePath)
+	data := newCaptionData(filePath, relPath, info)
+
+	caption, err := s.caption(data)
+	if err != nil {
+		return albumItem{}, false
+	}
+
+	hash, err := data.Hash()
+	if err != nil {
+		return albumItem{}, false
+	}
+
+	// sent by file_id instead
+	if s.dedup != nil {
+		if _, ok, err := s.dedup.Get(hash); ok || err != nil {
+			return albumItem{}, false
+		}
+	}
+
+	return albumItem{
+		path:    filePath,
+		caption: caption,
+		size:    info.Size(),
+		entry:   IndexEntry{ChatID: chatID, Root: root, RelPath: s.indexedRelPath(relPath), Instance: s.instance, Hash: hash},
+	}, true
+}
+
+// sendAlbum uploads items as one album and indexes every file with its message.
+func (s *SyncService) sendAlbum(chatID, root string, items []albumItem, replyTo int64) error {
+	var (
+		total    int64
+		paths    = make([]string, len(items))
+		captions []string
+	)
+
+	for i, item := range items {
+		total += item.size
+		paths[i] = item.path
+
+		if item.caption != "" {
+			captions = append(captions, item.caption)
+		}
+	}
+
+	window, err := s.reserveUpload(total)
+	if err != nil {
+		return err
+	}
+
+	stopAction := s.showUploadAction(chatID, KindPhoto)
+	start := time.Now()
+
+	opts := s.fileOptions(chatID, root, replyTo)
+	msgs, err := s.bot.SendMediaGroup(s.ctx, chatID, paths, strings.Join(captions, "\n"), opts...)
+
+	stopAction()
+
+	if err != nil && len(msgs) == 0 {
+		s.refundUpload(total, window)
+
+		return fmt.Errorf("send album of %d files: %w", len(items), err)
+	}
+
+	// the files with a message are posted, sending them again would duplicate them
+	if err != nil {
+		s.logger.Warn("album was sent only partly", "dir", root, "sent", len(msgs), "files", len(items), "error", err)
+	}
+
+	for i, item := range items {
+		if i >= len(msgs) {
+			// retried on the next tick
+			s.logger.Error("no message for file of album", "file", item.path)
+			s.watcher.Forget(item.path)
+			s.refundUpload(item.size, window)
+
+			continue
+		}
+
+		s.albumFileSent(item, &msgs[i], time.Since(start))
+	}
+
+	return nil
+}
+
+// albumFileSent records the upload of item as msg like syncFile does.
+func (s *SyncService) albumFileSent(item albumItem, msg *telegram.Message, took time.Duration) {
+	if err := verifyUploadSize(item.path, msg); err != nil {
+		s.logger.Error("failed to sync file", "file", item.path, "error", err)
+		s.stats.failed()
+		s.reportError(item.path, err)
+
+		return
+	}
+
+	s.stats.uploaded(item.size)
+	s.metrics.FileSynced(item.size, took)
+
+	entry := item.entry
+	entry.MessageID, entry.FileID = msg.MessageID, fileIDOf(msg)
+
+	entry.Kind = KindPhoto
+	if msg.Video != nil {
+		entry.Kind = KindVideo
+	}
+
+	s.mirror(&entry, item.path, item.caption)
+
+	if err := s.index.Put(item.path, entry); err != nil {
+		s.logger.Error("failed to update index", "file", item.path, "error", err)
+	}
+
+	if s.dedup != nil {
+		if err := s.dedup.Put(entry.Hash, entry); err != nil {
+			s.logger.Error("failed to update dedup index", "file", item.path, "error", err)
+		}
+	}
+
+	s.resetFailures(item.path)
+	s.resolveError(item.path)
+}
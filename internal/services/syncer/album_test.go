@@ -0,0 +1,138 @@
+package syncer
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/k0ff1l/tgcloudbot/internal/services/file"
+	"github.com/k0ff1l/tgcloudbot/internal/services/telegram"
+	"github.com/k0ff1l/tgcloudbot/internal/services/telegram/telegramtest"
+)
+
+func newAlbumService(t *testing.T, dir string) (*SyncService, *telegramtest.FakeClient) {
+	t.Helper()
+
+	watcher := file.NewWatcher()
+	if err := watcher.AddDir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	bot := telegramtest.NewFakeClient()
+	bot.RespondWith("SendMediaGroup", telegram.Message{MessageID: 7, Photo: []telegram.PhotoSize{{FileID: "photo"}}})
+
+	s := NewSyncService(bot, watcher, WithChatID("chat"), WithClassifier(ExtensionClassifier{}))
+	s.SetAlbums(true)
+
+	return s, bot
+}
+
+func TestAlbums(t *testing.T) {
+	dir := t.TempDir()
+
+	photos := []string{
+		writeFile(t, dir, "a.jpg", []byte("a")),
+		writeFile(t, dir, "b.jpg", []byte("b")),
+		writeFile(t, dir, "c.mp4", []byte("c")),
+	}
+	writeFile(t, dir, "notes.txt", []byte("notes"))
+
+	s, bot := newAlbumService(t, dir)
+	s.syncDirectoryOnce(dir)
+
+	albums := bot.CallsTo("SendMediaGroup")
+	if len(albums) != 1 || len(albums[0].Paths) != len(photos) {
+		t.Fatalf("expected one album of the photos and the video, got %+v", albums)
+	}
+
+	if lines := strings.Split(albums[0].Caption, "\n"); len(lines) != len(photos) {
+		t.Errorf("expected the captions of the files, got %q", albums[0].Caption)
+	}
+
+	if docs := bot.CallsTo("SendDocument"); len(docs) != 1 || !strings.HasSuffix(docs[0].Paths[0], "notes.txt") {
+		t.Errorf("expected the other file to be sent on its own, got %+v", docs)
+	}
+
+	for _, path := range photos {
+		if entry, ok := s.indexEntry(path); !ok || entry.MessageID != 7 || entry.FileID != "photo" {
+			t.Errorf("%s: unexpected index entry %+v", path, entry)
+		}
+	}
+
+	// updated, it is no longer part of an album
+	writeFile(t, dir, "a.jpg", []byte("updated"))
+	writeFile(t, dir, "d.jpg", []byte("d"))
+	s.syncDirectoryOnce(dir)
+
+	if albums := bot.CallsTo("SendMediaGroup"); len(albums) != 1 {
+		t.Errorf("a single new photo is no album, got %+v", albums)
+	}
+
+	if sent := bot.CallsTo("SendPhoto"); len(sent) != 2 {
+		t.Errorf("expected the photos to be sent on their own, got %+v", sent)
+	}
+}
+
+func TestAlbumFailureFallsBack(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "a.jpg", []byte("a"))
+	writeFile(t, dir, "b.jpg", []byte("b"))
+
+	s, bot := newAlbumService(t, dir)
+	bot.FailWith("SendMediaGroup", errors.New("Bad Request: group send failed"))
+
+	s.syncDirectoryOnce(dir)
+
+	if sent := bot.CallsTo("SendPhoto"); len(sent) != 2 {
+		t.Errorf("expected the files of the failed album to be sent one by one, got %+v", sent)
+	}
+}
+
+// partlySentBot posts only the first file of an album and fails the rest.
+type partlySentBot struct {
+	*telegramtest.FakeClient
+}
+
+func (b partlySentBot) SendMediaGroup(
+	ctx context.Context, chatID string, filePaths []string, caption string, opts ...telegram.SendOption,
+) ([]telegram.Message, error) {
+	msgs, err := b.FakeClient.SendMediaGroup(ctx, chatID, filePaths, caption, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return msgs[:1], errors.New("internal server error")
+}
+
+func TestPartlySentAlbumIsNotSentAgain(t *testing.T) {
+	dir := t.TempDir()
+	sent := writeFile(t, dir, "a.jpg", []byte("a"))
+	writeFile(t, dir, "b.jpg", []byte("b"))
+
+	watcher := file.NewWatcher()
+	if err := watcher.AddDir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	bot := partlySentBot{telegramtest.NewFakeClient()}
+	s := NewSyncService(bot, watcher, WithChatID("chat"), WithClassifier(ExtensionClassifier{}))
+	s.SetAlbums(true)
+
+	s.syncDirectoryOnce(dir)
+
+	if photos := bot.CallsTo("SendPhoto"); len(photos) != 0 {
+		t.Errorf("expected no file of the album to be sent again, got %+v", photos)
+	}
+
+	if _, ok := s.indexEntry(sent); !ok {
+		t.Errorf("expected the posted file to be indexed")
+	}
+
+	// the file without a message comes again with the next tick
+	s.syncDirectoryOnce(dir)
+
+	if photos := bot.CallsTo("SendPhoto"); len(photos) != 1 || !strings.HasSuffix(photos[0].Paths[0], "b.jpg") {
+		t.Errorf("expected the file without a message to be sent on its own, got %+v", photos)
+	}
+}
@@ -0,0 +1,119 @@
+package syncer
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/k0ff1l/tgcloudbot/internal/services/telegram"
+)
+
+// DefaultAlertCooldown is how long the same error isn't alerted again, see SetErrorAlerts.
+const DefaultAlertCooldown = time.Hour
+
+// errorAlerts keeps the last error of every directory and file that failed, scope is its path.
+type errorAlerts struct {
+	// chatID receives the alerts, empty means the default chat
+	chatID   string
+	cooldown time.Duration
+
+	mu     sync.Mutex
+	errors map[string]*scopeError
+}
+
+// scopeError is the current error of a scope, the alert is sent once it repeats.
+type scopeError struct {
+	fingerprint string
+	count       int
+	// sentAt is when the error was last alerted, zero if not yet
+	sentAt time.Time
+}
+
+// SetErrorAlerts sends errors that persist to chatID (the default chat if empty): a file or directory
+// that fails twice in a row with the same error, or once with an error that is not retried.
+// The same error of the same path is alerted again only after cooldown, DefaultAlertCooldown if 0,
+// and a success clears it.
+func (s *SyncService) SetErrorAlerts(chatID string, cooldown time.Duration) {
+	if cooldown <= 0 {
+		cooldown = DefaultAlertCooldown
+	}
+
+	s.alerts = &errorAlerts{chatID: chatID, cooldown: cooldown, errors: make(map[string]*scopeError)}
+}
+
+// reportError records err of the file or directory at scope and alerts it if it persists.
+func (s *SyncService) reportError(scope string, err error) {
+	if s.alerts == nil || s.dryRun {
+		return
+	}
+
+	if !s.alerts.shouldSend(scope, err.Error(), !telegram.IsRetryable(err), s.now()) {
+		return
+	}
+
+	if s.sendAlert(scope, fmt.Sprintf("Sync error in %s:\n%v", scope, err)) {
+		s.alerts.sent(scope, s.now())
+	}
+}
+
+// sendAlert sends text about scope to the alert chat and reports whether it was sent.
+func (s *SyncService) sendAlert(scope, text string) bool {
+	if s.alerts == nil || s.dryRun {
+		return false
+	}
+
+	chatID := s.alerts.chatID
+	if chatID == "" {
+		chatID = s.chatID
+	}
+
+	if _, err := s.bot.SendMessage(s.ctx, chatID, text, s.sendOptions(0)...); err != nil {
+		s.logger.Error("failed to send error alert", "path", scope, "error", err)
+
+		return false
+	}
+
+	return true
+}
+
+// resolveError clears the error of scope after it synced.
+func (s *SyncService) resolveError(scope string) {
+	if s.alerts == nil {
+		return
+	}
+
+	s.alerts.mu.Lock()
+	defer s.alerts.mu.Unlock()
+
+	delete(s.alerts.errors, scope)
+}
+
+// shouldSend records the error with the given fingerprint and reports whether it is to be alerted now.
+func (a *errorAlerts) shouldSend(scope, fingerprint string, permanent bool, now time.Time) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	e, ok := a.errors[scope]
+	if !ok || e.fingerprint != fingerprint {
+		e = &scopeError{fingerprint: fingerprint}
+		a.errors[scope] = e
+	}
+
+	e.count++
+
+	if e.count < 2 && !permanent {
+		return false
+	}
+
+	return e.sentAt.IsZero() || now.Sub(e.sentAt) >= a.cooldown
+}
+
+// sent records that the current error of scope was alerted.
+func (a *errorAlerts) sent(scope string, now time.Time) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if e, ok := a.errors[scope]; ok {
+		e.sentAt = now
+	}
+}
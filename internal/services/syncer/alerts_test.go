@@ -0,0 +1,68 @@
+package syncer
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/k0ff1l/tgcloudbot/internal/services/file"
+	"github.com/k0ff1l/tgcloudbot/internal/services/telegram/telegramtest"
+)
+
+func TestErrorAlerts(t *testing.T) {
+	dir := t.TempDir()
+	path := writeFile(t, dir, "a.txt", []byte("a"))
+
+	watcher := file.NewWatcher()
+	if err := watcher.AddDir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	bot := telegramtest.NewFakeClient()
+	s := NewSyncService(bot, watcher, WithChatID("chat"))
+	s.SetErrorAlerts("alerts", time.Hour)
+
+	now := time.Now()
+	s.now = func() time.Time { return now }
+
+	alerts := func() int { return len(bot.CallsTo("SendMessage")) }
+
+	bot.FailWith("SendDocument", errors.New("network down"))
+
+	s.syncDirectoryOnce(dir)
+
+	if alerts() != 0 {
+		t.Fatal("a single failure must not be alerted")
+	}
+
+	s.syncDirectoryOnce(dir)
+	s.syncDirectoryOnce(dir)
+
+	calls := bot.CallsTo("SendMessage")
+	if len(calls) != 1 || calls[0].ChatID != "alerts" {
+		t.Fatalf("expected one alert to the alert chat, got %+v", calls)
+	}
+
+	now = now.Add(2 * time.Hour)
+	s.syncDirectoryOnce(dir)
+
+	if alerts() != 2 {
+		t.Errorf("expected the error to be alerted again after the cooldown, got %d alerts", alerts())
+	}
+
+	// a success clears the error, a new failure starts over
+	bot.FailWith("SendDocument", nil)
+	s.syncDirectoryOnce(dir)
+
+	bot.FailWith("SendDocument", errors.New("network down"))
+	writeFile(t, dir, "a.txt", []byte("changed"))
+	s.syncDirectoryOnce(dir)
+
+	if alerts() != 2 {
+		t.Errorf("expected no alert for the first failure after a success, got %d alerts", alerts())
+	}
+
+	if len(bot.CallsTo("SendDocument")) != 6 {
+		t.Errorf("expected %s to be retried on every tick, got %+v", path, bot.CallsTo("SendDocument"))
+	}
+}
@@ -0,0 +1,44 @@
+package syncer
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// The messages sent by AnnounceTemplate unless configured otherwise.
+const (
+	DefaultStartupMessage  = "Bot {{.Version}} started"
+	DefaultShutdownMessage = "Bot {{.Version}} stopped"
+)
+
+// AnnouncementData are the variables of the startup and shutdown message templates.
+type AnnouncementData struct {
+	Hostname string
+	Version  string
+}
+
+// RenderAnnouncement renders the text/template of a startup or shutdown message with data.
+func RenderAnnouncement(text string, data AnnouncementData) (string, error) {
+	tmpl, err := template.New("announcement").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("announcement template: %w", err)
+	}
+
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("announcement template: %w", err)
+	}
+
+	return strings.TrimSpace(b.String()), nil
+}
+
+// AnnounceTemplate renders text with data and sends it to the default chat, see Announce.
+func (s *SyncService) AnnounceTemplate(text string, data AnnouncementData) error {
+	message, err := RenderAnnouncement(text, data)
+	if err != nil {
+		return err
+	}
+
+	return s.Announce(message)
+}
@@ -0,0 +1,31 @@
+package syncer
+
+import (
+	"testing"
+
+	"github.com/k0ff1l/tgcloudbot/internal/services/file"
+	"github.com/k0ff1l/tgcloudbot/internal/services/telegram/telegramtest"
+)
+
+func TestAnnounceTemplate(t *testing.T) {
+	bot := telegramtest.NewFakeClient()
+	s := NewSyncService(bot, file.NewWatcher(), WithChatID("chat"))
+	data := AnnouncementData{Hostname: "nas", Version: "v1.2.0"}
+
+	if err := s.AnnounceTemplate(DefaultStartupMessage, data); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.AnnounceTemplate("Бот на {{.Hostname}} остановлен", data); err != nil {
+		t.Fatal(err)
+	}
+
+	texts := bot.Texts()
+	if len(texts) != 2 || texts[0] != "Bot v1.2.0 started" || texts[1] != "Бот на nas остановлен" {
+		t.Errorf("unexpected messages %q", texts)
+	}
+
+	if err := s.AnnounceTemplate("{{.Uptime}}", data); err == nil {
+		t.Error("expected an error for an unknown variable")
+	}
+}
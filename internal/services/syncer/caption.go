@@ -0,0 +1,104 @@
+package syncer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// DefaultCaptionTemplate is the caption of the uploaded files unless SetCaptionTemplate replaces it.
+const DefaultCaptionTemplate = "File: {{.RelPath}}"
+
+// CaptionData are the variables of a caption template, see ParseCaptionTemplate.
+type CaptionData struct {
+	// Name is the base name of the file and RelPath its slash-separated path below the watched directory
+	Name    string
+	RelPath string
+	Size    int64
+	ModTime time.Time
+
+	path string
+	hash string
+}
+
+// newCaptionData returns the caption variables of the file at path, info may be nil if it can't be stat'ed.
+func newCaptionData(path, relPath string, info os.FileInfo) *CaptionData {
+	data := &CaptionData{Name: filepath.Base(path), RelPath: relPath, path: path}
+	if info != nil {
+		data.Size, data.ModTime = info.Size(), info.ModTime()
+	}
+
+	return data
+}
+
+// Hash returns the hex sha256 of the file content, it is computed on first use.
+func (d *CaptionData) Hash() (string, error) {
+	if d.hash == "" && d.path != "" {
+		hash, err := hashFile(d.path)
+		if err != nil {
+			return "", err
+		}
+
+		d.hash = hash
+	}
+
+	return d.hash, nil
+}
+
+// ParseCaptionTemplate parses a text/template caption with the fields of CaptionData, e.g.
+// `{{.Name}} ({{.Size}} bytes, {{.ModTime.Format "2006-01-02"}})`. The template is tried on sample data,
+// so that unknown variables fail here and not with the first upload. An empty template sends no caption.
+func ParseCaptionTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("caption").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("caption template: %w", err)
+	}
+
+	sample := &CaptionData{Name: "a.txt", RelPath: "dir/a.txt", ModTime: time.Now(), hash: "0"}
+	if err := tmpl.Execute(new(strings.Builder), sample); err != nil {
+		return nil, fmt.Errorf("caption template: %w", err)
+	}
+
+	return tmpl, nil
+}
+
+// SetCaptionTemplate sets the caption of the uploaded files, see ParseCaptionTemplate.
+func (s *SyncService) SetCaptionTemplate(tmpl *template.Template) {
+	s.captionTemplate = tmpl
+}
+
+// SetInstance names this machine when several of them back up into the same chat. A non-empty name is
+// prepended to the captions as "[name]" and recorded in the index. inPath also puts it in front of the
+// relative path of the indexed files, so that Restore lays out the files of every machine in a directory
+// of its own and a restore from a shared index can be filtered per machine.
+func (s *SyncService) SetInstance(name string, inPath bool) {
+	s.instance, s.instanceInPath = name, inPath && name != ""
+}
+
+// indexedRelPath returns the relative path of a file as it is indexed, see SetInstance.
+func (s *SyncService) indexedRelPath(relPath string) string {
+	if !s.instanceInPath {
+		return relPath
+	}
+
+	return s.instance + "/" + relPath
+}
+
+// caption renders the caption template for a file, surrounding whitespace is dropped.
+// An empty caption stays empty, otherwise it is prefixed with the instance name.
+func (s *SyncService) caption(data *CaptionData) (string, error) {
+	var b strings.Builder
+	if err := s.captionTemplate.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("render caption of %s: %w", data.path, err)
+	}
+
+	caption := strings.TrimSpace(b.String())
+	if caption != "" && s.instance != "" {
+		caption = "[" + s.instance + "] " + caption
+	}
+
+	return caption, nil
+}
@@ -0,0 +1,160 @@
+package syncer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/k0ff1l/tgcloudbot/internal/services/file"
+	"github.com/k0ff1l/tgcloudbot/internal/services/telegram"
+	"github.com/k0ff1l/tgcloudbot/internal/services/telegram/telegramtest"
+)
+
+func TestCaptionTemplate(t *testing.T) {
+	dir := t.TempDir()
+	path := writeFile(t, dir, "a.txt", []byte("hello"))
+
+	modTime := time.Date(2024, 5, 1, 12, 0, 0, 0, time.Local)
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl, err := ParseCaptionTemplate(`{{.Name}} in {{.RelPath}}, {{.Size}} bytes, ` +
+		`{{.ModTime.Format "2006-01-02"}}, sha256 {{printf "%.8s" .Hash}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bot := telegramtest.NewFakeClient()
+	s := NewSyncService(bot, file.NewWatcher(), WithChatID("chat"))
+	s.SetCaptionTemplate(tmpl)
+
+	if err := s.SyncFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "a.txt in a.txt, 5 bytes, 2024-05-01, sha256 2cf24dba"
+	if calls := bot.CallsTo("SendDocument"); len(calls) != 1 || calls[0].Caption != want {
+		t.Errorf("expected caption %q, got %+v", want, calls)
+	}
+
+	// an empty template sends no caption
+	empty, err := ParseCaptionTemplate("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.SetCaptionTemplate(empty)
+
+	if err := s.syncFile("chat", dir, path, 0, true, false); err != nil {
+		t.Fatal(err)
+	}
+
+	if calls := bot.CallsTo("SendDocument"); len(calls) != 2 || calls[1].Caption != "" {
+		t.Errorf("expected no caption, got %+v", calls)
+	}
+}
+
+func TestParseCaptionTemplate(t *testing.T) {
+	for _, text := range []string{"{{.Name", "{{.Unknown}}"} {
+		if _, err := ParseCaptionTemplate(text); err == nil {
+			t.Errorf("expected an error for %q", text)
+		}
+	}
+}
+
+func TestInstanceNames(t *testing.T) {
+	bot := telegramtest.NewFakeClient()
+	bot.RespondWith("SendDocument", telegram.Message{MessageID: 1, Document: &telegram.Document{FileID: "doc"}})
+
+	entries := map[string]IndexEntry{}
+
+	// two machines back up a file of the same name into the same chat
+	for _, instance := range []string{"alpha", "beta"} {
+		dir := t.TempDir()
+		if err := os.Mkdir(filepath.Join(dir, "sub"), 0o700); err != nil {
+			t.Fatal(err)
+		}
+
+		path := writeFile(t, dir, "sub/a.txt", []byte(instance))
+
+		s := NewSyncService(bot, file.NewWatcher(), WithChatID("chat"))
+		s.SetInstance(instance, true)
+
+		if err := s.syncFile("chat", dir, path, 0, false, false); err != nil {
+			t.Fatal(err)
+		}
+
+		entry, ok := s.indexEntry(path)
+		if !ok {
+			t.Fatalf("%s: expected the file in the index", instance)
+		}
+
+		entries[instance] = entry
+
+		// a forwarded file gets the caption of the upload
+		if err := s.ForwardFile(path, "other"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the upload and the forward of every machine
+	want := []string{
+		"[alpha] File: sub/a.txt", "[alpha] File: sub/a.txt",
+		"[beta] File: sub/a.txt", "[beta] File: sub/a.txt",
+	}
+	if calls := bot.CallsTo("SendDocument", "SendDocumentByRef"); len(calls) != len(want) {
+		t.Fatalf("expected %d messages, got %+v", len(want), calls)
+	} else {
+		for i, call := range calls {
+			if call.Caption != want[i] {
+				t.Errorf("%s: got caption %q, want %q", call.Method, call.Caption, want[i])
+			}
+		}
+	}
+
+	for instance, entry := range entries {
+		if entry.Instance != instance || entry.RelPath != instance+"/sub/a.txt" {
+			t.Errorf("%s: unexpected index entry %+v", instance, entry)
+		}
+	}
+
+	// restored into a directory per machine
+	if path, err := restorePath("/restore", "/x/sub/a.txt", entries["beta"]); err != nil ||
+		path != filepath.Join("/restore", "beta", "sub", "a.txt") {
+		t.Errorf("unexpected restore path %q, %v", path, err)
+	}
+}
+
+func TestInstanceNameNotInPath(t *testing.T) {
+	dir := t.TempDir()
+	path := writeFile(t, dir, "a.txt", []byte("a"))
+
+	bot := telegramtest.NewFakeClient()
+	s := NewSyncService(bot, file.NewWatcher(), WithChatID("chat"))
+	s.SetInstance("alpha", false)
+
+	if err := s.syncFile("chat", dir, path, 0, false, false); err != nil {
+		t.Fatal(err)
+	}
+
+	if entry, _ := s.indexEntry(path); entry.Instance != "alpha" || entry.RelPath != "a.txt" {
+		t.Errorf("expected the instance only in its field, got %+v", entry)
+	}
+
+	// no name, no prefix
+	s.SetInstance("", true)
+
+	if err := s.syncFile("chat", dir, path, 0, true, false); err != nil {
+		t.Fatal(err)
+	}
+
+	if calls := bot.CallsTo("SendDocument"); len(calls) != 2 || calls[1].Caption != "File: a.txt" {
+		t.Errorf("expected the caption without instance, got %+v", calls)
+	}
+
+	if entry, _ := s.indexEntry(path); entry.Instance != "" || entry.RelPath != "a.txt" {
+		t.Errorf("unexpected index entry %+v", entry)
+	}
+}
@@ -0,0 +1,224 @@
+package syncer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/k0ff1l/tgcloudbot/internal/services/encryption"
+	"github.com/k0ff1l/tgcloudbot/internal/services/state"
+	"github.com/k0ff1l/tgcloudbot/internal/services/telegram"
+)
+
+// Chunk is an uploaded part of a file split by SetChunkSize.
+type Chunk struct {
+	MessageID int64  `json:"message_id"`
+	FileID    string `json:"file_id"`
+	// Hash is the hex sha256 of the part before encryption
+	Hash string `json:"hash,omitempty"`
+}
+
+// ChunkProgress are the chunks of a file uploaded so far, kept until all of them are.
+// Size, ModTime, ChunkSize and Encrypted must match for an upload to resume.
+type ChunkProgress struct {
+	Size      int64     `json:"size"`
+	ModTime   time.Time `json:"mod_time"`
+	ChunkSize int64     `json:"chunk_size"`
+	Encrypted bool      `json:"encrypted,omitempty"`
+	Chunks    []Chunk   `json:"chunks"`
+}
+
+// matches reports whether the progress was recorded for the same upload.
+func (p ChunkProgress) matches(want ChunkProgress) bool {
+	return p.Size == want.Size && p.ModTime.Equal(want.ModTime) && p.ChunkSize == want.ChunkSize &&
+		p.Encrypted == want.Encrypted
+}
+
+// newMemoryChunkProgress returns a chunk progress store kept in memory only.
+func newMemoryChunkProgress() state.Store[ChunkProgress] {
+	store, _ := state.NewFileStore[ChunkProgress]("") // can't fail without a file
+
+	return store
+}
+
+// SetChunkSize uploads files larger than size as documents of that size, "name.part001" and so on,
+// instead of skipping them. Every chunk is encrypted on its own with SetEncryptionKey, compression is
+// skipped. Restore joins the chunks again. 0, the default, disables it.
+// The size must leave room below the upload limit for the encryption overhead. Chunks over 20MB
+// can't be downloaded from the public Bot API, see Restore.
+func (s *SyncService) SetChunkSize(size int64) {
+	s.chunkSize = size
+}
+
+// SetChunkProgressFile keeps the progress of chunked uploads in path, so that an upload interrupted
+// by a restart resumes from the chunks that are still missing.
+func (s *SyncService) SetChunkProgressFile(path string) error {
+	store, err := state.NewFileStore[ChunkProgress](path)
+	if err != nil {
+		return err
+	}
+
+	s.chunkProgress = store
+
+	return nil
+}
+
+// syncChunked uploads the file at localPath in chunks and indexes it as entry.
+// The chunks uploaded before, also by an earlier run, are sent again only if Telegram lost them.
+// It returns the bytes of the chunks it sent, also when it fails.
+func (s *SyncService) syncChunked(
+	chatID, localPath, caption string, entry IndexEntry, fileInfo os.FileInfo, replyTo int64,
+) (sent int64, err error) {
+	want := ChunkProgress{
+		Size:      fileInfo.Size(),
+		ModTime:   fileInfo.ModTime(),
+		ChunkSize: s.chunkSize,
+		Encrypted: s.encryptionKey != nil,
+	}
+
+	progress, ok, err := s.chunkProgress.Get(localPath)
+	if err != nil {
+		s.logger.Error("failed to read chunk progress", "file", localPath, "error", err)
+	}
+
+	if !ok || !progress.matches(want) {
+		progress = want
+	}
+
+	f, err := os.Open(localPath)
+	if err != nil {
+		return sent, fmt.Errorf("open %s: %w", localPath, err)
+	}
+	defer f.Close()
+
+	tmpDir, err := os.MkdirTemp("", "tgcloudbot-")
+	if err != nil {
+		return sent, fmt.Errorf("create temp dir: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	n := int((fileInfo.Size() + s.chunkSize - 1) / s.chunkSize)
+	opts := s.fileOptions(chatID, entry.Root, replyTo)
+
+	stopAction := s.showUploadAction(chatID, KindDocument)
+	defer stopAction()
+
+	start := time.Now()
+
+	for i := range n {
+		if i < len(progress.Chunks) && s.chunkExists(progress.Chunks[i]) {
+			continue
+		}
+
+		chunk, err := s.uploadChunk(chatID, f, tmpDir, caption, i, n, opts)
+		if err != nil {
+			return sent, fmt.Errorf("upload chunk %d of %d of %s: %w", i+1, n, localPath, err)
+		}
+
+		sent += min(s.chunkSize, fileInfo.Size()-int64(i)*s.chunkSize)
+
+		if i < len(progress.Chunks) {
+			progress.Chunks[i] = chunk
+		} else {
+			progress.Chunks = append(progress.Chunks, chunk)
+		}
+
+		if err := s.chunkProgress.Put(localPath, progress); err != nil {
+			s.logger.Error("failed to save chunk progress", "file", localPath, "error", err)
+		}
+	}
+
+	s.stats.uploaded(fileInfo.Size())
+	s.metrics.FileSynced(fileInfo.Size(), time.Since(start))
+
+	entry.MessageID, entry.Kind = progress.Chunks[0].MessageID, KindDocument
+	entry.Chunks, entry.Encrypted = progress.Chunks, progress.Encrypted
+
+	if err := s.index.Put(localPath, entry); err != nil {
+		s.logger.Error("failed to update index", "file", localPath, "error", err)
+	}
+
+	if err := s.chunkProgress.Delete(localPath); err != nil {
+		s.logger.Error("failed to save chunk progress", "file", localPath, "error", err)
+	}
+
+	return sent, nil
+}
+
+// chunkExists reports whether Telegram still has the uploaded chunk, only a rejected file_id means it
+// was lost. The public Bot API refuses getFile for files over 20MB, and the upload of a chunk would fail
+// on other errors, e.g. of the network, the same way.
+func (s *SyncService) chunkExists(chunk Chunk) bool {
+	if chunk.FileID == "" {
+		return false
+	}
+
+	_, err := s.bot.GetFileInfo(s.ctx, chunk.FileID)
+
+	return err == nil || !errors.Is(err, telegram.ErrBadRequest) || errors.Is(err, telegram.ErrFileTooLarge)
+}
+
+// uploadChunk uploads chunk i of n of f, written to a file in tmpDir first.
+func (s *SyncService) uploadChunk(
+	chatID string, f *os.File, tmpDir, caption string, i, n int, opts []telegram.SendOption,
+) (Chunk, error) {
+	path := filepath.Join(tmpDir, fmt.Sprintf("%s.part%03d", filepath.Base(f.Name()), i+1))
+
+	if err := writeChunk(path, io.NewSectionReader(f, int64(i)*s.chunkSize, s.chunkSize)); err != nil {
+		return Chunk{}, err
+	}
+	defer os.Remove(path)
+
+	hash, err := hashFile(path)
+	if err != nil {
+		return Chunk{}, err
+	}
+
+	if caption != "" {
+		caption += fmt.Sprintf(" (part %d/%d)", i+1, n)
+	}
+
+	if s.encryptionKey != nil {
+		encPath, err := encryption.EncryptFile(s.encryptionKey, path, tmpDir)
+		if err != nil {
+			return Chunk{}, err
+		}
+		defer os.Remove(encPath)
+
+		path, caption = encPath, ""
+	}
+
+	msg, err := s.bot.SendDocument(s.ctx, chatID, path, caption, opts...)
+	if err != nil {
+		return Chunk{}, err
+	}
+
+	if err := verifyUploadSize(path, msg); err != nil {
+		return Chunk{}, err
+	}
+
+	return Chunk{MessageID: msg.MessageID, FileID: fileIDOf(msg), Hash: hash}, nil
+}
+
+// writeChunk copies r into a new file at path.
+func writeChunk(path string, r io.Reader) error {
+	out, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create %s: %w", path, err)
+	}
+
+	if _, err := io.Copy(out, r); err != nil {
+		_ = out.Close()
+
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+
+	if err := out.Close(); err != nil {
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+
+	return nil
+}
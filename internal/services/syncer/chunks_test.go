@@ -0,0 +1,161 @@
+package syncer
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/k0ff1l/tgcloudbot/internal/services/file"
+	"github.com/k0ff1l/tgcloudbot/internal/services/telegram"
+	"github.com/k0ff1l/tgcloudbot/internal/services/telegram/telegramtest"
+)
+
+// storingBot serves every uploaded document under its base name as file_id and fails the uploads
+// after the first failAfter ones, if set.
+type storingBot struct {
+	*telegramtest.FakeClient
+
+	failAfter int
+	uploads   int
+}
+
+func (b *storingBot) SendDocument(
+	ctx context.Context, chatID, filePath, caption string, opts ...telegram.SendOption,
+) (*telegram.Message, error) {
+	if b.failAfter > 0 && b.uploads >= b.failAfter {
+		return nil, errors.New("killed")
+	}
+
+	b.uploads++
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	msg, err := b.FakeClient.SendDocument(ctx, chatID, filePath, caption, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	b.ServeFile(filepath.Base(filePath), data)
+	msg.Document = &telegram.Document{FileID: filepath.Base(filePath)}
+
+	return msg, nil
+}
+
+func uploadedNames(bot *telegramtest.FakeClient) []string {
+	var names []string
+	for _, path := range bot.Uploaded() {
+		names = append(names, filepath.Base(path))
+	}
+
+	return names
+}
+
+func TestChunkedUploadResumes(t *testing.T) {
+	dir := t.TempDir()
+	content := "0123456789"
+	path := writeFile(t, dir, "big.bin", []byte(content))
+	progressFile := filepath.Join(t.TempDir(), "chunks.json")
+
+	interrupted := &storingBot{FakeClient: telegramtest.NewFakeClient(), failAfter: 2}
+	s := NewSyncService(interrupted, file.NewWatcher(), WithChatID("chat"))
+	s.SetChunkSize(3)
+
+	if err := s.SetChunkProgressFile(progressFile); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.SyncFile(path); err == nil {
+		t.Fatal("expected the interrupted upload to fail")
+	}
+
+	// a restart with the same progress file uploads the missing chunks only
+	bot := &storingBot{FakeClient: interrupted.FakeClient}
+	restarted := NewSyncService(bot, file.NewWatcher(), WithChatID("chat"))
+	restarted.SetChunkSize(3)
+
+	if err := restarted.SetChunkProgressFile(progressFile); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := restarted.SyncFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "big.bin.part001 big.bin.part002 big.bin.part003 big.bin.part004"
+	if got := strings.Join(uploadedNames(bot.FakeClient), " "); got != want {
+		t.Errorf("expected every chunk uploaded once, got %s", got)
+	}
+
+	entry, ok := restarted.indexEntry(path)
+	if !ok || len(entry.Chunks) != 4 {
+		t.Fatalf("unexpected index entry %+v", entry)
+	}
+
+	if progress, _ := restarted.chunkProgress.List(); len(progress) != 0 {
+		t.Errorf("expected the progress to be dropped, got %+v", progress)
+	}
+
+	dest := t.TempDir()
+	if err := restarted.Restore(dest); err != nil {
+		t.Fatal(err)
+	}
+
+	if data, err := os.ReadFile(filepath.Join(dest, "big.bin")); err != nil || string(data) != content {
+		t.Errorf("restored %q, %v", data, err)
+	}
+}
+
+func TestChunkedUploadReplacesExpiredChunks(t *testing.T) {
+	dir := t.TempDir()
+	path := writeFile(t, dir, "big.bin", []byte("0123456789"))
+
+	bot := &storingBot{FakeClient: telegramtest.NewFakeClient(), failAfter: 2}
+	s := NewSyncService(bot, file.NewWatcher(), WithChatID("chat"))
+	s.SetChunkSize(3)
+
+	if err := s.SyncFile(path); err == nil {
+		t.Fatal("expected the interrupted upload to fail")
+	}
+
+	bot.failAfter = 0
+	bot.FailWith("GetFileInfo", &telegram.APIError{Code: 400, Description: "Bad Request: invalid file_id"})
+
+	if err := s.SyncFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	if uploads := bot.Uploaded(); len(uploads) != 6 {
+		t.Errorf("expected the expired chunks to be uploaded again, got %v", uploadedNames(bot.FakeClient))
+	}
+}
+
+func TestChunkedUploadKeepsChunksTooBigToCheck(t *testing.T) {
+	dir := t.TempDir()
+	path := writeFile(t, dir, "big.bin", []byte("0123456789"))
+
+	bot := &storingBot{FakeClient: telegramtest.NewFakeClient(), failAfter: 2}
+	s := NewSyncService(bot, file.NewWatcher(), WithChatID("chat"))
+	s.SetChunkSize(3)
+
+	if err := s.SyncFile(path); err == nil {
+		t.Fatal("expected the interrupted upload to fail")
+	}
+
+	// the answer of the public Bot API for chunks over 20MB
+	bot.failAfter = 0
+	bot.FailWith("GetFileInfo", &telegram.APIError{Code: 400, Description: "Bad Request: file is too big"})
+
+	if err := s.SyncFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	if uploads := bot.Uploaded(); len(uploads) != 4 {
+		t.Errorf("expected only the missing chunks to be uploaded, got %v", uploadedNames(bot.FakeClient))
+	}
+}
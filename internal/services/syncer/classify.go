@@ -0,0 +1,110 @@
+package syncer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// sniffLen is how many bytes http.DetectContentType looks at.
+const sniffLen = 512
+
+// Classifier picks the send method of a file, see SyncService.SetClassifier.
+type Classifier interface {
+	Classify(path string) (SendKind, error)
+}
+
+// ClassifierFunc is a function used as a Classifier.
+type ClassifierFunc func(path string) (SendKind, error)
+
+func (f ClassifierFunc) Classify(path string) (SendKind, error) {
+	return f(path)
+}
+
+// FixedKind classifies every file as itself, e.g. FixedKind(KindDocument) keeps photos from being recompressed.
+type FixedKind SendKind
+
+func (k FixedKind) Classify(string) (SendKind, error) {
+	return SendKind(k), nil
+}
+
+// kindByExt is the extension-only classification.
+//
+//nolint:gochecknoglobals // read-only lookup table
+var kindByExt = map[string]SendKind{
+	".jpg":  KindPhoto,
+	".jpeg": KindPhoto,
+	".png":  KindPhoto,
+	".gif":  KindAnimation,
+	".webp": KindPhoto,
+	".mp3":  KindAudio,
+	".m4a":  KindAudio,
+	".flac": KindAudio,
+	".wav":  KindAudio,
+	".ogg":  KindAudio,
+	".opus": KindAudio,
+	".mp4":  KindVideo,
+	".mov":  KindVideo,
+	".mkv":  KindVideo,
+	".webm": KindVideo,
+	".avi":  KindVideo,
+}
+
+// ExtensionClassifier classifies files by their extension alone, unknown ones are documents.
+type ExtensionClassifier struct{}
+
+func (ExtensionClassifier) Classify(path string) (SendKind, error) {
+	return kindByExt[strings.ToLower(filepath.Ext(path))], nil
+}
+
+// ContentClassifier sniffs the first 512 bytes of the file, the default.
+// Content that can't be recognised (application/octet-stream) falls back to the extension,
+// so e.g. an mp3 without an ID3 header is still sent as audio.
+type ContentClassifier struct{}
+
+func (ContentClassifier) Classify(path string) (SendKind, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return KindDocument, fmt.Errorf("open %s: %w", path, err)
+	}
+	defer file.Close()
+
+	buf := make([]byte, sniffLen)
+
+	n, err := io.ReadFull(file, buf)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return KindDocument, fmt.Errorf("read %s: %w", path, err)
+	}
+
+	contentType := http.DetectContentType(buf[:n])
+	if i := strings.IndexByte(contentType, ';'); i >= 0 {
+		contentType = contentType[:i]
+	}
+
+	switch {
+	case contentType == "image/gif", contentType == "image/webp" && isAnimatedWebP(buf[:n]):
+		return KindAnimation, nil
+	case contentType == "image/jpeg", contentType == "image/png", contentType == "image/webp":
+		return KindPhoto, nil
+	case strings.HasPrefix(contentType, "audio/"), contentType == "application/ogg":
+		return KindAudio, nil
+	case strings.HasPrefix(contentType, "video/"):
+		return KindVideo, nil
+	case contentType == "application/octet-stream":
+		return ExtensionClassifier{}.Classify(path)
+	default:
+		return KindDocument, nil
+	}
+}
+
+// isAnimatedWebP reports whether the WebP starting with head is animated: the extended format (VP8X chunk)
+// with the animation flag set.
+func isAnimatedWebP(head []byte) bool {
+	const flagsOffset, animationFlag = 20, 0x02
+
+	return len(head) > flagsOffset && string(head[12:16]) == "VP8X" && head[flagsOffset]&animationFlag != 0
+}
@@ -0,0 +1,123 @@
+package syncer
+
+import (
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/k0ff1l/tgcloudbot/internal/services/file"
+	"github.com/k0ff1l/tgcloudbot/internal/services/telegram/telegramtest"
+)
+
+func TestContentClassifier(t *testing.T) {
+	dir := t.TempDir()
+
+	pngHeader := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	mp4Header := []byte("\x00\x00\x00\x10ftypmp42\x00\x00\x00\x00")
+	// the flags of the VP8X chunk follow its size, 0x02 is animation
+	webpHeader := func(flags byte) []byte {
+		return append([]byte("RIFF\x00\x00\x00\x00WEBPVP8X\x0a\x00\x00\x00"), flags, 0, 0, 0)
+	}
+
+	tests := []struct {
+		name string
+		path string
+		want SendKind
+	}{
+		{"text named mp3", writeFile(t, dir, "song.mp3", []byte("just some notes\n")), KindDocument},
+		{"extensionless png", writeFile(t, dir, "picture", pngHeader), KindPhoto},
+		{"extensionless mp4", writeFile(t, dir, "clip", mp4Header), KindVideo},
+		{"png named txt", writeFile(t, dir, "image.txt", pngHeader), KindPhoto},
+		{"gif", writeFile(t, dir, "loop.gif", []byte("GIF89a\x01\x00\x01\x00")), KindAnimation},
+		{"animated webp", writeFile(t, dir, "loop.webp", webpHeader(0x02)), KindAnimation},
+		{"still webp", writeFile(t, dir, "still.webp", webpHeader(0x10)), KindPhoto},
+		{"unknown binary falls back to extension", writeFile(t, dir, "raw.mp3", []byte{0xff, 0xfb, 0x90, 0x00}), KindAudio},
+		{"unknown binary without extension", writeFile(t, dir, "blob", []byte{0x00, 0x01, 0x02}), KindDocument},
+		{"empty file", writeFile(t, dir, "empty.mp4", nil), KindDocument},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ContentClassifier{}.Classify(tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got != tt.want {
+				t.Errorf("ContentClassifier(%s) = %s, want %s", filepath.Base(tt.path), got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtensionClassifier(t *testing.T) {
+	for want, paths := range map[SendKind][]string{
+		KindPhoto:     {"a.jpg", "a.JPEG", "a.png", "a.webp"},
+		KindAnimation: {"a.gif", "a.GIF"},
+		KindAudio:     {"a.mp3", "a.m4a", "a.flac", "a.wav", "a.ogg", "a.opus"},
+		KindVideo:     {"a.mp4", "a.mov", "a.mkv", "a.webm", "a.avi"},
+		KindDocument:  {"a.txt", "a.tar.gz", "Makefile", "a."},
+	} {
+		for _, path := range paths {
+			// the file is never read
+			if got, err := (ExtensionClassifier{}).Classify(path); err != nil || got != want {
+				t.Errorf("ExtensionClassifier(%s) = %s, %v, want %s", path, got, err, want)
+			}
+		}
+	}
+}
+
+func TestCustomClassifier(t *testing.T) {
+	dir, forced := t.TempDir(), t.TempDir()
+
+	bot := telegramtest.NewFakeClient()
+	s := NewSyncService(bot, file.NewWatcher(), WithChatID("chat"))
+	s.SetPreferVoice(true)
+	s.SetClassifier(ClassifierFunc(func(path string) (SendKind, error) {
+		if filepath.Ext(path) == ".bad" {
+			return KindDocument, errors.New("unclassifiable")
+		}
+
+		return KindAudio, nil
+	}))
+	s.SetDirClassifier(forced, FixedKind(KindVideo))
+
+	for _, path := range []string{
+		writeFile(t, dir, "a.txt", []byte("a")),
+		writeFile(t, dir, "b.ogg", []byte("b")),
+		writeFile(t, forced, "c.ogg", []byte("c")),
+	} {
+		if err := s.SyncFile(path); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var methods []string
+	for _, call := range bot.Calls() {
+		methods = append(methods, call.Method)
+	}
+
+	// the directory classifier applies as is, without preferring voice
+	if want := []string{"SendAudio", "SendVoice", "SendVideo"}; !slices.Equal(methods, want) {
+		t.Errorf("methods %v, want %v", methods, want)
+	}
+
+	if err := s.SyncFile(writeFile(t, dir, "d.bad", nil)); err == nil {
+		t.Error("expected the classifier error")
+	}
+}
+
+func TestGIFIsSentAsAnimation(t *testing.T) {
+	bot := telegramtest.NewFakeClient()
+	s := NewSyncService(bot, file.NewWatcher(), WithChatID("chat"))
+
+	path := writeFile(t, t.TempDir(), "loop.gif", []byte("GIF89a\x01\x00\x01\x00"))
+	if err := s.SyncFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(bot.CallsTo("SendAnimation")) != 1 || len(bot.CallsTo("SendPhoto")) != 0 {
+		t.Errorf("expected the gif to be sent with sendAnimation, got %+v", bot.Calls())
+	}
+}
@@ -0,0 +1,132 @@
+package syncer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/k0ff1l/tgcloudbot/internal/services/state"
+)
+
+// DeadLetter is a file that is no longer retried after failing RetryBudget times in a row, see SetRetryBudget.
+// Size and ModTime are of the file when it was given up, a change of either retries it.
+type DeadLetter struct {
+	Attempts int       `json:"attempts"`
+	Error    string    `json:"error"`
+	Size     int64     `json:"size"`
+	ModTime  time.Time `json:"mod_time"`
+	Since    time.Time `json:"since"`
+}
+
+// newMemoryDeadLetters returns a dead-letter store kept in memory only.
+func newMemoryDeadLetters() state.Store[DeadLetter] {
+	store, _ := state.NewFileStore[DeadLetter]("") // can't fail without a file
+
+	return store
+}
+
+// SetRetryBudget gives up on a file after it failed attempts times in a row with an error that
+// is retried: it is logged and alerted once, dead-lettered and not retried until it changes.
+// 0, the default, retries failing files on every tick.
+func (s *SyncService) SetRetryBudget(attempts int) {
+	s.retryBudget = attempts
+}
+
+// SetDeadLetterFile keeps the dead-lettered files in path, so they are not retried after a restart either.
+func (s *SyncService) SetDeadLetterFile(path string) error {
+	store, err := state.NewFileStore[DeadLetter](path)
+	if err != nil {
+		return err
+	}
+
+	s.deadLetters = store
+
+	return nil
+}
+
+// DeadLetters returns the dead-lettered files by path.
+func (s *SyncService) DeadLetters() (map[string]DeadLetter, error) {
+	return s.deadLetters.List()
+}
+
+// ClearDeadLetter drops filePath from the dead letters, it is retried with the next sync.
+func (s *SyncService) ClearDeadLetter(filePath string) error {
+	filePath = filepath.Clean(filePath)
+
+	s.resetFailures(filePath)
+	s.watcher.Forget(filePath)
+
+	if err := s.deadLetters.Delete(filePath); err != nil {
+		return fmt.Errorf("clear dead letter %s: %w", filePath, err)
+	}
+
+	return nil
+}
+
+// giveUp counts a failed attempt to sync filePath and reports whether the retry budget is spent,
+// the file is dead-lettered then.
+func (s *SyncService) giveUp(filePath string, err error) bool {
+	if s.retryBudget <= 0 {
+		return false
+	}
+
+	s.mu.Lock()
+	s.failures[filePath]++
+	attempts := s.failures[filePath]
+	s.mu.Unlock()
+
+	if attempts < s.retryBudget {
+		return false
+	}
+
+	letter := DeadLetter{Attempts: attempts, Error: err.Error(), Since: s.now()}
+
+	if info, statErr := os.Stat(filePath); statErr == nil {
+		letter.Size, letter.ModTime = info.Size(), info.ModTime()
+	}
+
+	if putErr := s.deadLetters.Put(filePath, letter); putErr != nil {
+		s.logger.Error("failed to record dead letter", "file", filePath, "error", putErr)
+	}
+
+	s.resetFailures(filePath)
+	s.logger.Error("giving up on file until it changes", "file", filePath, "attempts", attempts, "error", err)
+	s.sendAlert(filePath, fmt.Sprintf("Giving up on %s after %d attempts:\n%v", filePath, attempts, err))
+
+	return true
+}
+
+// deadLettered reports whether filePath was given up and didn't change since, a changed file
+// is dropped from the dead letters.
+func (s *SyncService) deadLettered(filePath string) bool {
+	letter, ok, err := s.deadLetters.Get(filePath)
+	if err != nil {
+		s.logger.Error("failed to read dead letters", "file", filePath, "error", err)
+
+		return false
+	}
+
+	if !ok {
+		return false
+	}
+
+	info, err := os.Stat(filePath)
+	if err == nil && info.Size() == letter.Size && info.ModTime().Equal(letter.ModTime) {
+		return true
+	}
+
+	if err := s.deadLetters.Delete(filePath); err != nil {
+		s.logger.Error("failed to update dead letters", "file", filePath, "error", err)
+	}
+
+	return false
+}
+
+// resetFailures clears the failed attempts of filePath after it synced.
+func (s *SyncService) resetFailures(filePath string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.failures, filePath)
+}
@@ -0,0 +1,114 @@
+package syncer
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/k0ff1l/tgcloudbot/internal/services/file"
+	"github.com/k0ff1l/tgcloudbot/internal/services/telegram/telegramtest"
+)
+
+func TestRetryBudget(t *testing.T) {
+	dir := t.TempDir()
+	path := writeFile(t, dir, "a.txt", []byte("a"))
+
+	watcher := file.NewWatcher()
+	if err := watcher.AddDir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	bot := telegramtest.NewFakeClient()
+	bot.FailWith("SendDocument", errors.New("network down"))
+
+	s := NewSyncService(bot, watcher, WithChatID("chat"))
+	s.SetRetryBudget(3)
+	s.SetErrorAlerts("alerts", time.Hour)
+
+	deadLetterFile := filepath.Join(t.TempDir(), "dead.json")
+	if err := s.SetDeadLetterFile(deadLetterFile); err != nil {
+		t.Fatal(err)
+	}
+
+	for range 5 {
+		s.syncDirectoryOnce(dir)
+	}
+
+	if attempts := len(bot.CallsTo("SendDocument")); attempts != 3 {
+		t.Errorf("expected retries to stop after 3 attempts, got %d", attempts)
+	}
+
+	letters, err := s.DeadLetters()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if letter, ok := letters[path]; !ok || letter.Attempts != 3 || !strings.HasSuffix(letter.Error, "network down") {
+		t.Errorf("expected the file to be dead-lettered, got %+v", letters)
+	}
+
+	// one alert for the repeated error and one for giving up
+	if alerts := bot.CallsTo("SendMessage"); len(alerts) != 2 {
+		t.Errorf("expected 2 alerts, got %+v", alerts)
+	}
+
+	// a restart without watcher state reports the file again, it is still skipped
+	restarted := NewSyncService(bot, file.NewWatcher(), WithChatID("chat"))
+	if err := restarted.SetDeadLetterFile(deadLetterFile); err != nil {
+		t.Fatal(err)
+	}
+
+	if !restarted.deadLettered(path) {
+		t.Error("expected the unchanged file to stay dead-lettered")
+	}
+
+	// a change retries it
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+
+	bot.FailWith("SendDocument", nil)
+	s.syncDirectoryOnce(dir)
+
+	if attempts := len(bot.CallsTo("SendDocument")); attempts != 4 {
+		t.Errorf("expected the changed file to be retried, got %d attempts", attempts)
+	}
+
+	if letters, _ := s.DeadLetters(); len(letters) != 0 {
+		t.Errorf("expected no dead letters, got %+v", letters)
+	}
+}
+
+func TestClearDeadLetter(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "a.txt", []byte("a"))
+
+	watcher := file.NewWatcher()
+	if err := watcher.AddDir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	bot := telegramtest.NewFakeClient()
+	bot.FailWith("SendDocument", errors.New("network down"))
+
+	s := NewSyncService(bot, watcher, WithChatID("chat"))
+	s.SetRetryBudget(1)
+
+	s.syncDirectoryOnce(dir)
+	s.syncDirectoryOnce(dir)
+
+	if err := s.ClearDeadLetter(filepath.Join(dir, "a.txt")); err != nil {
+		t.Fatal(err)
+	}
+
+	bot.FailWith("SendDocument", nil)
+	s.syncDirectoryOnce(dir)
+
+	if attempts := len(bot.CallsTo("SendDocument")); attempts != 2 {
+		t.Errorf("expected the cleared file to be retried once, got %d attempts", attempts)
+	}
+}
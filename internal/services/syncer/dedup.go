@@ -0,0 +1,96 @@
+package syncer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/k0ff1l/tgcloudbot/internal/services/state"
+)
+
+// SetDedupFile enables deduplication with the hash index kept in path, see SetDedupStore.
+func (s *SyncService) SetDedupFile(path string) error {
+	store, err := state.NewFileStore[IndexEntry](path)
+	if err != nil {
+		return err
+	}
+
+	s.dedup = store
+
+	return nil
+}
+
+// SetDedupStore enables deduplication: a file with the content of an already uploaded one,
+// e.g. a copy in another watched directory or a moved file, is sent by the file_id of that upload
+// instead of being uploaded again. store maps the sha256 of the content to the upload.
+func (s *SyncService) SetDedupStore(store state.Store[IndexEntry]) {
+	s.dedup = store
+}
+
+// SetDedupCacheSize keeps the size most recently used hashes in memory in front of the dedup store,
+// so that a slow store isn't read for every file, see state.LRUStore. It must be called after the store
+// is set, a size of 0 leaves the store as is.
+func (s *SyncService) SetDedupCacheSize(size int) {
+	if s.dedup == nil || size <= 0 {
+		return
+	}
+
+	s.dedup = state.NewLRUStore(s.dedup, size)
+}
+
+// sendDuplicate sends the file with the given content hash and caption by the file_id of an earlier upload
+// and indexes entry as that message. It reports false when the file has to be uploaded.
+func (s *SyncService) sendDuplicate(chatID, localPath, hash, caption string, entry IndexEntry, replyTo int64) bool {
+	dup, ok, err := s.dedup.Get(hash)
+	if err != nil {
+		s.logger.Error("failed to read dedup index", "file", localPath, "error", err)
+
+		return false
+	}
+
+	// an encrypted upload is reused only while encrypting, and a plain one only while not
+	if !ok || dup.FileID == "" || dup.Encrypted != (s.encryptionKey != nil) {
+		return false
+	}
+
+	if dup.Encrypted {
+		caption = ""
+	}
+
+	msg, err := s.sendByRef(chatID, dup.Kind, dup.FileID, caption, s.fileOptions(chatID, entry.Root, replyTo))
+	if err != nil {
+		s.logger.Warn("failed to send duplicate by file_id, uploading it", "file", localPath, "error", err)
+
+		return false
+	}
+
+	entry.MessageID, entry.FileID, entry.Kind = msg.MessageID, dup.FileID, dup.Kind
+	entry.Gzip, entry.Encrypted = dup.Gzip, dup.Encrypted
+	s.mirror(&entry, localPath, caption)
+
+	if err := s.index.Put(localPath, entry); err != nil {
+		s.logger.Error("failed to update index", "file", localPath, "error", err)
+	}
+
+	s.logger.Info("sent duplicate by file_id", "file", localPath, "kind", dup.Kind.String())
+
+	return true
+}
+
+// hashFile returns the hex sha256 of the file content.
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("open %s: %w", path, err)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("hash %s: %w", path, err)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,119 @@
+package syncer
+
+import (
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/k0ff1l/tgcloudbot/internal/services/file"
+	"github.com/k0ff1l/tgcloudbot/internal/services/state"
+	"github.com/k0ff1l/tgcloudbot/internal/services/telegram"
+	"github.com/k0ff1l/tgcloudbot/internal/services/telegram/telegramtest"
+)
+
+func TestDedup(t *testing.T) {
+	dir := t.TempDir()
+	first := writeFile(t, dir, "a.txt", []byte("same"))
+	copied := writeFile(t, dir, "b.txt", []byte("same"))
+	other := writeFile(t, dir, "c.txt", []byte("other"))
+	dedupFile := filepath.Join(t.TempDir(), "dedup.json")
+
+	bot := telegramtest.NewFakeClient()
+	bot.RespondWith("SendDocument", telegram.Message{MessageID: 1, Document: &telegram.Document{FileID: "doc"}})
+
+	s := NewSyncService(bot, file.NewWatcher(), WithChatID("chat"))
+
+	if err := s.SetDedupFile(dedupFile); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{first, copied, other} {
+		if err := s.SyncFile(path); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if uploads := bot.CallsTo("SendDocument"); len(uploads) != 2 {
+		t.Errorf("expected a.txt and c.txt to be uploaded, got %+v", uploads)
+	}
+
+	uploaded, _ := s.MessageFor(first)
+	refs := bot.CallsTo("SendDocumentByRef")
+
+	if len(refs) != 1 || refs[0].FileID != uploaded.FileID || refs[0].Caption != "File: b.txt" {
+		t.Fatalf("expected b.txt to be sent by the file_id of a.txt, got %+v", refs)
+	}
+
+	if entry, _ := s.MessageFor(copied); entry.FileID != uploaded.FileID || entry.RelPath != "b.txt" {
+		t.Errorf("unexpected index entry of the duplicate %+v", entry)
+	}
+
+	// the hash index survives a restart
+	restarted := NewSyncService(bot, file.NewWatcher(), WithChatID("chat"))
+	if err := restarted.SetDedupFile(dedupFile); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := restarted.SyncFile(writeFile(t, t.TempDir(), "moved.txt", []byte("other"))); err != nil {
+		t.Fatal(err)
+	}
+
+	if refs := bot.CallsTo("SendDocumentByRef"); len(refs) != 2 {
+		t.Errorf("expected the moved file to be sent by file_id, got %+v", refs)
+	}
+}
+
+// countingStore counts the reads that reach the store.
+type countingStore struct {
+	state.Store[IndexEntry]
+
+	gets atomic.Int64
+}
+
+func (s *countingStore) Get(key string) (IndexEntry, bool, error) {
+	s.gets.Add(1)
+
+	return s.Store.Get(key)
+}
+
+func TestDedupCache(t *testing.T) {
+	dir := t.TempDir()
+
+	bot := telegramtest.NewFakeClient()
+	bot.RespondWith("SendDocument", telegram.Message{MessageID: 1, Document: &telegram.Document{FileID: "doc"}})
+
+	store := &countingStore{Store: newMemoryIndex()}
+	s := NewSyncService(bot, file.NewWatcher(), WithChatID("chat"))
+	s.SetDedupStore(store)
+	s.SetDedupCacheSize(10)
+
+	for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		if err := s.SyncFile(writeFile(t, dir, name, []byte("same"))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if refs := bot.CallsTo("SendDocumentByRef"); len(refs) != 2 {
+		t.Errorf("expected the copies to be sent by file_id, got %+v", refs)
+	}
+
+	// only the miss of the first upload reaches the store, the upload is cached
+	if n := store.gets.Load(); n != 1 {
+		t.Errorf("expected 1 read of the dedup store, got %d", n)
+	}
+
+	if _, ok, _ := store.Store.Get(mustHash(t, filepath.Join(dir, "a.txt"))); !ok {
+		t.Error("the upload must be written through to the store")
+	}
+}
+
+func mustHash(t *testing.T, path string) string {
+	t.Helper()
+
+	hash, err := hashFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return hash
+}
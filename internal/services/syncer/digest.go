@@ -0,0 +1,94 @@
+package syncer
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// maxDigestFiles is the number of files listed by a batch digest, the rest is counted only.
+const maxDigestFiles = 50
+
+// SetBatchDigest announces a sync batch of at least threshold files, e.g. the first scan of a full
+// directory, with a single message listing the files and their sizes instead of a folder header.
+// The files of such a batch are then uploaded one at a time, without captions if noCaptions is set.
+// 0, the default, disables it.
+func (s *SyncService) SetBatchDigest(threshold int, noCaptions bool) {
+	s.digestThreshold = max(threshold, 0)
+	s.digestNoCaptions = noCaptions
+}
+
+// isDigestBatch reports whether a batch of n files is announced by a digest.
+func (s *SyncService) isDigestBatch(n int) bool {
+	return s.digestThreshold > 0 && n >= s.digestThreshold
+}
+
+// postBatchDigest sends the digest of the files of a batch of dirPath and returns its id to reply to
+// with SetReplyThreads, 0 otherwise or when it could not be sent.
+func (s *SyncService) postBatchDigest(chatID, dirPath string, files []string) int64 {
+	text := batchDigest(dirPath, files)
+
+	if s.dryRun {
+		s.logger.Info("dry run: would send batch digest", "dir", dirPath, "chat", chatID, "files", len(files))
+
+		return 0
+	}
+
+	msg, err := s.bot.SendMessage(s.ctx, chatID, text, append(s.sendOptions(0), s.topicOptions(chatID, dirPath)...)...)
+	if err != nil {
+		s.logger.Error("failed to send batch digest", "dir", dirPath, "error", err)
+
+		return 0
+	}
+
+	if !s.replyThreads {
+		return 0
+	}
+
+	return msg.MessageID
+}
+
+// batchDigest returns the text listing the files of a batch of dirPath with their sizes.
+func batchDigest(dirPath string, files []string) string {
+	var (
+		b     strings.Builder
+		total int64
+	)
+
+	for i, path := range files {
+		info, err := os.Stat(path)
+		if err != nil {
+			// it is skipped or fails when its turn comes
+			continue
+		}
+
+		total += info.Size()
+
+		if i < maxDigestFiles {
+			fmt.Fprintf(&b, "\n%s (%s)", relativePath(dirPath, path), formatSize(info.Size()))
+		}
+	}
+
+	if len(files) > maxDigestFiles {
+		fmt.Fprintf(&b, "\n... and %d more", len(files)-maxDigestFiles)
+	}
+
+	return fmt.Sprintf("Directory: %s, uploading %d files (%s):", dirPath, len(files), formatSize(total)) + b.String()
+}
+
+// formatSize returns size in bytes with a binary unit, e.g. "1.5 MiB".
+func formatSize(size int64) string {
+	const unit = 1024
+
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
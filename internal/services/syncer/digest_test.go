@@ -0,0 +1,62 @@
+package syncer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/k0ff1l/tgcloudbot/internal/services/file"
+	"github.com/k0ff1l/tgcloudbot/internal/services/telegram/telegramtest"
+)
+
+func TestBatchDigest(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		writeFile(t, dir, name, []byte(name))
+	}
+
+	bot := telegramtest.NewFakeClient()
+	watcher := file.NewWatcher()
+	s := NewSyncService(bot, watcher, WithChatID("chat"))
+	s.SetBatchDigest(3, true)
+
+	if err := watcher.AddDir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	s.syncDirectoryOnce(dir)
+
+	texts := bot.Texts()
+	if len(texts) != 1 || !strings.Contains(texts[0], "uploading 3 files (15 B):\na.txt (5 B)\nb.txt (5 B)\nc.txt (5 B)") {
+		t.Fatalf("expected one digest of the batch, got %q", texts)
+	}
+
+	for _, call := range bot.CallsTo("SendDocument") {
+		if call.Caption != "" {
+			t.Errorf("expected no captions in a digest batch, got %q", call.Caption)
+		}
+	}
+
+	// a smaller batch is uploaded as usual
+	writeFile(t, dir, "a.txt", []byte("changed"))
+	s.syncDirectoryOnce(dir)
+
+	calls := bot.CallsTo("SendDocument")
+	if len(calls) != 4 || calls[3].Caption != "File: a.txt" || len(bot.Texts()) != 1 {
+		t.Errorf("expected a.txt uploaded with its caption and no digest, got %+v", calls)
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	for size, want := range map[int64]string{
+		0:             "0 B",
+		1023:          "1023 B",
+		1536:          "1.5 KiB",
+		5 << 20:       "5.0 MiB",
+		3 << 30:       "3.0 GiB",
+		1<<40 + 1<<39: "1.5 TiB",
+	} {
+		if got := formatSize(size); got != want {
+			t.Errorf("formatSize(%d) = %q, want %q", size, got, want)
+		}
+	}
+}
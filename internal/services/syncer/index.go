@@ -0,0 +1,71 @@
+package syncer
+
+import (
+	"github.com/k0ff1l/tgcloudbot/internal/services/state"
+	"github.com/k0ff1l/tgcloudbot/internal/services/telegram"
+)
+
+// IndexEntry is the message a local file was last uploaded as.
+type IndexEntry struct {
+	ChatID    string `json:"chat_id"`
+	MessageID int64  `json:"message_id"`
+	FileID    string `json:"file_id,omitempty"`
+	// Kind is the send method of the upload, a file_id can only be resent with it
+	Kind SendKind `json:"kind,omitempty"`
+	// Root is the watched directory of the file and RelPath the slash-separated path below it, prefixed
+	// with the instance name if SetInstance puts it in the path. Restore rebuilds the layout from RelPath
+	Root    string `json:"root,omitempty"`
+	RelPath string `json:"rel_path,omitempty"`
+	// Instance is the name of the machine that uploaded the file, see SetInstance
+	Instance string `json:"instance,omitempty"`
+	// Gzip and Encrypted record how the uploaded copy was transformed
+	Gzip      bool `json:"gzip,omitempty"`
+	Encrypted bool `json:"encrypted,omitempty"`
+	// Hash is the hex sha256 of the local content, Restore verifies the downloaded file against it
+	Hash string `json:"hash,omitempty"`
+	// Chunks are the parts of a file uploaded in chunks, MessageID is the first one then
+	Chunks []Chunk `json:"chunks,omitempty"`
+	// Mirrors are the messages of the file in the mirror chats by chat id, see SetMirrorChats
+	Mirrors map[string]int64 `json:"mirrors,omitempty"`
+}
+
+// newMemoryIndex returns an index kept in memory only.
+func newMemoryIndex() state.Store[IndexEntry] {
+	store, _ := state.NewFileStore[IndexEntry]("") // can't fail without a file
+
+	return store
+}
+
+// indexEntry returns the index entry of filePath, a failing store is logged and reported as not indexed.
+func (s *SyncService) indexEntry(filePath string) (IndexEntry, bool) {
+	entry, ok, err := s.index.Get(filePath)
+	if err != nil {
+		s.logger.Error("failed to read index", "file", filePath, "error", err)
+
+		return IndexEntry{}, false
+	}
+
+	return entry, ok
+}
+
+// fileIDOf returns the file_id of the file sent with msg, the largest size for photos.
+func fileIDOf(msg *telegram.Message) string {
+	switch {
+	case msg.Animation != nil:
+		return msg.Animation.FileID
+	case msg.Document != nil:
+		return msg.Document.FileID
+	case msg.Audio != nil:
+		return msg.Audio.FileID
+	case msg.Video != nil:
+		return msg.Video.FileID
+	case msg.Voice != nil:
+		return msg.Voice.FileID
+	case msg.VideoNote != nil:
+		return msg.VideoNote.FileID
+	case len(msg.Photo) > 0:
+		return msg.Photo[len(msg.Photo)-1].FileID
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,28 @@
+package syncer
+
+import "time"
+
+// SetJitter spreads the syncs of directories with the same interval, so that they don't scan and upload
+// all at once: the first sync of a directory is delayed by up to fraction of its interval and every later
+// one comes up to fraction of the interval earlier or later. fraction is capped to 1, 0 disables it.
+func (s *SyncService) SetJitter(fraction float64) {
+	s.jitter = min(max(fraction, 0), 1)
+}
+
+// startDelay returns the random delay of the first sync of a directory with interval.
+func (s *SyncService) startDelay(interval time.Duration) time.Duration {
+	if s.jitter <= 0 {
+		return 0
+	}
+
+	return time.Duration(s.random() * s.jitter * float64(interval))
+}
+
+// jittered returns interval changed by a random part of up to jitter of it.
+func (s *SyncService) jittered(interval time.Duration) time.Duration {
+	if s.jitter <= 0 {
+		return interval
+	}
+
+	return interval + time.Duration((2*s.random()-1)*s.jitter*float64(interval))
+}
@@ -0,0 +1,117 @@
+package syncer
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/k0ff1l/tgcloudbot/internal/services/file"
+	"github.com/k0ff1l/tgcloudbot/internal/services/telegram"
+	"github.com/k0ff1l/tgcloudbot/internal/services/telegram/telegramtest"
+)
+
+// timedBot records when every directory got its first upload.
+type timedBot struct {
+	*telegramtest.FakeClient
+
+	mu    sync.Mutex
+	first map[string]time.Time
+}
+
+func (b *timedBot) SendDocument(
+	ctx context.Context, chatID, filePath, caption string, opts ...telegram.SendOption,
+) (*telegram.Message, error) {
+	b.mu.Lock()
+	if _, ok := b.first[filepath.Dir(filePath)]; !ok {
+		b.first[filepath.Dir(filePath)] = time.Now()
+	}
+	b.mu.Unlock()
+
+	return b.FakeClient.SendDocument(ctx, chatID, filePath, caption, opts...)
+}
+
+func TestJitterStaggersFirstSync(t *testing.T) {
+	const interval = time.Second
+
+	bot := &timedBot{FakeClient: telegramtest.NewFakeClient(), first: make(map[string]time.Time)}
+	s := NewSyncService(bot, file.NewWatcher(), WithChatID("chat"))
+	t.Cleanup(s.Stop)
+
+	s.SetJitter(0.5)
+
+	// the first directory starts right away, the second after 0.9 * 0.5 of the interval
+	var (
+		mu      sync.Mutex
+		randoms = []float64{0, 0.9}
+	)
+
+	s.random = func() float64 {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if len(randoms) == 0 {
+			return 0.5
+		}
+
+		r := randoms[0]
+		randoms = randoms[1:]
+
+		return r
+	}
+
+	var dirs []string
+
+	for range 2 {
+		dir := t.TempDir()
+		writeFile(t, dir, "a.txt", []byte("a"))
+		dirs = append(dirs, dir)
+	}
+
+	start := time.Now()
+
+	for _, dir := range dirs {
+		if err := s.StartContinuousSync(dir, interval); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	deadline := time.Now().Add(2 * interval)
+	for len(bot.Uploaded()) < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	bot.mu.Lock()
+	defer bot.mu.Unlock()
+
+	if len(bot.first) != 2 {
+		t.Fatalf("expected both directories to sync, got %v", bot.first)
+	}
+
+	if d := bot.first[dirs[0]].Sub(start); d > 200*time.Millisecond {
+		t.Errorf("expected the first directory to sync right away, took %s", d)
+	}
+
+	if d := bot.first[dirs[1]].Sub(start); d < 400*time.Millisecond || d > interval/2 {
+		t.Errorf("expected the second directory to sync after about 450ms, took %s", d)
+	}
+}
+
+func TestJittered(t *testing.T) {
+	s := NewSyncService(telegramtest.NewFakeClient(), file.NewWatcher(), WithChatID("chat"))
+
+	if d := s.jittered(time.Minute); d != time.Minute {
+		t.Errorf("expected no jitter by default, got %s", d)
+	}
+
+	s.SetJitter(0.1)
+
+	for _, r := range []float64{0, 0.5, 0.999} {
+		s.random = func() float64 { return r }
+
+		if d := s.jittered(time.Minute); d < 54*time.Second || d > 66*time.Second {
+			t.Errorf("jittered(1m) with random %v = %s, want within 10%%", r, d)
+		}
+	}
+}
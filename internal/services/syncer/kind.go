@@ -0,0 +1,111 @@
+package syncer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/k0ff1l/tgcloudbot/internal/services/telegram"
+)
+
+type SendKind int
+
+const (
+	KindDocument SendKind = iota
+	KindPhoto
+	KindAudio
+	KindVideo
+	// KindVoice is used for .ogg/.opus audio only when voice is preferred, see SyncService.SetPreferVoice.
+	KindVoice
+	// KindAnimation keeps GIFs and animated WebPs playing, as photos they would show their first frame only.
+	KindAnimation
+)
+
+func (k SendKind) String() string {
+	switch k {
+	case KindPhoto:
+		return "photo"
+	case KindAudio:
+		return "audio"
+	case KindVideo:
+		return "video"
+	case KindVoice:
+		return "voice"
+	case KindAnimation:
+		return "animation"
+	default:
+		return "document"
+	}
+}
+
+// ParseForceKind parses the kind forced for a directory: "document", "photo", "audio", "video" or "animation".
+// "auto" or empty means detection and is reported as not forced.
+func ParseForceKind(s string) (kind SendKind, forced bool, err error) {
+	switch s {
+	case "", "auto":
+		return KindDocument, false, nil
+	case "document":
+		return KindDocument, true, nil
+	case "photo":
+		return KindPhoto, true, nil
+	case "audio":
+		return KindAudio, true, nil
+	case "video":
+		return KindVideo, true, nil
+	case "animation":
+		return KindAnimation, true, nil
+	default:
+		return KindDocument, false,
+			fmt.Errorf("unknown kind %q, expected auto, document, photo, audio, video or animation", s)
+	}
+}
+
+// isVoiceExtension reports whether the file may be sent as a voice message, which must be OGG/OPUS.
+func isVoiceExtension(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+
+	return ext == ".ogg" || ext == ".opus"
+}
+
+// siblingThumbnail returns the .jpg or .jpeg next to filePath with the same base name,
+// or "" if there is none that Telegram accepts as a thumbnail.
+func (s *SyncService) siblingThumbnail(filePath string) string {
+	base := strings.TrimSuffix(filePath, filepath.Ext(filePath))
+
+	for _, ext := range []string{".jpg", ".jpeg"} {
+		thumb := base + ext
+		if thumb == filePath {
+			continue
+		}
+
+		if _, err := os.Stat(thumb); err != nil {
+			continue
+		}
+
+		if err := telegram.ValidateThumbnail(thumb); err != nil {
+			s.logger.Warn("skipping thumbnail", "file", filePath, "error", err)
+
+			continue
+		}
+
+		return thumb
+	}
+
+	return ""
+}
+
+// audioTags returns the performer and title of the audio file at path from its name,
+// "Performer - Title.mp3" or just "Title.mp3".
+func audioTags(path string) (performer, title string) {
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+
+	if performer, title, ok := strings.Cut(name, " - "); ok {
+		performer, title = strings.TrimSpace(performer), strings.TrimSpace(title)
+		if performer != "" && title != "" {
+			return performer, title
+		}
+	}
+
+	return "", name
+}
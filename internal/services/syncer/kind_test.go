@@ -0,0 +1,94 @@
+package syncer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/k0ff1l/tgcloudbot/internal/services/file"
+	"github.com/k0ff1l/tgcloudbot/internal/services/telegram/telegramtest"
+)
+
+func writeFile(t *testing.T, dir, name string, data []byte) string {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestSendKindByExtensionOnly(t *testing.T) {
+	path := writeFile(t, t.TempDir(), "song.mp3", []byte("just some notes\n"))
+
+	s := NewSyncService(nil, nil, WithClassifier(ExtensionClassifier{}))
+
+	kind, err := s.sendKind(filepath.Dir(path), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if kind != KindAudio {
+		t.Errorf("expected audio in extension-only mode, got %s", kind)
+	}
+}
+
+func TestForceKind(t *testing.T) {
+	dir := t.TempDir()
+	path := writeFile(t, dir, "photo.jpg", []byte("\xff\xd8\xff\xe0"))
+
+	kind, forced, err := ParseForceKind("document")
+	if err != nil || !forced {
+		t.Fatalf("ParseForceKind(document) = %s, %v, %v", kind, forced, err)
+	}
+
+	if _, _, err := ParseForceKind("sticker"); err == nil {
+		t.Error("expected an error for an unknown kind")
+	}
+
+	bot := telegramtest.NewFakeClient()
+	s := NewSyncService(bot, file.NewWatcher(), WithChatID("chat"))
+	s.SetDirKind(dir, kind)
+
+	if err := s.SyncFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	if calls := bot.CallsTo("SendDocument", "SendPhoto"); len(calls) != 1 || calls[0].Method != "SendDocument" {
+		t.Errorf("expected the jpg to be sent as a document, got %+v", calls)
+	}
+}
+
+func TestAudioTagsFromName(t *testing.T) {
+	for name, want := range map[string][2]string{
+		"Artist - Song.mp3":   {"Artist", "Song"},
+		"A - B - C.flac":      {"A", "B - C"},
+		"Song.mp3":            {"", "Song"},
+		" - Untitled.mp3":     {"", " - Untitled"},
+		"Artist-Song.ogg":     {"", "Artist-Song"},
+		"Artist  -  Song.m4a": {"Artist", "Song"},
+	} {
+		if performer, title := audioTags(name); [2]string{performer, title} != want {
+			t.Errorf("audioTags(%q) = %q, %q, want %q", name, performer, title, want)
+		}
+	}
+
+	dir := t.TempDir()
+
+	bot := telegramtest.NewFakeClient()
+	s := NewSyncService(bot, file.NewWatcher(), WithChatID("chat"), WithClassifier(ExtensionClassifier{}))
+	s.SetAudioTagsFromName(true)
+
+	for _, path := range []string{writeFile(t, dir, "Artist - Song.mp3", nil), writeFile(t, dir, "a.txt", nil)} {
+		if err := s.SyncFile(path); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	audio, docs := bot.CallsTo("SendAudio"), bot.CallsTo("SendDocument")
+	if len(audio) != 1 || len(audio[0].Options) != 1 || len(docs) != 1 || len(docs[0].Options) != 0 {
+		t.Errorf("expected the tags with the audio file only, got %+v", bot.Calls())
+	}
+}
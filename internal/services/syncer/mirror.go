@@ -0,0 +1,52 @@
+package syncer
+
+import (
+	"errors"
+	"fmt"
+)
+
+// SetMirrorChats sends every uploaded file to chatIDs as well, by the file_id of the upload
+// to the directory's chat, so the file is uploaded once. A chat that fails is logged and
+// doesn't keep the file from the others, it gets the file again once it changes.
+func (s *SyncService) SetMirrorChats(chatIDs ...string) {
+	s.mirrorChats = chatIDs
+}
+
+// mirror sends the file of entry to the mirror chats and records their messages in entry.
+func (s *SyncService) mirror(entry *IndexEntry, localPath, caption string) {
+	if len(s.mirrorChats) == 0 {
+		return
+	}
+
+	if entry.FileID == "" {
+		s.logger.Error("can't mirror file without file_id", "file", localPath)
+
+		return
+	}
+
+	var errs []error
+
+	for _, chatID := range s.mirrorChats {
+		if chatID == entry.ChatID {
+			continue
+		}
+
+		msg, err := s.sendByRef(chatID, entry.Kind, entry.FileID, caption, s.fileOptions(chatID, entry.Root, 0))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("chat %s: %w", chatID, err))
+
+			continue
+		}
+
+		if entry.Mirrors == nil {
+			entry.Mirrors = make(map[string]int64, len(s.mirrorChats))
+		}
+
+		entry.Mirrors[chatID] = msg.MessageID
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		s.stats.failed()
+		s.logger.Error("failed to mirror file", "file", localPath, "error", err)
+	}
+}
@@ -0,0 +1,72 @@
+package syncer
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/k0ff1l/tgcloudbot/internal/services/file"
+	"github.com/k0ff1l/tgcloudbot/internal/services/telegram"
+	"github.com/k0ff1l/tgcloudbot/internal/services/telegram/telegramtest"
+)
+
+// chatDownBot fails every send by file_id to one chat.
+type chatDownBot struct {
+	*telegramtest.FakeClient
+
+	chatID string
+}
+
+func (b chatDownBot) SendDocumentByRef(
+	ctx context.Context, chatID, ref, caption string, opts ...telegram.SendOption,
+) (*telegram.Message, error) {
+	if chatID == b.chatID {
+		return nil, errors.New("chat not found")
+	}
+
+	return b.FakeClient.SendDocumentByRef(ctx, chatID, ref, caption, opts...)
+}
+
+func TestMirrorChats(t *testing.T) {
+	dir := t.TempDir()
+	path := writeFile(t, dir, "a.txt", []byte("report"))
+
+	watcher := file.NewWatcher()
+	if err := watcher.AddDir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	fake := telegramtest.NewFakeClient()
+	fake.RespondWith("SendDocument", telegram.Message{MessageID: 1, Document: &telegram.Document{FileID: "doc"}})
+
+	s := NewSyncService(chatDownBot{FakeClient: fake, chatID: "down"}, watcher, WithChatID("chat"))
+	s.SetMirrorChats("chat", "down", "backup")
+
+	s.syncDirectoryOnce(dir)
+
+	if uploads := fake.Uploaded(); len(uploads) != 1 {
+		t.Errorf("expected a single upload, got %v", uploads)
+	}
+
+	refs := fake.CallsTo("SendDocumentByRef")
+	if len(refs) != 1 || refs[0].ChatID != "backup" || refs[0].FileID != "doc" {
+		t.Errorf("expected the file to be sent to the backup chat by file_id, got %+v", refs)
+	}
+
+	entry, ok := s.indexEntry(path)
+	if !ok || entry.ChatID != "chat" || len(entry.Mirrors) != 1 {
+		t.Fatalf("unexpected index entry %+v", entry)
+	}
+
+	if _, ok := entry.Mirrors["backup"]; !ok {
+		t.Errorf("expected the backup message in the index, got %v", entry.Mirrors)
+	}
+
+	if err := s.DeleteFileMessage(path); err != nil {
+		t.Fatal(err)
+	}
+
+	if deletes := fake.CallsTo("DeleteMessage"); len(deletes) != 2 {
+		t.Errorf("expected the message and its mirror to be deleted, got %+v", deletes)
+	}
+}
@@ -0,0 +1,111 @@
+package syncer
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// missingDir is a watched directory that disappeared, e.g. an unmounted network share.
+type missingDir struct {
+	since time.Time
+	// removed is set once the directory was removed after the grace period of SetMissingDirs
+	removed bool
+}
+
+// SetMissingDirs sets what happens to a watched directory that disappears while the service runs.
+// It is logged and alerted once (see SetErrorAlerts), and synced again when it is back. A grace period
+// above 0 removes it from the watcher and the status once it has been missing that long, its loop ends then
+// unless reAdd keeps checking for it and adds it again when it reappears. The recorded files are kept,
+// only the files changed meanwhile are uploaded.
+func (s *SyncService) SetMissingDirs(grace time.Duration, reAdd bool) {
+	s.missingDirGrace, s.reAddMissingDirs = grace, reAdd
+}
+
+// restoreRemovedDir adds dirPath to the watcher again if it was removed for missing and is back.
+// It reports whether the directory is watched and to be synced.
+func (s *SyncService) restoreRemovedDir(dirPath string) bool {
+	dirPath = filepath.Clean(dirPath)
+
+	s.mu.Lock()
+	missing := s.missingDirs[dirPath]
+	s.mu.Unlock()
+
+	if missing == nil || !missing.removed {
+		return true
+	}
+
+	// fails while it is still gone
+	if !s.reAddMissingDirs || s.watcher.AddDir(dirPath) != nil {
+		return false
+	}
+
+	s.mu.Lock()
+	s.syncDirs = append(s.syncDirs, dirPath)
+	missing.removed = false
+	s.mu.Unlock()
+
+	return true
+}
+
+// dirGone records that dirPath is missing. The first time it is logged and alerted,
+// once it has been missing for the grace period it is removed.
+func (s *SyncService) dirGone(dirPath string) {
+	dirPath = filepath.Clean(dirPath)
+	now := s.now()
+
+	s.mu.Lock()
+
+	missing, known := s.missingDirs[dirPath]
+	if !known {
+		missing = &missingDir{since: now}
+		s.missingDirs[dirPath] = missing
+	}
+
+	remove := known && !missing.removed && s.missingDirGrace > 0 && now.Sub(missing.since) >= s.missingDirGrace
+	if remove {
+		missing.removed = true
+		s.syncDirs = slices.DeleteFunc(s.syncDirs, func(dir string) bool { return dir == dirPath })
+	}
+
+	s.mu.Unlock()
+
+	switch {
+	case !known:
+		s.stats.failed()
+		s.logger.Error("watched directory is gone, it is synced again once it is back", "dir", dirPath)
+		s.sendAlert(dirPath, fmt.Sprintf("Watched directory %s is gone, it is synced again once it is back.", dirPath))
+	case remove:
+		s.watcher.RemoveDir(dirPath)
+		s.logger.Warn("removed the missing watched directory",
+			"dir", dirPath, "missing", now.Sub(missing.since), "readd", s.reAddMissingDirs)
+	}
+}
+
+// dirBack clears the missing state of dirPath after it was scanned again.
+func (s *SyncService) dirBack(dirPath string) {
+	dirPath = filepath.Clean(dirPath)
+
+	s.mu.Lock()
+	missing, ok := s.missingDirs[dirPath]
+	delete(s.missingDirs, dirPath)
+	s.mu.Unlock()
+
+	if !ok {
+		return
+	}
+
+	s.logger.Info("watched directory is back", "dir", dirPath, "missing", s.now().Sub(missing.since))
+	s.sendAlert(dirPath, fmt.Sprintf("Watched directory %s is back.", dirPath))
+}
+
+// dirDropped reports whether dirPath was removed for good, its loop ends then.
+func (s *SyncService) dirDropped(dirPath string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	missing := s.missingDirs[filepath.Clean(dirPath)]
+
+	return missing != nil && missing.removed && !s.reAddMissingDirs
+}
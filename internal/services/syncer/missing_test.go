@@ -0,0 +1,174 @@
+package syncer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/k0ff1l/tgcloudbot/internal/services/file"
+	"github.com/k0ff1l/tgcloudbot/internal/services/telegram/telegramtest"
+)
+
+// newMissingDirService returns a service syncing dir without a loop, its clock is at *now.
+func newMissingDirService(t *testing.T, dir string, now *time.Time) (*SyncService, *telegramtest.FakeClient) {
+	t.Helper()
+
+	watcher := file.NewWatcher()
+	if err := watcher.AddDir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	bot := telegramtest.NewFakeClient()
+	s := NewSyncService(bot, watcher, WithChatID("chat"))
+	s.SetErrorAlerts("alerts", 0)
+	s.syncDirs = append(s.syncDirs, dir)
+	s.now = func() time.Time { return *now }
+
+	return s, bot
+}
+
+// alertTexts returns the texts of the alerts sent so far.
+func alertTexts(bot *telegramtest.FakeClient) []string {
+	var texts []string
+	for _, call := range bot.CallsTo("SendMessage") {
+		texts = append(texts, call.Text)
+	}
+
+	return texts
+}
+
+func TestMissingDirIsReportedOnce(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "a.txt", []byte("a"))
+
+	now := time.Now()
+	s, bot := newMissingDirService(t, dir, &now)
+	s.syncDirectoryOnce(dir)
+
+	// unmounted
+	if err := os.Rename(dir, dir+".gone"); err != nil {
+		t.Fatal(err)
+	}
+
+	for range 3 {
+		s.syncDirectoryOnce(dir)
+		now = now.Add(time.Hour)
+	}
+
+	if texts := alertTexts(bot); len(texts) != 1 || !strings.Contains(texts[0], dir+" is gone") {
+		t.Fatalf("expected one alert of the missing directory, got %q", texts)
+	}
+
+	// mounted again, with a new file
+	if err := os.Rename(dir+".gone", dir); err != nil {
+		t.Fatal(err)
+	}
+
+	writeFile(t, dir, "b.txt", []byte("b"))
+	s.syncDirectoryOnce(dir)
+
+	if texts := alertTexts(bot); len(texts) != 2 || !strings.Contains(texts[1], dir+" is back") {
+		t.Errorf("expected an alert of the directory being back, got %q", texts)
+	}
+
+	uploaded := bot.Uploaded()
+	if len(uploaded) != 2 || filepath.Base(uploaded[1]) != "b.txt" {
+		t.Errorf("expected only the new file to be uploaded once the directory is back, got %v", uploaded)
+	}
+
+	if len(s.Status().Directories) != 1 {
+		t.Error("a missing directory is kept without a grace period")
+	}
+}
+
+func TestMissingDirIsRemovedAndAddedAgain(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "a.txt", []byte("a"))
+
+	now := time.Now()
+	s, bot := newMissingDirService(t, dir, &now)
+	s.SetMissingDirs(time.Minute, true)
+	s.syncDirectoryOnce(dir)
+
+	if err := os.Rename(dir, dir+".gone"); err != nil {
+		t.Fatal(err)
+	}
+
+	s.syncDirectoryOnce(dir)
+	now = now.Add(2 * time.Minute)
+	s.syncDirectoryOnce(dir)
+
+	if dirs := s.Status().Directories; len(dirs) != 0 {
+		t.Errorf("expected the directory to be removed after the grace period, got %+v", dirs)
+	}
+
+	if _, err := s.watcher.GetUpdatedFilesIn(dir); err == nil {
+		t.Error("expected the directory to be removed from the watcher")
+	}
+
+	if s.dirDropped(dir) {
+		t.Error("the loop must keep checking for a directory that is added again")
+	}
+
+	// still gone, nothing happens
+	s.syncDirectoryOnce(dir)
+
+	if err := os.Rename(dir+".gone", dir); err != nil {
+		t.Fatal(err)
+	}
+
+	writeFile(t, dir, "b.txt", []byte("b"))
+	s.syncDirectoryOnce(dir)
+
+	if dirs := s.Status().Directories; len(dirs) != 1 || dirs[0].Path != dir {
+		t.Errorf("expected the directory to be watched again, got %+v", dirs)
+	}
+
+	if texts := alertTexts(bot); len(texts) != 2 || !strings.Contains(texts[1], "is back") {
+		t.Errorf("expected the alerts of the directory being gone and back, got %q", texts)
+	}
+
+	uploaded := bot.Uploaded()
+	if len(uploaded) != 2 || filepath.Base(uploaded[1]) != "b.txt" {
+		t.Errorf("expected only the new file to be uploaded, got %v", uploaded)
+	}
+}
+
+func TestMissingDirIsDropped(t *testing.T) {
+	dir := t.TempDir()
+
+	now := time.Now()
+	s, bot := newMissingDirService(t, dir, &now)
+	s.SetMissingDirs(time.Minute, false)
+
+	if err := os.Remove(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	s.syncDirectoryOnce(dir)
+
+	if s.dirDropped(dir) {
+		t.Fatal("the directory must not be dropped before the grace period")
+	}
+
+	now = now.Add(time.Minute)
+	s.syncDirectoryOnce(dir)
+
+	if !s.dirDropped(dir) {
+		t.Fatal("expected the directory to be dropped after the grace period")
+	}
+
+	// not added again when it reappears
+	if err := os.Mkdir(dir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	writeFile(t, dir, "a.txt", []byte("a"))
+	s.syncDirectoryOnce(dir)
+
+	if uploaded := bot.Uploaded(); len(uploaded) != 0 {
+		t.Errorf("a dropped directory must not be synced, got %v", uploaded)
+	}
+}
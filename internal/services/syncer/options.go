@@ -0,0 +1,66 @@
+package syncer
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/k0ff1l/tgcloudbot/internal/services/state"
+)
+
+// Option configures a SyncService in NewSyncService.
+type Option func(s *SyncService)
+
+// WithChatID sends the files to chatID, unless their directory has a chat of its own, see SetDirChatID.
+func WithChatID(chatID string) Option {
+	return func(s *SyncService) {
+		s.chatID = chatID
+	}
+}
+
+// WithLogger replaces slog.Default, a nil logger keeps it.
+func WithLogger(logger *slog.Logger) Option {
+	return func(s *SyncService) {
+		if logger != nil {
+			s.logger = logger
+		}
+	}
+}
+
+// WithContext stops the sync loops once ctx is done, as Stop does. Stop still has to be called to wait for them.
+func WithContext(ctx context.Context) Option {
+	return func(s *SyncService) {
+		s.ctx = ctx
+	}
+}
+
+// WithConcurrency sets how many files of a directory are uploaded at once, 4 by default.
+// Values below 1 keep the default.
+func WithConcurrency(n int) Option {
+	return func(s *SyncService) {
+		if n > 0 {
+			s.concurrency = n
+		}
+	}
+}
+
+// WithStateStore keeps the index of the uploaded files in store instead of memory, see SetIndexStore.
+func WithStateStore(store state.Store[IndexEntry]) Option {
+	return func(s *SyncService) {
+		s.index = store
+	}
+}
+
+// WithClassifier picks the send method of the files with classifier instead of ContentClassifier,
+// see SetClassifier.
+func WithClassifier(classifier Classifier) Option {
+	return func(s *SyncService) {
+		s.classifier = classifier
+	}
+}
+
+// WithDryRun logs what would be uploaded instead of calling the bot, see SetDryRun.
+func WithDryRun(keepState bool) Option {
+	return func(s *SyncService) {
+		s.dryRun, s.dryRunKeepState = true, keepState
+	}
+}
@@ -0,0 +1,112 @@
+package syncer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/k0ff1l/tgcloudbot/internal/services/file"
+	"github.com/k0ff1l/tgcloudbot/internal/services/telegram/telegramtest"
+)
+
+func TestDefaultOptions(t *testing.T) {
+	s := NewSyncService(telegramtest.NewFakeClient(), file.NewWatcher())
+
+	if s.chatID != "" || s.logger != slog.Default() || s.concurrency != defaultConcurrency || s.dryRun {
+		t.Errorf("unexpected defaults: chat %q, concurrency %d, dry run %v", s.chatID, s.concurrency, s.dryRun)
+	}
+
+	if _, ok := s.classifier.(ContentClassifier); !ok {
+		t.Errorf("expected the content classifier, got %T", s.classifier)
+	}
+
+	// ignored values keep the defaults
+	s = NewSyncService(nil, nil, WithConcurrency(0), WithLogger(nil))
+	if s.concurrency != defaultConcurrency || s.logger == nil {
+		t.Errorf("expected the defaults, got concurrency %d, logger %v", s.concurrency, s.logger)
+	}
+}
+
+func TestOptions(t *testing.T) {
+	dir := t.TempDir()
+	path := writeFile(t, dir, "a.txt", []byte("a"))
+
+	var logs bytes.Buffer
+
+	index := newMemoryIndex()
+	bot := telegramtest.NewFakeClient()
+	s := NewSyncService(bot, file.NewWatcher(),
+		WithChatID("other"),
+		WithLogger(slog.New(slog.NewTextHandler(&logs, nil))),
+		WithConcurrency(2),
+		WithStateStore(index),
+		WithClassifier(ExtensionClassifier{}),
+	)
+
+	if err := s.SyncFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	if calls := bot.CallsTo("SendDocument"); len(calls) != 1 || calls[0].ChatID != "other" {
+		t.Errorf("expected the upload to the chat of WithChatID, got %+v", calls)
+	}
+
+	if entry, ok, _ := index.Get(path); !ok || entry.ChatID != "other" {
+		t.Errorf("expected the upload in the given store, got %+v, %v", entry, ok)
+	}
+
+	if s.concurrency != 2 {
+		t.Errorf("expected a concurrency of 2, got %d", s.concurrency)
+	}
+
+	// a dry run logs to the given logger and sends nothing
+	dry := NewSyncService(bot, file.NewWatcher(), WithChatID("other"), WithDryRun(false),
+		WithLogger(slog.New(slog.NewTextHandler(&logs, nil))))
+
+	if err := dry.SyncFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	if calls := bot.CallsTo("SendDocument"); len(calls) != 1 {
+		t.Errorf("a dry run must not upload, got %+v", calls)
+	}
+
+	if !strings.Contains(logs.String(), "dry run: would sync file") {
+		t.Errorf("expected the dry run in the log, got %q", logs.String())
+	}
+}
+
+func TestWithContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	s := NewSyncService(telegramtest.NewFakeClient(), file.NewWatcher(), WithChatID("chat"), WithContext(ctx))
+	if err := s.StartContinuousSync(t.TempDir(), time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(s.Stop)
+	cancel()
+
+	// the loop ends with the parent context, without Stop
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		s.wg.Wait()
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("the sync loop didn't end with its context")
+	}
+
+	if err := s.StartContinuousSync(t.TempDir(), time.Hour); !errors.Is(err, ErrServiceStopped) {
+		t.Errorf("expected ErrServiceStopped after the context is done, got %v", err)
+	}
+}
@@ -0,0 +1,85 @@
+package syncer
+
+import (
+	"cmp"
+	"fmt"
+	"os"
+	"slices"
+)
+
+// SyncOrder is the order the files of a sync batch are uploaded in.
+type SyncOrder int
+
+const (
+	// OrderPath uploads in path order, the default.
+	OrderPath SyncOrder = iota
+	// OrderNewestFirst uploads the most recently modified files first.
+	OrderNewestFirst
+	// OrderSmallestFirst uploads small files first, so that a large one doesn't hold up the rest.
+	OrderSmallestFirst
+)
+
+// ParseSyncOrder parses "path", "newest" or "smallest", empty is OrderPath.
+func ParseSyncOrder(s string) (SyncOrder, error) {
+	switch s {
+	case "", "path":
+		return OrderPath, nil
+	case "newest":
+		return OrderNewestFirst, nil
+	case "smallest":
+		return OrderSmallestFirst, nil
+	default:
+		return OrderPath, fmt.Errorf("unknown sync order %q, expected path, newest or smallest", s)
+	}
+}
+
+func (o SyncOrder) String() string {
+	switch o {
+	case OrderNewestFirst:
+		return "newest"
+	case OrderSmallestFirst:
+		return "smallest"
+	default:
+		return "path"
+	}
+}
+
+// sortBatch sorts files in place, files that can't be stat'ed go last (their upload reports the error).
+func sortBatch(files []string, order SyncOrder) {
+	if order == OrderPath {
+		slices.Sort(files)
+
+		return
+	}
+
+	infos := make(map[string]os.FileInfo, len(files))
+
+	for _, path := range files {
+		if info, err := os.Stat(path); err == nil {
+			infos[path] = info
+		}
+	}
+
+	slices.SortStableFunc(files, func(a, b string) int {
+		infoA, okA := infos[a]
+		infoB, okB := infos[b]
+
+		switch {
+		case !okA || !okB:
+			// true sorts after false
+			return cmp.Compare(boolToInt(!okA), boolToInt(!okB))
+		case order == OrderNewestFirst:
+			return infoB.ModTime().Compare(infoA.ModTime())
+		default:
+			return cmp.Compare(infoA.Size(), infoB.Size())
+		}
+	})
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,65 @@
+package syncer
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/k0ff1l/tgcloudbot/internal/services/file"
+	"github.com/k0ff1l/tgcloudbot/internal/services/telegram/telegramtest"
+)
+
+func TestSyncOrder(t *testing.T) {
+	dir := t.TempDir()
+
+	now := time.Now()
+
+	for i, f := range []struct {
+		name string
+		size int
+	}{{"a-archive.zip", 300}, {"b-note.txt", 1}, {"c-photo.jpg", 20}} {
+		path := writeFile(t, dir, f.name, []byte(strings.Repeat("x", f.size)))
+
+		// the later in the list the newer
+		modTime := now.Add(time.Duration(i-3) * time.Hour)
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := map[SyncOrder][]string{
+		OrderPath:          {"a-archive.zip", "b-note.txt", "c-photo.jpg"},
+		OrderNewestFirst:   {"c-photo.jpg", "b-note.txt", "a-archive.zip"},
+		OrderSmallestFirst: {"b-note.txt", "c-photo.jpg", "a-archive.zip"},
+	}
+
+	for order, want := range tests {
+		watcher := file.NewWatcher()
+		if err := watcher.AddDir(dir); err != nil {
+			t.Fatal(err)
+		}
+
+		bot := telegramtest.NewFakeClient()
+		// a single worker uploads in dispatch order
+		s := NewSyncService(bot, watcher, WithChatID("chat"), WithConcurrency(1))
+		s.SetSyncOrder(order)
+
+		s.syncDirectoryOnce(dir)
+
+		var got []string
+		for _, path := range bot.Uploaded() {
+			got = append(got, filepath.Base(path))
+		}
+
+		if !slices.Equal(got, want) {
+			t.Errorf("%s: uploaded %v, want %v", order, got, want)
+		}
+	}
+
+	if _, err := ParseSyncOrder("largest"); err == nil {
+		t.Error("expected an error for an unknown order")
+	}
+}
@@ -0,0 +1,30 @@
+package syncer
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+// NotifyOversized tells the chat of the watched directory of filePath that the file is not uploaded
+// because its size is over limit, see file.IWatcher.OnOversized.
+func (s *SyncService) NotifyOversized(filePath string, size, limit int64) {
+	root := s.syncDirOf(filePath)
+	if root == "" {
+		root = filepath.Dir(filePath)
+	}
+
+	chatID := s.chatIDFor(root)
+	text := fmt.Sprintf("Skipped %s: %s is over the upload limit of %s",
+		relativePath(root, filePath), formatSize(size), formatSize(limit))
+
+	if s.dryRun {
+		s.logger.Info("dry run: would send oversized file notice", "file", filePath, "chat", chatID, "text", text)
+
+		return
+	}
+
+	opts := append(s.sendOptions(0), s.topicOptions(chatID, root)...)
+	if _, err := s.bot.SendMessage(s.ctx, chatID, text, opts...); err != nil {
+		s.logger.Error("failed to send oversized file notice", "file", filePath, "error", err)
+	}
+}
@@ -0,0 +1,68 @@
+package syncer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/k0ff1l/tgcloudbot/internal/services/file"
+	"github.com/k0ff1l/tgcloudbot/internal/services/telegram/telegramtest"
+)
+
+func TestOversizedFileNotice(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	writeFile(t, dir, "sub/big.bin", make([]byte, 3<<10))
+	writeFile(t, dir, "small.txt", []byte("a"))
+
+	watcher := file.NewWatcher()
+	watcher.MaxFileSize = 1 << 10
+
+	bot := telegramtest.NewFakeClient()
+	s := NewSyncService(bot, watcher, WithChatID("chat"))
+	s.SetDirChatID(dir, "dir-chat")
+	watcher.OnOversized = s.NotifyOversized
+
+	if err := s.StartContinuousSync(dir, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(s.Stop)
+
+	// the file stays too large, the chat hears about it once
+	for range 3 {
+		if err := s.SyncNow(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	notices := bot.CallsTo("SendMessage")
+	if len(notices) != 1 {
+		t.Fatalf("expected one notice, got %+v", notices)
+	}
+
+	want := "Skipped sub/big.bin: 3.0 KiB is over the upload limit of 1.0 KiB"
+	if notices[0].ChatID != "dir-chat" || notices[0].Text != want {
+		t.Errorf("got notice %q to %s, want %q to dir-chat", notices[0].Text, notices[0].ChatID, want)
+	}
+
+	if uploaded := bot.Uploaded(); len(uploaded) != 1 || filepath.Base(uploaded[0]) != "small.txt" {
+		t.Errorf("expected only the small file to be uploaded, got %v", uploaded)
+	}
+}
+
+func TestOversizedFileNoticeDryRun(t *testing.T) {
+	bot := telegramtest.NewFakeClient()
+	s := NewSyncService(bot, file.NewWatcher(), WithChatID("chat"))
+	s.SetDryRun(true, false)
+
+	s.NotifyOversized("/data/big.bin", 100<<20, 50<<20)
+
+	if len(bot.Calls()) != 0 {
+		t.Errorf("a dry run must not send the notice, got %+v", bot.Calls())
+	}
+}
@@ -0,0 +1,22 @@
+package syncer
+
+// Pause stops uploading until Resume, the sync loops keep running but skip their directories.
+// Changed files are not recorded as synced and are uploaded by the first sync after Resume,
+// uploads already in progress finish. It is safe to call concurrently and Stop works while paused.
+func (s *SyncService) Pause() {
+	if s.paused.CompareAndSwap(false, true) {
+		s.logger.Info("sync paused")
+	}
+}
+
+// Resume undoes Pause, the files changed in between are uploaded by the next tick or SyncNow.
+func (s *SyncService) Resume() {
+	if s.paused.CompareAndSwap(true, false) {
+		s.logger.Info("sync resumed")
+	}
+}
+
+// Paused reports whether the sync is paused.
+func (s *SyncService) Paused() bool {
+	return s.paused.Load()
+}
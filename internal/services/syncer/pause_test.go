@@ -0,0 +1,94 @@
+package syncer
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/k0ff1l/tgcloudbot/internal/services/file"
+	"github.com/k0ff1l/tgcloudbot/internal/services/telegram/telegramtest"
+)
+
+func TestPauseAndResume(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "a.txt", []byte("a"))
+
+	bot := telegramtest.NewFakeClient()
+	watcher := file.NewWatcher()
+	s := NewSyncService(bot, watcher, WithChatID("chat"))
+
+	if err := watcher.AddDir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	s.syncDirectoryOnce(dir)
+
+	s.Pause()
+
+	if !s.Paused() {
+		t.Fatal("expected the service to be paused")
+	}
+
+	modified := writeFile(t, dir, "a.txt", []byte("changed"))
+	added := writeFile(t, dir, "b.txt", []byte("b"))
+
+	s.syncDirectoryOnce(dir)
+
+	if n := len(bot.Uploaded()); n != 1 {
+		t.Fatalf("expected no uploads while paused, got %d in total", n)
+	}
+
+	s.Resume()
+	s.syncDirectoryOnce(dir)
+
+	uploaded := bot.Uploaded()
+	if len(uploaded) != 3 || !sameFiles(uploaded[1:], modified, added) {
+		t.Errorf("expected the files changed while paused after resuming, got %v", uploaded)
+	}
+}
+
+func TestPauseConcurrentlyAndStop(t *testing.T) {
+	s := NewSyncService(telegramtest.NewFakeClient(), file.NewWatcher(), WithChatID("chat"))
+
+	if err := s.StartContinuousSync(t.TempDir(), time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+
+	for i := range 8 {
+		wg.Go(func() {
+			if i%2 == 0 {
+				s.Pause()
+			} else {
+				s.Resume()
+			}
+		})
+	}
+
+	wg.Wait()
+	s.Pause()
+
+	// the loop keeps ticking while paused, it must still stop
+	s.Stop()
+}
+
+// sameFiles reports whether got holds exactly the paths of want in any order.
+func sameFiles(got []string, want ...string) bool {
+	if len(got) != len(want) {
+		return false
+	}
+
+	seen := make(map[string]bool, len(got))
+	for _, path := range got {
+		seen[path] = true
+	}
+
+	for _, path := range want {
+		if !seen[path] {
+			return false
+		}
+	}
+
+	return true
+}
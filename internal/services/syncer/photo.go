@@ -0,0 +1,49 @@
+package syncer
+
+import (
+	"image"
+	_ "image/gif"  // registered for image.DecodeConfig
+	_ "image/jpeg" // registered for image.DecodeConfig
+	_ "image/png"  // registered for image.DecodeConfig
+	"os"
+)
+
+// SetPhotoLimits sends images wider or higher than maxSide pixels or larger than maxSize bytes as documents,
+// so that Telegram doesn't downscale and recompress them, smaller ones still go as photos with a preview.
+// 0 disables either limit. Dimensions are read for JPEG, PNG and GIF, other images are checked by size only.
+func (s *SyncService) SetPhotoLimits(maxSide int, maxSize int64) {
+	s.photoMaxSide, s.photoMaxSize = maxSide, maxSize
+}
+
+// photoTooLarge reports whether the image at path exceeds the limits of SetPhotoLimits.
+func (s *SyncService) photoTooLarge(path string) (bool, error) {
+	if s.photoMaxSize > 0 {
+		info, err := os.Stat(path)
+		if err != nil {
+			return false, err
+		}
+
+		if info.Size() > s.photoMaxSize {
+			return true, nil
+		}
+	}
+
+	if s.photoMaxSide <= 0 {
+		return false, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	// only the header is read
+	cfg, _, err := image.DecodeConfig(f)
+	if err != nil {
+		// e.g. webp, nothing to compare
+		return false, nil //nolint:nilerr // unknown dimensions are within the limit
+	}
+
+	return cfg.Width > s.photoMaxSide || cfg.Height > s.photoMaxSide, nil
+}
@@ -0,0 +1,56 @@
+package syncer
+
+import (
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/k0ff1l/tgcloudbot/internal/services/file"
+	"github.com/k0ff1l/tgcloudbot/internal/services/telegram/telegramtest"
+)
+
+func writePNG(t *testing.T, path string, width, height int) {
+	t.Helper()
+
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if err := png.Encode(f, image.NewGray(image.Rect(0, 0, width, height))); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLargePhotosAreSentAsDocuments(t *testing.T) {
+	dir := t.TempDir()
+	small, large := filepath.Join(dir, "small.png"), filepath.Join(dir, "large.png")
+
+	writePNG(t, small, 100, 80)
+	writePNG(t, large, 300, 20)
+
+	bot := telegramtest.NewFakeClient()
+	s := NewSyncService(bot, file.NewWatcher(), WithChatID("chat"))
+	s.SetPhotoLimits(200, 0)
+
+	for _, path := range []string{small, large} {
+		if err := s.SyncFile(path); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	photos, docs := bot.CallsTo("SendPhoto"), bot.CallsTo("SendDocument")
+	if len(photos) != 1 || photos[0].Paths[0] != small || len(docs) != 1 || docs[0].Paths[0] != large {
+		t.Errorf("expected small.png as photo and large.png as document, got %+v", bot.Calls())
+	}
+
+	// by size alone
+	s.SetPhotoLimits(0, 50)
+
+	if kind, err := s.sendKind(dir, small); err != nil || kind != KindDocument {
+		t.Errorf("expected a document over the size limit, got %s, %v", kind, err)
+	}
+}
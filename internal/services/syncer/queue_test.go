@@ -0,0 +1,91 @@
+package syncer
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/k0ff1l/tgcloudbot/internal/services/file"
+	"github.com/k0ff1l/tgcloudbot/internal/services/telegram"
+	"github.com/k0ff1l/tgcloudbot/internal/services/telegram/telegramtest"
+)
+
+// slowBot holds every upload until release is closed and records the most concurrent ones.
+type slowBot struct {
+	*telegramtest.FakeClient
+
+	release  chan struct{}
+	inFlight atomic.Int64
+	max      atomic.Int64
+}
+
+func (b *slowBot) SendDocument(
+	ctx context.Context, chatID, filePath, caption string, opts ...telegram.SendOption,
+) (*telegram.Message, error) {
+	n := b.inFlight.Add(1)
+	defer b.inFlight.Add(-1)
+
+	for {
+		m := b.max.Load()
+		if n <= m || b.max.CompareAndSwap(m, n) {
+			break
+		}
+	}
+
+	<-b.release
+
+	return b.FakeClient.SendDocument(ctx, chatID, filePath, caption, opts...)
+}
+
+func TestUploadQueueIsBounded(t *testing.T) {
+	const queueSize = 4
+
+	bot := &slowBot{FakeClient: telegramtest.NewFakeClient(), release: make(chan struct{})}
+	watcher := file.NewWatcher()
+	s := NewSyncService(bot, watcher, WithChatID("chat"))
+	s.SetUploadQueueSize(queueSize)
+
+	var dirs []string
+
+	for range 3 {
+		dir := t.TempDir()
+		for i := range 10 {
+			writeFile(t, dir, fmt.Sprintf("%d.txt", i), []byte("a"))
+		}
+
+		if err := watcher.AddDir(dir); err != nil {
+			t.Fatal(err)
+		}
+
+		dirs = append(dirs, dir)
+	}
+
+	var wg sync.WaitGroup
+
+	for _, dir := range dirs {
+		wg.Go(func() { s.syncDirectoryOnce(dir) })
+	}
+
+	// let the syncs fill the queue before the uploads go through
+	for bot.inFlight.Load() < queueSize {
+		if n := s.QueueLength(); n > queueSize {
+			t.Fatalf("queue length %d over its capacity %d", n, queueSize)
+		}
+
+		runtime.Gosched()
+	}
+
+	close(bot.release)
+	wg.Wait()
+
+	if n := bot.max.Load(); n > queueSize {
+		t.Errorf("%d uploads ran at once, the queue allows %d", n, queueSize)
+	}
+
+	if uploaded := len(bot.Uploaded()); uploaded != 30 || s.QueueLength() != 0 {
+		t.Errorf("expected the queue to drain after 30 uploads, got %d uploads and %d queued", uploaded, s.QueueLength())
+	}
+}
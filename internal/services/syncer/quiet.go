@@ -0,0 +1,56 @@
+package syncer
+
+import (
+	"fmt"
+	"time"
+)
+
+// QuietHours is a daily period of the local time, from Start until End after midnight.
+// End before Start spans midnight, e.g. 22:00-07:00. The zero value is never quiet.
+type QuietHours struct {
+	Start time.Duration
+	End   time.Duration
+}
+
+// ParseQuietHours parses the "15:04" start and end of the period.
+func ParseQuietHours(start, end string) (QuietHours, error) {
+	var (
+		q   QuietHours
+		err error
+	)
+
+	if q.Start, err = parseClock(start); err != nil {
+		return QuietHours{}, err
+	}
+
+	if q.End, err = parseClock(end); err != nil {
+		return QuietHours{}, err
+	}
+
+	return q, nil
+}
+
+// Contains reports whether t falls into the period.
+func (q QuietHours) Contains(t time.Time) bool {
+	if q.Start == q.End {
+		return false
+	}
+
+	h, m, sec := t.Clock()
+	clock := time.Duration(h)*time.Hour + time.Duration(m)*time.Minute + time.Duration(sec)*time.Second
+
+	if q.Start < q.End {
+		return clock >= q.Start && clock < q.End
+	}
+
+	return clock >= q.Start || clock < q.End
+}
+
+func parseClock(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q, expected HH:MM", s)
+	}
+
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
@@ -0,0 +1,55 @@
+package syncer
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQuietHoursContains(t *testing.T) {
+	overnight, err := ParseQuietHours("22:00", "07:00")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	daytime, err := ParseQuietHours("12:00", "13:30")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	at := func(clock string) time.Time {
+		t.Helper()
+
+		tm, err := time.Parse("15:04", clock)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return tm
+	}
+
+	tests := []struct {
+		quiet QuietHours
+		clock string
+		want  bool
+	}{
+		{overnight, "23:30", true},
+		{overnight, "00:00", true},
+		{overnight, "06:59", true},
+		{overnight, "07:00", false},
+		{overnight, "21:59", false},
+		{daytime, "12:00", true},
+		{daytime, "13:30", false},
+		{daytime, "11:00", false},
+		{QuietHours{}, "12:00", false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.quiet.Contains(at(tt.clock)); got != tt.want {
+			t.Errorf("%+v contains %s = %t, want %t", tt.quiet, tt.clock, got, tt.want)
+		}
+	}
+
+	if _, err := ParseQuietHours("22", "07:00"); err == nil {
+		t.Error("expected an error for an invalid time")
+	}
+}
@@ -0,0 +1,160 @@
+package syncer
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/k0ff1l/tgcloudbot/internal/services/state"
+)
+
+// DefaultQuotaWindow is the window of SetQuota when none is given.
+const DefaultQuotaWindow = 24 * time.Hour
+
+// usageKey is the key of the usage in its store.
+const usageKey = "usage"
+
+// ErrQuotaExceeded is returned for an upload that would exceed the quota of the current window, see SetQuota.
+var ErrQuotaExceeded = errors.New("upload quota exceeded")
+
+// Usage are the bytes uploaded, counted by the size of the local files.
+type Usage struct {
+	// Total counts every upload since the usage was first recorded
+	Total int64 `json:"total"`
+	// WindowBytes counts the uploads of the quota window ending at ResetAt, zero without a quota
+	WindowBytes int64     `json:"window_bytes"`
+	ResetAt     time.Time `json:"reset_at,omitzero"`
+}
+
+// newMemoryUsage returns a usage store kept in memory only.
+func newMemoryUsage() state.Store[Usage] {
+	store, _ := state.NewFileStore[Usage]("") // can't fail without a file
+
+	return store
+}
+
+// SetQuota allows uploads of at most quota bytes per window, DefaultQuotaWindow if not positive.
+// A window starts with the first upload after the last one ended. Files over the quota fail with
+// ErrQuotaExceeded, a warning is sent once per window and they are synced again once it resets.
+// 0, the default, disables it.
+func (s *SyncService) SetQuota(quota int64, window time.Duration) {
+	if window <= 0 {
+		window = DefaultQuotaWindow
+	}
+
+	s.quota, s.quotaWindow = quota, window
+}
+
+// SetUsageFile keeps the usage in path, so that it and the quota window carry over restarts.
+func (s *SyncService) SetUsageFile(path string) error {
+	store, err := state.NewFileStore[Usage](path)
+	if err != nil {
+		return err
+	}
+
+	s.usage = store
+
+	return nil
+}
+
+// Usage returns the bytes uploaded so far and in the current quota window.
+func (s *SyncService) Usage() (Usage, error) {
+	s.usageMu.Lock()
+	defer s.usageMu.Unlock()
+
+	return s.currentUsage()
+}
+
+// currentUsage reads the usage and starts a new window if the last one ended, usageMu must be held.
+func (s *SyncService) currentUsage() (Usage, error) {
+	usage, _, err := s.usage.Get(usageKey)
+	if err != nil {
+		return Usage{}, fmt.Errorf("read usage: %w", err)
+	}
+
+	if s.quota > 0 && !s.now().Before(usage.ResetAt) {
+		usage.WindowBytes, usage.ResetAt = 0, s.now().Add(s.quotaWindow)
+	}
+
+	return usage, nil
+}
+
+// reserveUpload counts size bytes as uploaded before the upload starts, so that concurrent uploads can't
+// exceed the quota together, and returns the end of the quota window they count in.
+// An upload that fails gives them back with refundUpload.
+func (s *SyncService) reserveUpload(size int64) (time.Time, error) {
+	s.usageMu.Lock()
+	defer s.usageMu.Unlock()
+
+	usage, err := s.currentUsage()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if s.quota > 0 && usage.WindowBytes+size > s.quota {
+		return time.Time{}, fmt.Errorf("%w: %s of %s used until %s, %s more needed", ErrQuotaExceeded,
+			formatSize(usage.WindowBytes), formatSize(s.quota), usage.ResetAt.Format(time.DateTime), formatSize(size))
+	}
+
+	usage.Total += size
+
+	if s.quota > 0 {
+		usage.WindowBytes += size
+	}
+
+	if err := s.usage.Put(usageKey, usage); err != nil {
+		return time.Time{}, fmt.Errorf("save usage: %w", err)
+	}
+
+	return usage.ResetAt, nil
+}
+
+// refundUpload takes back the bytes of a failed upload counted by reserveUpload in the window ending at window.
+func (s *SyncService) refundUpload(size int64, window time.Time) {
+	s.usageMu.Lock()
+	defer s.usageMu.Unlock()
+
+	usage, err := s.currentUsage()
+	if err != nil {
+		s.logger.Error("failed to refund upload", "error", err)
+
+		return
+	}
+
+	usage.Total = max(usage.Total-size, 0)
+
+	// a window that started since the reservation doesn't count them
+	if usage.ResetAt.Equal(window) {
+		usage.WindowBytes = max(usage.WindowBytes-size, 0)
+	}
+
+	if err := s.usage.Put(usageKey, usage); err != nil {
+		s.logger.Error("failed to save usage", "error", err)
+	}
+}
+
+// warnQuota sends the quota error err to the alert chat, the default chat without alerts,
+// once per quota window.
+func (s *SyncService) warnQuota(err error) {
+	s.usageMu.Lock()
+	usage, _, getErr := s.usage.Get(usageKey)
+	warned := getErr == nil && s.quotaWarnedUntil.Equal(usage.ResetAt)
+	s.quotaWarnedUntil = usage.ResetAt
+	s.usageMu.Unlock()
+
+	s.logger.Warn("upload quota reached, files are synced again after the reset", "error", err)
+
+	if warned {
+		return
+	}
+
+	chatID := s.chatID
+	if s.alerts != nil && s.alerts.chatID != "" {
+		chatID = s.alerts.chatID
+	}
+
+	text := fmt.Sprintf("Uploads paused: %v", err)
+	if _, err := s.bot.SendMessage(s.ctx, chatID, text, s.sendOptions(0)...); err != nil {
+		s.logger.Error("failed to send quota warning", "error", err)
+	}
+}
@@ -0,0 +1,169 @@
+package syncer
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/k0ff1l/tgcloudbot/internal/services/file"
+	"github.com/k0ff1l/tgcloudbot/internal/services/telegram"
+	"github.com/k0ff1l/tgcloudbot/internal/services/telegram/telegramtest"
+)
+
+func TestQuota(t *testing.T) {
+	dir := t.TempDir()
+	for i := range 10 {
+		writeFile(t, dir, fmt.Sprintf("%d.txt", i), []byte("1234"))
+	}
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	usageFile := filepath.Join(t.TempDir(), "usage.json")
+
+	bot := telegramtest.NewFakeClient()
+	watcher := file.NewWatcher()
+	s := NewSyncService(bot, watcher, WithChatID("chat"))
+	s.now = func() time.Time { return now }
+	s.SetQuota(10, time.Hour)
+
+	if err := s.SetUsageFile(usageFile); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := watcher.AddDir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	// the workers upload concurrently, only two files fit
+	s.syncDirectoryOnce(dir)
+	s.syncDirectoryOnce(dir)
+
+	if n := len(bot.Uploaded()); n != 2 {
+		t.Fatalf("expected 2 uploads within the quota, got %d", n)
+	}
+
+	if texts := bot.Texts(); len(texts) != 1 || !strings.HasPrefix(texts[0], "Uploads paused: upload quota exceeded") {
+		t.Errorf("expected a single warning, got %q", texts)
+	}
+
+	usage, err := s.Usage()
+	if err != nil || usage.Total != 8 || usage.WindowBytes != 8 || !usage.ResetAt.Equal(now.Add(time.Hour)) {
+		t.Errorf("unexpected usage %+v, %v", usage, err)
+	}
+
+	if err := s.SyncFile(filepath.Join(dir, "9.txt")); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("expected ErrQuotaExceeded, got %v", err)
+	}
+
+	// a restart keeps the usage, the next window takes the next two files
+	restarted := NewSyncService(bot, watcher, WithChatID("chat"))
+	restarted.now = func() time.Time { return now.Add(time.Hour) }
+	restarted.SetQuota(10, time.Hour)
+
+	if err := restarted.SetUsageFile(usageFile); err != nil {
+		t.Fatal(err)
+	}
+
+	restarted.syncDirectoryOnce(dir)
+
+	if n := len(bot.Uploaded()); n != 4 {
+		t.Errorf("expected 2 more uploads after the reset, got %d in total", n)
+	}
+
+	if usage, _ := restarted.Usage(); usage.Total != 16 || usage.WindowBytes != 8 {
+		t.Errorf("unexpected usage after the reset %+v", usage)
+	}
+}
+
+func TestFailedUploadIsRefunded(t *testing.T) {
+	path := writeFile(t, t.TempDir(), "a.txt", []byte("1234"))
+
+	bot := telegramtest.NewFakeClient()
+	bot.FailWith("SendDocument", errors.New("network down"))
+
+	s := NewSyncService(bot, file.NewWatcher(), WithChatID("chat"))
+	s.SetQuota(10, time.Hour)
+
+	if err := s.SyncFile(path); err == nil {
+		t.Fatal("expected the upload to fail")
+	}
+
+	if usage, _ := s.Usage(); usage.Total != 0 || usage.WindowBytes != 0 {
+		t.Errorf("expected the failed upload not to count, got %+v", usage)
+	}
+}
+
+func TestSentUploadIsCounted(t *testing.T) {
+	path := writeFile(t, t.TempDir(), "a.txt", []byte("1234"))
+
+	// sent, but the message reports another size
+	bot := telegramtest.NewFakeClient()
+	bot.RespondWith("SendDocument", telegram.Message{MessageID: 1, Document: &telegram.Document{FileSize: 3}})
+
+	s := NewSyncService(bot, file.NewWatcher(), WithChatID("chat"))
+	s.SetQuota(10, time.Hour)
+
+	if err := s.SyncFile(path); !errors.Is(err, ErrSizeMismatch) {
+		t.Fatalf("expected ErrSizeMismatch, got %v", err)
+	}
+
+	if usage, _ := s.Usage(); usage.Total != 4 || usage.WindowBytes != 4 {
+		t.Errorf("expected the sent upload to count, got %+v", usage)
+	}
+}
+
+func TestChunkedUploadCountsSentChunks(t *testing.T) {
+	path := writeFile(t, t.TempDir(), "big.bin", []byte("0123456789"))
+
+	bot := &storingBot{FakeClient: telegramtest.NewFakeClient(), failAfter: 2}
+	s := NewSyncService(bot, file.NewWatcher(), WithChatID("chat"))
+	s.SetChunkSize(3)
+	s.SetQuota(100, time.Hour)
+
+	if err := s.SyncFile(path); err == nil {
+		t.Fatal("expected the interrupted upload to fail")
+	}
+
+	if usage, _ := s.Usage(); usage.Total != 6 {
+		t.Errorf("expected the 2 sent chunks to count, got %+v", usage)
+	}
+
+	// the resumed upload counts the rest only
+	bot.failAfter = 0
+
+	if err := s.SyncFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	if usage, _ := s.Usage(); usage.Total != 10 || usage.WindowBytes != 10 {
+		t.Errorf("expected the file to count once, got %+v", usage)
+	}
+}
+
+func TestRefundAfterWindowReset(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	s := NewSyncService(telegramtest.NewFakeClient(), file.NewWatcher(), WithChatID("chat"))
+	s.now = func() time.Time { return now }
+	s.SetQuota(10, time.Hour)
+
+	window, err := s.reserveUpload(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the upload fails after its window ended, the next window already counts another one
+	now = now.Add(time.Hour)
+
+	if _, err := s.reserveUpload(3); err != nil {
+		t.Fatal(err)
+	}
+
+	s.refundUpload(4, window)
+
+	if usage, _ := s.Usage(); usage.Total != 3 || usage.WindowBytes != 3 {
+		t.Errorf("expected the refund to leave the new window alone, got %+v", usage)
+	}
+}
@@ -0,0 +1,196 @@
+package syncer
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/k0ff1l/tgcloudbot/internal/services/file"
+	"github.com/k0ff1l/tgcloudbot/internal/services/telegram"
+)
+
+// ReconcileReport is the drift between the watched directories and the index found by Reconcile.
+type ReconcileReport struct {
+	// NotUploaded are local files without an index entry
+	NotUploaded []string
+	// Orphaned are index entries whose local file is gone, their messages are kept
+	Orphaned []string
+	// Missing are index entries whose Telegram file can't be fetched anymore
+	Missing []string
+}
+
+// Empty reports whether no drift was found.
+func (r ReconcileReport) Empty() bool {
+	return len(r.NotUploaded) == 0 && len(r.Orphaned) == 0 && len(r.Missing) == 0
+}
+
+// String returns the report as a message text.
+func (r ReconcileReport) String() string {
+	if r.Empty() {
+		return "Reconcile: the index matches the local files"
+	}
+
+	var b strings.Builder
+
+	b.WriteString("Reconcile:")
+
+	for _, section := range []struct {
+		title string
+		paths []string
+	}{
+		{"not uploaded", r.NotUploaded},
+		{"local file gone", r.Orphaned},
+		{"message gone", r.Missing},
+	} {
+		if len(section.paths) == 0 {
+			continue
+		}
+
+		fmt.Fprintf(&b, "\n\n%s (%d):", section.title, len(section.paths))
+
+		for _, path := range section.paths {
+			b.WriteString("\n" + path)
+		}
+	}
+
+	return b.String()
+}
+
+// Reconcile audits the files under dirs against the index: files never uploaded, entries whose local
+// file is gone and entries whose Telegram file no longer exists, checked with getFile. The dirs must
+// have been added to the watcher, their filters apply. With fix the missing entries are pruned and
+// the files not uploaded, or whose message is gone, are uploaded; orphaned entries are only reported,
+// their messages are still the backup of the deleted files.
+func (s *SyncService) Reconcile(dirs []string, fix bool) (ReconcileReport, error) {
+	var report ReconcileReport
+
+	entries, err := s.index.List()
+	if err != nil {
+		return report, fmt.Errorf("read index: %w", err)
+	}
+
+	local := make(map[string]string) // path -> watched directory
+
+	for _, dir := range dirs {
+		dir = filepath.Clean(dir)
+
+		files, err := s.watcher.ListFiles(dir)
+
+		switch {
+		case errors.Is(err, file.ErrPartialScan):
+			s.logger.Warn("some files couldn't be read", "dir", dir, "error", err)
+		case err != nil:
+			return report, fmt.Errorf("list %s: %w", dir, err)
+		}
+
+		for _, path := range files {
+			local[path] = dir
+
+			if _, ok := entries[path]; !ok {
+				report.NotUploaded = append(report.NotUploaded, path)
+			}
+		}
+	}
+
+	for _, path := range slices.Sorted(maps.Keys(entries)) {
+		if _, err := os.Lstat(path); errors.Is(err, fs.ErrNotExist) {
+			report.Orphaned = append(report.Orphaned, path)
+		}
+
+		gone, err := s.messageGone(entries[path])
+		if err != nil {
+			return report, fmt.Errorf("check %s: %w", path, err)
+		}
+
+		if gone {
+			report.Missing = append(report.Missing, path)
+		}
+	}
+
+	if fix {
+		err = s.fixDrift(report, local)
+	}
+
+	return report, err
+}
+
+// messageGone reports whether the Telegram file of entry, or one of its chunks, can't be fetched.
+// Entries without a file_id can't be checked and count as present, transient errors are returned.
+func (s *SyncService) messageGone(entry IndexEntry) (bool, error) {
+	fileIDs := []string{entry.FileID}
+
+	if len(entry.Chunks) > 0 {
+		fileIDs = fileIDs[:0]
+
+		for _, chunk := range entry.Chunks {
+			fileIDs = append(fileIDs, chunk.FileID)
+		}
+	}
+
+	for _, fileID := range fileIDs {
+		if fileID == "" {
+			continue
+		}
+
+		_, err := s.bot.GetFileInfo(s.ctx, fileID)
+
+		switch {
+		case err == nil:
+		case telegram.IsRetryable(err):
+			return false, err
+		default:
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// fixDrift prunes the missing entries of report and uploads the local files without a message.
+func (s *SyncService) fixDrift(report ReconcileReport, local map[string]string) error {
+	upload := slices.Clone(report.NotUploaded)
+
+	for _, path := range report.Missing {
+		if err := s.index.Delete(path); err != nil {
+			return fmt.Errorf("prune %s: %w", path, err)
+		}
+
+		s.logger.Info("pruned index entry, the message is gone", "file", path)
+
+		if _, ok := local[path]; ok {
+			upload = append(upload, path)
+		}
+	}
+
+	var failed int
+
+	for _, path := range upload {
+		root := local[path]
+
+		lock := s.dirLock(root)
+		lock.Lock()
+		err := s.syncFile(s.chatIDFor(root), root, path, 0, true, false)
+		lock.Unlock()
+
+		if err != nil {
+			failed++
+
+			s.logger.Error("failed to upload", "file", path, "error", err)
+
+			continue
+		}
+
+		s.logger.Info("uploaded", "file", path)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d files not uploaded", failed, len(upload))
+	}
+
+	return nil
+}
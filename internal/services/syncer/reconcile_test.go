@@ -0,0 +1,105 @@
+package syncer
+
+import (
+	"context"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/k0ff1l/tgcloudbot/internal/services/file"
+	"github.com/k0ff1l/tgcloudbot/internal/services/telegram"
+	"github.com/k0ff1l/tgcloudbot/internal/services/telegram/telegramtest"
+)
+
+// expiredBot fails getFile for the file ids of deleted messages.
+type expiredBot struct {
+	*telegramtest.FakeClient
+
+	expired []string
+}
+
+func (b *expiredBot) GetFileInfo(ctx context.Context, fileID string) (*telegram.File, error) {
+	if slices.Contains(b.expired, fileID) {
+		return nil, &telegram.APIError{Method: "getFile", Code: http.StatusBadRequest, Description: "wrong file_id"}
+	}
+
+	return b.FakeClient.GetFileInfo(ctx, fileID)
+}
+
+func TestReconcile(t *testing.T) {
+	root := t.TempDir()
+	synced := writeFile(t, root, "synced.txt", []byte("synced"))
+	fresh := writeFile(t, root, "fresh.txt", []byte("fresh"))
+	lost := writeFile(t, root, "lost.txt", []byte("lost"))
+	deleted := filepath.Join(root, "deleted.txt")
+
+	bot := &expiredBot{FakeClient: telegramtest.NewFakeClient(), expired: []string{"lost"}}
+	watcher := file.NewWatcher()
+
+	if err := watcher.AddDir(root); err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewSyncService(bot, watcher, WithChatID("chat"))
+	_ = s.index.Put(synced, IndexEntry{ChatID: "chat", FileID: "synced", Root: root})
+	_ = s.index.Put(lost, IndexEntry{ChatID: "chat", FileID: "lost", Root: root})
+	_ = s.index.Put(deleted, IndexEntry{ChatID: "chat", FileID: "deleted", Root: root})
+
+	report, err := s.Reconcile([]string{root}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := ReconcileReport{NotUploaded: []string{fresh}, Orphaned: []string{deleted}, Missing: []string{lost}}
+	if !slices.Equal(report.NotUploaded, want.NotUploaded) || !slices.Equal(report.Orphaned, want.Orphaned) ||
+		!slices.Equal(report.Missing, want.Missing) {
+		t.Errorf("report = %+v, want %+v", report, want)
+	}
+
+	if len(bot.CallsTo("SendDocument")) != 0 {
+		t.Error("nothing must be uploaded without fix")
+	}
+
+	if _, err := s.Reconcile([]string{root}, true); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := bot.Uploaded(); !sameFiles(got, fresh, lost) {
+		t.Errorf("fix uploaded %v, want the new and the lost file", got)
+	}
+
+	if _, ok := s.MessageFor(deleted); !ok {
+		t.Error("the entry of a deleted local file must be kept")
+	}
+
+	bot.expired = nil
+
+	report, err = s.Reconcile([]string{root}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(report.NotUploaded) != 0 || len(report.Missing) != 0 || len(report.Orphaned) != 1 {
+		t.Errorf("unexpected report after the fix: %+v", report)
+	}
+}
+
+func TestReconcileTransientError(t *testing.T) {
+	root := t.TempDir()
+	path := writeFile(t, root, "a.txt", []byte("a"))
+
+	bot := telegramtest.NewFakeClient()
+	bot.FailWith("GetFileInfo", &telegram.APIError{Code: http.StatusBadGateway})
+
+	s := NewSyncService(bot, file.NewWatcher(), WithChatID("chat"))
+	_ = s.index.Put(path, IndexEntry{ChatID: "chat", FileID: "a"})
+
+	if _, err := s.Reconcile(nil, true); err == nil {
+		t.Error("a transient error must not report the message as gone")
+	}
+
+	if _, ok := s.MessageFor(path); !ok {
+		t.Error("the entry must not be pruned after a transient error")
+	}
+}
@@ -0,0 +1,116 @@
+package syncer
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/k0ff1l/tgcloudbot/internal/services/telegram"
+)
+
+// SetRenameDetection treats a new file with the content of an indexed file that no longer exists
+// as that file renamed or moved within the same chat: its index entry moves to the new path instead of
+// the file being uploaded again. editCaption also updates the caption of the message to the new path.
+func (s *SyncService) SetRenameDetection(enabled, editCaption bool) {
+	s.detectRenames = enabled
+	s.renameEditCaption = editCaption
+}
+
+// detectRename reports whether the not yet indexed localPath is a renamed indexed file with the given
+// content hash, and if so moves the index entry of that file to entry.
+func (s *SyncService) detectRename(chatID, localPath, hash, caption string, entry IndexEntry) bool {
+	if _, ok := s.indexEntry(localPath); ok {
+		return false
+	}
+
+	entries, err := s.index.List()
+	if err != nil {
+		s.logger.Error("failed to read index", "file", localPath, "error", err)
+
+		return false
+	}
+
+	candidates := renameCandidates(entries, chatID, localPath, hash)
+
+	oldPath, ok := renameSource(localPath, candidates)
+	if !ok {
+		if len(candidates) > 1 {
+			s.logger.Info("ambiguous rename, uploading", "file", localPath, "candidates", candidates)
+		}
+
+		return false
+	}
+
+	moved := entries[oldPath]
+	moved.Root, moved.RelPath = entry.Root, entry.RelPath
+
+	if s.renameEditCaption && !moved.Encrypted {
+		_, err := s.bot.EditMessageCaption(s.ctx, chatID, moved.MessageID, caption)
+		if err != nil && !errors.Is(err, telegram.ErrMessageNotModified) {
+			s.logger.Warn("failed to edit caption of renamed file", "file", localPath, "error", err)
+		}
+	}
+
+	if err := s.index.Put(localPath, moved); err != nil {
+		s.logger.Error("failed to update index", "file", localPath, "error", err)
+
+		return false
+	}
+
+	if err := s.index.Delete(oldPath); err != nil {
+		s.logger.Error("failed to update index", "file", oldPath, "error", err)
+	}
+
+	s.watcher.Forget(oldPath)
+	s.logger.Info("detected rename", "from", oldPath, "to", localPath)
+
+	return true
+}
+
+// renameCandidates returns the indexed files of chatID with the content hash that no longer exist,
+// other than localPath.
+func renameCandidates(entries map[string]IndexEntry, chatID, localPath, hash string) []string {
+	var candidates []string
+
+	for path, e := range entries {
+		if e.Hash != hash || e.ChatID != chatID || path == localPath {
+			continue
+		}
+
+		// a file that still exists was copied, not renamed
+		if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+			candidates = append(candidates, path)
+		}
+	}
+
+	return candidates
+}
+
+// renameSource picks the old path of a file renamed to newPath among the vanished files with its content.
+// Of several, the only one with the same name or else the only one in the same directory is taken,
+// anything else is ambiguous.
+func renameSource(newPath string, candidates []string) (string, bool) {
+	if len(candidates) == 1 {
+		return candidates[0], true
+	}
+
+	for _, same := range []func(string) bool{
+		func(path string) bool { return filepath.Base(path) == filepath.Base(newPath) },
+		func(path string) bool { return filepath.Dir(path) == filepath.Dir(newPath) },
+	} {
+		var match []string
+
+		for _, path := range candidates {
+			if same(path) {
+				match = append(match, path)
+			}
+		}
+
+		if len(match) == 1 {
+			return match[0], true
+		}
+	}
+
+	return "", false
+}
@@ -0,0 +1,83 @@
+package syncer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/k0ff1l/tgcloudbot/internal/services/file"
+	"github.com/k0ff1l/tgcloudbot/internal/services/telegram/telegramtest"
+)
+
+func TestRenameDetection(t *testing.T) {
+	dir := t.TempDir()
+	oldPath := writeFile(t, dir, "a.txt", []byte("report"))
+	writeFile(t, dir, "copy1.txt", []byte("same"))
+	writeFile(t, dir, "copy2.txt", []byte("same"))
+
+	watcher := file.NewWatcher()
+	if err := watcher.AddDir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	bot := telegramtest.NewFakeClient()
+	s := NewSyncService(bot, watcher, WithChatID("chat"))
+	s.SetRenameDetection(true, true)
+
+	s.syncDirectoryOnce(dir)
+
+	uploaded, _ := s.MessageFor(oldPath)
+
+	newPath := filepath.Join(dir, "b.txt")
+	if err := os.Rename(oldPath, newPath); err != nil {
+		t.Fatal(err)
+	}
+
+	// two vanished files with the same content can't tell which one was renamed
+	for _, name := range []string{"copy1.txt", "copy2.txt"} {
+		if err := os.Remove(filepath.Join(dir, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	writeFile(t, dir, "merged.txt", []byte("same"))
+
+	s.syncDirectoryOnce(dir)
+
+	if uploads := bot.Uploaded(); len(uploads) != 4 || uploads[3] != filepath.Join(dir, "merged.txt") {
+		t.Errorf("expected only the ambiguous file to be uploaded again, got %v", uploads)
+	}
+
+	if entry, ok := s.MessageFor(newPath); !ok || entry.MessageID != uploaded.MessageID || entry.RelPath != "b.txt" {
+		t.Errorf("expected the index entry to move to b.txt, got %+v", entry)
+	}
+
+	if _, ok := s.MessageFor(oldPath); ok {
+		t.Error("the old path must be dropped from the index")
+	}
+
+	edits := bot.CallsTo("EditMessageCaption")
+	if len(edits) != 1 || edits[0].MessageID != uploaded.MessageID || edits[0].Caption != "File: b.txt" {
+		t.Errorf("expected the caption to be edited to the new name, got %+v", edits)
+	}
+}
+
+func TestRenameSource(t *testing.T) {
+	tests := []struct {
+		newPath    string
+		candidates []string
+		want       string
+	}{
+		{"/a/new.txt", []string{"/b/old.txt"}, "/b/old.txt"},
+		{"/b/x.txt", []string{"/a/x.txt", "/a/y.txt"}, "/a/x.txt"},
+		{"/a/z.txt", []string{"/a/x.txt", "/b/y.txt"}, "/a/x.txt"},
+		{"/c/z.txt", []string{"/a/x.txt", "/b/y.txt"}, ""},
+		{"/a/z.txt", nil, ""},
+	}
+
+	for _, tt := range tests {
+		if got, _ := renameSource(tt.newPath, tt.candidates); got != tt.want {
+			t.Errorf("renameSource(%s, %v) = %q, want %q", tt.newPath, tt.candidates, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,279 @@
+package syncer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/k0ff1l/tgcloudbot/internal/services/compression"
+	"github.com/k0ff1l/tgcloudbot/internal/services/encryption"
+)
+
+var (
+	// ErrNoEncryptionKey is returned when restoring an encrypted file without SetEncryptionKey.
+	ErrNoEncryptionKey = errors.New("file is encrypted, an encryption key is needed")
+	// ErrChecksumMismatch is returned when a restored file or chunk doesn't have the sha256 of the upload.
+	ErrChecksumMismatch = errors.New("checksum mismatch")
+)
+
+// Restore downloads every indexed file into destDir, keeping its path relative to its watched directory.
+// Compressed and encrypted uploads are unpacked and checked against the sha256 in the index, a mismatch
+// fails with ErrChecksumMismatch and leaves nothing in place. Files that can't be restored, e.g. because
+// their file_id expired, are logged and skipped.
+//
+// The public Bot API only serves files of up to 20MB, larger uploads and chunks can only be restored
+// through a local Bot API server. Without one a file is restorable if it was uploaded whole below 20MB
+// or in chunks of 20MB at most, see SetChunkSize.
+func (s *SyncService) Restore(destDir string) error {
+	entries, err := s.index.List()
+	if err != nil {
+		return fmt.Errorf("read index: %w", err)
+	}
+
+	var failed int
+
+	for path, entry := range entries {
+		if err := s.restoreFile(destDir, path, entry); err != nil {
+			failed++
+
+			s.logger.Error("failed to restore file", "file", path, "error", err)
+
+			continue
+		}
+
+		s.logger.Info("restored", "file", path)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d files not restored", failed, len(entries))
+	}
+
+	return nil
+}
+
+func (s *SyncService) restoreFile(destDir, filePath string, entry IndexEntry) error {
+	if entry.FileID == "" && len(entry.Chunks) == 0 {
+		return errors.New("no file_id in the index")
+	}
+
+	if entry.Encrypted && s.encryptionKey == nil {
+		return ErrNoEncryptionKey
+	}
+
+	dstPath, err := restorePath(destDir, filePath, entry)
+	if err != nil {
+		return err
+	}
+
+	if s.dryRun {
+		s.logger.Info("dry run: would restore file", "file", filePath, "to", dstPath)
+
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dstPath), 0o750); err != nil {
+		return fmt.Errorf("create %s: %w", filepath.Dir(dstPath), err)
+	}
+
+	// next to dstPath, so the result can be renamed into place
+	tmpDir, err := os.MkdirTemp(filepath.Dir(dstPath), ".restore-")
+	if err != nil {
+		return fmt.Errorf("create temp dir: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	var path string
+
+	if len(entry.Chunks) > 0 {
+		path, err = s.joinChunks(tmpDir, entry)
+	} else {
+		path, err = s.downloadFile(tmpDir, entry)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	// Telegram recompresses photos, their content differs from the upload
+	if entry.Kind != KindPhoto {
+		if err := verifyChecksum(path, entry.Hash); err != nil {
+			return err
+		}
+	}
+
+	return moveIntoPlace(path, dstPath)
+}
+
+// downloadFile downloads the file of entry into tmpDir, decrypting and unpacking it if needed,
+// and returns the path of the result.
+func (s *SyncService) downloadFile(tmpDir string, entry IndexEntry) (string, error) {
+	path := filepath.Join(tmpDir, "download")
+
+	if err := s.downloadByID(entry.FileID, path); err != nil {
+		return "", err
+	}
+
+	var err error
+
+	// every step writes into its own directory, the unpacked name may be anything
+	if entry.Encrypted {
+		if path, err = encryption.DecryptFile(s.encryptionKey, path, mkdir(tmpDir, "decrypted")); err != nil {
+			return "", err
+		}
+	}
+
+	if entry.Gzip {
+		if path, err = compression.GunzipFile(path, mkdir(tmpDir, "gunzipped")); err != nil {
+			return "", err
+		}
+	}
+
+	return path, nil
+}
+
+// verifyChecksum checks that the file at path has the hex sha256 want, an empty want isn't checked.
+func verifyChecksum(path, want string) error {
+	if want == "" {
+		return nil
+	}
+
+	got, err := hashFile(path)
+	if err != nil {
+		return err
+	}
+
+	if got != want {
+		return fmt.Errorf("%w: sha256 %s, uploaded %s", ErrChecksumMismatch, got, want)
+	}
+
+	return nil
+}
+
+// joinChunks downloads the chunks of entry into tmpDir, decrypting every one of them if encrypted,
+// and returns the path of the joined file.
+func (s *SyncService) joinChunks(tmpDir string, entry IndexEntry) (string, error) {
+	joinedPath := filepath.Join(tmpDir, "joined")
+
+	joined, err := os.Create(joinedPath)
+	if err != nil {
+		return "", fmt.Errorf("create %s: %w", joinedPath, err)
+	}
+	defer joined.Close()
+
+	for i, chunk := range entry.Chunks {
+		path := filepath.Join(tmpDir, fmt.Sprintf("chunk%03d", i+1))
+
+		if err := s.downloadByID(chunk.FileID, path); err != nil {
+			return "", fmt.Errorf("chunk %d: %w", i+1, err)
+		}
+
+		if entry.Encrypted {
+			if path, err = encryption.DecryptFile(s.encryptionKey, path, mkdir(tmpDir, "decrypted")); err != nil {
+				return "", fmt.Errorf("chunk %d: %w", i+1, err)
+			}
+		}
+
+		if err := verifyChecksum(path, chunk.Hash); err != nil {
+			return "", fmt.Errorf("chunk %d: %w", i+1, err)
+		}
+
+		if err := appendFile(joined, path); err != nil {
+			return "", err
+		}
+	}
+
+	if err := joined.Close(); err != nil {
+		return "", fmt.Errorf("write %s: %w", joinedPath, err)
+	}
+
+	return joinedPath, nil
+}
+
+// appendFile copies the file at path to the end of dst and removes it.
+func appendFile(dst *os.File, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("open %s: %w", path, err)
+	}
+	defer os.Remove(path)
+	defer f.Close()
+
+	if _, err := io.Copy(dst, f); err != nil {
+		return fmt.Errorf("write %s: %w", dst.Name(), err)
+	}
+
+	return nil
+}
+
+// moveIntoPlace renames the restored file at path to dstPath.
+func moveIntoPlace(path, dstPath string) error {
+	if err := os.Rename(path, dstPath); err != nil {
+		return fmt.Errorf("move %s into place: %w", dstPath, err)
+	}
+
+	return nil
+}
+
+// downloadByID writes the Telegram file with fileID to the local path.
+func (s *SyncService) downloadByID(fileID, path string) error {
+	info, err := s.bot.GetFileInfo(s.ctx, fileID)
+	if err != nil {
+		return fmt.Errorf("get file: %w", err)
+	}
+
+	return s.download(info.FilePath, path)
+}
+
+// download writes the Telegram file at filePath to the local path.
+func (s *SyncService) download(filePath, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create %s: %w", path, err)
+	}
+
+	if err := s.bot.DownloadFile(s.ctx, filePath, f); err != nil {
+		_ = f.Close()
+
+		return fmt.Errorf("download: %w", err)
+	}
+
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// mkdir creates dir/name and returns it, an error shows up when writing into it.
+func mkdir(dir, name string) string {
+	path := filepath.Join(dir, name)
+	_ = os.Mkdir(path, 0o700)
+
+	return path
+}
+
+// restorePath returns where filePath is restored under destDir, entries written before the
+// relative path was indexed fall back to the root and then to the base name.
+func restorePath(destDir, filePath string, entry IndexEntry) (string, error) {
+	rel := filepath.FromSlash(entry.RelPath)
+
+	switch {
+	case rel != "":
+	case entry.Root != "":
+		var err error
+		if rel, err = filepath.Rel(entry.Root, filePath); err != nil {
+			return "", fmt.Errorf("%s is not under %s", filePath, entry.Root)
+		}
+	default:
+		rel = filepath.Base(filePath)
+	}
+
+	// the index may have been edited, never write outside destDir
+	if !filepath.IsLocal(rel) {
+		return "", fmt.Errorf("%s: %q is not a relative path below the restore directory", filePath, rel)
+	}
+
+	return filepath.Join(destDir, rel), nil
+}
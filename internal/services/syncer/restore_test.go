@@ -0,0 +1,167 @@
+package syncer
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/k0ff1l/tgcloudbot/internal/services/compression"
+	"github.com/k0ff1l/tgcloudbot/internal/services/file"
+	"github.com/k0ff1l/tgcloudbot/internal/services/telegram/telegramtest"
+)
+
+func TestRestore(t *testing.T) {
+	root := t.TempDir()
+	if err := os.Mkdir(filepath.Join(root, "logs"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	logPath := writeFile(t, filepath.Join(root, "logs"), "app.log", []byte(strings.Repeat("line\n", 100)))
+
+	gzPath, err := compression.GzipFile(logPath, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gz, err := os.ReadFile(gzPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bot := telegramtest.NewFakeClient()
+	bot.ServeFile("log", gz)
+	bot.ServeFile("note", []byte("note"))
+
+	s := NewSyncService(bot, file.NewWatcher(), WithChatID("chat"))
+	_ = s.index.Put(logPath, IndexEntry{ChatID: "chat", FileID: "log", Root: root, Gzip: true})
+	_ = s.index.Put(filepath.Join(root, "note.txt"), IndexEntry{ChatID: "chat", FileID: "note", Root: root})
+	// e.g. uploaded before file ids were indexed
+	_ = s.index.Put(filepath.Join(root, "old.txt"), IndexEntry{ChatID: "chat"})
+
+	dest := t.TempDir()
+
+	if err := s.Restore(dest); err == nil || !strings.HasPrefix(err.Error(), "1 of 3") {
+		t.Errorf("expected one file not to be restored, got %v", err)
+	}
+
+	for path, want := range map[string]string{
+		"logs/app.log": strings.Repeat("line\n", 100),
+		"note.txt":     "note",
+	} {
+		data, err := os.ReadFile(filepath.Join(dest, path))
+		if err != nil || string(data) != want {
+			t.Errorf("%s restored as %q, %v", path, data, err)
+		}
+	}
+}
+
+func TestRestorePath(t *testing.T) {
+	if _, err := restorePath("/dest", "/other/a.txt", IndexEntry{Root: "/srv"}); err == nil {
+		t.Error("expected an error for a file outside its root")
+	}
+
+	if _, err := restorePath("/dest", "/srv/a.txt", IndexEntry{RelPath: "../a.txt"}); err == nil {
+		t.Error("expected an error for a relative path escaping the restore directory")
+	}
+
+	if path, _ := restorePath("/dest", "/srv/a.txt", IndexEntry{}); path != "/dest/a.txt" {
+		t.Errorf("unexpected path %s", path)
+	}
+}
+
+func TestRestoreCollidingNames(t *testing.T) {
+	root := t.TempDir()
+
+	var paths []string
+
+	for _, sub := range []string{"2023", "2024"} {
+		if err := os.Mkdir(filepath.Join(root, sub), 0o700); err != nil {
+			t.Fatal(err)
+		}
+
+		paths = append(paths, writeFile(t, filepath.Join(root, sub), "report.pdf", []byte(sub)))
+	}
+
+	bot := telegramtest.NewFakeClient()
+	s := NewSyncService(bot, file.NewWatcher(), WithChatID("chat"))
+
+	if err := s.StartContinuousSync(root, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	waitFor(t, func() bool { return len(bot.Uploaded()) == 2 })
+	s.Stop()
+
+	var captions []string
+
+	for i, call := range bot.CallsTo("SendDocument") {
+		captions = append(captions, call.Caption)
+
+		// the fake answers without a document, serve the content under a made up file id
+		entry, _ := s.MessageFor(call.Paths[0])
+		entry.FileID = "file" + strconv.Itoa(i)
+		_ = s.index.Put(call.Paths[0], entry)
+
+		bot.ServeFile(entry.FileID, []byte(filepath.Base(filepath.Dir(call.Paths[0]))))
+	}
+
+	slices.Sort(captions)
+
+	if want := []string{"File: 2023/report.pdf", "File: 2024/report.pdf"}; !slices.Equal(captions, want) {
+		t.Errorf("captions %q, want %q", captions, want)
+	}
+
+	dest := t.TempDir()
+
+	if err := s.Restore(dest); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range paths {
+		rel, _ := filepath.Rel(root, path)
+
+		data, err := os.ReadFile(filepath.Join(dest, rel))
+		if err != nil || string(data) != filepath.Base(filepath.Dir(path)) {
+			t.Errorf("%s restored as %q, %v", rel, data, err)
+		}
+	}
+}
+
+func TestRestoreChecksumMismatch(t *testing.T) {
+	root := t.TempDir()
+	notePath := writeFile(t, root, "note.txt", []byte("note"))
+	bigPath := writeFile(t, root, "big.bin", []byte("0123456789"))
+
+	bot := &storingBot{FakeClient: telegramtest.NewFakeClient()}
+	s := NewSyncService(bot, file.NewWatcher(), WithChatID("chat"))
+	s.SetChunkSize(5)
+
+	for _, path := range []string{notePath, bigPath} {
+		if err := s.SyncFile(path); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// a download altered on the way
+	bot.ServeFile("note.txt", []byte("nope"))
+	bot.ServeFile("big.bin.part002", []byte("56788"))
+
+	dest := t.TempDir()
+
+	for _, path := range []string{notePath, bigPath} {
+		entry, _ := s.indexEntry(path)
+
+		if err := s.restoreFile(dest, path, entry); !errors.Is(err, ErrChecksumMismatch) {
+			t.Errorf("%s: expected ErrChecksumMismatch, got %v", filepath.Base(path), err)
+		}
+	}
+
+	if entries, _ := os.ReadDir(dest); len(entries) != 0 {
+		t.Errorf("expected nothing left in the restore directory, got %v", entries)
+	}
+}
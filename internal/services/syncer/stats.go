@@ -0,0 +1,43 @@
+package syncer
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// syncStats counts the sync activity since the last summary.
+type syncStats struct {
+	filesUploaded atomic.Int64
+	bytesUploaded atomic.Int64
+	errors        atomic.Int64
+}
+
+type statsSnapshot struct {
+	filesUploaded int64
+	bytesUploaded int64
+	errors        int64
+}
+
+func (st *syncStats) uploaded(size int64) {
+	st.filesUploaded.Add(1)
+	st.bytesUploaded.Add(size)
+}
+
+func (st *syncStats) failed() {
+	st.errors.Add(1)
+}
+
+// reset returns the counters and starts counting from zero.
+func (st *syncStats) reset() statsSnapshot {
+	return statsSnapshot{
+		filesUploaded: st.filesUploaded.Swap(0),
+		bytesUploaded: st.bytesUploaded.Swap(0),
+		errors:        st.errors.Swap(0),
+	}
+}
+
+func (s statsSnapshot) summary(period time.Duration, dirs int) string {
+	return fmt.Sprintf("Sync summary for the last %s:\nfiles uploaded: %d\nbytes transferred: %d\nerrors: %d\ndirectories watched: %d",
+		period, s.filesUploaded, s.bytesUploaded, s.errors, dirs)
+}
@@ -0,0 +1,60 @@
+package syncer
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Status is a snapshot of the sync service, e.g. for the admin API.
+type Status struct {
+	Paused      bool        `json:"paused"`
+	QueueLength int         `json:"queue_length"`
+	Directories []DirStatus `json:"directories"`
+	// FilesUploaded, BytesUploaded and Errors count since the start or the last periodic summary
+	FilesUploaded int64 `json:"files_uploaded"`
+	BytesUploaded int64 `json:"bytes_uploaded"`
+	Errors        int64 `json:"errors"`
+}
+
+// DirStatus is a directory with a sync loop.
+type DirStatus struct {
+	Path         string `json:"path"`
+	ChatID       string `json:"chat_id"`
+	TrackedFiles int    `json:"tracked_files"`
+}
+
+// Status returns the current state of the service.
+func (s *SyncService) Status() Status {
+	s.mu.Lock()
+	dirs := slices.Clone(s.syncDirs)
+	s.mu.Unlock()
+
+	status := Status{
+		Paused:        s.Paused(),
+		QueueLength:   s.QueueLength(),
+		Directories:   make([]DirStatus, 0, len(dirs)),
+		FilesUploaded: s.stats.filesUploaded.Load(),
+		BytesUploaded: s.stats.bytesUploaded.Load(),
+		Errors:        s.stats.errors.Load(),
+	}
+
+	for _, dir := range dirs {
+		status.Directories = append(status.Directories, DirStatus{
+			Path:         dir,
+			ChatID:       s.chatIDFor(dir),
+			TrackedFiles: s.watcher.TrackedFiles(dir),
+		})
+	}
+
+	return status
+}
+
+// Files returns the index, the message every uploaded local file was last sent as by its path.
+func (s *SyncService) Files() (map[string]IndexEntry, error) {
+	entries, err := s.index.List()
+	if err != nil {
+		return nil, fmt.Errorf("read index: %w", err)
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,1434 @@
+package syncer
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"text/template"
+	"time"
+
+	"github.com/k0ff1l/tgcloudbot/internal/services/compression"
+	"github.com/k0ff1l/tgcloudbot/internal/services/encryption"
+	"github.com/k0ff1l/tgcloudbot/internal/services/file"
+	"github.com/k0ff1l/tgcloudbot/internal/services/metrics"
+	"github.com/k0ff1l/tgcloudbot/internal/services/state"
+	"github.com/k0ff1l/tgcloudbot/internal/services/telegram"
+)
+
+const (
+	defaultConcurrency = 4
+	// DefaultUploadQueueSize is the default capacity of the upload queue, see SetUploadQueueSize.
+	DefaultUploadQueueSize = 64
+
+	// progressMinSize is the smallest upload ProgressLogger reports.
+	progressMinSize = 1 << 20
+)
+
+var (
+	ErrServiceStopped = errors.New("sync service is stopped")
+	// ErrNotIndexed is returned for local files without a known message.
+	ErrNotIndexed = errors.New("file is not in the index")
+	// ErrSizeMismatch is returned when Telegram reports another size than the one uploaded.
+	ErrSizeMismatch = errors.New("uploaded size mismatch")
+)
+
+type SyncService struct {
+	bot     telegram.Bot
+	watcher file.Watcher
+	chatID  string
+	logger  *slog.Logger
+
+	// dirChatIDs overrides chatID for files of a watched directory
+	dirChatIDs map[string]string
+	// dirClassifiers classify the files of a watched directory instead of classifier, guarded by mu
+	dirClassifiers map[string]Classifier
+	// dirProtection are the directories whose files are protected or sent as spoilers, guarded by mu
+	dirProtection map[string]Protection
+	// dirTopics are the forum topics the files of a watched directory go to, guarded by mu
+	dirTopics map[string]int64
+	// mirrorChats also get every uploaded file, see SetMirrorChats
+	mirrorChats []string
+
+	// classifier picks the send method of the files, see SetClassifier
+	classifier Classifier
+	// preferVoice sends .ogg/.opus audio as voice messages instead of audio files
+	preferVoice bool
+	// photoMaxSide and photoMaxSize send larger images as documents, 0 disables them
+	photoMaxSide int
+	photoMaxSize int64
+	// siblingThumbnails attaches a .jpg with the same base name as the thumbnail of videos and documents
+	siblingThumbnails bool
+	// audioTagsFromName sends the performer and title of audio files parsed from their names
+	audioTagsFromName bool
+	// chatActions shows an upload action in the chat during uploads
+	chatActions bool
+
+	// dryRun logs what would be uploaded instead of calling the bot.
+	dryRun bool
+	// dryRunKeepState leaves the watcher state untouched during a dry run,
+	// so the same files are detected again by a later real run.
+	dryRunKeepState bool
+
+	// compress gzips compressible documents before upload
+	compress bool
+
+	// replyThreads posts a header message per directory and sync batch
+	// and sends the files of the batch as replies to it.
+	replyThreads bool
+
+	// albums sends the new photos and videos of a batch as albums
+	albums bool
+
+	// index maps the uploaded local files to their messages
+	index state.Store[IndexEntry]
+	// chunkSize splits larger files into chunks, 0 disables it. chunkProgress keeps the chunks uploaded
+	// of the files not complete yet
+	chunkSize     int64
+	chunkProgress state.Store[ChunkProgress]
+	// dedup maps content hashes to their uploads, nil disables deduplication
+	dedup state.Store[IndexEntry]
+	// detectRenames moves the index entry of a vanished file to a new file with its content,
+	// renameEditCaption also edits the caption of its message
+	detectRenames     bool
+	renameEditCaption bool
+	// captionTemplate renders the caption of the uploaded files
+	captionTemplate *template.Template
+	// instance names this machine in the captions and the index, instanceInPath also in the relative paths
+	instance       string
+	instanceInPath bool
+	// editOnResync edits the caption of the existing message of a re-synced file instead of uploading it again
+	editOnResync bool
+	// replaceOnResync uploads a re-synced file in place of the file of its existing message
+	replaceOnResync bool
+
+	// encryptionKey enables AES-GCM encryption of the uploaded files, nil means plain uploads
+	encryptionKey []byte
+
+	// disableNotification sends everything silently, quietHours only during its hours
+	disableNotification bool
+	quietHours          QuietHours
+	// now is time.Now, replaced in tests
+	now func() time.Time
+
+	// jitter is the fraction of the interval the syncs of a directory are randomly moved by, see SetJitter
+	jitter float64
+	// random is rand.Float64, replaced in tests
+	random func() float64
+
+	concurrency int
+	// digestThreshold is the batch size announced by a digest, see SetBatchDigest
+	digestThreshold  int
+	digestNoCaptions bool
+	// queue holds a slot for every file queued or being uploaded across all directories,
+	// a sync blocks while it is full
+	queue chan struct{}
+	// order is the upload order within a sync batch
+	order SyncOrder
+
+	stats syncStats
+	// summaryInPlace edits the last summary message instead of sending a new one every period
+	summaryInPlace bool
+	// summaryMessageID is the last summary message, used only by the summary loop
+	summaryMessageID int64
+	// alerts sends persisting errors to the chat, nil disables it
+	alerts *errorAlerts
+	// retryBudget is the number of failed attempts before a file is dead-lettered, 0 retries forever
+	retryBudget int
+	// failures counts the failed attempts of the files in a row, guarded by mu
+	failures    map[string]int
+	deadLetters state.Store[DeadLetter]
+	// quota caps the bytes uploaded per quotaWindow, 0 disables it. usage is guarded by usageMu,
+	// so is quotaWarnedUntil, the end of the last window a warning was sent in
+	quota            int64
+	quotaWindow      time.Duration
+	usage            state.Store[Usage]
+	usageMu          sync.Mutex
+	quotaWarnedUntil time.Time
+	// metrics is optional, nil disables it
+	metrics *metrics.Metrics
+	// dirs is the number of directories with a running sync loop
+	dirs atomic.Int64
+	// paused skips the syncs of all directories, see Pause
+	paused atomic.Bool
+	// syncDirs are the directories of the sync loops, guarded by mu
+	syncDirs []string
+	// missingDirs are the watched directories that disappeared, guarded by mu, see SetMissingDirs
+	missingDirs      map[string]*missingDir
+	missingDirGrace  time.Duration
+	reAddMissingDirs bool
+	// dirLocks serializes the syncs of a directory by the loop, SyncNow and ForceSync, guarded by mu
+	dirLocks map[string]*sync.Mutex
+
+	ctx    context.Context //nolint:containedctx // cancelled by Stop or the parent of WithContext
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	// mu guards stopped so that no loop is added after Stop started waiting
+	mu       sync.Mutex
+	stopped  bool
+	stopOnce sync.Once
+}
+
+// NewSyncService creates the service configured by opts, e.g.
+//
+//	NewSyncService(bot, watcher, WithChatID(chatID), WithLogger(logger))
+//
+// Files are classified by their content unless WithClassifier replaces it.
+func NewSyncService(bot telegram.Bot, watcher file.Watcher, opts ...Option) *SyncService {
+	s := &SyncService{
+		bot:             bot,
+		watcher:         watcher,
+		logger:          slog.Default(),
+		dirChatIDs:      make(map[string]string),
+		dirProtection:   make(map[string]Protection),
+		dirTopics:       make(map[string]int64),
+		dirClassifiers:  make(map[string]Classifier),
+		dirLocks:        make(map[string]*sync.Mutex),
+		missingDirs:     make(map[string]*missingDir),
+		index:           newMemoryIndex(),
+		captionTemplate: template.Must(ParseCaptionTemplate(DefaultCaptionTemplate)),
+		failures:        make(map[string]int),
+		chunkProgress:   newMemoryChunkProgress(),
+		deadLetters:     newMemoryDeadLetters(),
+		usage:           newMemoryUsage(),
+		classifier:      ContentClassifier{},
+		concurrency:     defaultConcurrency,
+		queue:           make(chan struct{}, DefaultUploadQueueSize),
+		now:             time.Now,
+		random:          rand.Float64, //nolint:gosec // jitter, not security
+		ctx:             context.Background(),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	s.ctx, s.cancel = context.WithCancel(s.ctx)
+
+	return s
+}
+
+// SetDryRun enables the dry-run mode, see dryRun and dryRunKeepState.
+func (s *SyncService) SetDryRun(dryRun, keepState bool) {
+	s.dryRun = dryRun
+	s.dryRunKeepState = keepState
+}
+
+// SetPreferVoice sends .ogg and .opus audio with sendVoice instead of sendAudio.
+func (s *SyncService) SetPreferVoice(enabled bool) {
+	s.preferVoice = enabled
+}
+
+// SetSiblingThumbnails attaches "name.jpg" as the preview of the video or document "name.ext" when it is
+// a valid thumbnail, see telegram.ValidateThumbnail. The .jpg is still synced as a file of its own.
+func (s *SyncService) SetSiblingThumbnails(enabled bool) {
+	s.siblingThumbnails = enabled
+}
+
+// SetAudioTagsFromName sends the performer and title of audio files taken from names like
+// "Performer - Title.mp3", or the name alone as title, instead of letting Telegram read the file's tags.
+func (s *SyncService) SetAudioTagsFromName(enabled bool) {
+	s.audioTagsFromName = enabled
+}
+
+// SetDisableNotification sends all messages silently.
+func (s *SyncService) SetDisableNotification(disabled bool) {
+	s.disableNotification = disabled
+}
+
+// SetQuietHours sends all messages silently during the given hours of the local time.
+func (s *SyncService) SetQuietHours(quietHours QuietHours) {
+	s.quietHours = quietHours
+}
+
+// sendOptions returns the options of a message sent now, as a reply to replyTo if not 0.
+func (s *SyncService) sendOptions(replyTo int64) []telegram.SendOption {
+	var opts []telegram.SendOption
+
+	if replyTo != 0 {
+		opts = append(opts, telegram.ReplyTo(replyTo))
+	}
+
+	if s.disableNotification || s.quietHours.Contains(s.now()) {
+		opts = append(opts, telegram.DisableNotification())
+	}
+
+	return opts
+}
+
+// SetSyncOrder sets the order the files of a sync batch are uploaded in, by path unless set.
+func (s *SyncService) SetSyncOrder(order SyncOrder) {
+	s.order = order
+}
+
+// SetUploadQueueSize sets how many files may be queued or uploading at once across all directories,
+// DefaultUploadQueueSize if not positive. A sync waits for a free slot before it hands on the next file,
+// so a directory isn't scanned again until its queued files are uploaded. It must be called before the sync starts.
+func (s *SyncService) SetUploadQueueSize(size int) {
+	if size <= 0 {
+		size = DefaultUploadQueueSize
+	}
+
+	s.queue = make(chan struct{}, size)
+}
+
+// QueueLength returns the number of files queued or being uploaded.
+func (s *SyncService) QueueLength() int {
+	return len(s.queue)
+}
+
+// enqueue takes a queue slot, it reports false when the service stopped while waiting.
+func (s *SyncService) enqueue() bool {
+	select {
+	case <-s.ctx.Done():
+		return false
+	case s.queue <- struct{}{}:
+		s.metrics.SetUploadQueueDepth(len(s.queue))
+
+		return true
+	}
+}
+
+// dequeue frees the slot of a file that finished.
+func (s *SyncService) dequeue() {
+	<-s.queue
+	s.metrics.SetUploadQueueDepth(len(s.queue))
+}
+
+// SetCompression gzips documents that are not compressed already before upload.
+func (s *SyncService) SetCompression(enabled bool) {
+	s.compress = enabled
+}
+
+// SendDirectoryZip bundles the whole directory into one zip and sends it as a single document,
+// handy for trees of many small files.
+func (s *SyncService) SendDirectoryZip(dirPath string) error {
+	tmpDir, err := os.MkdirTemp("", "tgcloudbot-")
+	if err != nil {
+		return fmt.Errorf("create temp dir: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	zipPath, err := compression.ZipDir(dirPath, tmpDir)
+	if err != nil {
+		return err
+	}
+
+	chatID := s.chatIDFor(dirPath)
+
+	if s.dryRun {
+		s.logger.Info("dry run: would send directory archive", "dir", dirPath, "chat", chatID)
+
+		return nil
+	}
+
+	caption := "Directory: " + filepath.Base(dirPath)
+
+	if _, err := s.bot.SendDocument(s.ctx, chatID, zipPath, caption, s.fileOptions(chatID, dirPath, 0)...); err != nil {
+		return fmt.Errorf("send archive of %s: %w", dirPath, err)
+	}
+
+	return nil
+}
+
+// SetReplyThreads threads the files of every sync batch under a directory header message.
+func (s *SyncService) SetReplyThreads(enabled bool) {
+	s.replyThreads = enabled
+}
+
+// SetIndexFile loads the path to message index from path and saves it there after every upload.
+func (s *SyncService) SetIndexFile(path string) error {
+	store, err := state.NewFileStore[IndexEntry](path)
+	if err != nil {
+		return err
+	}
+
+	s.index = store
+
+	return nil
+}
+
+// SetIndexStore keeps the path to message index in store instead of memory, see SetIndexFile.
+func (s *SyncService) SetIndexStore(store state.Store[IndexEntry]) {
+	s.index = store
+}
+
+// SetEditOnResync updates the caption of the message of a modified file instead of posting a duplicate,
+// note that the content of the message is not replaced.
+func (s *SyncService) SetEditOnResync(enabled bool) {
+	s.editOnResync = enabled
+}
+
+// SetReplaceOnResync uploads a modified file in place of the file of its message instead of posting a new one,
+// so the chat keeps one message per file. Voice messages and files uploaded in chunks are sent anew,
+// as are files whose message can't be edited, e.g. because it was deleted. The mirror chats get a new message.
+func (s *SyncService) SetReplaceOnResync(enabled bool) {
+	s.replaceOnResync = enabled
+}
+
+// SetEncryptionKey encrypts every file before upload, see encryption.EncryptFile.
+func (s *SyncService) SetEncryptionKey(key []byte) {
+	s.encryptionKey = key
+}
+
+// SetMetrics records the sync activity in m.
+func (s *SyncService) SetMetrics(m *metrics.Metrics) {
+	s.metrics = m
+}
+
+// SetDirChatID sends the files of dirPath to chatID instead of the default chat.
+func (s *SyncService) SetDirChatID(dirPath, chatID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.dirChatIDs[filepath.Clean(dirPath)] = chatID
+}
+
+// SetClassifier replaces the classification of files into send methods, see ContentClassifier
+// and ExtensionClassifier. SetDirClassifier, SetPreferVoice and SetPhotoLimits still apply.
+func (s *SyncService) SetClassifier(classifier Classifier) {
+	s.classifier = classifier
+}
+
+// SetDirClassifier classifies the files of dirPath with classifier, as is, instead of the default one.
+func (s *SyncService) SetDirClassifier(dirPath string, classifier Classifier) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.dirClassifiers[filepath.Clean(dirPath)] = classifier
+}
+
+// SetDirKind sends all files of dirPath with the send method of kind instead of detecting it,
+// e.g. KindDocument keeps Telegram from recompressing photos.
+func (s *SyncService) SetDirKind(dirPath string, kind SendKind) {
+	s.SetDirClassifier(dirPath, FixedKind(kind))
+}
+
+// Protection are the flags of the files of a watched directory, see SetDirProtection.
+type Protection struct {
+	// ProtectContent keeps the messages from being forwarded and saved
+	ProtectContent bool
+	// Spoiler blurs photos and videos until they are tapped
+	Spoiler bool
+}
+
+// SetDirProtection sends the files of dirPath with the given flags, by default none is set.
+func (s *SyncService) SetDirProtection(dirPath string, protection Protection) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.dirProtection[filepath.Clean(dirPath)] = protection
+}
+
+// SetDirTopic sends the files of dirPath into the forum topic threadID of its chat, see SetDirChatID.
+// The chat must be a supergroup with topics, 0 sends them to the general topic.
+func (s *SyncService) SetDirTopic(dirPath string, threadID int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.dirTopics[filepath.Clean(dirPath)] = threadID
+}
+
+// topicOptions returns the option sending a message about the watched directory root into its topic,
+// none for the general topic or another chat, e.g. a mirror chat.
+func (s *SyncService) topicOptions(chatID, root string) []telegram.SendOption {
+	root = filepath.Clean(root)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	threadID := s.dirTopics[root]
+	if threadID == 0 || chatID != cmp.Or(s.dirChatIDs[root], s.chatID) {
+		return nil
+	}
+
+	return []telegram.SendOption{telegram.MessageThread(threadID)}
+}
+
+// fileOptions returns the send options of a file of the watched directory root sent to chatID, see sendOptions.
+func (s *SyncService) fileOptions(chatID, root string, replyTo int64) []telegram.SendOption {
+	opts := append(s.sendOptions(replyTo), s.topicOptions(chatID, root)...)
+
+	s.mu.Lock()
+	protection := s.dirProtection[filepath.Clean(root)]
+	s.mu.Unlock()
+
+	if protection.ProtectContent {
+		opts = append(opts, telegram.ProtectContent())
+	}
+
+	if protection.Spoiler {
+		opts = append(opts, telegram.HasSpoiler())
+	}
+
+	return opts
+}
+
+func (s *SyncService) chatIDFor(dirPath string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if chatID, ok := s.dirChatIDs[filepath.Clean(dirPath)]; ok {
+		return chatID
+	}
+
+	return s.chatID
+}
+
+// StartContinuousSync watches dirPath and syncs its updated files every interval until Stop,
+// every directory runs its own timer. The interval counts from the end of a sync, see also SetJitter.
+// It returns ErrServiceStopped once the service has been stopped.
+func (s *SyncService) StartContinuousSync(dirPath string, interval time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stopped || s.ctx.Err() != nil {
+		return ErrServiceStopped
+	}
+
+	if interval <= 0 {
+		return fmt.Errorf("invalid sync interval %s for %s", interval, dirPath)
+	}
+
+	if err := s.watcher.AddDir(dirPath); err != nil {
+		return fmt.Errorf("watch %s: %w", dirPath, err)
+	}
+
+	s.syncDirs = append(s.syncDirs, filepath.Clean(dirPath))
+	s.dirs.Add(1)
+	s.wg.Add(1)
+
+	delay := s.startDelay(interval)
+
+	go func() {
+		defer s.wg.Done()
+		defer s.dirs.Add(-1)
+
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+
+		for {
+			select {
+			case <-s.ctx.Done():
+				return
+			case <-timer.C:
+				s.syncDirectoryOnce(dirPath)
+
+				if s.dirDropped(dirPath) {
+					return
+				}
+
+				timer.Reset(s.jittered(interval))
+			}
+		}
+	}()
+
+	return nil
+}
+
+// StartPeriodicSummary sends a summary of the sync activity to the default chat every interval until Stop,
+// with inPlace the first summary message is edited afterwards instead of sending new ones.
+func (s *SyncService) StartPeriodicSummary(interval time.Duration, inPlace bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stopped || s.ctx.Err() != nil {
+		return ErrServiceStopped
+	}
+
+	if interval <= 0 {
+		return fmt.Errorf("invalid summary interval %s", interval)
+	}
+
+	s.summaryInPlace = inPlace
+	s.wg.Add(1)
+
+	go func() {
+		defer s.wg.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-s.ctx.Done():
+				return
+			case <-ticker.C:
+				s.sendSummary(interval)
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (s *SyncService) sendSummary(period time.Duration) {
+	text := s.stats.reset().summary(period, int(s.dirs.Load()))
+
+	if s.dryRun {
+		s.logger.Info("dry run: would send summary", "chat", s.chatID, "text", text)
+
+		return
+	}
+
+	if s.summaryInPlace && s.summaryMessageID != 0 {
+		_, err := s.bot.EditMessageText(s.ctx, s.chatID, s.summaryMessageID, text)
+		if err == nil || errors.Is(err, telegram.ErrMessageNotModified) {
+			return
+		}
+
+		s.logger.Warn("failed to edit summary, sending a new one", "error", err)
+	}
+
+	msg, err := s.bot.SendMessage(s.ctx, s.chatID, text, s.sendOptions(0)...)
+	if err != nil {
+		s.logger.Error("failed to send summary", "error", err)
+
+		return
+	}
+
+	s.summaryMessageID = msg.MessageID
+}
+
+// Stop cancels all sync loops and the uploads in progress and waits for them to finish,
+// it is safe to call more than once.
+func (s *SyncService) Stop() {
+	s.stopOnce.Do(func() {
+		s.mu.Lock()
+		s.stopped = true
+		s.mu.Unlock()
+
+		s.cancel()
+	})
+
+	s.wg.Wait()
+}
+
+// Ping checks that the bot token is valid and the Bot API is reachable.
+func (s *SyncService) Ping() error {
+	if _, err := s.bot.GetMe(s.ctx); err != nil {
+		return fmt.Errorf("bot api: %w", err)
+	}
+
+	return nil
+}
+
+// Announce sends text to the default chat, e.g. a startup notice.
+// It is sent after Stop too, e.g. a shutdown notice.
+func (s *SyncService) Announce(text string) error {
+	if s.dryRun {
+		s.logger.Info("dry run: would send message", "chat", s.chatID, "text", text)
+
+		return nil
+	}
+
+	if _, err := s.bot.SendMessage(context.WithoutCancel(s.ctx), s.chatID, text, s.sendOptions(0)...); err != nil {
+		return fmt.Errorf("send message: %w", err)
+	}
+
+	return nil
+}
+
+// SyncNow syncs the updated files of all directories with a sync loop right away
+// and returns once they are done.
+func (s *SyncService) SyncNow() error {
+	s.mu.Lock()
+	stopped, dirs := s.stopped, slices.Clone(s.syncDirs)
+	s.mu.Unlock()
+
+	if stopped || s.ctx.Err() != nil {
+		return ErrServiceStopped
+	}
+
+	for _, dir := range dirs {
+		s.syncDirectoryOnce(dir)
+	}
+
+	return nil
+}
+
+// ForceSync uploads filePath even if it is unchanged, e.g. after its message was lost.
+// It goes to the chat of the watched directory it is in, the default chat otherwise.
+func (s *SyncService) ForceSync(filePath string) error {
+	filePath = filepath.Clean(filePath)
+	root := s.syncDirOf(filePath)
+
+	if root == "" {
+		return s.syncFile(s.chatID, filepath.Dir(filePath), filePath, 0, true, false)
+	}
+
+	lock := s.dirLock(root)
+	lock.Lock()
+	defer lock.Unlock()
+
+	return s.syncFile(s.chatIDFor(root), root, filePath, 0, true, false)
+}
+
+// syncDirOf returns the innermost directory with a sync loop containing filePath, "" if none does.
+func (s *SyncService) syncDirOf(filePath string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var root string
+
+	for _, dir := range s.syncDirs {
+		if strings.HasPrefix(filePath, dir+string(filepath.Separator)) && len(dir) > len(root) {
+			root = dir
+		}
+	}
+
+	return root
+}
+
+func (s *SyncService) dirLock(dirPath string) *sync.Mutex {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	lock, ok := s.dirLocks[dirPath]
+	if !ok {
+		lock = &sync.Mutex{}
+		s.dirLocks[dirPath] = lock
+	}
+
+	return lock
+}
+
+func (s *SyncService) syncDirectoryOnce(dirPath string) {
+	// a tick and SyncNow must not upload the same files twice
+	lock := s.dirLock(dirPath)
+	lock.Lock()
+	defer lock.Unlock()
+
+	// the watcher doesn't record the changes, they are found again after Resume
+	if s.paused.Load() || !s.restoreRemovedDir(dirPath) {
+		return
+	}
+
+	files, ok := s.updatedFiles(dirPath)
+	if !ok {
+		return
+	}
+
+	// the workers take the files in this order
+	sortBatch(files, s.order)
+
+	chatID := s.chatIDFor(dirPath)
+	digest, workers := s.isDigestBatch(len(files)), min(s.concurrency, len(files))
+
+	var replyTo int64
+
+	if digest {
+		// a large batch trickles in one file at a time
+		replyTo, workers = s.postBatchDigest(chatID, dirPath, files), 1
+	} else {
+		replyTo = s.postFolderHeader(chatID, dirPath, len(files))
+	}
+
+	defer func() {
+		s.metrics.SetTrackedFiles(dirPath, s.watcher.TrackedFiles(dirPath))
+	}()
+
+	if !digest {
+		files = s.sendAlbums(chatID, dirPath, files, replyTo)
+	}
+
+	jobs := make(chan string)
+
+	var wg sync.WaitGroup
+
+	for range workers {
+		wg.Go(func() {
+			for path := range jobs {
+				s.syncJob(chatID, dirPath, path, replyTo, digest && s.digestNoCaptions)
+			}
+		})
+	}
+
+	dispatched := s.dispatch(files, jobs)
+
+	close(jobs)
+	wg.Wait()
+
+	// the watcher recorded them as synced, a restart with a state store must see them again
+	for _, path := range files[dispatched:] {
+		s.watcher.Forget(path)
+	}
+}
+
+// updatedFiles returns the files of dirPath changed since the last scan.
+// It reports false when there is nothing to sync, the failure is logged and reported.
+func (s *SyncService) updatedFiles(dirPath string) ([]string, bool) {
+	var (
+		files []string
+		err   error
+	)
+
+	if s.dryRun && s.dryRunKeepState {
+		files, err = s.watcher.PeekUpdatedFilesIn(dirPath)
+	} else {
+		files, err = s.watcher.GetUpdatedFilesIn(dirPath)
+	}
+
+	switch {
+	case errors.Is(err, file.ErrPartialScan):
+		// the readable files are synced anyway
+		s.logger.Error("failed to read some paths", "dir", dirPath, "error", err)
+		s.reportError(dirPath, err)
+		s.dirBack(dirPath)
+	case errors.Is(err, fs.ErrNotExist):
+		s.dirGone(dirPath)
+
+		return nil, false
+	case err != nil:
+		s.logger.Error("failed to get updated files", "dir", dirPath, "error", err)
+		s.stats.failed()
+		s.reportError(dirPath, err)
+
+		return nil, false
+	default:
+		s.resolveError(dirPath)
+		s.dirBack(dirPath)
+	}
+
+	return files, true
+}
+
+// dispatch hands files to the workers until the sync is paused or stopped and returns how many it handed.
+func (s *SyncService) dispatch(files []string, jobs chan<- string) int {
+	for i, path := range files {
+		// paused during the batch, the rest is forgotten by the caller
+		if s.paused.Load() {
+			return i
+		}
+
+		// blocks while uploads of any directory fill the queue
+		if !s.enqueue() {
+			return i
+		}
+
+		select {
+		case <-s.ctx.Done():
+			s.dequeue()
+
+			return i
+		case jobs <- path:
+		}
+	}
+
+	return len(files)
+}
+
+// syncJob syncs a file of the batch of dirPath taken by a worker and decides whether and when it is retried.
+func (s *SyncService) syncJob(chatID, dirPath, path string, replyTo int64, noCaption bool) {
+	// reported again after a restart, it is retried only once it changed
+	if s.deadLettered(path) {
+		s.dequeue()
+
+		return
+	}
+
+	err := s.syncFile(chatID, dirPath, path, replyTo, false, noCaption)
+	s.dequeue()
+
+	if err != nil && s.ctx.Err() != nil {
+		// cancelled by Stop, uploaded again after a restart
+		s.watcher.Forget(path)
+
+		return
+	}
+
+	if errors.Is(err, ErrQuotaExceeded) {
+		// not a failure of the file, it is synced again once the window resets
+		s.watcher.Forget(path)
+		s.warnQuota(err)
+
+		return
+	}
+
+	if err != nil && vanished(path, err) {
+		// deleted or moved since the scan, not a failure, a new path is found by the next scan
+		s.logger.Info("file gone before upload, skipped", "dir", dirPath, "file", path)
+		s.watcher.Forget(path)
+
+		err = nil
+	}
+
+	if err != nil {
+		s.syncFailed(dirPath, path, err)
+
+		return
+	}
+
+	s.resetFailures(path)
+	s.resolveError(path)
+}
+
+// syncFailed records the failed sync of path and schedules its retry.
+func (s *SyncService) syncFailed(dirPath, path string, err error) {
+	s.logger.Error("failed to sync file", "dir", dirPath, "file", path, "error", err,
+		"retry", telegram.IsRetryable(err))
+	s.stats.failed()
+
+	switch {
+	case !telegram.IsRetryable(err):
+		// e.g. a file over the limit, retried only once it changes
+		s.reportError(path, err)
+	case s.giveUp(path, err):
+		// alerted by giveUp, retried only once it changes
+		s.resolveError(path)
+	default:
+		// retried on the next tick
+		s.watcher.Forget(path)
+		s.reportError(path, err)
+	}
+}
+
+// vanished reports whether err is caused by the file at path not existing anymore.
+func vanished(path string, err error) bool {
+	if !errors.Is(err, fs.ErrNotExist) {
+		return false
+	}
+
+	_, err = os.Lstat(path)
+
+	return errors.Is(err, fs.ErrNotExist)
+}
+
+// SyncFile uploads a single file to the default chat with the send method matching its kind.
+func (s *SyncService) SyncFile(filePath string) error {
+	return s.syncFile(s.chatID, filepath.Dir(filePath), filePath, 0, false, false)
+}
+
+// postFolderHeader sends the header message of a batch of n files and returns its id,
+// 0 when threading is disabled or the header could not be sent.
+func (s *SyncService) postFolderHeader(chatID, dirPath string, n int) int64 {
+	if !s.replyThreads || n == 0 {
+		return 0
+	}
+
+	text := fmt.Sprintf("Directory: %s (%d files)", dirPath, n)
+
+	if s.dryRun {
+		s.logger.Info("dry run: would send folder header", "dir", dirPath, "chat", chatID, "text", text)
+
+		return 0
+	}
+
+	msg, err := s.bot.SendMessage(s.ctx, chatID, text, append(s.sendOptions(0), s.topicOptions(chatID, dirPath)...)...)
+	if err != nil {
+		s.logger.Error("failed to send folder header", "dir", dirPath, "error", err)
+
+		return 0
+	}
+
+	return msg.MessageID
+}
+
+// upload is a file on its way from a watched directory to a chat, see syncFile.
+type upload struct {
+	chatID, root string
+	// localPath is the watched file, the index keeps it,
+	// filePath the one sent, its compressed or encrypted copy
+	localPath, filePath string
+	info                os.FileInfo
+	data                *CaptionData
+	kind                SendKind
+	caption             string
+	replyTo             int64
+	entry               IndexEntry
+}
+
+// syncFile uploads filePath of the watched directory root to chatID, as a reply to replyTo if not 0.
+// force uploads the file even when only its caption would be edited, see SetEditOnResync,
+// noCaption sends it without a caption.
+func (s *SyncService) syncFile(chatID, root, filePath string, replyTo int64, force, noCaption bool) error {
+	up, err := s.prepareUpload(chatID, root, filePath, replyTo, noCaption)
+	if err != nil {
+		return err
+	}
+
+	if s.dryRun {
+		s.logger.Info("dry run: would sync file",
+			"file", filePath, "chat", chatID, "kind", up.kind.String(), "size", up.info.Size(), "caption", up.caption)
+
+		return nil
+	}
+
+	if !force {
+		if edited, err := s.editResyncedCaption(chatID, filePath, up.caption, up.info); edited || err != nil {
+			return err
+		}
+	}
+
+	// Restore verifies the download against it
+	if up.entry.Hash, err = up.data.Hash(); err != nil {
+		return err
+	}
+
+	if s.reuseUploaded(up) {
+		return nil
+	}
+
+	window, err := s.reserveUpload(up.info.Size())
+	if err != nil {
+		return err
+	}
+
+	if s.chunkSize > 0 && up.info.Size() > s.chunkSize {
+		return s.syncChunkedUpload(up, window)
+	}
+
+	cleanup, err := s.transformUpload(up)
+	defer cleanup()
+
+	if err != nil {
+		s.refundUpload(up.info.Size(), window)
+
+		return err
+	}
+
+	start := time.Now()
+
+	msg, err := s.sendUpload(up)
+	if err != nil {
+		s.refundUpload(up.info.Size(), window)
+
+		return fmt.Errorf("send %s as %s: %w", up.filePath, up.kind, err)
+	}
+
+	// the bytes count once Telegram accepted the file, even if its message turns out to be wrong
+	return s.recordUpload(up, msg, time.Since(start))
+}
+
+// prepareUpload finds the kind, the caption and the index entry of filePath.
+func (s *SyncService) prepareUpload(chatID, root, filePath string, replyTo int64, noCaption bool) (*upload, error) {
+	fileInfo, err := os.Stat(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("stat %s: %w", filePath, err)
+	}
+
+	kind, err := s.sendKind(root, filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	// the path below the watched directory tells apart files with the same name in different folders
+	relPath := relativePath(root, filePath)
+
+	data := newCaptionData(filePath, relPath, fileInfo)
+
+	caption, err := s.caption(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if noCaption {
+		caption = ""
+	}
+
+	return &upload{
+		chatID: chatID, root: root, localPath: filePath, filePath: filePath,
+		info: fileInfo, data: data, kind: kind, caption: caption, replyTo: replyTo,
+		entry: IndexEntry{ChatID: chatID, Root: root, RelPath: s.indexedRelPath(relPath), Instance: s.instance},
+	}, nil
+}
+
+// reuseUploaded reports whether the file was synced without an upload,
+// as a rename or a duplicate of an already uploaded file.
+func (s *SyncService) reuseUploaded(up *upload) bool {
+	if s.detectRenames && s.detectRename(up.chatID, up.localPath, up.entry.Hash, up.caption, up.entry) {
+		return true
+	}
+
+	return s.dedup != nil && s.sendDuplicate(up.chatID, up.localPath, up.entry.Hash, up.caption, up.entry, up.replyTo)
+}
+
+// syncChunkedUpload uploads the file over the chunk size in parts, see syncChunked.
+// window is the quota window of its reservation.
+func (s *SyncService) syncChunkedUpload(up *upload, window time.Time) error {
+	sent, err := s.syncChunked(up.chatID, up.localPath, up.caption, up.entry, up.info, up.replyTo)
+
+	// only the chunks sent now count, the ones kept from an earlier attempt were counted by it
+	if unsent := up.info.Size() - sent; unsent > 0 {
+		s.refundUpload(unsent, window)
+	}
+
+	return err
+}
+
+// transformUpload replaces the sent file with its compressed and then its encrypted copy, when enabled.
+// The returned func removes the copies, it is never nil.
+func (s *SyncService) transformUpload(up *upload) (func(), error) {
+	var temps []string
+
+	cleanup := func() {
+		for _, path := range temps {
+			os.RemoveAll(path)
+		}
+	}
+
+	if s.compress && up.kind == KindDocument && compression.IsCompressible(up.filePath) {
+		tmpDir, err := os.MkdirTemp("", "tgcloudbot-")
+		if err != nil {
+			return cleanup, fmt.Errorf("create temp dir: %w", err)
+		}
+
+		temps = append(temps, tmpDir)
+
+		if up.filePath, err = compression.GzipFile(up.filePath, tmpDir); err != nil {
+			return cleanup, err
+		}
+
+		up.entry.Gzip = true
+	}
+
+	if s.encryptionKey != nil {
+		encPath, err := encryption.EncryptFile(s.encryptionKey, up.filePath, "")
+		if err != nil {
+			return cleanup, err
+		}
+
+		temps = append(temps, encPath)
+
+		// the original name is inside the encrypted file, don't leak it in the caption
+		up.filePath, up.kind, up.caption = encPath, KindDocument, ""
+		up.entry.Encrypted = true
+	}
+
+	return cleanup, nil
+}
+
+// sendUpload sends the file with the options of its kind, in place of its resynced message if enabled.
+func (s *SyncService) sendUpload(up *upload) (*telegram.Message, error) {
+	opts := s.fileOptions(up.chatID, up.root, up.replyTo)
+
+	// an encrypted upload must not come with a readable preview
+	if s.siblingThumbnails && !up.entry.Encrypted && (up.kind == KindVideo || up.kind == KindDocument) {
+		if thumb := s.siblingThumbnail(up.localPath); thumb != "" {
+			opts = append(opts, telegram.Thumbnail(thumb))
+		}
+	}
+
+	if s.audioTagsFromName && up.kind == KindAudio {
+		performer, title := audioTags(up.localPath)
+		opts = append(opts, telegram.AudioInfo(performer, title, 0))
+	}
+
+	stopAction := s.showUploadAction(up.chatID, up.kind)
+	defer stopAction()
+
+	if msg := s.replaceResynced(up.chatID, up.localPath, up.filePath, up.kind, up.caption, opts); msg != nil {
+		return msg, nil
+	}
+
+	return s.sendByKind(up.chatID, up.filePath, up.kind, up.caption, opts)
+}
+
+// recordUpload checks the message of the sent file and records it in the stats and the indexes.
+func (s *SyncService) recordUpload(up *upload, msg *telegram.Message, elapsed time.Duration) error {
+	if err := verifyUploadSize(up.filePath, msg); err != nil {
+		return err
+	}
+
+	s.stats.uploaded(up.info.Size())
+	s.metrics.FileSynced(up.info.Size(), elapsed)
+
+	up.entry.MessageID, up.entry.FileID, up.entry.Kind = msg.MessageID, fileIDOf(msg), up.kind
+	s.mirror(&up.entry, up.localPath, up.caption)
+
+	if err := s.index.Put(up.localPath, up.entry); err != nil {
+		s.logger.Error("failed to update index", "file", up.localPath, "error", err)
+	}
+
+	if s.dedup != nil {
+		if err := s.dedup.Put(up.entry.Hash, up.entry); err != nil {
+			s.logger.Error("failed to update dedup index", "file", up.localPath, "error", err)
+		}
+	}
+
+	return nil
+}
+
+// sendByKind uploads the file with the send method of kind.
+func (s *SyncService) sendByKind(
+	chatID, filePath string, kind SendKind, caption string, opts []telegram.SendOption,
+) (*telegram.Message, error) {
+	switch kind {
+	case KindPhoto:
+		return s.bot.SendPhoto(s.ctx, chatID, filePath, caption, opts...)
+	case KindAudio:
+		return s.bot.SendAudio(s.ctx, chatID, filePath, caption, opts...)
+	case KindVideo:
+		return s.bot.SendVideo(s.ctx, chatID, filePath, caption, opts...)
+	case KindVoice:
+		return s.bot.SendVoice(s.ctx, chatID, filePath, caption, opts...)
+	case KindAnimation:
+		return s.bot.SendAnimation(s.ctx, chatID, filePath, caption, opts...)
+	default:
+		return s.bot.SendDocument(s.ctx, chatID, filePath, caption, opts...)
+	}
+}
+
+// replaceResynced uploads the file at filePath, the upload of localPath, in place of the file of the indexed
+// message of localPath, see SetReplaceOnResync. It returns nil when the file has to be sent as a new message.
+func (s *SyncService) replaceResynced(
+	chatID, localPath, filePath string, kind SendKind, caption string, opts []telegram.SendOption,
+) *telegram.Message {
+	if !s.replaceOnResync || kind == KindVoice {
+		return nil
+	}
+
+	entry, ok := s.indexEntry(localPath)
+	if !ok || entry.ChatID != chatID || entry.MessageID == 0 || entry.Kind == KindVoice || len(entry.Chunks) > 0 {
+		return nil
+	}
+
+	msg, err := s.bot.EditMessageMedia(s.ctx, chatID, entry.MessageID, kind.String(), filePath, caption, opts...)
+	if err != nil {
+		// e.g. the message was deleted or belongs to an album of another kind
+		s.logger.Warn("failed to replace file in its message, sending it again", "file", localPath, "error", err)
+
+		return nil
+	}
+
+	return msg
+}
+
+// editResyncedCaption updates the caption of the message of an already uploaded file
+// instead of uploading it again, see SetEditOnResync.
+// It reports false when the file has to be uploaded.
+func (s *SyncService) editResyncedCaption(chatID, filePath, caption string, fileInfo os.FileInfo) (bool, error) {
+	if !s.editOnResync || s.encryptionKey != nil {
+		return false, nil
+	}
+
+	entry, ok := s.indexEntry(filePath)
+	if !ok || entry.ChatID != chatID {
+		return false, nil
+	}
+
+	if caption != "" {
+		caption += "\n"
+	}
+
+	caption += "Updated: " + fileInfo.ModTime().Format(time.DateTime)
+
+	_, err := s.bot.EditMessageCaption(s.ctx, chatID, entry.MessageID, caption)
+	if err == nil || errors.Is(err, telegram.ErrMessageNotModified) {
+		return true, nil
+	}
+
+	// e.g. the message was deleted, upload the file again
+	s.logger.Warn("failed to edit caption, uploading again", "file", filePath, "error", err)
+
+	return false, nil
+}
+
+// DeleteFileMessage deletes the message the local file was last uploaded as, and its copies in the mirror chats,
+// and drops it from the index.
+// Messages older than 48 hours can't be deleted by bots, see telegram.ErrMessageCantBeDeleted,
+// their index entry is kept.
+func (s *SyncService) DeleteFileMessage(filePath string) error {
+	entry, ok := s.indexEntry(filePath)
+	if !ok {
+		return fmt.Errorf("%s: %w", filePath, ErrNotIndexed)
+	}
+
+	if s.dryRun {
+		s.logger.Info("dry run: would delete message", "file", filePath, "chat", entry.ChatID, "message", entry.MessageID)
+
+		return nil
+	}
+
+	if err := s.bot.DeleteMessage(s.ctx, entry.ChatID, entry.MessageID); err != nil {
+		return fmt.Errorf("delete message of %s: %w", filePath, err)
+	}
+
+	var errs []error
+
+	for _, chunk := range entry.Chunks[min(1, len(entry.Chunks)):] {
+		if err := s.bot.DeleteMessage(s.ctx, entry.ChatID, chunk.MessageID); err != nil {
+			errs = append(errs, fmt.Errorf("delete chunk of %s: %w", filePath, err))
+		}
+	}
+
+	for chatID, messageID := range entry.Mirrors {
+		if err := s.bot.DeleteMessage(s.ctx, chatID, messageID); err != nil {
+			errs = append(errs, fmt.Errorf("delete message of %s in %s: %w", filePath, chatID, err))
+		}
+	}
+
+	return errors.Join(append(errs, s.index.Delete(filePath))...)
+}
+
+// ForwardFile sends the already uploaded local file to chatID by its file_id, without uploading it again.
+// The index keeps the original message.
+func (s *SyncService) ForwardFile(filePath, chatID string) error {
+	entry, ok := s.indexEntry(filePath)
+	if !ok || entry.FileID == "" {
+		return fmt.Errorf("%s: %w", filePath, ErrNotIndexed)
+	}
+
+	relPath := entry.RelPath
+	if relPath == "" {
+		relPath = filepath.Base(filePath)
+	} else if s.instanceInPath && entry.Instance != "" {
+		relPath = strings.TrimPrefix(relPath, entry.Instance+"/")
+	}
+
+	// the file may be gone, the caption is rendered with what is known then
+	info, _ := os.Stat(filePath)
+	data := newCaptionData(filePath, relPath, info)
+	data.hash = entry.Hash
+
+	caption, err := s.caption(data)
+	if err != nil {
+		return err
+	}
+
+	if entry.Encrypted {
+		caption = ""
+	}
+
+	if s.dryRun {
+		s.logger.Info("dry run: would forward file", "file", filePath, "chat", chatID, "kind", entry.Kind.String())
+
+		return nil
+	}
+
+	if _, err := s.sendByRef(chatID, entry.Kind, entry.FileID, caption, s.fileOptions(chatID, entry.Root, 0)); err != nil {
+		return fmt.Errorf("forward %s: %w", filePath, err)
+	}
+
+	return nil
+}
+
+// sendByRef sends the file_id with the send method of kind.
+func (s *SyncService) sendByRef(
+	chatID string, kind SendKind, fileID, caption string, opts []telegram.SendOption,
+) (*telegram.Message, error) {
+	switch kind {
+	case KindPhoto:
+		return s.bot.SendPhotoByRef(s.ctx, chatID, fileID, caption, opts...)
+	case KindAudio:
+		return s.bot.SendAudioByRef(s.ctx, chatID, fileID, caption, opts...)
+	case KindVideo:
+		return s.bot.SendVideoByRef(s.ctx, chatID, fileID, caption, opts...)
+	case KindVoice:
+		return s.bot.SendVoiceByRef(s.ctx, chatID, fileID, caption, opts...)
+	case KindAnimation:
+		return s.bot.SendAnimationByRef(s.ctx, chatID, fileID, caption, opts...)
+	default:
+		return s.bot.SendDocumentByRef(s.ctx, chatID, fileID, caption, opts...)
+	}
+}
+
+// MessageFor returns the message the local file was last uploaded as.
+func (s *SyncService) MessageFor(filePath string) (IndexEntry, bool) {
+	return s.indexEntry(filePath)
+}
+
+// relativePath returns the slash-separated path of filePath below root, its base name when it is not below root.
+func relativePath(root, filePath string) string {
+	rel, err := filepath.Rel(root, filePath)
+	if err != nil || !filepath.IsLocal(rel) {
+		return filepath.Base(filePath)
+	}
+
+	return filepath.ToSlash(rel)
+}
+
+// verifyUploadSize compares the size of the uploaded file with the one reported in msg,
+// sizes that are not reported are not checked.
+func verifyUploadSize(filePath string, msg *telegram.Message) error {
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return fmt.Errorf("stat %s: %w", filePath, err)
+	}
+
+	size, exact := reportedSize(msg)
+	if size == 0 || !exact || size == info.Size() {
+		return nil
+	}
+
+	return fmt.Errorf("%s: %w: sent %d bytes, telegram reports %d", filePath, ErrSizeMismatch, info.Size(), size)
+}
+
+// reportedSize returns the file size reported in msg. Photos are re-encoded by Telegram,
+// so their largest size is returned as not exact.
+func reportedSize(msg *telegram.Message) (size int64, exact bool) {
+	switch {
+	case msg.Animation != nil:
+		return msg.Animation.FileSize, true
+	case msg.Document != nil:
+		return msg.Document.FileSize, true
+	case msg.Audio != nil:
+		return msg.Audio.FileSize, true
+	case msg.Video != nil:
+		return msg.Video.FileSize, true
+	case msg.Voice != nil:
+		return msg.Voice.FileSize, true
+	case len(msg.Photo) > 0:
+		for _, photo := range msg.Photo {
+			size = max(size, photo.FileSize)
+		}
+
+		return size, false
+	default:
+		return 0, false
+	}
+}
+
+// sendKind returns the send method of filePath of the watched directory root.
+func (s *SyncService) sendKind(root, filePath string) (SendKind, error) {
+	s.mu.Lock()
+	classifier, ok := s.dirClassifiers[filepath.Clean(root)]
+	s.mu.Unlock()
+
+	if ok {
+		return classifier.Classify(filePath)
+	}
+
+	kind, err := s.classifier.Classify(filePath)
+	if err != nil {
+		return kind, err
+	}
+
+	if kind == KindPhoto {
+		tooLarge, err := s.photoTooLarge(filePath)
+		if err != nil {
+			return kind, err
+		}
+
+		if tooLarge {
+			return KindDocument, nil
+		}
+	}
+
+	if s.preferVoice && kind == KindAudio && isVoiceExtension(filePath) {
+		return KindVoice, nil
+	}
+
+	return kind, nil
+}
+
+// ProgressLogger logs the progress of large uploads, to be passed to telegram.WithProgress.
+func ProgressLogger(logger *slog.Logger) telegram.ProgressFunc {
+	return func(name string, sent, total int64) {
+		if total < progressMinSize {
+			return
+		}
+
+		logger.Info("uploading", "file", name, "percent", sent*100/total)
+	}
+}
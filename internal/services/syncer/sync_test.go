@@ -0,0 +1,746 @@
+package syncer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/jpeg"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/k0ff1l/tgcloudbot/internal/services/file"
+	"github.com/k0ff1l/tgcloudbot/internal/services/state"
+	"github.com/k0ff1l/tgcloudbot/internal/services/telegram"
+	"github.com/k0ff1l/tgcloudbot/internal/services/telegram/telegramtest"
+)
+
+func TestDryRunMakesNoCalls(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "a.png", []byte("\x89PNG\r\n\x1a\n"))
+
+	watcher := file.NewWatcher()
+	if err := watcher.AddDir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	// a nil bot panics on any call
+	s := NewSyncService(nil, watcher, WithChatID("chat"))
+	s.SetDryRun(true, true)
+
+	s.syncDirectoryOnce(dir)
+
+	files, err := watcher.GetUpdatedFilesIn(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(files) != 1 {
+		t.Errorf("dry run with keep state must not advance the watcher, got %v", files)
+	}
+}
+
+func TestStopIsIdempotent(t *testing.T) {
+	s := NewSyncService(nil, file.NewWatcher(), WithChatID("chat"))
+	s.SetDryRun(true, false)
+
+	if err := s.StartContinuousSync(t.TempDir(), time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	s.Stop()
+	s.Stop()
+}
+
+func TestStartAfterStop(t *testing.T) {
+	s := NewSyncService(nil, file.NewWatcher(), WithChatID("chat"))
+	s.Stop()
+
+	err := s.StartContinuousSync(t.TempDir(), time.Hour)
+	if !errors.Is(err, ErrServiceStopped) {
+		t.Fatalf("expected ErrServiceStopped, got %v", err)
+	}
+
+	// nothing must be left running
+	s.Stop()
+}
+
+func TestPeriodicSummary(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "a.txt", []byte("hello"))
+
+	bot := telegramtest.NewFakeClient()
+	s := NewSyncService(bot, file.NewWatcher(), WithChatID("chat"))
+
+	if err := s.StartContinuousSync(dir, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	waitFor(t, func() bool { return len(bot.Uploaded()) == 1 })
+
+	if err := s.StartPeriodicSummary(20*time.Millisecond, false); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(bot.Texts()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	s.Stop()
+
+	msgs := bot.Texts()
+	if len(msgs) == 0 {
+		t.Fatal("no summary was sent")
+	}
+
+	if !strings.Contains(msgs[0], "files uploaded: 1") || !strings.Contains(msgs[0], "bytes transferred: 5") ||
+		!strings.Contains(msgs[0], "directories watched: 1") {
+		t.Errorf("unexpected summary: %q", msgs[0])
+	}
+
+	// the counters are reset after every summary
+	if len(msgs) > 1 && !strings.Contains(msgs[1], "files uploaded: 0") {
+		t.Errorf("counters were not reset: %q", msgs[1])
+	}
+
+	// nothing is sent after Stop
+	time.Sleep(50 * time.Millisecond)
+
+	if len(bot.Texts()) != len(msgs) {
+		t.Error("summary kept running after Stop")
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestSyncFileEncrypted(t *testing.T) {
+	path := writeFile(t, t.TempDir(), "photo.png", []byte("\x89PNG\r\n\x1a\n"))
+
+	bot := telegramtest.NewFakeClient()
+	s := NewSyncService(bot, file.NewWatcher(), WithChatID("chat"))
+	s.SetEncryptionKey(make([]byte, 32))
+
+	if err := s.SyncFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	sent := bot.Uploaded()
+	if len(sent) != 1 || !strings.HasSuffix(sent[0], ".enc") {
+		t.Fatalf("expected an encrypted temp file to be sent, got %v", sent)
+	}
+
+	if _, err := os.Stat(sent[0]); !os.IsNotExist(err) {
+		t.Errorf("temp file %s was not removed", sent[0])
+	}
+}
+
+func TestSyncFileCompressed(t *testing.T) {
+	dir := t.TempDir()
+	logPath := writeFile(t, dir, "app.log", []byte(strings.Repeat("line\n", 100)))
+	pngPath := writeFile(t, dir, "photo.png", []byte("\x89PNG\r\n\x1a\n"))
+
+	bot := telegramtest.NewFakeClient()
+	s := NewSyncService(bot, file.NewWatcher(), WithChatID("chat"))
+	s.SetCompression(true)
+
+	for _, path := range []string{logPath, pngPath} {
+		if err := s.SyncFile(path); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	sent := bot.Uploaded()
+	if len(sent) != 2 || filepath.Base(sent[0]) != "app.log.gz" || sent[1] != pngPath {
+		t.Errorf("expected only the log to be gzipped, got %v", sent)
+	}
+}
+
+func TestReplyThreads(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "a.txt", []byte("a"))
+	writeFile(t, dir, "b.txt", []byte("b"))
+
+	watcher := file.NewWatcher()
+	if err := watcher.AddDir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	bot := telegramtest.NewFakeClient()
+	s := NewSyncService(bot, watcher, WithChatID("chat"))
+	s.SetReplyThreads(true)
+
+	s.syncDirectoryOnce(dir)
+
+	if msgs := bot.Texts(); len(msgs) != 1 || !strings.Contains(msgs[0], dir) {
+		t.Fatalf("expected one folder header, got %q", msgs)
+	}
+
+	files := bot.CallsTo("SendDocument")
+	if len(files) != 2 || len(files[0].Options) == 0 || len(files[1].Options) == 0 {
+		t.Errorf("files must be sent as replies to the header, got %+v", files)
+	}
+
+	// no header for an empty batch
+	s.syncDirectoryOnce(dir)
+
+	if msgs := bot.Texts(); len(msgs) != 1 {
+		t.Errorf("unexpected header for an empty batch: %q", msgs)
+	}
+}
+
+func TestEditOnResync(t *testing.T) {
+	dir := t.TempDir()
+	path := writeFile(t, dir, "a.txt", []byte("a"))
+
+	bot := telegramtest.NewFakeClient()
+	s := NewSyncService(bot, file.NewWatcher(), WithChatID("chat"))
+	s.SetEditOnResync(true)
+
+	if err := s.SetIndexFile(filepath.Join(dir, "index.json")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.SyncFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	entry, ok := s.MessageFor(path)
+	if !ok || entry.ChatID != "chat" || entry.MessageID != 1 {
+		t.Fatalf("upload not indexed: %+v", entry)
+	}
+
+	if err := s.SyncFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	edits := bot.CallsTo("EditMessageCaption")
+	if len(bot.Uploaded()) != 1 || len(edits) != 1 || !strings.HasPrefix(edits[0].Caption, "File: a.txt\nUpdated: ") {
+		t.Errorf("re-sync must edit the caption, sent %v, edits %+v", bot.Uploaded(), edits)
+	}
+
+	// the index survives a restart
+	idx, err := state.NewFileStore[IndexEntry](filepath.Join(dir, "index.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, ok, _ := idx.Get(path); !ok || !reflect.DeepEqual(got, entry) {
+		t.Errorf("index not persisted: %+v", got)
+	}
+}
+
+func TestReplaceOnResync(t *testing.T) {
+	dir := t.TempDir()
+	path := writeFile(t, dir, "a.txt", []byte("a"))
+
+	bot := telegramtest.NewFakeClient()
+	s := NewSyncService(bot, file.NewWatcher(), WithChatID("chat"))
+	s.SetReplaceOnResync(true)
+
+	if err := s.SyncFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	writeFile(t, dir, "a.txt", []byte("ab"))
+
+	if err := s.SyncFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	edits := bot.CallsTo("EditMessageMedia")
+	if len(edits) != 1 || edits[0].MessageID != 1 || edits[0].Text != "document" || edits[0].Paths[0] != path {
+		t.Fatalf("expected the file to replace the first upload, got %+v", edits)
+	}
+
+	if sends := bot.CallsTo("SendDocument"); len(sends) != 1 {
+		t.Errorf("expected no new message, got %d uploads", len(sends))
+	}
+
+	if entry, ok := s.MessageFor(path); !ok || entry.MessageID != 1 {
+		t.Errorf("expected the index to keep the message, got %+v", entry)
+	}
+
+	// a deleted message can't be edited, the file is sent anew
+	bot.FailWith("EditMessageMedia", errors.New("message to edit not found"))
+
+	if err := s.SyncFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	if sends := bot.CallsTo("SendDocument"); len(sends) != 2 {
+		t.Errorf("expected the file to be sent again, got %d uploads", len(sends))
+	}
+}
+
+func TestDeleteFileMessage(t *testing.T) {
+	path := writeFile(t, t.TempDir(), "a.txt", []byte("a"))
+
+	bot := telegramtest.NewFakeClient()
+	s := NewSyncService(bot, file.NewWatcher(), WithChatID("chat"))
+
+	if err := s.DeleteFileMessage(path); !errors.Is(err, ErrNotIndexed) {
+		t.Fatalf("expected ErrNotIndexed, got %v", err)
+	}
+
+	if err := s.SyncFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.DeleteFileMessage(path); err != nil {
+		t.Fatal(err)
+	}
+
+	if deleted := bot.CallsTo("DeleteMessage"); len(deleted) != 1 || deleted[0].MessageID != 1 {
+		t.Errorf("unexpected deleted messages: %+v", deleted)
+	}
+
+	if _, ok := s.MessageFor(path); ok {
+		t.Error("deleted message is still indexed")
+	}
+}
+
+func TestPreferVoice(t *testing.T) {
+	dir := t.TempDir()
+	ogg := writeFile(t, dir, "note.ogg", []byte("OggS\x00\x02"))
+	mp3 := writeFile(t, dir, "song.mp3", []byte("ID3\x03"))
+
+	bot := telegramtest.NewFakeClient()
+	s := NewSyncService(bot, file.NewWatcher(), WithChatID("chat"))
+
+	if err := s.SyncFile(ogg); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(bot.CallsTo("SendVoice")) != 0 {
+		t.Fatal("ogg must be sent as audio by default")
+	}
+
+	s.SetPreferVoice(true)
+
+	for _, path := range []string{ogg, mp3} {
+		if err := s.SyncFile(path); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if voices := bot.CallsTo("SendVoice"); len(voices) != 1 || voices[0].Paths[0] != ogg {
+		t.Errorf("only the ogg file must be sent as voice, got %+v", voices)
+	}
+}
+
+func TestSyncFileRoutesPNGToSendPhoto(t *testing.T) {
+	path := writeFile(t, t.TempDir(), "photo.png", []byte("\x89PNG\r\n\x1a\n"))
+
+	bot := telegramtest.NewFakeClient()
+	s := NewSyncService(bot, file.NewWatcher(), WithChatID("chat"))
+
+	if err := s.SyncFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	calls := bot.Calls()
+	if len(calls) != 1 || calls[0].Method != "SendPhoto" || calls[0].ChatID != "chat" || calls[0].Paths[0] != path {
+		t.Fatalf("expected one SendPhoto call, got %+v", calls)
+	}
+
+	bot.FailWith("SendPhoto", errors.New("boom"))
+
+	if err := s.SyncFile(path); err == nil {
+		t.Error("expected the injected error")
+	}
+}
+
+func TestSyncFileSizeMismatch(t *testing.T) {
+	path := writeFile(t, t.TempDir(), "a.txt", []byte("hello"))
+
+	bot := telegramtest.NewFakeClient()
+	s := NewSyncService(bot, file.NewWatcher(), WithChatID("chat"))
+
+	bot.RespondWith("SendDocument", telegram.Message{MessageID: 1, Document: &telegram.Document{FileSize: 5}})
+
+	if err := s.SyncFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	bot.RespondWith("SendDocument", telegram.Message{MessageID: 2, Document: &telegram.Document{FileSize: 3}})
+
+	if err := s.SyncFile(path); !errors.Is(err, ErrSizeMismatch) {
+		t.Fatalf("expected ErrSizeMismatch, got %v", err)
+	}
+
+	if entry, _ := s.MessageFor(path); entry.MessageID != 1 {
+		t.Errorf("a mismatched upload must not be indexed, got %+v", entry)
+	}
+}
+
+func TestFailedSyncIsRetried(t *testing.T) {
+	dir := t.TempDir()
+	path := writeFile(t, dir, "a.txt", []byte("hello"))
+
+	watcher := file.NewWatcher()
+	if err := watcher.AddDir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	bot := telegramtest.NewFakeClient()
+	s := NewSyncService(bot, watcher, WithChatID("chat"))
+
+	bot.RespondWith("SendDocument", telegram.Message{Document: &telegram.Document{FileSize: 1}})
+	s.syncDirectoryOnce(dir)
+
+	bot.RespondWith("SendDocument", telegram.Message{Document: &telegram.Document{FileSize: 5}})
+	s.syncDirectoryOnce(dir)
+	s.syncDirectoryOnce(dir)
+
+	if uploaded := bot.Uploaded(); len(uploaded) != 2 || uploaded[1] != path {
+		t.Errorf("expected one retry after the mismatch, got %v", uploaded)
+	}
+}
+
+// removingBot deletes remove when the first document is sent.
+type removingBot struct {
+	*telegramtest.FakeClient
+
+	remove string
+}
+
+func (b *removingBot) SendDocument(
+	ctx context.Context, chatID, filePath, caption string, opts ...telegram.SendOption,
+) (*telegram.Message, error) {
+	if b.remove != "" {
+		_ = os.Remove(b.remove)
+		b.remove = ""
+	}
+
+	return b.FakeClient.SendDocument(ctx, chatID, filePath, caption, opts...)
+}
+
+func TestFileGoneBeforeUploadIsSkipped(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "a.txt", []byte("a"))
+	gone := writeFile(t, dir, "b.txt", []byte("b"))
+
+	watcher := file.NewWatcher()
+	if err := watcher.AddDir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	// b.txt is in the batch already when a.txt is uploaded
+	bot := &removingBot{FakeClient: telegramtest.NewFakeClient(), remove: gone}
+	s := NewSyncService(bot, watcher, WithChatID("chat"))
+	s.SetErrorAlerts("alerts", time.Hour)
+	s.SetRetryBudget(1)
+
+	s.syncDirectoryOnce(dir)
+	s.syncDirectoryOnce(dir)
+
+	if uploaded := bot.Uploaded(); len(uploaded) != 1 {
+		t.Errorf("expected a.txt only to be uploaded, got %v", uploaded)
+	}
+
+	if texts := bot.Texts(); len(texts) != 0 {
+		t.Errorf("expected no alerts, got %q", texts)
+	}
+
+	if n := s.stats.errors.Load(); n != 0 {
+		t.Errorf("expected no failures to be counted, got %d", n)
+	}
+
+	if letters, _ := s.DeadLetters(); len(letters) != 0 {
+		t.Errorf("expected nothing dead-lettered, got %v", letters)
+	}
+}
+
+// cancellingBot cancels the service while the first document is uploaded.
+type cancellingBot struct {
+	*telegramtest.FakeClient
+
+	cancel context.CancelFunc
+}
+
+func (b *cancellingBot) SendDocument(
+	ctx context.Context, _, _, _ string, _ ...telegram.SendOption,
+) (*telegram.Message, error) {
+	b.cancel()
+	<-ctx.Done()
+
+	return nil, ctx.Err()
+}
+
+func TestStopCancelsUpload(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "a.txt", []byte("hello"))
+
+	watcher := file.NewWatcher()
+	if err := watcher.AddDir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	bot := &cancellingBot{FakeClient: telegramtest.NewFakeClient(), cancel: cancel}
+	s := NewSyncService(bot, watcher, WithChatID("chat"), WithContext(ctx))
+	s.SetErrorAlerts("alerts", time.Hour)
+
+	s.syncDirectoryOnce(dir)
+
+	if n := s.stats.errors.Load(); n != 0 {
+		t.Errorf("expected the cancelled upload not to count as a failure, got %d", n)
+	}
+
+	if texts := bot.Texts(); len(texts) != 0 {
+		t.Errorf("expected no alerts, got %q", texts)
+	}
+
+	files, err := watcher.GetUpdatedFilesIn(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(files) != 1 {
+		t.Errorf("expected the cancelled file to be synced again, got %v", files)
+	}
+}
+
+func TestFileTooLargeIsNotRetried(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "a.txt", []byte("hello"))
+
+	watcher := file.NewWatcher()
+	if err := watcher.AddDir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	bot := telegramtest.NewFakeClient()
+	bot.FailWith("SendDocument", &telegram.APIError{Code: http.StatusRequestEntityTooLarge})
+
+	s := NewSyncService(bot, watcher, WithChatID("chat"))
+	s.syncDirectoryOnce(dir)
+	s.syncDirectoryOnce(dir)
+
+	if uploaded := bot.Uploaded(); len(uploaded) != 1 {
+		t.Errorf("a file over the limit must not be retried until it changes, got %v", uploaded)
+	}
+}
+
+func TestForwardFile(t *testing.T) {
+	path := writeFile(t, t.TempDir(), "photo.png", []byte("\x89PNG\r\n\x1a\n"))
+
+	bot := telegramtest.NewFakeClient()
+	bot.RespondWith("SendPhoto", telegram.Message{MessageID: 1, Photo: []telegram.PhotoSize{{FileID: "photo"}}})
+
+	s := NewSyncService(bot, file.NewWatcher(), WithChatID("chat"))
+
+	if err := s.ForwardFile(path, "mirror"); !errors.Is(err, ErrNotIndexed) {
+		t.Fatalf("expected ErrNotIndexed, got %v", err)
+	}
+
+	if err := s.SyncFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.ForwardFile(path, "mirror"); err != nil {
+		t.Fatal(err)
+	}
+
+	calls := bot.CallsTo("SendPhotoByRef")
+	if len(calls) != 1 || calls[0].ChatID != "mirror" || calls[0].FileID != "photo" {
+		t.Errorf("expected the photo to be resent by file_id, got %+v", calls)
+	}
+
+	if entry, _ := s.MessageFor(path); entry.ChatID != "chat" {
+		t.Errorf("forwarding must keep the original message indexed, got %+v", entry)
+	}
+}
+
+func TestQuietHoursDisableNotification(t *testing.T) {
+	path := writeFile(t, t.TempDir(), "a.txt", []byte("a"))
+
+	bot := telegramtest.NewFakeClient()
+	s := NewSyncService(bot, file.NewWatcher(), WithChatID("chat"))
+	s.SetQuietHours(QuietHours{Start: 22 * time.Hour, End: 7 * time.Hour})
+
+	for _, clock := range []string{"12:00", "23:00"} {
+		now, _ := time.Parse("15:04", clock)
+		s.now = func() time.Time { return now }
+
+		if err := s.SyncFile(path); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	calls := bot.CallsTo("SendDocument")
+	if len(calls) != 2 || len(calls[0].Options) != 0 || len(calls[1].Options) != 1 {
+		t.Errorf("expected only the upload at 23:00 to be silent, got %+v", calls)
+	}
+}
+
+func TestSyncNowAndForceSync(t *testing.T) {
+	dir := t.TempDir()
+	path := writeFile(t, dir, "a.txt", []byte("a"))
+
+	bot := telegramtest.NewFakeClient()
+	s := NewSyncService(bot, file.NewWatcher(), WithChatID("chat"))
+	s.SetDirChatID(dir, "dir-chat")
+	s.SetEditOnResync(true)
+
+	if err := s.StartContinuousSync(dir, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	// races with the first sync of the loop, the file must still be uploaded once
+	if err := s.SyncNow(); err != nil {
+		t.Fatal(err)
+	}
+
+	waitFor(t, func() bool { return len(bot.Uploaded()) > 0 })
+
+	if err := s.SyncNow(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.ForceSync(path); err != nil {
+		t.Fatal(err)
+	}
+
+	s.Stop()
+
+	calls := bot.CallsTo("SendDocument", "EditMessageCaption")
+	if len(calls) != 2 || calls[1].Method != "SendDocument" || calls[1].ChatID != "dir-chat" {
+		t.Errorf("expected one upload and one forced upload to the directory chat, got %+v", calls)
+	}
+
+	if err := s.SyncNow(); !errors.Is(err, ErrServiceStopped) {
+		t.Errorf("expected ErrServiceStopped, got %v", err)
+	}
+}
+
+func TestSiblingThumbnails(t *testing.T) {
+	dir := t.TempDir()
+	video := writeFile(t, dir, "clip.mp4", []byte("video"))
+	plain := writeFile(t, dir, "other.mp4", []byte("video"))
+
+	var thumb bytes.Buffer
+	if err := jpeg.Encode(&thumb, image.NewGray(image.Rect(0, 0, 32, 32)), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	writeFile(t, dir, "clip.jpg", thumb.Bytes())
+
+	bot := telegramtest.NewFakeClient()
+	s := NewSyncService(bot, file.NewWatcher(), WithChatID("chat"), WithClassifier(ExtensionClassifier{}))
+	s.SetSiblingThumbnails(true)
+
+	for _, path := range []string{video, plain} {
+		if err := s.SyncFile(path); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	calls := bot.CallsTo("SendVideo")
+	if len(calls) != 2 || len(calls[0].Options) != 1 || len(calls[1].Options) != 0 {
+		t.Errorf("expected a thumbnail for clip.mp4 only, got %+v", calls)
+	}
+}
+
+func TestDirProtection(t *testing.T) {
+	protected, plain := t.TempDir(), t.TempDir()
+
+	bot := telegramtest.NewFakeClient()
+	s := NewSyncService(bot, file.NewWatcher(), WithChatID("chat"))
+	s.SetDirProtection(protected, Protection{ProtectContent: true, Spoiler: true})
+
+	for _, path := range []string{writeFile(t, protected, "a.txt", []byte("a")), writeFile(t, plain, "b.txt", []byte("b"))} {
+		if err := s.SyncFile(path); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	calls := bot.CallsTo("SendDocument")
+	if len(calls) != 2 || len(calls[0].Options) != 2 || len(calls[1].Options) != 0 {
+		t.Errorf("expected only the protected directory to be sent with flags, got %+v", calls)
+	}
+}
+
+func TestDirTopic(t *testing.T) {
+	topic, plain := t.TempDir(), t.TempDir()
+
+	// answers with file ids, the copies in the mirror chat are sent by them
+	bot := &storingBot{FakeClient: telegramtest.NewFakeClient()}
+	watcher := file.NewWatcher()
+	s := NewSyncService(bot, watcher, WithChatID("chat"))
+	s.SetDirTopic(topic, 7)
+	s.SetMirrorChats("mirror")
+
+	writeFile(t, topic, "a.txt", []byte("a"))
+	writeFile(t, plain, "b.txt", []byte("b"))
+
+	for _, dir := range []string{topic, plain} {
+		if err := watcher.AddDir(dir); err != nil {
+			t.Fatal(err)
+		}
+
+		s.syncDirectoryOnce(dir)
+	}
+
+	calls := bot.CallsTo("SendDocument")
+	if len(calls) != 2 || len(calls[0].Options) != 1 || len(calls[1].Options) != 0 {
+		t.Errorf("expected only the files of the topic directory to be sent into a topic, got %+v", calls)
+	}
+
+	// the topic belongs to the chat of the directory
+	mirrored := bot.CallsTo("SendDocumentByRef")
+	if len(mirrored) != 2 || len(mirrored[0].Options) != 0 || len(mirrored[1].Options) != 0 {
+		t.Errorf("expected the mirror chat copies without a topic, got %+v", mirrored)
+	}
+}
+
+func TestPartialScanStillSyncs(t *testing.T) {
+	dir := t.TempDir()
+	path := writeFile(t, dir, "a.txt", []byte("a"))
+
+	// can't be stat'ed even by root
+	if err := os.Symlink("loop", filepath.Join(dir, "loop")); err != nil {
+		t.Fatal(err)
+	}
+
+	watcher := file.NewWatcher()
+	watcher.FollowSymlinks = true
+
+	if err := watcher.AddDir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	bot := telegramtest.NewFakeClient()
+	s := NewSyncService(bot, watcher, WithChatID("chat"))
+	s.SetErrorAlerts("alerts", time.Hour)
+
+	s.syncDirectoryOnce(dir)
+
+	if uploads := bot.Uploaded(); len(uploads) != 1 || uploads[0] != path {
+		t.Errorf("expected the readable file to be synced, got %v", uploads)
+	}
+
+	// alerted once it repeats
+	s.syncDirectoryOnce(dir)
+
+	if alerts := bot.CallsTo("SendMessage"); len(alerts) != 1 || !strings.Contains(alerts[0].Text, "loop") {
+		t.Errorf("expected the unreadable path to be alerted, got %+v", alerts)
+	}
+}
@@ -0,0 +1,48 @@
+package telegram
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// floodGate holds back every request of a bot until the retry_after of the last flood limit passed,
+// so that concurrent senders don't run into the limit one after another. The zero value is open.
+type floodGate struct {
+	mu    sync.Mutex
+	until time.Time
+}
+
+// closeUntil keeps the gate closed until t, an earlier t doesn't shorten the wait.
+func (g *floodGate) closeUntil(t time.Time) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if t.After(g.until) {
+		g.until = t
+	}
+}
+
+// wait blocks until the gate is open or ctx is done.
+func (g *floodGate) wait(ctx context.Context) error {
+	for {
+		g.mu.Lock()
+		d := time.Until(g.until)
+		g.mu.Unlock()
+
+		if d <= 0 {
+			return nil
+		}
+
+		// the gate may have been closed again meanwhile, it is checked once more
+		timer := time.NewTimer(d)
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
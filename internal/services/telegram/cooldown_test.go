@@ -0,0 +1,134 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// floodServer answers the first request with a flood limit of one second and records when
+// the others arrive.
+func floodServer(t *testing.T, mu *sync.Mutex, times *[]time.Time) *httptest.Server {
+	t.Helper()
+
+	var requests atomic.Int64
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if requests.Add(1) == 1 {
+			_, _ = w.Write([]byte(`{"ok":false,"error_code":429,"description":"Too Many Requests: retry after 1",` +
+				`"parameters":{"retry_after":1}}`))
+
+			return
+		}
+
+		mu.Lock()
+		*times = append(*times, time.Now())
+		mu.Unlock()
+
+		_, _ = w.Write([]byte(`{"ok":true,"result":{"message_id":1}}`))
+	}))
+}
+
+// limit sends a first request that runs into the flood limit of the server and gives up waiting for it,
+// it returns when the limit was reached.
+func limit(t *testing.T, bot *IBot) time.Time {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(t.Context())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+
+	if _, err := bot.SendMessage(ctx, "chat", "first"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the wait for the flood limit to be cancelled, got %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("the cancelled wait took %s", elapsed)
+	}
+
+	return start
+}
+
+func TestFloodLimitIsWaitedFor(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		times []time.Time
+	)
+
+	srv := floodServer(t, &mu, &times)
+	defer srv.Close()
+
+	bot := NewBot("token", WithAPIURL(srv.URL+"/bot"))
+
+	start := time.Now()
+
+	if _, err := bot.SendMessage(t.Context(), "chat", "first"); err != nil {
+		t.Fatalf("expected the request to be sent again after the flood limit, got %v", err)
+	}
+
+	if len(times) != 1 || times[0].Sub(start) < 900*time.Millisecond {
+		t.Errorf("expected one retry after the flood limit of 1s, got %v after %s", times, start)
+	}
+}
+
+func TestFloodLimitHoldsBackAllRequests(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		times []time.Time
+	)
+
+	srv := floodServer(t, &mu, &times)
+	defer srv.Close()
+
+	bot := NewBot("token", WithAPIURL(srv.URL+"/bot"))
+	limited := limit(t, bot)
+
+	if len(times) != 0 {
+		t.Fatal("a cancelled request must not be sent again")
+	}
+
+	var wg sync.WaitGroup
+
+	for range 4 {
+		wg.Go(func() {
+			if _, err := bot.SendMessage(t.Context(), "chat", "next"); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+
+	wg.Wait()
+
+	if len(times) != 4 {
+		t.Fatalf("expected 4 requests after the limit, got %d", len(times))
+	}
+
+	// retry_after is counted from the answer, a little earlier than limited
+	for _, at := range times {
+		if wait := at.Sub(limited); wait < 900*time.Millisecond {
+			t.Errorf("a request was sent %s after the flood limit of 1s", wait)
+		}
+	}
+}
+
+func TestFloodLimitWithoutWaiting(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		times []time.Time
+	)
+
+	srv := floodServer(t, &mu, &times)
+	defer srv.Close()
+
+	bot := NewBot("token", WithAPIURL(srv.URL+"/bot"), WithMaxFloodWait(0))
+
+	if _, err := bot.SendMessage(t.Context(), "chat", "first"); !errors.Is(err, ErrTooManyRequests) {
+		t.Errorf("expected the flood limit, got %v", err)
+	}
+}
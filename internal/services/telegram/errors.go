@@ -0,0 +1,95 @@
+package telegram
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// The errors a failed call can be matched against with errors.Is, an *APIError matches the ones
+// of its code and description.
+var (
+	// ErrFileTooLarge is returned for files over the upload limit, before or after uploading them.
+	ErrFileTooLarge = errors.New("file is too large")
+	// ErrChatNotFound means the chat id is wrong or the bot was never added to the chat.
+	ErrChatNotFound = errors.New("chat not found")
+	// ErrForbidden means the bot was blocked, kicked or may not post in the chat.
+	ErrForbidden = errors.New("forbidden")
+	// ErrUnauthorized means the bot token is invalid.
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrBadRequest is any request Telegram rejected as invalid, sending it again fails the same way.
+	ErrBadRequest = errors.New("bad request")
+	// ErrTooManyRequests means the bot hit a flood limit, see APIError.RetryAfter.
+	ErrTooManyRequests = errors.New("too many requests")
+	// ErrNotEnoughRights means the bot lacks the admin right the request needs, e.g. to pin messages.
+	ErrNotEnoughRights = errors.New("not enough rights")
+)
+
+// APIError is returned when the Bot API answers with ok=false.
+type APIError struct {
+	Method      string
+	Code        int
+	Description string
+	Parameters  *ResponseParameters
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s failed: %d %s", e.Method, e.Code, e.Description)
+}
+
+// Is matches the error with the sentinel errors of its code and description.
+func (e *APIError) Is(target error) bool {
+	description := strings.ToLower(e.Description)
+
+	switch target {
+	case ErrFileTooLarge:
+		return e.Code == http.StatusRequestEntityTooLarge || strings.Contains(description, "file is too big")
+	case ErrChatNotFound:
+		return e.Code == http.StatusBadRequest && strings.Contains(description, "chat not found")
+	case ErrForbidden:
+		return e.Code == http.StatusForbidden
+	case ErrUnauthorized:
+		return e.Code == http.StatusUnauthorized
+	case ErrBadRequest:
+		return e.Code == http.StatusBadRequest
+	case ErrTooManyRequests:
+		return e.Code == http.StatusTooManyRequests
+	case ErrNotEnoughRights:
+		return strings.Contains(description, "not enough rights") ||
+			strings.Contains(description, "administrator rights") || strings.Contains(description, "chat_admin_required")
+	default:
+		return false
+	}
+}
+
+// Retryable reports whether the same request may succeed later: flood limits and server errors.
+func (e *APIError) Retryable() bool {
+	return e.Code == http.StatusTooManyRequests || e.Code >= http.StatusInternalServerError
+}
+
+// RetryAfter is how long to wait before retrying after a flood limit, 0 if not given.
+func (e *APIError) RetryAfter() time.Duration {
+	if e.Parameters == nil {
+		return 0
+	}
+
+	return time.Duration(e.Parameters.RetryAfter) * time.Second
+}
+
+// IsRetryable reports whether a failed call may succeed when retried. Errors of the Bot API are
+// retryable as reported by APIError.Retryable, files over the limit never are, and all other errors
+// (e.g. network failures) are.
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, ErrFileTooLarge) {
+		return false
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Retryable()
+	}
+
+	return true
+}
@@ -0,0 +1,89 @@
+package telegram
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAPIErrorIs(t *testing.T) {
+	tests := []struct {
+		code        int
+		description string
+		want        []error
+		retryable   bool
+	}{
+		{400, "Bad Request: chat not found", []error{ErrChatNotFound, ErrBadRequest}, false},
+		{400, "Bad Request: message text is empty", []error{ErrBadRequest}, false},
+		{400, "Bad Request: file is too big", []error{ErrFileTooLarge, ErrBadRequest}, false},
+		{401, "Unauthorized", []error{ErrUnauthorized}, false},
+		{403, "Forbidden: bot was blocked by the user", []error{ErrForbidden}, false},
+		{413, "Request Entity Too Large", []error{ErrFileTooLarge}, false},
+		{429, "Too Many Requests: retry after 5", []error{ErrTooManyRequests}, true},
+		{502, "Bad Gateway", nil, true},
+	}
+
+	all := []error{ErrChatNotFound, ErrBadRequest, ErrFileTooLarge, ErrUnauthorized, ErrForbidden, ErrTooManyRequests}
+
+	for _, tt := range tests {
+		// wrapped like the errors of the send methods
+		err := fmt.Errorf("send: %w", &APIError{Method: "sendDocument", Code: tt.code, Description: tt.description})
+
+		for _, target := range all {
+			want := false
+
+			for _, w := range tt.want {
+				want = want || w == target
+			}
+
+			if got := errors.Is(err, target); got != want {
+				t.Errorf("%d %q: errors.Is(%v) = %t, want %t", tt.code, tt.description, target, got, want)
+			}
+		}
+
+		if got := IsRetryable(err); got != tt.retryable {
+			t.Errorf("%d %q: retryable = %t, want %t", tt.code, tt.description, got, tt.retryable)
+		}
+	}
+
+	if !IsRetryable(errors.New("connection reset")) {
+		t.Error("network errors must be retryable")
+	}
+}
+
+func TestAPIErrorRetryAfter(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"ok":false,"error_code":429,"description":"Too Many Requests: retry after 7",` +
+			`"parameters":{"retry_after":7}}`))
+	}))
+	defer srv.Close()
+
+	// longer than the bot waits for
+	bot := NewBot("token", WithAPIURL(srv.URL+"/bot"), WithMaxFloodWait(5*time.Second))
+
+	_, err := bot.SendMessage(t.Context(), "chat", "hello")
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || !errors.Is(err, ErrTooManyRequests) || apiErr.RetryAfter() != 7*time.Second {
+		t.Errorf("expected a flood limit with retry after 7s, got %v", err)
+	}
+}
+
+func TestFileTooLargeBeforeUpload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "big.bin")
+	if err := os.WriteFile(path, make([]byte, 2048), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// no server, the size is checked before
+	bot := NewBot("token", WithAPIURL("http://127.0.0.1:1/bot"), WithMaxFileSize(1024))
+
+	if _, err := bot.SendDocument(t.Context(), "chat", path, ""); !errors.Is(err, ErrFileTooLarge) || IsRetryable(err) {
+		t.Errorf("expected a not retryable ErrFileTooLarge, got %v", err)
+	}
+}
@@ -0,0 +1,118 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// GetFileInfo [https://core.telegram.org/bots/api#getfile]
+func (b *IBot) GetFileInfo(ctx context.Context, fileID string) (*File, error) {
+	var file File
+
+	if err := b.callJSON(ctx, "getFile", GetFileRequest{FileID: fileID}, &file); err != nil {
+		return nil, err
+	}
+
+	return &file, nil
+}
+
+// DownloadFile writes the file at filePath (File.FilePath of GetFileInfo) to w.
+func (b *IBot) DownloadFile(ctx context.Context, filePath string, w io.Writer) error {
+	body, err := b.openFile(ctx, filePath)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	if _, err := io.Copy(w, body); err != nil {
+		return fmt.Errorf("download %s: %w", filePath, err)
+	}
+
+	return nil
+}
+
+// OpenFileByID streams the content of the file with fileID, the caller closes it.
+// Files of up to 20MB can be downloaded from the public Bot API, a local server has no limit.
+func (b *IBot) OpenFileByID(ctx context.Context, fileID string) (io.ReadCloser, error) {
+	info, err := b.GetFileInfo(ctx, fileID)
+	if err != nil {
+		return nil, err
+	}
+
+	// a server started with --local answers with the absolute path of its copy
+	if b.localFiles && filepath.IsAbs(info.FilePath) {
+		f, err := os.Open(info.FilePath)
+		if err != nil {
+			return nil, fmt.Errorf("open local copy: %w", err)
+		}
+
+		return f, nil
+	}
+
+	return b.openFile(ctx, info.FilePath)
+}
+
+// DownloadFileByID writes the file with fileID to destPath, which is removed again if the download fails.
+func (b *IBot) DownloadFileByID(ctx context.Context, fileID, destPath string) error {
+	body, err := b.OpenFileByID(ctx, fileID)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	f, err := os.Create(destPath)
+	if err != nil {
+		return fmt.Errorf("create %s: %w", destPath, err)
+	}
+
+	_, err = io.Copy(f, body)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		_ = os.Remove(destPath)
+
+		return fmt.Errorf("download %s to %s: %w", fileID, destPath, err)
+	}
+
+	return nil
+}
+
+// openFile requests the file at filePath from the file download endpoint.
+func (b *IBot) openFile(ctx context.Context, filePath string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, b.fileURL+b.token+"/"+escapePath(filePath), nil)
+	if err != nil {
+		return nil, fmt.Errorf("create download request: %w", err)
+	}
+
+	resp, err := b.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("download %s: %w", filePath, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		_ = resp.Body.Close()
+
+		return nil, fmt.Errorf("download %s: HTTP %d", filePath, resp.StatusCode)
+	}
+
+	return resp.Body, nil
+}
+
+// escapePath escapes every segment of the slash-separated path, so that e.g. a '?' or '#'
+// in a file name is not taken as the query or fragment of the URL.
+func escapePath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+
+	return strings.Join(segments, "/")
+}
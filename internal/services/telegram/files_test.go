@@ -0,0 +1,147 @@
+package telegram
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDownloadFileFromLocalServer(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/bottoken/getFile":
+			_, _ = w.Write([]byte(`{"ok":true,"result":{"file_id":"id","file_path":"documents/a.txt"}}`))
+		case "/file/bottoken/documents/a.txt":
+			_, _ = w.Write([]byte("content"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	bot := NewBot("token", WithAPIURL(srv.URL+"/bot"), WithFileURL(srv.URL+"/file/bot"))
+
+	file, err := bot.GetFileInfo(t.Context(), "id")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := bot.DownloadFile(t.Context(), file.FilePath, &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	if buf.String() != "content" {
+		t.Errorf("unexpected content %q", buf.String())
+	}
+}
+
+func TestFileIDAndPathEscaping(t *testing.T) {
+	const fileID = "AgAC&file_id=x?y#z/+="
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/bottoken/getFile":
+			var req GetFileRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.FileID != fileID || r.URL.RawQuery != "" {
+				t.Errorf("file_id not passed intact: %q, query %q", req.FileID, r.URL.RawQuery)
+			}
+
+			_, _ = w.Write([]byte(`{"ok":true,"result":{"file_id":"id","file_path":"documents/a b?#.txt"}}`))
+		case "/file/bottoken/documents/a b?#.txt":
+			if r.RequestURI != "/file/bottoken/documents/a%20b%3F%23.txt" {
+				t.Errorf("unexpected escaping %q", r.RequestURI)
+			}
+
+			_, _ = w.Write([]byte("content"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	bot := NewBot("token", WithAPIURL(srv.URL+"/bot"), WithFileURL(srv.URL+"/file/bot"))
+
+	file, err := bot.GetFileInfo(t.Context(), fileID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := bot.DownloadFile(t.Context(), file.FilePath, &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	if buf.String() != "content" {
+		t.Errorf("unexpected content %q", buf.String())
+	}
+}
+
+func TestDownloadFileByID(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req GetFileRequest
+
+		switch r.URL.Path {
+		case "/bottoken/getFile":
+			_ = json.NewDecoder(r.Body).Decode(&req)
+			_, _ = w.Write([]byte(`{"ok":true,"result":{"file_id":"id","file_path":"documents/` + req.FileID + `"}}`))
+		case "/file/bottoken/documents/a.txt":
+			_, _ = w.Write([]byte("content"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	bot := NewBot("token", WithAPIURL(srv.URL+"/bot"), WithFileURL(srv.URL+"/file/bot"))
+	dir := t.TempDir()
+
+	if err := bot.DownloadFileByID(t.Context(), "a.txt", filepath.Join(dir, "a.txt")); err != nil {
+		t.Fatal(err)
+	}
+
+	if data, err := os.ReadFile(filepath.Join(dir, "a.txt")); err != nil || string(data) != "content" {
+		t.Errorf("unexpected download %q, %v", data, err)
+	}
+
+	// a failed download leaves no empty file behind
+	if err := bot.DownloadFileByID(t.Context(), "gone.txt", filepath.Join(dir, "gone.txt")); err == nil {
+		t.Error("expected the missing file to fail")
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "gone.txt")); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected no file after a failed download, got %v", err)
+	}
+}
+
+func TestOpenFileByIDOfLocalServer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.txt")
+	if err := os.WriteFile(path, []byte("content"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		result, _ := json.Marshal(File{FileID: "id", FilePath: path})
+		_, _ = w.Write([]byte(`{"ok":true,"result":` + string(result) + `}`))
+	}))
+	defer srv.Close()
+
+	bot := NewBot("token", WithAPIURL(srv.URL+"/bot"), WithLocalFiles())
+
+	body, err := bot.OpenFileByID(t.Context(), "id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer body.Close()
+
+	if data, _ := io.ReadAll(body); string(data) != "content" {
+		t.Errorf("expected the local copy to be read, got %q", data)
+	}
+}
@@ -0,0 +1,130 @@
+package telegram
+
+import (
+	"fmt"
+	"io"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+)
+
+// formWriter writes a multipart form. In the sizing pass the files are not read, only their sizes are
+// added up, so that the length of the body is known before it is streamed.
+type formWriter struct {
+	*multipart.Writer
+
+	sizeOnly  bool
+	fileBytes int64
+	// fileSizes are the sizes of the files by field taken by the sizing pass, the streamed form keeps them
+	// even if a file changed since, e.g. while it is being written
+	fileSizes map[string]int64
+}
+
+// formLayout is what the sizing pass learned about a form, see formLength.
+type formLayout struct {
+	size      int64
+	boundary  string
+	fileSizes map[string]int64
+}
+
+// countingWriter discards what is written and counts the bytes.
+type countingWriter struct {
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+
+	return len(p), nil
+}
+
+// formLength returns the length of the form written by build, the boundary it was written with
+// and the sizes of its files.
+func formLength(build func(w *formWriter) error) (formLayout, error) {
+	var cw countingWriter
+
+	w := &formWriter{Writer: multipart.NewWriter(&cw), sizeOnly: true, fileSizes: make(map[string]int64)}
+
+	if err := build(w); err != nil {
+		return formLayout{}, err
+	}
+
+	if err := w.Close(); err != nil {
+		return formLayout{}, fmt.Errorf("close multipart writer: %w", err)
+	}
+
+	return formLayout{size: cw.n + w.fileBytes, boundary: w.Boundary(), fileSizes: w.fileSizes}, nil
+}
+
+// streamForm writes the form built by build with the layout of the sizing pass into a pipe and returns
+// its reading end, a failing build fails the read.
+func streamForm(layout formLayout, build func(w *formWriter) error) (*io.PipeReader, error) {
+	pr, pw := io.Pipe()
+
+	w := &formWriter{Writer: multipart.NewWriter(pw), fileSizes: layout.fileSizes}
+	if err := w.SetBoundary(layout.boundary); err != nil {
+		return nil, fmt.Errorf("set boundary: %w", err)
+	}
+
+	go func() {
+		err := build(w)
+		if err == nil {
+			err = w.Close()
+		}
+
+		_ = pw.CloseWithError(err)
+	}()
+
+	return pr, nil
+}
+
+// writeFilePart writes the file at filePath as the form file field. Its size is taken once by the sizing
+// pass, a file grown since is cut to it and a shrunk one fails the request.
+func writeFilePart(w *formWriter, field, filePath string) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return fmt.Errorf("open %s: %w", filePath, err)
+	}
+	defer file.Close()
+
+	size, ok := w.fileSizes[field]
+	if w.sizeOnly || !ok {
+		info, err := file.Stat()
+		if err != nil {
+			return fmt.Errorf("stat %s: %w", filePath, err)
+		}
+
+		size = info.Size()
+		if w.sizeOnly {
+			w.fileSizes[field] = size
+		}
+	}
+
+	return writeReaderPart(w, field, filePart{file, filePath, filepath.Base(filePath), size})
+}
+
+// writeReaderPart writes the content of part as the form file field, the sizing pass doesn't read it.
+func writeReaderPart(w *formWriter, field string, part filePart) error {
+	dst, err := w.CreateFormFile(field, part.filename)
+	if err != nil {
+		return fmt.Errorf("create form file: %w", err)
+	}
+
+	if w.sizeOnly {
+		w.fileBytes += part.size
+
+		return nil
+	}
+
+	// the length of the body was sent already, more or less content would corrupt the request
+	n, err := io.Copy(dst, io.LimitReader(part.r, part.size))
+	if err != nil {
+		return fmt.Errorf("copy %s: %w", part.name, err)
+	}
+
+	if n != part.size {
+		return fmt.Errorf("copy %s: %w after %d of %d bytes", part.name, io.ErrUnexpectedEOF, n, part.size)
+	}
+
+	return nil
+}
@@ -0,0 +1,87 @@
+package telegram
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestUploadIsStreamed(t *testing.T) {
+	const size = 32 << 20
+
+	path := filepath.Join(t.TempDir(), "big.bin")
+	if err := os.WriteFile(path, make([]byte, size), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var contentLength, received int64
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentLength = r.ContentLength
+		received, _ = io.Copy(io.Discard, r.Body)
+
+		_, _ = w.Write([]byte(`{"ok":true,"result":{"message_id":1}}`))
+	}))
+	defer srv.Close()
+
+	bot := NewBot("token", WithAPIURL(srv.URL+"/bot"), WithMaxFileSize(size))
+
+	var before, after runtime.MemStats
+
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+
+	if _, err := bot.SendDocument(t.Context(), "chat", path, "caption"); err != nil {
+		t.Fatal(err)
+	}
+
+	runtime.ReadMemStats(&after)
+
+	if contentLength <= size || contentLength != received {
+		t.Errorf("content length %d, received %d bytes", contentLength, received)
+	}
+
+	// allocations of the client and the test server together, a buffered body alone would be over 32MB
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > size/4 {
+		t.Errorf("uploading %d bytes allocated %d bytes", size, allocated)
+	}
+}
+
+func TestFormKeepsTheSizedLength(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.txt")
+	if err := os.WriteFile(path, []byte("first"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	build := func(w *formWriter) error {
+		return writeFilePart(w, "document", path)
+	}
+
+	layout, err := formLength(build)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the file grows between the passes, e.g. while it is being written
+	if err := os.WriteFile(path, []byte("first and second"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	body, err := streamForm(layout, build)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if int64(len(data)) != layout.size {
+		t.Errorf("streamed %d bytes, the content length is %d", len(data), layout.size)
+	}
+}
@@ -0,0 +1,401 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// maxFileSize is the upload limit of the public Bot API, see WithMaxFileSize.
+const maxFileSize = 50 << 20
+
+var (
+	// ErrInvalidRef is returned by the ByRef methods for a ref that is neither an HTTP URL nor a file_id.
+	ErrInvalidRef = errors.New("invalid file reference")
+	// errNotSeekable is returned when the content of a reader would have to be read again.
+	errNotSeekable = errors.New("the reader can't be read again, it is no io.Seeker")
+)
+
+// SendDocument [https://core.telegram.org/bots/api#senddocument]
+func (b *IBot) SendDocument(
+	ctx context.Context, chatID, filePath, caption string, opts ...SendOption,
+) (*Message, error) {
+	return b.sendFile(ctx, "sendDocument", mediaTypeDocument, chatID, filePath, caption, newSendOptions(opts))
+}
+
+// SendAudio [https://core.telegram.org/bots/api#sendaudio]
+func (b *IBot) SendAudio(ctx context.Context, chatID, filePath, caption string, opts ...SendOption) (*Message, error) {
+	return b.sendFile(ctx, "sendAudio", mediaTypeAudio, chatID, filePath, caption, newSendOptions(opts))
+}
+
+// SendPhoto [https://core.telegram.org/bots/api#sendphoto]
+func (b *IBot) SendPhoto(ctx context.Context, chatID, filePath, caption string, opts ...SendOption) (*Message, error) {
+	return b.sendFile(ctx, "sendPhoto", mediaTypePhoto, chatID, filePath, caption, newSendOptions(opts))
+}
+
+// SendVideo [https://core.telegram.org/bots/api#sendvideo]
+func (b *IBot) SendVideo(ctx context.Context, chatID, filePath, caption string, opts ...SendOption) (*Message, error) {
+	return b.sendFile(ctx, "sendVideo", mediaTypeVideo, chatID, filePath, caption, newSendOptions(opts))
+}
+
+// SendAnimation [https://core.telegram.org/bots/api#sendanimation]
+//
+// GIFs and silent MP4s keep playing in the chat, sent as photos they show their first frame only.
+func (b *IBot) SendAnimation(
+	ctx context.Context, chatID, filePath, caption string, opts ...SendOption,
+) (*Message, error) {
+	return b.sendFile(ctx, "sendAnimation", mediaTypeAnimation, chatID, filePath, caption, newSendOptions(opts))
+}
+
+// SendVoice [https://core.telegram.org/bots/api#sendvoice]
+//
+// The file must be OGG encoded with OPUS to be shown as a voice message.
+func (b *IBot) SendVoice(ctx context.Context, chatID, filePath, caption string, opts ...SendOption) (*Message, error) {
+	return b.sendFile(ctx, "sendVoice", "voice", chatID, filePath, caption, newSendOptions(opts))
+}
+
+// SendVideoNote [https://core.telegram.org/bots/api#sendvideonote]
+func (b *IBot) SendVideoNote(ctx context.Context, chatID, filePath string, opts ...SendOption) (*Message, error) {
+	return b.sendFile(ctx, "sendVideoNote", "video_note", chatID, filePath, "", newSendOptions(opts))
+}
+
+// SendDocumentReader is SendDocument with the content read from r instead of a file, e.g. generated
+// or encrypted on the fly. filename is the name shown in the chat and size the exact number of bytes r
+// yields, the form is streamed with a known length. r is read once, unless the chat turns out to be
+// migrated: the upload is then repeated if r is an io.Seeker and fails otherwise.
+func (b *IBot) SendDocumentReader(
+	ctx context.Context, chatID string, r io.Reader, filename string, size int64, caption string, opts ...SendOption,
+) (*Message, error) {
+	return b.sendReader(ctx, "sendDocument", mediaTypeDocument, chatID, filePart{r, filename, filename, size}, caption,
+		newSendOptions(opts))
+}
+
+// SendAudioReader is SendAudio with the content read from r, see SendDocumentReader.
+func (b *IBot) SendAudioReader(
+	ctx context.Context, chatID string, r io.Reader, filename string, size int64, caption string, opts ...SendOption,
+) (*Message, error) {
+	return b.sendReader(ctx, "sendAudio", mediaTypeAudio, chatID, filePart{r, filename, filename, size}, caption,
+		newSendOptions(opts))
+}
+
+// SendPhotoReader is SendPhoto with the content read from r, see SendDocumentReader.
+func (b *IBot) SendPhotoReader(
+	ctx context.Context, chatID string, r io.Reader, filename string, size int64, caption string, opts ...SendOption,
+) (*Message, error) {
+	return b.sendReader(ctx, "sendPhoto", mediaTypePhoto, chatID, filePart{r, filename, filename, size}, caption,
+		newSendOptions(opts))
+}
+
+// SendVideoReader is SendVideo with the content read from r, see SendDocumentReader.
+func (b *IBot) SendVideoReader(
+	ctx context.Context, chatID string, r io.Reader, filename string, size int64, caption string, opts ...SendOption,
+) (*Message, error) {
+	return b.sendReader(ctx, "sendVideo", mediaTypeVideo, chatID, filePart{r, filename, filename, size}, caption,
+		newSendOptions(opts))
+}
+
+// SendVoiceReader is SendVoice with the content read from r, see SendDocumentReader.
+func (b *IBot) SendVoiceReader(
+	ctx context.Context, chatID string, r io.Reader, filename string, size int64, caption string, opts ...SendOption,
+) (*Message, error) {
+	return b.sendReader(ctx, "sendVoice", "voice", chatID, filePart{r, filename, filename, size}, caption,
+		newSendOptions(opts))
+}
+
+// SendAnimationReader is SendAnimation with the content read from r, see SendDocumentReader.
+func (b *IBot) SendAnimationReader(
+	ctx context.Context, chatID string, r io.Reader, filename string, size int64, caption string, opts ...SendOption,
+) (*Message, error) {
+	return b.sendReader(ctx, "sendAnimation", mediaTypeAnimation, chatID, filePart{r, filename, filename, size}, caption,
+		newSendOptions(opts))
+}
+
+// filePart is the content of an upload.
+type filePart struct {
+	r io.Reader
+	// name identifies the upload in errors and progress reports, filename is sent to Telegram
+	name     string
+	filename string
+	size     int64
+}
+
+// localFileURL is the file:// URL a local Bot API server reads the file at the absolute path from.
+func localFileURL(path string) string {
+	return "file://" + filepath.ToSlash(path)
+}
+
+// sendFile uploads the file at filePath as the given multipart field.
+func (b *IBot) sendFile(
+	ctx context.Context, method, field, chatID, filePath, caption string, opts sendOptions,
+) (*Message, error) {
+	fileInfo, err := os.Stat(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("stat %s: %w", filePath, err)
+	}
+
+	// checked before the file is opened
+	if fileInfo.Size() > b.maxFileSize {
+		return nil, fmt.Errorf("%w: %s is %d bytes (max %d)", ErrFileTooLarge, filePath, fileInfo.Size(), b.maxFileSize)
+	}
+
+	if b.localFiles {
+		return b.sendLocalFile(ctx, method, field, chatID, filePath, caption, opts)
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", filePath, err)
+	}
+	defer file.Close()
+
+	return b.sendReader(ctx, method, field, chatID, filePart{file, filePath, filepath.Base(filePath), fileInfo.Size()},
+		caption, opts)
+}
+
+// sendReader uploads part as the given multipart field.
+func (b *IBot) sendReader(
+	ctx context.Context, method, field, chatID string, part filePart, caption string, opts sendOptions,
+) (*Message, error) {
+	if part.size < 0 {
+		return nil, fmt.Errorf("%s: invalid size %d", part.name, part.size)
+	}
+
+	if part.size > b.maxFileSize {
+		return nil, fmt.Errorf("%w: %s is %d bytes (max %d)", ErrFileTooLarge, part.name, part.size, b.maxFileSize)
+	}
+
+	return b.sendForm(ctx, method, field, chatID, part.name, caption, opts, rewinder(part.r),
+		func(w *formWriter) error {
+			return writeReaderPart(w, field, part)
+		})
+}
+
+// sendLocalFile sends the file at filePath as its file:// URL, see WithLocalFiles.
+func (b *IBot) sendLocalFile(
+	ctx context.Context, method, field, chatID, filePath, caption string, opts sendOptions,
+) (*Message, error) {
+	localPath, err := filepath.Abs(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("resolve %s: %w", filePath, err)
+	}
+
+	// read by the server, nothing to rewind
+	return b.sendForm(ctx, method, field, chatID, filePath, caption, opts, nil, func(w *formWriter) error {
+		return w.WriteField(field, localFileURL(localPath))
+	})
+}
+
+// sendForm sends a multipart form with the caption and opts, writeFile writes the file field.
+// rewind moves the content written by writeFile back to be sent again, nil if it needs none.
+func (b *IBot) sendForm(
+	ctx context.Context, method, field, chatID, name, caption string, opts sendOptions,
+	rewind func() error, writeFile func(w *formWriter) error,
+) (*Message, error) {
+	if opts.thumbnailPath != "" {
+		if err := ValidateThumbnail(opts.thumbnailPath); err != nil {
+			return nil, err
+		}
+	}
+
+	caption, rest := b.fitCaption(caption)
+
+	var (
+		msg      Message
+		attempts int
+	)
+
+	err := b.withChatMigration(ctx, chatID, func(chatID string) error {
+		// the first attempt read the content
+		if attempts++; attempts > 1 && rewind != nil {
+			if err := rewind(); err != nil {
+				return fmt.Errorf("send %s to the migrated chat: %w", name, err)
+			}
+		}
+
+		return b.callMultipart(ctx, method, name, func(w *formWriter) error {
+			if err := w.WriteField("chat_id", chatID); err != nil {
+				return err
+			}
+
+			if caption != "" {
+				if err := w.WriteField("caption", caption); err != nil {
+					return err
+				}
+			}
+
+			if err := opts.writeFields(w.Writer); err != nil {
+				return err
+			}
+
+			if err := opts.writeMediaFields(w.Writer, field); err != nil {
+				return err
+			}
+
+			if err := opts.writeThumbnail(w); err != nil {
+				return err
+			}
+
+			return writeFile(w)
+		}, rewind, &msg)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	b.sendCaptionRest(ctx, chatID, msg.MessageID, rest)
+
+	return &msg, nil
+}
+
+// rewinder returns a function that moves r back to its current offset.
+func rewinder(r io.Reader) func() error {
+	seeker, ok := r.(io.Seeker)
+	if !ok {
+		return func() error { return errNotSeekable }
+	}
+
+	// fails e.g. for a pipe opened as *os.File
+	start, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return func() error { return errNotSeekable }
+	}
+
+	return func() error {
+		_, err := seeker.Seek(start, io.SeekStart)
+
+		return err
+	}
+}
+
+// SendDocumentByRef sends a file already on the Telegram servers (its file_id) or an HTTP URL
+// Telegram downloads itself, nothing is uploaded.
+func (b *IBot) SendDocumentByRef(
+	ctx context.Context, chatID, ref, caption string, opts ...SendOption,
+) (*Message, error) {
+	return b.sendFileByRef(ctx, "sendDocument", mediaTypeDocument, chatID, ref, caption, newSendOptions(opts))
+}
+
+// SendAudioByRef is SendAudio with a file_id or an HTTP URL, see SendDocumentByRef.
+func (b *IBot) SendAudioByRef(ctx context.Context, chatID, ref, caption string, opts ...SendOption) (*Message, error) {
+	return b.sendFileByRef(ctx, "sendAudio", mediaTypeAudio, chatID, ref, caption, newSendOptions(opts))
+}
+
+// SendPhotoByRef is SendPhoto with a file_id or an HTTP URL, see SendDocumentByRef.
+func (b *IBot) SendPhotoByRef(ctx context.Context, chatID, ref, caption string, opts ...SendOption) (*Message, error) {
+	return b.sendFileByRef(ctx, "sendPhoto", mediaTypePhoto, chatID, ref, caption, newSendOptions(opts))
+}
+
+// SendVideoByRef is SendVideo with a file_id or an HTTP URL, see SendDocumentByRef.
+func (b *IBot) SendVideoByRef(ctx context.Context, chatID, ref, caption string, opts ...SendOption) (*Message, error) {
+	return b.sendFileByRef(ctx, "sendVideo", mediaTypeVideo, chatID, ref, caption, newSendOptions(opts))
+}
+
+// SendAnimationByRef is SendAnimation with a file_id or an HTTP URL, see SendDocumentByRef.
+func (b *IBot) SendAnimationByRef(
+	ctx context.Context, chatID, ref, caption string, opts ...SendOption,
+) (*Message, error) {
+	return b.sendFileByRef(ctx, "sendAnimation", mediaTypeAnimation, chatID, ref, caption, newSendOptions(opts))
+}
+
+// SendVoiceByRef is SendVoice with a file_id or an HTTP URL, see SendDocumentByRef.
+func (b *IBot) SendVoiceByRef(ctx context.Context, chatID, ref, caption string, opts ...SendOption) (*Message, error) {
+	return b.sendFileByRef(ctx, "sendVoice", "voice", chatID, ref, caption, newSendOptions(opts))
+}
+
+// sendFileByRef posts ref as the given field of a JSON request.
+func (b *IBot) sendFileByRef(
+	ctx context.Context, method, field, chatID, ref, caption string, opts sendOptions,
+) (*Message, error) {
+	if err := validateRef(ref); err != nil {
+		return nil, err
+	}
+
+	caption, rest := b.fitCaption(caption)
+
+	var msg Message
+
+	err := b.withChatMigration(ctx, chatID, func(chatID string) error {
+		payload := map[string]any{"chat_id": chatID, field: ref}
+
+		if caption != "" {
+			payload["caption"] = caption
+		}
+
+		if opts.parseMode != "" {
+			payload["parse_mode"] = opts.parseMode
+		}
+
+		if opts.replyToMessageID != 0 {
+			payload["reply_to_message_id"] = opts.replyToMessageID
+		}
+
+		if opts.messageThreadID != 0 {
+			payload["message_thread_id"] = opts.messageThreadID
+		}
+
+		if opts.disableNotification {
+			payload["disable_notification"] = true
+		}
+
+		if opts.protectContent {
+			payload["protect_content"] = true
+		}
+
+		if opts.hasSpoiler && hasSpoilerField(field) {
+			payload["has_spoiler"] = true
+		}
+
+		return b.callJSON(ctx, method, payload, &msg)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	b.sendCaptionRest(ctx, chatID, msg.MessageID, rest)
+
+	return &msg, nil
+}
+
+// sendCaptionRest sends the part of a caption over the limit as a reply to msg. The file is posted
+// already, so a failed reply is only logged rather than failing the send and getting the file sent again.
+func (b *IBot) sendCaptionRest(ctx context.Context, chatID string, messageID int64, rest string) {
+	if rest == "" {
+		return
+	}
+
+	if _, err := b.SendMessage(ctx, chatID, rest, ReplyTo(messageID)); err != nil {
+		b.logger.Warn("failed to send the rest of the caption", "chat", chatID, "message", messageID, "error", err)
+	}
+}
+
+// validateRef accepts HTTP URLs and strings that look like a file_id (URL-safe base64).
+func validateRef(ref string) error {
+	if strings.HasPrefix(ref, "http://") || strings.HasPrefix(ref, "https://") {
+		if u, err := url.Parse(ref); err != nil || u.Host == "" {
+			return fmt.Errorf("%w: %q is not a valid url", ErrInvalidRef, ref)
+		}
+
+		return nil
+	}
+
+	if ref == "" {
+		return fmt.Errorf("%w: empty file_id", ErrInvalidRef)
+	}
+
+	for _, r := range ref {
+		if !isFileIDRune(r) {
+			return fmt.Errorf("%w: %q is neither a url nor a file_id", ErrInvalidRef, ref)
+		}
+	}
+
+	return nil
+}
+
+func isFileIDRune(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_'
+}
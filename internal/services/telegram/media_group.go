@@ -0,0 +1,238 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// maxMediaGroupSize [https://core.telegram.org/bots/api#sendmediagroup]
+const maxMediaGroupSize = 10
+
+const (
+	mediaTypePhoto    = "photo"
+	mediaTypeVideo    = "video"
+	mediaTypeAudio    = "audio"
+	mediaTypeDocument = "document"
+	// mediaTypeAnimation can't be part of an album
+	mediaTypeAnimation = "animation"
+)
+
+// mediaTypeByExt maps extensions to InputMedia types, everything else is a document.
+//
+//nolint:gochecknoglobals // read-only lookup table
+var mediaTypeByExt = map[string]string{
+	".jpg":  mediaTypePhoto,
+	".jpeg": mediaTypePhoto,
+	".png":  mediaTypePhoto,
+	".webp": mediaTypePhoto,
+	".mp4":  mediaTypeVideo,
+	".mov":  mediaTypeVideo,
+	".mkv":  mediaTypeVideo,
+	".webm": mediaTypeVideo,
+	".avi":  mediaTypeVideo,
+	".mp3":  mediaTypeAudio,
+	".m4a":  mediaTypeAudio,
+	".flac": mediaTypeAudio,
+	".wav":  mediaTypeAudio,
+	".ogg":  mediaTypeAudio,
+}
+
+func mediaTypeOf(path string) string {
+	if t, ok := mediaTypeByExt[strings.ToLower(filepath.Ext(path))]; ok {
+		return t
+	}
+
+	return mediaTypeDocument
+}
+
+// IsAlbumMedia reports whether SendMediaGroup sends the file as a photo or video,
+// files that may share one album in any order.
+func IsAlbumMedia(filePath string) bool {
+	return albumGroupOf(mediaTypeOf(filePath)) == mediaTypePhoto
+}
+
+// albumGroupOf returns the key of files that may share one album:
+// photos and videos can be mixed, audio and documents only with their own kind.
+func albumGroupOf(mediaType string) string {
+	if mediaType == mediaTypeVideo {
+		return mediaTypePhoto
+	}
+
+	return mediaType
+}
+
+// groupMediaFiles splits files into albums of compatible types of at most maxMediaGroupSize items,
+// keeping the order in which the groups first appear.
+func groupMediaFiles(filePaths []string) [][]string {
+	var (
+		order  []string
+		byKind = make(map[string][]string)
+	)
+
+	for _, path := range filePaths {
+		key := albumGroupOf(mediaTypeOf(path))
+		if _, ok := byKind[key]; !ok {
+			order = append(order, key)
+		}
+
+		byKind[key] = append(byKind[key], path)
+	}
+
+	var albums [][]string
+
+	for _, key := range order {
+		files := byKind[key]
+		for len(files) > 0 {
+			n := min(len(files), maxMediaGroupSize)
+			albums = append(albums, files[:n])
+			files = files[n:]
+		}
+	}
+
+	return albums
+}
+
+// SendMediaGroup [https://core.telegram.org/bots/api#sendmediagroup]
+//
+// Files are grouped by compatible type and split into albums of up to 10 items,
+// the caption is attached to the first item of every album.
+// An album with a single file is sent with the matching single-file method.
+func (b *IBot) SendMediaGroup(
+	ctx context.Context, chatID string, filePaths []string, caption string, opts ...SendOption,
+) ([]Message, error) {
+	var sent []Message
+
+	for _, album := range groupMediaFiles(filePaths) {
+		if len(album) == 1 {
+			msg, err := b.sendSingleMedia(ctx, chatID, album[0], caption, opts)
+			if err != nil {
+				return sent, err
+			}
+
+			sent = append(sent, *msg)
+
+			continue
+		}
+
+		msgs, err := b.sendAlbum(ctx, chatID, album, caption, newSendOptions(opts))
+		if err != nil {
+			return sent, err
+		}
+
+		sent = append(sent, msgs...)
+	}
+
+	return sent, nil
+}
+
+func (b *IBot) sendAlbum(
+	ctx context.Context, chatID string, filePaths []string, caption string, opts sendOptions,
+) ([]Message, error) {
+	caption, rest := b.fitCaption(caption)
+
+	media, err := b.albumMedia(filePaths, caption, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	mediaJSON, err := json.Marshal(media)
+	if err != nil {
+		return nil, fmt.Errorf("marshal media: %w", err)
+	}
+
+	var msgs []Message
+
+	err = b.withChatMigration(ctx, chatID, func(chatID string) error {
+		return b.callMultipart(ctx, "sendMediaGroup", strings.Join(filePaths, ", "), func(w *formWriter) error {
+			if err := w.WriteField("chat_id", chatID); err != nil {
+				return err
+			}
+
+			if err := w.WriteField("media", string(mediaJSON)); err != nil {
+				return err
+			}
+
+			if err := opts.writeFields(w.Writer); err != nil {
+				return err
+			}
+
+			if b.localFiles {
+				return nil
+			}
+
+			for i, path := range filePaths {
+				if err := writeFilePart(w, attachName(i), path); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		}, nil, &msgs)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if rest != "" && len(msgs) > 0 {
+		b.sendCaptionRest(ctx, chatID, msgs[0].MessageID, rest)
+	}
+
+	return msgs, nil
+}
+
+// albumMedia describes the files of an album, the first one carries the caption.
+func (b *IBot) albumMedia(filePaths []string, caption string, opts sendOptions) ([]InputMedia, error) {
+	media := make([]InputMedia, 0, len(filePaths))
+
+	for i, path := range filePaths {
+		item := InputMedia{
+			Type:  mediaTypeOf(path),
+			Media: "attach://" + attachName(i),
+		}
+
+		if b.localFiles {
+			localPath, err := filepath.Abs(path)
+			if err != nil {
+				return nil, fmt.Errorf("resolve %s: %w", path, err)
+			}
+
+			item.Media = localFileURL(localPath)
+		}
+
+		if i == 0 {
+			item.Caption = caption
+			item.ParseMode = opts.parseMode
+		}
+
+		if opts.hasSpoiler && (item.Type == mediaTypePhoto || item.Type == mediaTypeVideo) {
+			item.HasSpoiler = true
+		}
+
+		media = append(media, item)
+	}
+
+	return media, nil
+}
+
+func (b *IBot) sendSingleMedia(
+	ctx context.Context, chatID, filePath, caption string, opts []SendOption,
+) (*Message, error) {
+	switch mediaTypeOf(filePath) {
+	case mediaTypePhoto:
+		return b.SendPhoto(ctx, chatID, filePath, caption, opts...)
+	case mediaTypeVideo:
+		return b.SendVideo(ctx, chatID, filePath, caption, opts...)
+	case mediaTypeAudio:
+		return b.SendAudio(ctx, chatID, filePath, caption, opts...)
+	default:
+		return b.SendDocument(ctx, chatID, filePath, caption, opts...)
+	}
+}
+
+func attachName(i int) string {
+	return "file" + strconv.Itoa(i)
+}
@@ -0,0 +1,92 @@
+package telegram
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestGroupMediaFiles(t *testing.T) {
+	files := []string{"a.jpg", "b.pdf", "c.mp4"}
+	for i := range 9 {
+		files = append(files, "p"+strconv.Itoa(i)+".png")
+	}
+
+	albums := groupMediaFiles(files)
+
+	if len(albums) != 3 {
+		t.Fatalf("expected 3 albums, got %d: %v", len(albums), albums)
+	}
+
+	if len(albums[0]) != 10 || albums[0][0] != "a.jpg" || albums[0][1] != "c.mp4" {
+		t.Errorf("unexpected first album: %v", albums[0])
+	}
+
+	if len(albums[1]) != 1 || albums[1][0] != "p8.png" {
+		t.Errorf("unexpected photo remainder: %v", albums[1])
+	}
+
+	if len(albums[2]) != 1 || albums[2][0] != "b.pdf" {
+		t.Errorf("documents must not join photos: %v", albums[2])
+	}
+}
+
+func TestSendMediaGroup(t *testing.T) {
+	dir := t.TempDir()
+
+	var paths []string
+
+	for _, name := range []string{"a.jpg", "b.mp4"} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(name), 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		paths = append(paths, path)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/bottoken/sendMediaGroup" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Fatal(err)
+		}
+
+		var media []InputMedia
+		if err := json.Unmarshal([]byte(r.FormValue("media")), &media); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(media) != 2 || media[0].Media != "attach://file0" || media[1].Type != mediaTypeVideo {
+			t.Errorf("unexpected media: %+v", media)
+		}
+
+		if media[0].Caption != "album" || media[1].Caption != "" {
+			t.Errorf("caption must be set on the first item only: %+v", media)
+		}
+
+		if _, _, err := r.FormFile("file1"); err != nil {
+			t.Errorf("missing attached file: %v", err)
+		}
+
+		_, _ = w.Write([]byte(`{"ok":true,"result":[{"message_id":1},{"message_id":2}]}`))
+	}))
+	defer srv.Close()
+
+	bot := NewBot("token", WithAPIURL(srv.URL+"/bot"))
+
+	msgs, err := bot.SendMediaGroup(t.Context(), "chat", paths, "album")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(msgs) != 2 || msgs[1].MessageID != 2 {
+		t.Errorf("unexpected messages: %+v", msgs)
+	}
+}
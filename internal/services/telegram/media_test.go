@@ -0,0 +1,668 @@
+package telegram
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSendDocumentReplyTo(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.txt")
+	if err := os.WriteFile(path, []byte("a"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var replies []string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Fatal(err)
+		}
+
+		replies = append(replies, r.FormValue("reply_to_message_id"))
+
+		_, _ = w.Write([]byte(`{"ok":true,"result":{"message_id":1}}`))
+	}))
+	defer srv.Close()
+
+	bot := NewBot("token", WithAPIURL(srv.URL+"/bot"))
+
+	if _, err := bot.SendDocument(t.Context(), "chat", path, "", ReplyTo(42)); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := bot.SendDocument(t.Context(), "chat", path, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(replies) != 2 || replies[0] != "42" || replies[1] != "" {
+		t.Errorf("reply_to_message_id must be set only when given: %q", replies)
+	}
+}
+
+func TestSendVoiceAndVideoNoteFields(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.ogg")
+	if err := os.WriteFile(path, []byte("OggS"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Fatal(err)
+		}
+
+		field := map[string]string{"/bottoken/sendVoice": "voice", "/bottoken/sendVideoNote": "video_note"}[r.URL.Path]
+		if _, _, err := r.FormFile(field); err != nil {
+			t.Errorf("%s: missing %q file field: %v", r.URL.Path, field, err)
+		}
+
+		_, _ = w.Write([]byte(`{"ok":true,"result":{"message_id":1}}`))
+	}))
+	defer srv.Close()
+
+	bot := NewBot("token", WithAPIURL(srv.URL+"/bot"))
+
+	if _, err := bot.SendVoice(t.Context(), "chat", path, "voice"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := bot.SendVideoNote(t.Context(), "chat", path); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSendDocumentByRef(t *testing.T) {
+	var req map[string]any
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error(err)
+		}
+
+		_, _ = w.Write([]byte(`{"ok":true,"result":{"message_id":1}}`))
+	}))
+	defer srv.Close()
+
+	bot := NewBot("token", WithAPIURL(srv.URL+"/bot"))
+
+	if _, err := bot.SendDocumentByRef(t.Context(), "chat", "BQACAgIAAxkBAAIB", "caption"); err != nil {
+		t.Fatal(err)
+	}
+
+	if req["document"] != "BQACAgIAAxkBAAIB" || req["caption"] != "caption" || req["chat_id"] != "chat" {
+		t.Errorf("unexpected request %v", req)
+	}
+
+	for _, ref := range []string{"", "not a file id", "https://"} {
+		if _, err := bot.SendDocumentByRef(t.Context(), "chat", ref, ""); !errors.Is(err, ErrInvalidRef) {
+			t.Errorf("%q: expected ErrInvalidRef, got %v", ref, err)
+		}
+	}
+
+	if err := validateRef("https://example.com/a.pdf"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDisableNotificationField(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.txt")
+	if err := os.WriteFile(path, []byte("a"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var values []string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Fatal(err)
+		}
+
+		values = append(values, r.FormValue("disable_notification"))
+
+		_, _ = w.Write([]byte(`{"ok":true,"result":{"message_id":1}}`))
+	}))
+	defer srv.Close()
+
+	bot := NewBot("token", WithAPIURL(srv.URL+"/bot"))
+
+	if _, err := bot.SendDocument(t.Context(), "chat", path, "", DisableNotification()); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := bot.SendDocument(t.Context(), "chat", path, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(values) != 2 || values[0] != "true" || values[1] != "" {
+		t.Errorf("disable_notification must be set only when given: %q", values)
+	}
+}
+
+func TestMessageThreadField(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.txt")
+	if err := os.WriteFile(path, []byte("a"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var values []string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") == "application/json" {
+			var payload map[string]any
+			if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+				t.Fatal(err)
+			}
+
+			thread, ok := payload["message_thread_id"]
+			if !ok {
+				thread = ""
+			}
+
+			values = append(values, fmt.Sprint(thread))
+		} else {
+			if err := r.ParseMultipartForm(1 << 20); err != nil {
+				t.Fatal(err)
+			}
+
+			values = append(values, r.FormValue("message_thread_id"))
+		}
+
+		_, _ = w.Write([]byte(`{"ok":true,"result":{"message_id":1}}`))
+	}))
+	defer srv.Close()
+
+	bot := NewBot("token", WithAPIURL(srv.URL+"/bot"))
+
+	for _, opts := range [][]SendOption{{MessageThread(7)}, nil} {
+		if _, err := bot.SendDocument(t.Context(), "chat", path, "", opts...); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := bot.SendDocumentByRef(t.Context(), "chat", "file-id", "", opts...); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := bot.SendMessage(t.Context(), "chat", "text", opts...); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if want := []string{"7", "7", "7", "", "", ""}; !slices.Equal(values, want) {
+		t.Errorf("message_thread_id must be set only when given: %q, want %q", values, want)
+	}
+}
+
+func writeJPEG(t *testing.T, path string, width, height int) {
+	t.Helper()
+
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if err := jpeg.Encode(f, image.NewGray(image.Rect(0, 0, width, height)), nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSendVideoThumbnail(t *testing.T) {
+	dir := t.TempDir()
+	video, thumb, big := filepath.Join(dir, "clip.mp4"), filepath.Join(dir, "clip.jpg"), filepath.Join(dir, "big.jpg")
+
+	if err := os.WriteFile(video, []byte("video"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	writeJPEG(t, thumb, 320, 180)
+	writeJPEG(t, big, 640, 360)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Fatal(err)
+		}
+
+		if got := r.FormValue("thumbnail"); got != "attach://thumbnail_file" {
+			t.Errorf("thumbnail = %q", got)
+		}
+
+		if r.MultipartForm.File["thumbnail_file"] == nil {
+			t.Error("the thumbnail was not uploaded")
+		}
+
+		if r.FormValue("width") != "320" || r.FormValue("height") != "180" || r.FormValue("duration") != "90" {
+			t.Errorf("unexpected video fields %v", r.MultipartForm.Value)
+		}
+
+		_, _ = w.Write([]byte(`{"ok":true,"result":{"message_id":1}}`))
+	}))
+	defer srv.Close()
+
+	bot := NewBot("token", WithAPIURL(srv.URL+"/bot"))
+
+	_, err := bot.SendVideo(t.Context(), "chat", video, "", Thumbnail(thumb), VideoInfo(320, 180, 90*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := bot.SendVideo(t.Context(), "chat", video, "", Thumbnail(big)); !errors.Is(err, ErrInvalidThumbnail) {
+		t.Errorf("expected ErrInvalidThumbnail for a 640x360 thumbnail, got %v", err)
+	}
+
+	if err := ValidateThumbnail(video); !errors.Is(err, ErrInvalidThumbnail) {
+		t.Errorf("expected ErrInvalidThumbnail for a non-jpeg, got %v", err)
+	}
+}
+
+func TestAudioInfoAndStreamingFields(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.mp3")
+	if err := os.WriteFile(path, []byte("ID3"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []url.Values
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Fatal(err)
+		}
+
+		got = append(got, url.Values(r.MultipartForm.Value))
+
+		_, _ = w.Write([]byte(`{"ok":true,"result":{"message_id":1}}`))
+	}))
+	defer srv.Close()
+
+	bot := NewBot("token", WithAPIURL(srv.URL+"/bot"))
+	opts := []SendOption{AudioInfo("Artist", "Song", 3*time.Minute), SupportsStreaming()}
+
+	if _, err := bot.SendAudio(t.Context(), "chat", path, "", opts...); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := bot.SendVideo(t.Context(), "chat", path, "", opts...); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := bot.SendAudio(t.Context(), "chat", path, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got) != 3 {
+		t.Fatalf("expected 3 requests, got %d", len(got))
+	}
+
+	audio, video, plain := got[0], got[1], got[2]
+
+	if audio.Get("performer") != "Artist" || audio.Get("title") != "Song" || audio.Get("duration") != "180" ||
+		audio.Has("supports_streaming") {
+		t.Errorf("unexpected audio fields %v", audio)
+	}
+
+	if video.Get("supports_streaming") != "true" || video.Has("performer") || video.Has("title") {
+		t.Errorf("unexpected video fields %v", video)
+	}
+
+	for _, name := range []string{"performer", "title", "duration", "supports_streaming"} {
+		if plain.Has(name) {
+			t.Errorf("%s must be set only when given: %v", name, plain)
+		}
+	}
+}
+
+func TestProtectContentAndSpoilerFields(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.png")
+	if err := os.WriteFile(path, []byte("a"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var got [][2]string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Fatal(err)
+		}
+
+		got = append(got, [2]string{r.FormValue("protect_content"), r.FormValue("has_spoiler")})
+
+		_, _ = w.Write([]byte(`{"ok":true,"result":{"message_id":1}}`))
+	}))
+	defer srv.Close()
+
+	bot := NewBot("token", WithAPIURL(srv.URL+"/bot"))
+
+	if _, err := bot.SendPhoto(t.Context(), "chat", path, "", ProtectContent(), HasSpoiler()); err != nil {
+		t.Fatal(err)
+	}
+
+	// a document can't be a spoiler
+	if _, err := bot.SendDocument(t.Context(), "chat", path, "", HasSpoiler()); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := bot.SendPhoto(t.Context(), "chat", path, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	want := [][2]string{{"true", "true"}, {"", ""}, {"", ""}}
+	if !slices.Equal(got, want) {
+		t.Errorf("protect_content and has_spoiler = %q, want %q", got, want)
+	}
+}
+
+func TestSendAnimation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "loop.gif")
+	if err := os.WriteFile(path, []byte("GIF89a"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		method string
+		fields url.Values
+		file   string
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Fatal(err)
+		}
+
+		method, fields = filepath.Base(r.URL.Path), url.Values(r.MultipartForm.Value)
+		if files := r.MultipartForm.File["animation"]; len(files) == 1 {
+			file = files[0].Filename
+		}
+
+		_, _ = w.Write([]byte(`{"ok":true,"result":{"message_id":1,"animation":{"file_id":"anim","width":2,"height":2}}}`))
+	}))
+	defer srv.Close()
+
+	bot := NewBot("token", WithAPIURL(srv.URL+"/bot"))
+
+	msg, err := bot.SendAnimation(t.Context(), "chat", path, "", VideoInfo(320, 240, 2*time.Second), HasSpoiler())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if method != "sendAnimation" || file != "loop.gif" {
+		t.Errorf("sent %s with the file %q in the animation field", method, file)
+	}
+
+	if fields.Get("width") != "320" || fields.Get("height") != "240" || fields.Get("duration") != "2" ||
+		fields.Get("has_spoiler") != "true" {
+		t.Errorf("unexpected fields %v", fields)
+	}
+
+	if msg.Animation == nil || msg.Animation.FileID != "anim" {
+		t.Errorf("unexpected message %+v", msg)
+	}
+}
+
+// documentServer records the uploaded documents, with migrate the first request fails with a chat migration.
+func documentServer(t *testing.T, migrate bool, contents *[]string) *httptest.Server {
+	t.Helper()
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength <= 0 {
+			t.Errorf("expected a known content length, got %d", r.ContentLength)
+		}
+
+		// a body cut short by the client
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+
+			return
+		}
+
+		files := r.MultipartForm.File["document"]
+		if len(files) != 1 || files[0].Filename != "bundle.zip" {
+			t.Fatalf("unexpected document part %+v", files)
+		}
+
+		f, err := files[0].Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		data, _ := io.ReadAll(f)
+		*contents = append(*contents, string(data))
+
+		if migrate && len(*contents) == 1 {
+			_, _ = w.Write([]byte(`{"ok":false,"error_code":400,"description":"Bad Request: group chat was upgraded",` +
+				`"parameters":{"migrate_to_chat_id":-100123}}`))
+
+			return
+		}
+
+		_, _ = w.Write([]byte(`{"ok":true,"result":{"message_id":1}}`))
+	}))
+}
+
+func TestSendDocumentReader(t *testing.T) {
+	var contents []string
+
+	srv := documentServer(t, false, &contents)
+	defer srv.Close()
+
+	bot := NewBot("token", WithAPIURL(srv.URL+"/bot"))
+	data := "generated in memory"
+	size := int64(len(data))
+
+	_, err := bot.SendDocumentReader(t.Context(), "chat", bytes.NewReader([]byte(data)), "bundle.zip", size, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(contents) != 1 || contents[0] != data {
+		t.Errorf("unexpected uploads %q", contents)
+	}
+
+	// a wrong size would corrupt the request, it fails before the body is complete
+	if _, err := bot.SendDocumentReader(
+		t.Context(), "chat", strings.NewReader("short"), "bundle.zip", 10, "",
+	); err == nil {
+		t.Error("expected an error for a reader shorter than its size")
+	}
+
+	if _, err := bot.SendDocumentReader(t.Context(), "chat", strings.NewReader(data), "bundle.zip", -1, ""); err == nil {
+		t.Error("expected an error for a negative size")
+	}
+
+	small := NewBot("token", WithAPIURL(srv.URL+"/bot"), WithMaxFileSize(4))
+	_, err = small.SendDocumentReader(t.Context(), "chat", strings.NewReader(data), "bundle.zip", size, "")
+	if !errors.Is(err, ErrFileTooLarge) {
+		t.Errorf("expected ErrFileTooLarge, got %v", err)
+	}
+}
+
+func TestSendDocumentReaderChatMigration(t *testing.T) {
+	var contents []string
+
+	srv := documentServer(t, true, &contents)
+	defer srv.Close()
+
+	data := "generated in memory"
+
+	// a seeker is read again for the migrated chat
+	bot := NewBot("token", WithAPIURL(srv.URL+"/bot"))
+	if _, err := bot.SendDocumentReader(
+		t.Context(), "chat", strings.NewReader(data), "bundle.zip", int64(len(data)), "",
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(contents) != 2 || contents[1] != data {
+		t.Errorf("expected the full content sent to the migrated chat, got %q", contents)
+	}
+
+	// a plain reader can't be
+	contents = nil
+	bot = NewBot("token", WithAPIURL(srv.URL+"/bot"))
+	r := io.MultiReader(strings.NewReader(data))
+
+	_, err := bot.SendDocumentReader(t.Context(), "chat", r, "bundle.zip", int64(len(data)), "")
+	if !errors.Is(err, errNotSeekable) {
+		t.Errorf("expected errNotSeekable, got %v", err)
+	}
+}
+
+func TestFailedCaptionRestKeepsTheFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.jpg")
+	if err := os.WriteFile(path, []byte("a"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var replies atomic.Int64
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/sendMessage"):
+			replies.Add(1)
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte(`{"ok":false,"error_code":500,"description":"Internal Server Error"}`))
+		case strings.HasSuffix(r.URL.Path, "/sendMediaGroup"):
+			_, _ = w.Write([]byte(`{"ok":true,"result":[{"message_id":1},{"message_id":2}]}`))
+		default:
+			_, _ = w.Write([]byte(`{"ok":true,"result":{"message_id":1}}`))
+		}
+	}))
+	defer srv.Close()
+
+	bot := NewBot("token", WithAPIURL(srv.URL+"/bot"), WithCaptionOverflow(CaptionSplit))
+	caption := strings.Repeat("a", 2*maxCaptionLength)
+
+	// the file is posted, the reply with the rest of the caption only fails
+	if msg, err := bot.SendDocument(t.Context(), "chat", path, caption); err != nil || msg.MessageID != 1 {
+		t.Errorf("expected the document to be sent, got %+v, %v", msg, err)
+	}
+
+	msgs, err := bot.SendMediaGroup(t.Context(), "chat", []string{path, path}, caption)
+	if err != nil || len(msgs) != 2 {
+		t.Errorf("expected the album to be sent, got %+v, %v", msgs, err)
+	}
+
+	if n := replies.Load(); n != 2 {
+		t.Errorf("expected a reply to each, got %d", n)
+	}
+}
+
+func TestSendLargeFileToLocalServer(t *testing.T) {
+	const (
+		size    = 64 << 20
+		timeout = 100 * time.Millisecond
+	)
+
+	// sparse, over the limit of the public Bot API
+	path := filepath.Join(t.TempDir(), "a.bin")
+	if err := os.WriteFile(path, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Truncate(path, size); err != nil {
+		t.Fatal(err)
+	}
+
+	var received atomic.Int64
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// a slow link, the upload takes longer than the timeout
+		time.Sleep(3 * timeout)
+
+		n, _ := io.Copy(io.Discard, r.Body)
+		received.Store(n)
+
+		_, _ = w.Write([]byte(`{"ok":true,"result":{"message_id":1}}`))
+	}))
+	defer srv.Close()
+
+	transport, err := NewTransport("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	transport.ResponseHeaderTimeout = timeout
+
+	bot := NewBot("token", WithAPIURL(srv.URL+"/bot"), WithMaxFileSize(2000<<20),
+		WithHTTPClient(&http.Client{Transport: transport}))
+	bot.timeout = timeout
+
+	if _, err := bot.SendDocument(t.Context(), "chat", path, ""); err != nil {
+		t.Fatalf("expected the upload to succeed, got %v", err)
+	}
+
+	if n := received.Load(); n < size {
+		t.Errorf("expected the whole file to be uploaded, got %d bytes", n)
+	}
+}
+
+func TestSendLocalFile(t *testing.T) {
+	dir := t.TempDir()
+
+	path := filepath.Join(dir, "a.jpg")
+	if err := os.WriteFile(path, []byte("a"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var forms []url.Values
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Error(err)
+		}
+
+		if len(r.MultipartForm.File) != 0 {
+			t.Errorf("expected no file to be uploaded, got %v", r.MultipartForm.File)
+		}
+
+		forms = append(forms, r.MultipartForm.Value)
+
+		if strings.HasSuffix(r.URL.Path, "/sendMediaGroup") {
+			_, _ = w.Write([]byte(`{"ok":true,"result":[{"message_id":1},{"message_id":2}]}`))
+
+			return
+		}
+
+		_, _ = w.Write([]byte(`{"ok":true,"result":{"message_id":1}}`))
+	}))
+	defer srv.Close()
+
+	bot := NewBot("token", WithAPIURL(srv.URL+"/bot"), WithLocalFiles())
+
+	if _, err := bot.SendDocument(t.Context(), "chat", path, "caption"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := bot.SendMediaGroup(t.Context(), "chat", []string{path, path}, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(forms) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(forms))
+	}
+
+	if got := forms[0].Get("document"); got != "file://"+path {
+		t.Errorf("expected the document as its local path, got %q", got)
+	}
+
+	var media []InputMedia
+	if err := json.Unmarshal([]byte(forms[1].Get("media")), &media); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(media) != 2 || media[1].Media != "file://"+path {
+		t.Errorf("expected the album items as local paths, got %+v", media)
+	}
+}
@@ -0,0 +1,278 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// The upload actions of SendChatAction, see [https://core.telegram.org/bots/api#sendchataction].
+const (
+	ActionUploadPhoto    = "upload_photo"
+	ActionUploadVideo    = "upload_video"
+	ActionUploadVoice    = "upload_voice"
+	ActionUploadDocument = "upload_document"
+)
+
+var (
+	// ErrMessageNotModified is returned by the edit methods when the new content equals the current one.
+	ErrMessageNotModified = errors.New("message is not modified")
+	// ErrMessageCantBeDeleted is returned by DeleteMessage for messages the bot may not delete,
+	// most often messages older than 48 hours.
+	ErrMessageCantBeDeleted = errors.New("message can't be deleted")
+)
+
+// EditMessageText [https://core.telegram.org/bots/api#editmessagetext]
+func (b *IBot) EditMessageText(
+	ctx context.Context, chatID string, messageID int64, text string, opts ...SendOption,
+) (*Message, error) {
+	o := newSendOptions(opts)
+
+	return b.editMessage(ctx, "editMessageText", EditMessageTextRequest{
+		ChatID:    b.currentChatID(chatID),
+		MessageID: messageID,
+		Text:      text,
+		ParseMode: o.parseMode,
+	})
+}
+
+// EditMessageCaption [https://core.telegram.org/bots/api#editmessagecaption]
+func (b *IBot) EditMessageCaption(
+	ctx context.Context, chatID string, messageID int64, caption string,
+) (*Message, error) {
+	return b.editMessage(ctx, "editMessageCaption", EditMessageCaptionRequest{
+		ChatID:    b.currentChatID(chatID),
+		MessageID: messageID,
+		Caption:   caption,
+	})
+}
+
+// EditMessageMedia [https://core.telegram.org/bots/api#editmessagemedia]
+//
+// It uploads the file at filePath as mediaType, "document", "photo", "audio", "video" or "animation",
+// in place of the file of the message, e.g. a newer version of it. The caption is replaced too and cut
+// to the limit. Of opts only the parse mode, the spoiler and the thumbnail are used.
+// A message of an album can only be edited to the kind of the album.
+func (b *IBot) EditMessageMedia(
+	ctx context.Context, chatID string, messageID int64, mediaType, filePath, caption string, opts ...SendOption,
+) (*Message, error) {
+	fileInfo, err := os.Stat(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("stat %s: %w", filePath, err)
+	}
+
+	if fileInfo.Size() > b.maxFileSize {
+		return nil, fmt.Errorf("%w: %s is %d bytes (max %d)", ErrFileTooLarge, filePath, fileInfo.Size(), b.maxFileSize)
+	}
+
+	o := newSendOptions(opts)
+
+	media, err := b.editedMedia(mediaType, filePath, caption, o)
+	if err != nil {
+		return nil, err
+	}
+
+	mediaJSON, err := json.Marshal(media)
+	if err != nil {
+		return nil, fmt.Errorf("marshal media: %w", err)
+	}
+
+	var msg Message
+
+	err = b.callMultipart(ctx, "editMessageMedia", filePath, func(w *formWriter) error {
+		if err := w.WriteField("chat_id", b.currentChatID(chatID)); err != nil {
+			return err
+		}
+
+		if err := w.WriteField("message_id", strconv.FormatInt(messageID, 10)); err != nil {
+			return err
+		}
+
+		if err := w.WriteField("media", string(mediaJSON)); err != nil {
+			return err
+		}
+
+		if media.Thumbnail != "" {
+			if err := writeFilePart(w, thumbnailPart, o.thumbnailPath); err != nil {
+				return err
+			}
+		}
+
+		if b.localFiles {
+			return nil
+		}
+
+		return writeFilePart(w, attachName(0), filePath)
+	}, nil, &msg)
+	if err != nil {
+		return nil, notModified(err)
+	}
+
+	return &msg, nil
+}
+
+// editedMedia describes the file replacing the one of a message, see EditMessageMedia.
+func (b *IBot) editedMedia(mediaType, filePath, caption string, o sendOptions) (InputMedia, error) {
+	media := InputMedia{
+		Type:       mediaType,
+		Media:      "attach://" + attachName(0),
+		Caption:    truncateText(caption, maxCaptionLength),
+		ParseMode:  o.parseMode,
+		HasSpoiler: o.hasSpoiler && hasSpoilerField(mediaType),
+	}
+
+	if b.localFiles {
+		localPath, err := filepath.Abs(filePath)
+		if err != nil {
+			return InputMedia{}, fmt.Errorf("resolve %s: %w", filePath, err)
+		}
+
+		media.Media = localFileURL(localPath)
+	}
+
+	if o.thumbnailPath != "" && mediaType != mediaTypePhoto {
+		if err := ValidateThumbnail(o.thumbnailPath); err != nil {
+			return InputMedia{}, err
+		}
+
+		media.Thumbnail = "attach://" + thumbnailPart
+	}
+
+	return media, nil
+}
+
+func (b *IBot) editMessage(ctx context.Context, method string, payload any) (*Message, error) {
+	var msg Message
+
+	if err := b.callJSON(ctx, method, payload, &msg); err != nil {
+		return nil, notModified(err)
+	}
+
+	return &msg, nil
+}
+
+// notModified marks the error of an edit that changed nothing with ErrMessageNotModified.
+func notModified(err error) error {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && strings.Contains(apiErr.Description, ErrMessageNotModified.Error()) {
+		return fmt.Errorf("%w: %w", ErrMessageNotModified, err)
+	}
+
+	return err
+}
+
+// DeleteMessage [https://core.telegram.org/bots/api#deletemessage]
+func (b *IBot) DeleteMessage(ctx context.Context, chatID string, messageID int64) error {
+	err := b.callJSON(ctx, "deleteMessage", DeleteMessageRequest{
+		ChatID:    b.currentChatID(chatID),
+		MessageID: messageID,
+	}, nil)
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && strings.Contains(apiErr.Description, ErrMessageCantBeDeleted.Error()) {
+		return fmt.Errorf("%w: %w", ErrMessageCantBeDeleted, err)
+	}
+
+	return err
+}
+
+// ForwardMessage [https://core.telegram.org/bots/api#forwardmessage]
+//
+// It sends the message messageID of fromChatID to chatID with a link to its origin, e.g. to mirror
+// an uploaded file into another chat without uploading it again. Of opts only the message thread,
+// DisableNotification and ProtectContent are used.
+func (b *IBot) ForwardMessage(
+	ctx context.Context, chatID, fromChatID string, messageID int64, opts ...SendOption,
+) (*Message, error) {
+	o := newSendOptions(opts)
+	fromChatID = b.currentChatID(fromChatID)
+
+	var msg Message
+
+	err := b.withChatMigration(ctx, chatID, func(chatID string) error {
+		return b.callJSON(ctx, "forwardMessage", ForwardMessageRequest{
+			ChatID:              chatID,
+			FromChatID:          fromChatID,
+			MessageID:           messageID,
+			MessageThreadID:     o.messageThreadID,
+			DisableNotification: o.disableNotification,
+			ProtectContent:      o.protectContent,
+		}, &msg)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &msg, nil
+}
+
+// CopyMessage [https://core.telegram.org/bots/api#copymessage]
+//
+// It is ForwardMessage without the link to the origin, the copy keeps the caption of the message.
+// Telegram answers with the id of the copy only. Of opts the parse mode and the upload options are not used.
+func (b *IBot) CopyMessage(
+	ctx context.Context, chatID, fromChatID string, messageID int64, opts ...SendOption,
+) (int64, error) {
+	o := newSendOptions(opts)
+	fromChatID = b.currentChatID(fromChatID)
+
+	var id MessageID
+
+	err := b.withChatMigration(ctx, chatID, func(chatID string) error {
+		return b.callJSON(ctx, "copyMessage", CopyMessageRequest{
+			ChatID:              chatID,
+			FromChatID:          fromChatID,
+			MessageID:           messageID,
+			ReplyToMessageID:    o.replyToMessageID,
+			MessageThreadID:     o.messageThreadID,
+			DisableNotification: o.disableNotification,
+			ProtectContent:      o.protectContent,
+		}, &id)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return id.MessageID, nil
+}
+
+// PinChatMessage [https://core.telegram.org/bots/api#pinchatmessage]
+//
+// It pins messageID in chatID, e.g. an index of the uploaded files. Of opts only DisableNotification is used,
+// it keeps the members from being notified. A bot without the right to pin messages, an admin right in groups
+// and channels, fails with ErrNotEnoughRights.
+func (b *IBot) PinChatMessage(ctx context.Context, chatID string, messageID int64, opts ...SendOption) error {
+	o := newSendOptions(opts)
+
+	return b.withChatMigration(ctx, chatID, func(chatID string) error {
+		return b.callJSON(ctx, "pinChatMessage", PinChatMessageRequest{
+			ChatID:              chatID,
+			MessageID:           messageID,
+			DisableNotification: o.disableNotification,
+		}, nil)
+	})
+}
+
+// UnpinChatMessage [https://core.telegram.org/bots/api#unpinchatmessage]
+//
+// It unpins messageID in chatID, 0 unpins the most recent pinned message. It fails with ErrNotEnoughRights
+// like PinChatMessage.
+func (b *IBot) UnpinChatMessage(ctx context.Context, chatID string, messageID int64) error {
+	return b.withChatMigration(ctx, chatID, func(chatID string) error {
+		return b.callJSON(ctx, "unpinChatMessage", UnpinChatMessageRequest{
+			ChatID:    chatID,
+			MessageID: messageID,
+		}, nil)
+	})
+}
+
+// SendChatAction [https://core.telegram.org/bots/api#sendchataction]
+// The action is shown for 5 seconds or until the next message of the bot arrives.
+func (b *IBot) SendChatAction(ctx context.Context, chatID, action string) error {
+	return b.callJSON(ctx, "sendChatAction", SendChatActionRequest{ChatID: b.currentChatID(chatID), Action: action}, nil)
+}
@@ -1,11 +1,260 @@
 package telegram
 
+import (
+	"encoding/json"
+)
+
+// Response [https://core.telegram.org/bots/api#making-requests]
+type Response struct {
+	Ok          bool                `json:"ok"`
+	Result      json.RawMessage     `json:"result,omitempty"`
+	ErrorCode   int                 `json:"error_code,omitempty"`
+	Description string              `json:"description,omitempty"`
+	Parameters  *ResponseParameters `json:"parameters,omitempty"`
+}
+
+// ResponseParameters [https://core.telegram.org/bots/api#responseparameters]
+type ResponseParameters struct {
+	MigrateToChatID int64 `json:"migrate_to_chat_id,omitempty"`
+	RetryAfter      int   `json:"retry_after,omitempty"`
+}
+
+// SendMessageRequest [https://core.telegram.org/bots/api#sendmessage]
+type SendMessageRequest struct {
+	ChatID              string `json:"chat_id"`
+	Text                string `json:"text"`
+	ParseMode           string `json:"parse_mode,omitempty"`
+	ReplyToMessageID    int64  `json:"reply_to_message_id,omitempty"`
+	MessageThreadID     int64  `json:"message_thread_id,omitempty"`
+	DisableNotification bool   `json:"disable_notification,omitempty"`
+	ProtectContent      bool   `json:"protect_content,omitempty"`
+}
+
+// EditMessageTextRequest [https://core.telegram.org/bots/api#editmessagetext]
+type EditMessageTextRequest struct {
+	ChatID    string `json:"chat_id"`
+	MessageID int64  `json:"message_id"`
+	Text      string `json:"text"`
+	ParseMode string `json:"parse_mode,omitempty"`
+}
+
+// EditMessageCaptionRequest [https://core.telegram.org/bots/api#editmessagecaption]
+type EditMessageCaptionRequest struct {
+	ChatID    string `json:"chat_id"`
+	MessageID int64  `json:"message_id"`
+	Caption   string `json:"caption"`
+}
+
+// DeleteMessageRequest [https://core.telegram.org/bots/api#deletemessage]
+type DeleteMessageRequest struct {
+	ChatID    string `json:"chat_id"`
+	MessageID int64  `json:"message_id"`
+}
+
+// ForwardMessageRequest [https://core.telegram.org/bots/api#forwardmessage]
+type ForwardMessageRequest struct {
+	ChatID              string `json:"chat_id"`
+	FromChatID          string `json:"from_chat_id"`
+	MessageID           int64  `json:"message_id"`
+	MessageThreadID     int64  `json:"message_thread_id,omitempty"`
+	DisableNotification bool   `json:"disable_notification,omitempty"`
+	ProtectContent      bool   `json:"protect_content,omitempty"`
+}
+
+// CopyMessageRequest [https://core.telegram.org/bots/api#copymessage]
+type CopyMessageRequest struct {
+	ChatID              string `json:"chat_id"`
+	FromChatID          string `json:"from_chat_id"`
+	MessageID           int64  `json:"message_id"`
+	ReplyToMessageID    int64  `json:"reply_to_message_id,omitempty"`
+	MessageThreadID     int64  `json:"message_thread_id,omitempty"`
+	DisableNotification bool   `json:"disable_notification,omitempty"`
+	ProtectContent      bool   `json:"protect_content,omitempty"`
+}
+
+// MessageID [https://core.telegram.org/bots/api#messageid]
+type MessageID struct {
+	MessageID int64 `json:"message_id"`
+}
+
+// PinChatMessageRequest [https://core.telegram.org/bots/api#pinchatmessage]
+type PinChatMessageRequest struct {
+	ChatID              string `json:"chat_id"`
+	MessageID           int64  `json:"message_id"`
+	DisableNotification bool   `json:"disable_notification,omitempty"`
+}
+
+// UnpinChatMessageRequest [https://core.telegram.org/bots/api#unpinchatmessage]
+type UnpinChatMessageRequest struct {
+	ChatID    string `json:"chat_id"`
+	MessageID int64  `json:"message_id,omitempty"`
+}
+
+// SendChatActionRequest [https://core.telegram.org/bots/api#sendchataction]
+type SendChatActionRequest struct {
+	ChatID string `json:"chat_id"`
+	Action string `json:"action"`
+}
+
+// SetWebhookRequest [https://core.telegram.org/bots/api#setwebhook]
+type SetWebhookRequest struct {
+	URL                string   `json:"url"`
+	SecretToken        string   `json:"secret_token,omitempty"`
+	AllowedUpdates     []string `json:"allowed_updates,omitempty"`
+	MaxConnections     int      `json:"max_connections,omitempty"`
+	DropPendingUpdates bool     `json:"drop_pending_updates,omitempty"`
+}
+
+// GetUpdatesRequest [https://core.telegram.org/bots/api#getupdates]
+type GetUpdatesRequest struct {
+	Offset         int64    `json:"offset,omitempty"`
+	Limit          int      `json:"limit,omitempty"`
+	Timeout        int      `json:"timeout,omitempty"`
+	AllowedUpdates []string `json:"allowed_updates,omitempty"`
+}
+
+// DeleteWebhookRequest [https://core.telegram.org/bots/api#deletewebhook]
+type DeleteWebhookRequest struct {
+	DropPendingUpdates bool `json:"drop_pending_updates,omitempty"`
+}
+
+// GetFileRequest [https://core.telegram.org/bots/api#getfile]
+type GetFileRequest struct {
+	FileID string `json:"file_id"`
+}
+
+// File [https://core.telegram.org/bots/api#file]
+type File struct {
+	FileID       string `json:"file_id"`
+	FileUniqueID string `json:"file_unique_id"`
+	FileSize     int64  `json:"file_size,omitempty"`
+	FilePath     string `json:"file_path,omitempty"`
+}
+
+// User [https://core.telegram.org/bots/api#user]
+type User struct {
+	ID        int64  `json:"id"`
+	IsBot     bool   `json:"is_bot"`
+	FirstName string `json:"first_name"`
+	Username  string `json:"username,omitempty"`
+}
+
 // Chat [https://core.telegram.org/bots/api#chat]
+type Chat struct {
+	ID       int64  `json:"id"`
+	Type     string `json:"type"`
+	Title    string `json:"title,omitempty"`
+	Username string `json:"username,omitempty"`
+}
 
 // Message [https://core.telegram.org/bots/api#message]
+type Message struct {
+	MessageID    int64       `json:"message_id"`
+	From         *User       `json:"from,omitempty"`
+	Chat         Chat        `json:"chat"`
+	Date         int64       `json:"date"`
+	MediaGroupID string      `json:"media_group_id,omitempty"`
+	Text         string      `json:"text,omitempty"`
+	Caption      string      `json:"caption,omitempty"`
+	Audio        *Audio      `json:"audio,omitempty"`
+	Document     *Document   `json:"document,omitempty"`
+	Photo        []PhotoSize `json:"photo,omitempty"`
+	Video        *Video      `json:"video,omitempty"`
+	Voice        *Voice      `json:"voice,omitempty"`
+	VideoNote    *VideoNote  `json:"video_note,omitempty"`
+	// Animation is set along with Document for GIFs and silent videos
+	Animation *Animation `json:"animation,omitempty"`
+}
 
 // Audio [https://core.telegram.org/bots/api#audio]
+type Audio struct {
+	FileID       string `json:"file_id"`
+	FileUniqueID string `json:"file_unique_id"`
+	Duration     int    `json:"duration"`
+	Performer    string `json:"performer,omitempty"`
+	Title        string `json:"title,omitempty"`
+	FileName     string `json:"file_name,omitempty"`
+	MimeType     string `json:"mime_type,omitempty"`
+	FileSize     int64  `json:"file_size,omitempty"`
+}
 
 // Document [https://core.telegram.org/bots/api#document]
+type Document struct {
+	FileID       string `json:"file_id"`
+	FileUniqueID string `json:"file_unique_id"`
+	FileName     string `json:"file_name,omitempty"`
+	MimeType     string `json:"mime_type,omitempty"`
+	FileSize     int64  `json:"file_size,omitempty"`
+}
+
+// PhotoSize [https://core.telegram.org/bots/api#photosize]
+type PhotoSize struct {
+	FileID       string `json:"file_id"`
+	FileUniqueID string `json:"file_unique_id"`
+	Width        int    `json:"width"`
+	Height       int    `json:"height"`
+	FileSize     int64  `json:"file_size,omitempty"`
+}
+
+// Video [https://core.telegram.org/bots/api#video]
+type Video struct {
+	FileID       string `json:"file_id"`
+	FileUniqueID string `json:"file_unique_id"`
+	Width        int    `json:"width"`
+	Height       int    `json:"height"`
+	Duration     int    `json:"duration"`
+	FileName     string `json:"file_name,omitempty"`
+	MimeType     string `json:"mime_type,omitempty"`
+	FileSize     int64  `json:"file_size,omitempty"`
+}
+
+// Voice [https://core.telegram.org/bots/api#voice]
+type Voice struct {
+	FileID       string `json:"file_id"`
+	FileUniqueID string `json:"file_unique_id"`
+	Duration     int    `json:"duration"`
+	MimeType     string `json:"mime_type,omitempty"`
+	FileSize     int64  `json:"file_size,omitempty"`
+}
+
+// Animation [https://core.telegram.org/bots/api#animation]
+type Animation struct {
+	FileID       string `json:"file_id"`
+	FileUniqueID string `json:"file_unique_id"`
+	Width        int    `json:"width"`
+	Height       int    `json:"height"`
+	Duration     int    `json:"duration"`
+	FileName     string `json:"file_name,omitempty"`
+	MimeType     string `json:"mime_type,omitempty"`
+	FileSize     int64  `json:"file_size,omitempty"`
+}
+
+// VideoNote [https://core.telegram.org/bots/api#videonote]
+type VideoNote struct {
+	FileID       string `json:"file_id"`
+	FileUniqueID string `json:"file_unique_id"`
+	Length       int    `json:"length"`
+	Duration     int    `json:"duration"`
+	FileSize     int64  `json:"file_size,omitempty"`
+}
+
+// Update [https://core.telegram.org/bots/api#update]
+type Update struct {
+	UpdateID          int64    `json:"update_id"`
+	Message           *Message `json:"message,omitempty"`
+	EditedMessage     *Message `json:"edited_message,omitempty"`
+	ChannelPost       *Message `json:"channel_post,omitempty"`
+	EditedChannelPost *Message `json:"edited_channel_post,omitempty"`
+}
 
-// Media, video, ... если что-то ещё можно
+// InputMedia [https://core.telegram.org/bots/api#inputmedia]
+type InputMedia struct {
+	Type      string `json:"type"`
+	Media     string `json:"media"`
+	Caption   string `json:"caption,omitempty"`
+	ParseMode string `json:"parse_mode,omitempty"`
+	// HasSpoiler blurs a photo or video until it is tapped
+	HasSpoiler bool `json:"has_spoiler,omitempty"`
+	// Thumbnail is the attach:// name of the preview of a video, document, audio or animation
+	Thumbnail string `json:"thumbnail,omitempty"`
+}
@@ -0,0 +1,46 @@
+package telegram
+
+import (
+	"io"
+	"time"
+)
+
+// progressInterval throttles the progress callback, the final call is always made.
+const progressInterval = time.Second
+
+// ProgressFunc receives the bytes sent so far and the total size of the upload named name.
+type ProgressFunc func(name string, sent, total int64)
+
+// progressReader counts the bytes read from r and reports them to fn.
+type progressReader struct {
+	r     io.Reader
+	name  string
+	total int64
+	fn    ProgressFunc
+
+	sent       int64
+	lastReport time.Time
+}
+
+func newProgressReader(r io.Reader, name string, total int64, fn ProgressFunc) *progressReader {
+	return &progressReader{
+		r:          r,
+		name:       name,
+		total:      total,
+		fn:         fn,
+		lastReport: time.Now(),
+	}
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.sent += int64(n)
+
+	done := r.sent >= r.total
+	if n > 0 && (done || time.Since(r.lastReport) >= progressInterval) {
+		r.lastReport = time.Now()
+		r.fn(r.name, r.sent, r.total)
+	}
+
+	return n, err
+}
@@ -0,0 +1,27 @@
+package telegram
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestProgressReaderThrottled(t *testing.T) {
+	const total = 1 << 20
+
+	var calls [][2]int64
+
+	r := newProgressReader(bytes.NewReader(make([]byte, total)), "file", total, func(name string, sent, total int64) {
+		calls = append(calls, [2]int64{sent, total})
+	})
+
+	buf := make([]byte, 1024)
+	if _, err := io.CopyBuffer(io.Discard, struct{ io.Reader }{r}, buf); err != nil {
+		t.Fatal(err)
+	}
+
+	// 1024 reads happen well within progressInterval, so only the final one is reported
+	if len(calls) != 1 || calls[0] != [2]int64{total, total} {
+		t.Errorf("unexpected progress calls: %v", calls)
+	}
+}
@@ -0,0 +1,127 @@
+package telegram
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The limits of sent messages [https://core.telegram.org/bots/faq#my-bot-is-hitting-limits-how-do-i-avoid-this],
+// exceeding them gets the bot flood limits.
+const (
+	defaultMessagesPerSecond      = 30
+	defaultGroupMessagesPerMinute = 20
+)
+
+// WithMessageRateLimit keeps the sent messages below perSecond overall and perGroupPerMinute to every group
+// or channel, e.g. when a burst of new files is synced. Bursts of up to the limit are sent at once.
+// The bot keeps the limits of Telegram, 30 and 20, by default. 0 disables a limit.
+func WithMessageRateLimit(perSecond, perGroupPerMinute int) Option {
+	return func(b *IBot) {
+		b.rateLimiter = newRateLimiter(perSecond, perGroupPerMinute)
+	}
+}
+
+// tokenBucket allows capacity events at once and refills at rate events per second.
+type tokenBucket struct {
+	capacity float64
+	rate     float64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(capacity int, per time.Duration) *tokenBucket {
+	return &tokenBucket{
+		capacity: float64(capacity),
+		rate:     float64(capacity) / per.Seconds(),
+		tokens:   float64(capacity),
+	}
+}
+
+// reserve takes a token and returns how long to wait before it may be used.
+func (tb *tokenBucket) reserve(now time.Time) time.Duration {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+
+	if !tb.last.IsZero() {
+		tb.tokens = min(tb.tokens+now.Sub(tb.last).Seconds()*tb.rate, tb.capacity)
+	}
+
+	tb.last = now
+	// a missing token is booked ahead, so that the waiters are served in order
+	tb.tokens--
+
+	if tb.tokens >= 0 {
+		return 0
+	}
+
+	return time.Duration(-tb.tokens / tb.rate * float64(time.Second))
+}
+
+// rateLimiter paces the messages of a bot, nil buckets are unlimited.
+type rateLimiter struct {
+	overall *tokenBucket
+
+	perGroup int
+	mu       sync.Mutex
+	groups   map[string]*tokenBucket
+}
+
+func newRateLimiter(perSecond, perGroupPerMinute int) *rateLimiter {
+	l := &rateLimiter{perGroup: perGroupPerMinute, groups: make(map[string]*tokenBucket)}
+
+	if perSecond > 0 {
+		l.overall = newTokenBucket(perSecond, time.Second)
+	}
+
+	return l
+}
+
+// wait blocks until a message may be sent to chatID or ctx is done.
+func (l *rateLimiter) wait(ctx context.Context, chatID string) error {
+	now := time.Now()
+
+	var d time.Duration
+	if l.overall != nil {
+		d = l.overall.reserve(now)
+	}
+
+	if group := l.group(chatID); group != nil {
+		d = max(d, group.reserve(now))
+	}
+
+	if d <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// group returns the bucket of chatID if it is a group or channel, their ids are negative or usernames.
+func (l *rateLimiter) group(chatID string) *tokenBucket {
+	if l.perGroup <= 0 || !strings.HasPrefix(chatID, "-") && !strings.HasPrefix(chatID, "@") {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	bucket, ok := l.groups[chatID]
+	if !ok {
+		bucket = newTokenBucket(l.perGroup, time.Minute)
+		l.groups[chatID] = bucket
+	}
+
+	return bucket
+}
@@ -0,0 +1,43 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTokenBucket(t *testing.T) {
+	bucket := newTokenBucket(2, time.Second)
+	now := time.Now()
+
+	// the burst goes at once, the next token comes after half a second
+	for i, want := range []time.Duration{0, 0, 500 * time.Millisecond, time.Second} {
+		if got := bucket.reserve(now); got != want {
+			t.Errorf("reservation %d: wait %s, want %s", i, got, want)
+		}
+	}
+
+	// refilled, minus the two tokens booked ahead
+	if got := bucket.reserve(now.Add(3 * time.Second)); got != 0 {
+		t.Errorf("expected a token after the refill, got a wait of %s", got)
+	}
+}
+
+func TestRateLimiterPerGroup(t *testing.T) {
+	l := newRateLimiter(0, 2)
+
+	for _, chatID := range []string{"-100123", "-100123", "@channel", "42", "42", "42"} {
+		if err := l.wait(t.Context(), chatID); err != nil {
+			t.Fatalf("%s: expected no wait within the limit, got %v", chatID, err)
+		}
+	}
+
+	// the third message to the group waits for half a minute
+	ctx, cancel := context.WithTimeout(t.Context(), 50*time.Millisecond)
+	defer cancel()
+
+	if err := l.wait(ctx, "-100123"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the group to be limited, got %v", err)
+	}
+}
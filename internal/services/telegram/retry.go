@@ -0,0 +1,118 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"math/rand/v2"
+	"time"
+)
+
+const (
+	// defaultRetryDelay doubles after every failed attempt up to maxRetryDelay
+	defaultRetryDelay = 500 * time.Millisecond
+	maxRetryDelay     = 30 * time.Second
+
+	// defaultMaxFloodWait is the longest retry_after a request waits for, see WithMaxFloodWait
+	defaultMaxFloodWait = 2 * time.Minute
+	// maxFloodRetries stops a request limited again and again
+	maxFloodRetries = 5
+)
+
+// WithRetries retries a request failed by a network error or a server error up to n times
+// with a jittered, doubling delay, so that a blip doesn't fail the upload. Flood limits are
+// not counted, see WithMaxFloodWait. The bot doesn't retry by default.
+//
+// A request that timed out may have been handled by Telegram anyway, so a retried upload
+// can rarely be sent twice.
+func WithRetries(n int) Option {
+	return func(b *IBot) {
+		if n > 0 {
+			b.retries = n
+		}
+	}
+}
+
+// WithMaxFloodWait sets the longest retry_after of a flood limit a request waits for before it is sent again,
+// 2 minutes by default. A request limited for longer fails with ErrTooManyRequests, 0 fails all of them.
+func WithMaxFloodWait(d time.Duration) Option {
+	return func(b *IBot) {
+		b.maxFloodWait = max(d, 0)
+	}
+}
+
+// retry calls attempt until it succeeds, fails for good or the retries of the bot are used up.
+// A request over a flood limit is sent again once the flood gate opens. ctx cancels the waits.
+func (b *IBot) retry(ctx context.Context, method string, attempt func() error) error {
+	delay := b.retryDelay
+	retries, floods := 0, 0
+
+	for {
+		err := attempt()
+		if ctx.Err() != nil {
+			return err
+		}
+
+		if b.waitsForFlood(err) && floods < maxFloodRetries {
+			// do waits for the flood gate closed by the answer
+			floods++
+			b.logger.Warn("telegram flood limit reached, waiting", "method", method, "retry_after", retryAfter(err))
+
+			continue
+		}
+
+		if retries >= b.retries || !transient(err) {
+			return err
+		}
+
+		retries++
+
+		wait := jitter(delay)
+		b.logger.Warn("telegram request failed, retrying", "method", method, "error", err,
+			"retry", retries, "retry_in", wait)
+
+		timer := time.NewTimer(wait)
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+
+			return err
+		case <-timer.C:
+		}
+
+		delay = min(2*delay, maxRetryDelay)
+	}
+}
+
+// waitsForFlood reports whether err is a flood limit short enough to wait for.
+func (b *IBot) waitsForFlood(err error) bool {
+	wait := retryAfter(err)
+
+	return wait > 0 && wait <= b.maxFloodWait
+}
+
+// retryAfter is the retry_after of the flood limit err, 0 for other errors.
+func retryAfter(err error) time.Duration {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return 0
+	}
+
+	return apiErr.RetryAfter()
+}
+
+// transient reports whether err may go away by itself within seconds, unlike e.g. a file
+// that can't be read. Flood limits are waited for by the flood gate instead.
+func transient(err error) bool {
+	var pathErr *fs.PathError
+
+	return IsRetryable(err) && !errors.Is(err, ErrTooManyRequests) && !errors.Is(err, errNotSeekable) &&
+		!errors.As(err, &pathErr)
+}
+
+// jitter returns a random duration between d/2 and d, so that concurrent uploads
+// failed by the same blip don't retry at once.
+func jitter(d time.Duration) time.Duration {
+	return d/2 + rand.N(d/2+1) //nolint:gosec // jitter, not security
+}
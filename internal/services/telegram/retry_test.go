@@ -0,0 +1,142 @@
+package telegram
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// failingServer answers the first failures requests with status and the others with a message,
+// the file of every multipart request must be complete.
+func failingServer(t *testing.T, failures int64, status int, requests *atomic.Int64) *httptest.Server {
+	t.Helper()
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/json" {
+			if _, _, err := r.FormFile("document"); err != nil {
+				t.Errorf("expected the whole form to be sent again, got %v", err)
+			}
+		}
+
+		if requests.Add(1) <= failures {
+			w.WriteHeader(status)
+			_, _ = w.Write([]byte(`{"ok":false,"error_code":` + strconv.Itoa(status) + `,"description":"failed"}`))
+
+			return
+		}
+
+		_, _ = w.Write([]byte(`{"ok":true,"result":{"message_id":1}}`))
+	}))
+}
+
+func TestRetriesTransientErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.txt")
+	if err := os.WriteFile(path, []byte("hello"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var requests atomic.Int64
+
+	srv := failingServer(t, 2, http.StatusBadGateway, &requests)
+	defer srv.Close()
+
+	bot := NewBot("token", WithAPIURL(srv.URL+"/bot"), WithRetries(2))
+	bot.retryDelay = time.Millisecond
+
+	if _, err := bot.SendDocument(t.Context(), "chat", path, "caption"); err != nil {
+		t.Fatalf("expected the upload to succeed on the third attempt, got %v", err)
+	}
+
+	if n := requests.Load(); n != 3 {
+		t.Errorf("expected 3 requests, got %d", n)
+	}
+}
+
+func TestRetriesAreLogged(t *testing.T) {
+	var requests atomic.Int64
+
+	srv := failingServer(t, 1, http.StatusBadGateway, &requests)
+	defer srv.Close()
+
+	var logs bytes.Buffer
+
+	bot := NewBot("token", WithAPIURL(srv.URL+"/bot"), WithRetries(1),
+		WithLogger(slog.New(slog.NewTextHandler(&logs, nil))))
+	bot.retryDelay = time.Millisecond
+
+	if _, err := bot.SendMessage(t.Context(), "chat", "text"); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(logs.String(), "telegram request failed, retrying") {
+		t.Errorf("expected the retry in the logs of the bot, got %q", logs.String())
+	}
+}
+
+func TestRetriesAreLimited(t *testing.T) {
+	var requests atomic.Int64
+
+	srv := failingServer(t, 10, http.StatusInternalServerError, &requests)
+	defer srv.Close()
+
+	bot := NewBot("token", WithAPIURL(srv.URL+"/bot"), WithRetries(2))
+	bot.retryDelay = time.Millisecond
+
+	if _, err := bot.SendMessage(t.Context(), "chat", "text"); err == nil {
+		t.Fatal("expected the request to fail once the retries are used up")
+	}
+
+	if n := requests.Load(); n != 3 {
+		t.Errorf("expected 3 requests, got %d", n)
+	}
+}
+
+func TestBadRequestIsNotRetried(t *testing.T) {
+	var requests atomic.Int64
+
+	srv := failingServer(t, 10, http.StatusBadRequest, &requests)
+	defer srv.Close()
+
+	bot := NewBot("token", WithAPIURL(srv.URL+"/bot"), WithRetries(2))
+	bot.retryDelay = time.Millisecond
+
+	if _, err := bot.SendMessage(t.Context(), "chat", "text"); !errors.Is(err, ErrBadRequest) {
+		t.Fatalf("expected ErrBadRequest, got %v", err)
+	}
+
+	if n := requests.Load(); n != 1 {
+		t.Errorf("expected a single request, got %d", n)
+	}
+}
+
+func TestPlainReaderIsNotRetried(t *testing.T) {
+	var requests atomic.Int64
+
+	srv := failingServer(t, 10, http.StatusBadGateway, &requests)
+	defer srv.Close()
+
+	bot := NewBot("token", WithAPIURL(srv.URL+"/bot"), WithRetries(2))
+	bot.retryDelay = time.Millisecond
+
+	// read by the first attempt and no io.Seeker
+	r := io.MultiReader(strings.NewReader("data"))
+
+	_, err := bot.SendDocumentReader(t.Context(), "chat", r, "a.bin", 4, "")
+	if !errors.Is(err, errNotSeekable) || !IsRetryable(err) {
+		t.Errorf("expected the failure to be kept for a later sync, got %v", err)
+	}
+
+	if n := requests.Load(); n != 1 {
+		t.Errorf("expected a single request, got %d", n)
+	}
+}
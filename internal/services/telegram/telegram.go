@@ -1,10 +1,28 @@
 package telegram
 
-import "mime/multipart"
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
 
 const (
 	tgApi  = "https://api.telegram.org/bot"
+	tgFile = "https://api.telegram.org/file/bot"
 	chatId = "@testchatbotkostik"
+
+	// defaultTimeout caps a request without a file and the wait for the answer to an upload, see NewTransport
+	defaultTimeout = 60 * time.Second
 )
 
 var _ Bot = (*IBot)(nil)
@@ -13,33 +31,582 @@ var _ Bot = (*IBot)(nil)
 // [https://core.telegram.org/bots/api#available-methods]
 
 type Bot interface {
-	// SendFile()
-	// SendMessage()
-	// EditMessage()
+	GetMe(ctx context.Context) (*User, error)
+	SendDocument(ctx context.Context, chatID, filePath, caption string, opts ...SendOption) (*Message, error)
+	SendDocumentReader(ctx context.Context, chatID string, r io.Reader, filename string, size int64, caption string,
+		opts ...SendOption) (*Message, error)
+	SendAudio(ctx context.Context, chatID, filePath, caption string, opts ...SendOption) (*Message, error)
+	SendPhoto(ctx context.Context, chatID, filePath, caption string, opts ...SendOption) (*Message, error)
+	SendVideo(ctx context.Context, chatID, filePath, caption string, opts ...SendOption) (*Message, error)
+	SendVoice(ctx context.Context, chatID, filePath, caption string, opts ...SendOption) (*Message, error)
+	SendAnimation(ctx context.Context, chatID, filePath, caption string, opts ...SendOption) (*Message, error)
+	SendVideoNote(ctx context.Context, chatID, filePath string, opts ...SendOption) (*Message, error)
+	SendDocumentByRef(ctx context.Context, chatID, ref, caption string, opts ...SendOption) (*Message, error)
+	SendAudioByRef(ctx context.Context, chatID, ref, caption string, opts ...SendOption) (*Message, error)
+	SendPhotoByRef(ctx context.Context, chatID, ref, caption string, opts ...SendOption) (*Message, error)
+	SendVideoByRef(ctx context.Context, chatID, ref, caption string, opts ...SendOption) (*Message, error)
+	SendVoiceByRef(ctx context.Context, chatID, ref, caption string, opts ...SendOption) (*Message, error)
+	SendAnimationByRef(ctx context.Context, chatID, ref, caption string, opts ...SendOption) (*Message, error)
+	SendMediaGroup(ctx context.Context, chatID string, filePaths []string, caption string,
+		opts ...SendOption) ([]Message, error)
+	SendMessage(ctx context.Context, chatID, text string, opts ...SendOption) (*Message, error)
+	GetFileInfo(ctx context.Context, fileID string) (*File, error)
+	DownloadFile(ctx context.Context, filePath string, w io.Writer) error
+	EditMessageText(ctx context.Context, chatID string, messageID int64, text string, opts ...SendOption) (*Message, error)
+	EditMessageCaption(ctx context.Context, chatID string, messageID int64, caption string) (*Message, error)
+	EditMessageMedia(ctx context.Context, chatID string, messageID int64, mediaType, filePath, caption string,
+		opts ...SendOption) (*Message, error)
+	DeleteMessage(ctx context.Context, chatID string, messageID int64) error
+	ForwardMessage(ctx context.Context, chatID, fromChatID string, messageID int64, opts ...SendOption) (*Message, error)
+	CopyMessage(ctx context.Context, chatID, fromChatID string, messageID int64, opts ...SendOption) (int64, error)
+	PinChatMessage(ctx context.Context, chatID string, messageID int64, opts ...SendOption) error
+	UnpinChatMessage(ctx context.Context, chatID string, messageID int64) error
+	SendChatAction(ctx context.Context, chatID, action string) error
 	// ...
 }
 
 type IBot struct {
+	token      string
+	apiURL     string
+	fileURL    string
+	httpClient *http.Client
+
+	// maxFileSize is the largest file the bot uploads, 50MB unless a local Bot API server is used
+	maxFileSize int64
+	// localFiles sends the paths of files instead of their content, see WithLocalFiles
+	localFiles bool
+
+	// uploadThrottle caps the upload rate of multipart bodies, nil means unlimited
+	uploadThrottle *uploadThrottle
+	// progress is called while multipart bodies are sent, nil means disabled
+	progress ProgressFunc
+
+	// migratedChats maps the ids of groups upgraded to supergroups to the new ids
+	migratedChats sync.Map
+	// captionOverflow handles captions over maxCaptionLength
+	captionOverflow CaptionOverflow
+
+	// onChatMigrated is called after a migration, e.g. to persist the new id
+	onChatMigrated func(oldChatID, newChatID string)
+
+	// floodGate delays all requests after a flood limit, the context of a request cancels its wait
+	floodGate floodGate
+
+	// retries is how often a request failed by a transient error is sent again, see WithRetries
+	retries    int
+	retryDelay time.Duration
+	// maxFloodWait is the longest flood limit a request is retried after, see WithMaxFloodWait
+	maxFloodWait time.Duration
+
+	// rateLimiter paces the sent messages, see WithMessageRateLimit
+	rateLimiter *rateLimiter
+
+	// timeout caps a JSON request, uploads and downloads are only cancelled by their context
+	timeout time.Duration
+
+	// logger gets the retries, flood limits and chat migrations, see WithLogger
+	logger *slog.Logger
 }
 
-func (b *IBot) SendMessage(msg string) error {
-	// chat_id
-	// text
-	//
+type Option func(b *IBot)
 
-	return nil
+// WithHTTPClient replaces the default http.Client, e.g. to point the bot at httptest.
+// Its Timeout should be unset, it would cut off uploads and downloads taking longer, e.g. throttled
+// ones, see NewTransport.
+func WithHTTPClient(client *http.Client) Option {
+	return func(b *IBot) {
+		b.httpClient = client
+	}
+}
+
+// WithLogger replaces slog.Default, a nil logger keeps it.
+func WithLogger(logger *slog.Logger) Option {
+	return func(b *IBot) {
+		if logger != nil {
+			b.logger = logger
+		}
+	}
+}
+
+// WithAPIURL overrides the API base, the token is appended to it.
+func WithAPIURL(apiURL string) Option {
+	return func(b *IBot) {
+		b.apiURL = apiURL
+	}
+}
+
+// WithFileURL overrides the file download base, the token and the file path are appended to it.
+// A local Bot API server serves files under "<server>/file/bot".
+func WithFileURL(fileURL string) Option {
+	return func(b *IBot) {
+		b.fileURL = fileURL
+	}
+}
+
+// WithMaxFileSize raises the upload limit, a local Bot API server accepts files up to 2GB.
+func WithMaxFileSize(size int64) Option {
+	return func(b *IBot) {
+		if size > 0 {
+			b.maxFileSize = size
+		}
+	}
+}
+
+// WithLocalFiles sends files by their absolute file:// path instead of uploading them, for a local Bot API
+// server started with --local that sees the files at the same paths. Readers are still uploaded.
+func WithLocalFiles() Option {
+	return func(b *IBot) {
+		b.localFiles = true
+	}
+}
+
+// WithProxy routes all requests through proxyURL, http://, https:// and socks5:// are supported.
+// Without it HTTPS_PROXY and the other proxy environment variables are respected.
+func WithProxy(proxyURL string) Option {
+	return func(b *IBot) {
+		transport, err := NewTransport(proxyURL)
+		if err != nil {
+			// fail every request rather than silently connecting directly
+			transport = &http.Transport{Proxy: func(*http.Request) (*url.URL, error) { return nil, err }}
+		}
+
+		client := *b.httpClient
+		client.Transport = transport
+		b.httpClient = &client
+	}
+}
+
+// NewTransport returns a copy of http.DefaultTransport using proxyURL,
+// an empty proxyURL keeps the proxy from the environment.
+// Its ResponseHeaderTimeout only starts once a request is sent, so that an upload may take as long as it
+// needs while a server that doesn't answer is given up on.
+func NewTransport(proxyURL string) (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone() //nolint:forcetypeassert // set by net/http
+	transport.ResponseHeaderTimeout = defaultTimeout
+
+	if proxyURL == "" {
+		return transport, nil
+	}
+
+	u, err := url.Parse(proxyURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy url: %w", err)
+	}
+
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q", u.Scheme)
+	}
+
+	transport.Proxy = http.ProxyURL(u)
+
+	return transport, nil
 }
 
-func (b *IBot) SendAudio(msg string) error {
-	// chat_id
-	// audio (
+// WithUploadRateLimit caps the upload speed in bytes per second.
+// The limit is shared by all concurrent uploads of the bot.
+func WithUploadRateLimit(bytesPerSec int64) Option {
+	return func(b *IBot) {
+		if bytesPerSec > 0 {
+			b.uploadThrottle = newUploadThrottle(bytesPerSec)
+		}
+	}
+}
+
+// WithProgress reports the progress of every upload to fn.
+func WithProgress(fn ProgressFunc) Option {
+	return func(b *IBot) {
+		b.progress = fn
+	}
+}
+
+// WithCaptionOverflow sets how captions over the 1024 characters limit are handled,
+// they are truncated by default.
+func WithCaptionOverflow(overflow CaptionOverflow) Option {
+	return func(b *IBot) {
+		b.captionOverflow = overflow
+	}
+}
+
+// WithChatMigrated calls fn when a group is upgraded to a supergroup,
+// the bot already sends to the new id without it, fn may persist it.
+func WithChatMigrated(fn func(oldChatID, newChatID string)) Option {
+	return func(b *IBot) {
+		b.onChatMigrated = fn
+	}
+}
+
+// SendOption sets an optional parameter of the send methods.
+type SendOption func(o *sendOptions)
+
+type sendOptions struct {
+	replyToMessageID    int64
+	messageThreadID     int64
+	parseMode           string
+	disableNotification bool
+	protectContent      bool
+	// hasSpoiler, thumbnailPath, width, height, duration, performer, title and supportsStreaming are only
+	// used for uploads, see HasSpoiler, Thumbnail, VideoInfo, AudioInfo and SupportsStreaming
+	hasSpoiler        bool
+	thumbnailPath     string
+	width             int
+	height            int
+	duration          time.Duration
+	performer         string
+	title             string
+	supportsStreaming bool
+}
+
+// ReplyTo sends the message as a reply to messageID, 0 means no reply.
+func ReplyTo(messageID int64) SendOption {
+	return func(o *sendOptions) {
+		o.replyToMessageID = messageID
+	}
+}
+
+// MessageThread sends the message into the forum topic threadID of a supergroup, 0 means the general topic.
+func MessageThread(threadID int64) SendOption {
+	return func(o *sendOptions) {
+		o.messageThreadID = threadID
+	}
+}
+
+// ParseMode formats the text or caption [https://core.telegram.org/bots/api#formatting-options],
+// e.g. "HTML" or "MarkdownV2".
+func ParseMode(mode string) SendOption {
+	return func(o *sendOptions) {
+		o.parseMode = mode
+	}
+}
+
+// DisableNotification sends the message silently, users get a notification without sound.
+func DisableNotification() SendOption {
+	return func(o *sendOptions) {
+		o.disableNotification = true
+	}
+}
+
+// ProtectContent keeps the message from being forwarded and saved.
+func ProtectContent() SendOption {
+	return func(o *sendOptions) {
+		o.protectContent = true
+	}
+}
+
+// HasSpoiler blurs an uploaded photo or video until it is tapped, other files ignore it.
+func HasSpoiler() SendOption {
+	return func(o *sendOptions) {
+		o.hasSpoiler = true
+	}
+}
+
+func newSendOptions(opts []SendOption) sendOptions {
+	var o sendOptions
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
+}
+
+// writeFields writes the options that are set into the multipart form.
+func (o sendOptions) writeFields(w *multipart.Writer) error {
+	if o.replyToMessageID != 0 {
+		if err := w.WriteField("reply_to_message_id", strconv.FormatInt(o.replyToMessageID, 10)); err != nil {
+			return err
+		}
+	}
+
+	if o.messageThreadID != 0 {
+		if err := w.WriteField("message_thread_id", strconv.FormatInt(o.messageThreadID, 10)); err != nil {
+			return err
+		}
+	}
+
+	if o.parseMode != "" {
+		if err := w.WriteField("parse_mode", o.parseMode); err != nil {
+			return err
+		}
+	}
+
+	if o.disableNotification {
+		if err := w.WriteField("disable_notification", "true"); err != nil {
+			return err
+		}
+	}
+
+	if o.protectContent {
+		if err := w.WriteField("protect_content", "true"); err != nil {
+			return err
+		}
+	}
 
 	return nil
 }
 
+func NewBot(token string, opts ...Option) *IBot {
+	// the proxy is taken from the environment, that can't fail
+	transport, _ := NewTransport("")
+
+	b := &IBot{
+		token:        token,
+		apiURL:       tgApi,
+		fileURL:      tgFile,
+		httpClient:   &http.Client{Transport: transport},
+		timeout:      defaultTimeout,
+		logger:       slog.Default(),
+		maxFileSize:  maxFileSize,
+		retryDelay:   defaultRetryDelay,
+		maxFloodWait: defaultMaxFloodWait,
+		rateLimiter:  newRateLimiter(defaultMessagesPerSecond, defaultGroupMessagesPerMinute),
+	}
+
+	for _, opt := range opts {
+		opt(b)
+	}
+
+	return b
+}
+
+// GetMe [https://core.telegram.org/bots/api#getme]
+func (b *IBot) GetMe(ctx context.Context) (*User, error) {
+	var user User
+
+	if err := b.callJSON(ctx, "getMe", struct{}{}, &user); err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// Ping checks the token and the connection to the Bot API, e.g. for a readiness probe.
+func (b *IBot) Ping(ctx context.Context) error {
+	_, err := b.GetMe(ctx)
+
+	return err
+}
+
+// SendMessage [https://core.telegram.org/bots/api#sendmessage]
+//
+// Text over the 4096 characters limit is split at line breaks or spaces and sent as several messages,
+// the first one is returned.
+func (b *IBot) SendMessage(ctx context.Context, chatID, text string, opts ...SendOption) (*Message, error) {
+	var first *Message
+
+	o := newSendOptions(opts)
+
+	for _, chunk := range splitText(text, maxMessageLength) {
+		var msg Message
+
+		err := b.withChatMigration(ctx, chatID, func(chatID string) error {
+			return b.callJSON(ctx, "sendMessage", SendMessageRequest{
+				ChatID:              chatID,
+				Text:                chunk,
+				ParseMode:           o.parseMode,
+				ReplyToMessageID:    o.replyToMessageID,
+				MessageThreadID:     o.messageThreadID,
+				DisableNotification: o.disableNotification,
+				ProtectContent:      o.protectContent,
+			}, &msg)
+		})
+		if err != nil {
+			return first, err
+		}
+
+		if first == nil {
+			first = &msg
+		}
+	}
+
+	return first, nil
+}
+
 func (b *IBot) UploadFile(file *multipart.FileHeader) error {
 	//
 	//
 
 	return nil
 }
+
+// withChatMigration calls send with the current id of chatID, when the group turns out to be
+// upgraded to a supergroup the new id is remembered and send is retried with it.
+// Every send waits for the message rate limit of the bot, ctx cancels the wait.
+func (b *IBot) withChatMigration(ctx context.Context, chatID string, send func(chatID string) error) error {
+	chatID = b.currentChatID(chatID)
+
+	err := b.sendLimited(ctx, chatID, send)
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Parameters == nil || apiErr.Parameters.MigrateToChatID == 0 {
+		return err
+	}
+
+	newChatID := strconv.FormatInt(apiErr.Parameters.MigrateToChatID, 10)
+	b.migratedChats.Store(chatID, newChatID)
+
+	b.logger.Warn("chat was upgraded to a supergroup, update the configured chat id",
+		"old_chat_id", chatID, "new_chat_id", newChatID)
+
+	if b.onChatMigrated != nil {
+		b.onChatMigrated(chatID, newChatID)
+	}
+
+	return b.sendLimited(ctx, newChatID, send)
+}
+
+// currentChatID returns the id of the supergroup chatID was upgraded to, chatID if it wasn't.
+func (b *IBot) currentChatID(chatID string) string {
+	if newID, ok := b.migratedChats.Load(chatID); ok {
+		return newID.(string) //nolint:forcetypeassert // only strings are stored
+	}
+
+	return chatID
+}
+
+// sendLimited calls send once a message may be sent to chatID.
+func (b *IBot) sendLimited(ctx context.Context, chatID string, send func(chatID string) error) error {
+	if err := b.rateLimiter.wait(ctx, chatID); err != nil {
+		return fmt.Errorf("wait for the rate limit of %s: %w", chatID, err)
+	}
+
+	return send(chatID)
+}
+
+func (b *IBot) methodURL(method string) string {
+	return b.apiURL + b.token + "/" + method
+}
+
+// call posts body to the given method and decodes the result into result (if not nil),
+// ctx cancels the request.
+func (b *IBot) call(ctx context.Context, method, contentType string, body io.Reader, result any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, b.methodURL(method), body)
+	if err != nil {
+		return fmt.Errorf("create %s request: %w", method, err)
+	}
+
+	req.Header.Set("Content-Type", contentType)
+
+	return b.do(method, req, result)
+}
+
+// do sends req once the bot is past any flood limit. A flood limit holds back the requests of all
+// goroutines for its retry_after, the limited request itself fails with the APIError and is retried
+// by callJSON and callMultipart.
+func (b *IBot) do(method string, req *http.Request, result any) error {
+	if err := b.floodGate.wait(req.Context()); err != nil {
+		return fmt.Errorf("wait for flood limit before %s: %w", method, err)
+	}
+
+	resp, err := b.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("send %s request: %w", method, err)
+	}
+	defer resp.Body.Close()
+
+	var apiResp Response
+	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+		return fmt.Errorf("decode %s response (HTTP %d): %w", method, resp.StatusCode, err)
+	}
+
+	if !apiResp.Ok {
+		apiErr := &APIError{
+			Method:      method,
+			Code:        apiResp.ErrorCode,
+			Description: apiResp.Description,
+			Parameters:  apiResp.Parameters,
+		}
+
+		if retryAfter := apiErr.RetryAfter(); retryAfter > 0 {
+			b.floodGate.closeUntil(time.Now().Add(retryAfter))
+		}
+
+		return apiErr
+	}
+
+	if result == nil {
+		return nil
+	}
+
+	if err := json.Unmarshal(apiResp.Result, result); err != nil {
+		return fmt.Errorf("decode %s result: %w", method, err)
+	}
+
+	return nil
+}
+
+// callJSON posts payload encoded as JSON, retried as set by WithRetries.
+// Every attempt is cancelled after the timeout of the bot.
+func (b *IBot) callJSON(ctx context.Context, method string, payload, result any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("marshal %s request: %w", method, err)
+	}
+
+	return b.retry(ctx, method, func() error {
+		ctx, cancel := context.WithTimeout(ctx, b.timeout)
+		defer cancel()
+
+		return b.call(ctx, method, "application/json", bytes.NewReader(body), result)
+	})
+}
+
+// callMultipart calls the method with the multipart form written by build,
+// name identifies the upload for the progress callback.
+// The form is built twice: once to get its length without reading the files, then streamed into the request,
+// so that files are never held in memory. A retry, see WithRetries, streams it again after rewind moved
+// the readers of build back, nil if build opens its files itself.
+func (b *IBot) callMultipart(
+	ctx context.Context, method, name string, build func(w *formWriter) error, rewind func() error, result any,
+) error {
+	layout, err := formLength(build)
+	if err != nil {
+		return err
+	}
+
+	var lastErr error
+
+	return b.retry(ctx, method, func() error {
+		if lastErr != nil && rewind != nil {
+			if err := rewind(); err != nil {
+				return fmt.Errorf("%w, not retried: %w", lastErr, err)
+			}
+		}
+
+		lastErr = b.postForm(ctx, method, name, build, layout, result)
+
+		return lastErr
+	})
+}
+
+// postForm streams the multipart form written by build with the given layout into one request.
+func (b *IBot) postForm(
+	ctx context.Context, method, name string, build func(w *formWriter) error, layout formLayout, result any,
+) error {
+	body, err := streamForm(layout, build)
+	if err != nil {
+		return err
+	}
+	// stops the writer when the request fails before the whole body is sent
+	defer body.Close()
+
+	var r io.Reader = body
+	if b.uploadThrottle != nil {
+		r = &throttledReader{r: r, throttle: b.uploadThrottle}
+	}
+
+	if b.progress != nil {
+		r = newProgressReader(r, name, layout.size, b.progress)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, b.methodURL(method), r)
+	if err != nil {
+		return fmt.Errorf("create %s request: %w", method, err)
+	}
+
+	req.ContentLength = layout.size
+	req.Header.Set("Content-Type", "multipart/form-data; boundary="+layout.boundary)
+
+	return b.do(method, req, result)
+}
@@ -0,0 +1,435 @@
+package telegram
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const migratedChatID = "-100123"
+
+// migratingServer answers with a migration error for any chat but migratedChatID.
+func migratingServer(t *testing.T, chats *[]string) *httptest.Server {
+	t.Helper()
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var chatID string
+
+		if r.Header.Get("Content-Type") == "application/json" {
+			var req SendMessageRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				t.Fatal(err)
+			}
+
+			chatID = req.ChatID
+		} else {
+			if err := r.ParseMultipartForm(1 << 20); err != nil {
+				t.Fatal(err)
+			}
+
+			chatID = r.FormValue("chat_id")
+		}
+
+		*chats = append(*chats, chatID)
+
+		if chatID != migratedChatID {
+			_, _ = w.Write([]byte(`{"ok":false,"error_code":400,` +
+				`"description":"Bad Request: group chat was upgraded to a supergroup chat",` +
+				`"parameters":{"migrate_to_chat_id":-100123}}`))
+
+			return
+		}
+
+		_, _ = w.Write([]byte(`{"ok":true,"result":{"message_id":7}}`))
+	}))
+}
+
+func TestChatMigration(t *testing.T) {
+	var chats []string
+
+	srv := migratingServer(t, &chats)
+	defer srv.Close()
+
+	var migrated []string
+
+	bot := NewBot("token", WithAPIURL(srv.URL+"/bot"), WithChatMigrated(func(oldChatID, newChatID string) {
+		migrated = append(migrated, oldChatID, newChatID)
+	}))
+
+	msg, err := bot.SendMessage(t.Context(), "-42", "hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if msg.MessageID != 7 {
+		t.Errorf("unexpected message: %+v", msg)
+	}
+
+	if len(chats) != 2 || chats[0] != "-42" || chats[1] != migratedChatID {
+		t.Errorf("expected a retry with the new chat id, got %v", chats)
+	}
+
+	if len(migrated) != 2 || migrated[0] != "-42" || migrated[1] != migratedChatID {
+		t.Errorf("unexpected migration callback: %v", migrated)
+	}
+
+	// later uploads go to the new chat directly
+	path := filepath.Join(t.TempDir(), "a.txt")
+	if err := os.WriteFile(path, []byte("a"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := bot.SendDocument(t.Context(), "-42", path, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(chats) != 3 || chats[2] != migratedChatID {
+		t.Errorf("expected the new chat id to be reused, got %v", chats)
+	}
+}
+
+func TestChatMigrationOfMessageRequests(t *testing.T) {
+	var chats []string
+
+	srv := migratingServer(t, &chats)
+	defer srv.Close()
+
+	bot := NewBot("token", WithAPIURL(srv.URL+"/bot"))
+
+	if _, err := bot.SendMessage(t.Context(), "-42", "hello"); err != nil {
+		t.Fatal(err)
+	}
+
+	// the messages of the old chat are edited and deleted in the new one
+	if _, err := bot.EditMessageCaption(t.Context(), "-42", 7, "caption"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := bot.DeleteMessage(t.Context(), "-42", 7); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := bot.SendChatAction(t.Context(), "-42", ActionUploadDocument); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(chats) != 5 {
+		t.Fatalf("expected 5 requests, got %v", chats)
+	}
+
+	for _, chatID := range chats[2:] {
+		if chatID != migratedChatID {
+			t.Errorf("expected the new chat id to be used, got %v", chats)
+		}
+	}
+}
+
+func TestAPIError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"ok":false,"error_code":403,"description":"Forbidden: bot was kicked"}`))
+	}))
+	defer srv.Close()
+
+	bot := NewBot("token", WithAPIURL(srv.URL+"/bot"))
+
+	_, err := bot.SendMessage(t.Context(), "chat", "hello")
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != http.StatusForbidden || apiErr.Method != "sendMessage" {
+		t.Fatalf("expected an APIError, got %v", err)
+	}
+}
+
+func TestEditMessageNotModified(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/bottoken/editMessageCaption" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+
+		var req EditMessageCaptionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatal(err)
+		}
+
+		if req.ChatID != "chat" || req.MessageID != 5 || req.Caption != "same" {
+			t.Errorf("unexpected request: %+v", req)
+		}
+
+		_, _ = w.Write([]byte(`{"ok":false,"error_code":400,"description":"Bad Request: message is not modified: ` +
+			`specified new message content and reply markup are exactly the same"}`))
+	}))
+	defer srv.Close()
+
+	bot := NewBot("token", WithAPIURL(srv.URL+"/bot"))
+
+	_, err := bot.EditMessageCaption(t.Context(), "chat", 5, "same")
+	if !errors.Is(err, ErrMessageNotModified) {
+		t.Fatalf("expected ErrMessageNotModified, got %v", err)
+	}
+}
+
+func TestEditMessageMedia(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.mp4")
+	if err := os.WriteFile(path, []byte("video"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/bottoken/editMessageMedia" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Fatal(err)
+		}
+
+		var media InputMedia
+		if err := json.Unmarshal([]byte(r.FormValue("media")), &media); err != nil {
+			t.Fatal(err)
+		}
+
+		if r.FormValue("chat_id") != "chat" || r.FormValue("message_id") != "5" ||
+			media != (InputMedia{Type: "video", Media: "attach://file0", Caption: "new", HasSpoiler: true}) {
+			t.Errorf("unexpected request %v, media %+v", r.MultipartForm.Value, media)
+		}
+
+		if files := r.MultipartForm.File["file0"]; len(files) != 1 || files[0].Size != 5 {
+			t.Errorf("expected the file to be uploaded, got %+v", files)
+		}
+
+		_, _ = w.Write([]byte(`{"ok":true,"result":{"message_id":5}}`))
+	}))
+	defer srv.Close()
+
+	bot := NewBot("token", WithAPIURL(srv.URL+"/bot"))
+
+	msg, err := bot.EditMessageMedia(t.Context(), "chat", 5, "video", path, "new", HasSpoiler())
+	if err != nil || msg.MessageID != 5 {
+		t.Fatalf("unexpected result %+v, %v", msg, err)
+	}
+}
+
+func TestDeleteMessage(t *testing.T) {
+	var calls int
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+
+		if r.URL.Path != "/bottoken/deleteMessage" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+
+		var req DeleteMessageRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatal(err)
+		}
+
+		if req.ChatID != "chat" || req.MessageID != 9 {
+			t.Errorf("unexpected request: %+v", req)
+		}
+
+		if calls == 1 {
+			_, _ = w.Write([]byte(`{"ok":true,"result":true}`))
+
+			return
+		}
+
+		_, _ = w.Write([]byte(`{"ok":false,"error_code":400,"description":"Bad Request: message can't be deleted"}`))
+	}))
+	defer srv.Close()
+
+	bot := NewBot("token", WithAPIURL(srv.URL+"/bot"))
+
+	if err := bot.DeleteMessage(t.Context(), "chat", 9); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := bot.DeleteMessage(t.Context(), "chat", 9); !errors.Is(err, ErrMessageCantBeDeleted) {
+		t.Fatalf("expected ErrMessageCantBeDeleted, got %v", err)
+	}
+}
+
+func TestForwardAndCopyMessage(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req CopyMessageRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatal(err)
+		}
+
+		if req.ChatID != "mirror" || req.FromChatID != "chat" || req.MessageID != 9 || !req.DisableNotification {
+			t.Errorf("unexpected request: %+v", req)
+		}
+
+		switch r.URL.Path {
+		case "/bottoken/forwardMessage":
+			_, _ = w.Write([]byte(`{"ok":true,"result":{"message_id":10,"chat":{"id":1}}}`))
+		case "/bottoken/copyMessage":
+			_, _ = w.Write([]byte(`{"ok":true,"result":{"message_id":11}}`))
+		default:
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+	}))
+	defer srv.Close()
+
+	bot := NewBot("token", WithAPIURL(srv.URL+"/bot"))
+
+	msg, err := bot.ForwardMessage(t.Context(), "mirror", "chat", 9, DisableNotification())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if msg.MessageID != 10 {
+		t.Errorf("expected the forwarded message, got %+v", msg)
+	}
+
+	id, err := bot.CopyMessage(t.Context(), "mirror", "chat", 9, DisableNotification())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if id != 11 {
+		t.Errorf("expected the id of the copy, got %d", id)
+	}
+}
+
+func TestPinChatMessage(t *testing.T) {
+	// the ways Telegram reports missing admin rights
+	denials := []string{"Bad Request: not enough rights", "Bad Request: CHAT_ADMIN_REQUIRED"}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req PinChatMessageRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatal(err)
+		}
+
+		switch r.URL.Path {
+		case "/bottoken/pinChatMessage":
+			if req.ChatID != "chat" || req.MessageID != 9 || !req.DisableNotification {
+				t.Errorf("unexpected request: %+v", req)
+			}
+
+			_, _ = w.Write([]byte(`{"ok":true,"result":true}`))
+		case "/bottoken/unpinChatMessage":
+			_, _ = w.Write([]byte(`{"ok":false,"error_code":400,"description":"` + denials[0] + `"}`))
+			denials = denials[1:]
+		default:
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+	}))
+	defer srv.Close()
+
+	bot := NewBot("token", WithAPIURL(srv.URL+"/bot"))
+
+	if err := bot.PinChatMessage(t.Context(), "chat", 9, DisableNotification()); err != nil {
+		t.Fatal(err)
+	}
+
+	for range 2 {
+		if err := bot.UnpinChatMessage(t.Context(), "chat", 9); !errors.Is(err, ErrNotEnoughRights) {
+			t.Errorf("expected ErrNotEnoughRights, got %v", err)
+		}
+	}
+}
+
+func TestPinChatMessageFollowsMigration(t *testing.T) {
+	var chats []string
+
+	srv := migratingServer(t, &chats)
+	defer srv.Close()
+
+	bot := NewBot("token", WithAPIURL(srv.URL+"/bot"))
+
+	if err := bot.PinChatMessage(t.Context(), "-42", 9); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(chats) != 2 || chats[1] != migratedChatID {
+		t.Errorf("expected a retry with the new chat id, got %v", chats)
+	}
+}
+
+func TestWithProxy(t *testing.T) {
+	var proxied []string
+
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// a proxy gets the absolute URL of the target
+		proxied = append(proxied, r.URL.String())
+
+		_, _ = w.Write([]byte(`{"ok":true,"result":{"message_id":1}}`))
+	}))
+	defer proxy.Close()
+
+	bot := NewBot("token", WithAPIURL("http://api.telegram.invalid/bot"), WithProxy(proxy.URL))
+
+	if _, err := bot.SendMessage(t.Context(), "chat", "hello"); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(proxied) != 1 || proxied[0] != "http://api.telegram.invalid/bottoken/sendMessage" {
+		t.Errorf("request did not go through the proxy: %v", proxied)
+	}
+}
+
+func TestWithInvalidProxy(t *testing.T) {
+	bot := NewBot("token", WithAPIURL("http://api.telegram.invalid/bot"), WithProxy("ftp://proxy"))
+
+	if _, err := bot.SendMessage(t.Context(), "chat", "hello"); err == nil {
+		t.Fatal("expected an error for an unsupported proxy")
+	}
+}
+
+func TestGetMe(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/bottoken/getMe" {
+			_, _ = w.Write([]byte(`{"ok":false,"error_code":401,"description":"Unauthorized"}`))
+
+			return
+		}
+
+		_, _ = w.Write([]byte(`{"ok":true,"result":{"id":42,"is_bot":true,"first_name":"Cloud","username":"cloud_bot"}}`))
+	}))
+	defer srv.Close()
+
+	user, err := NewBot("token", WithAPIURL(srv.URL+"/bot")).GetMe(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if user.ID != 42 || !user.IsBot || user.Username != "cloud_bot" {
+		t.Errorf("unexpected user %+v", user)
+	}
+
+	if err := NewBot("bad", WithAPIURL(srv.URL+"/bot")).Ping(t.Context()); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("expected ErrUnauthorized, got %v", err)
+	}
+}
+
+func TestSendChatAction(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/bottoken/sendChatAction" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+
+		var req SendChatActionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatal(err)
+		}
+
+		if req.ChatID != "chat" || req.Action != ActionUploadVideo {
+			t.Errorf("unexpected request: %+v", req)
+		}
+
+		_, _ = w.Write([]byte(`{"ok":true,"result":true}`))
+	}))
+	defer srv.Close()
+
+	bot := NewBot("token", WithAPIURL(srv.URL+"/bot"))
+
+	if err := bot.SendChatAction(t.Context(), "chat", ActionUploadVideo); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,471 @@
+package telegramtest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/k0ff1l/tgcloudbot/internal/services/telegram"
+)
+
+// maxMemory is how much of a multipart request Server keeps in memory, the rest goes to temp files.
+const maxMemory = 32 << 20
+
+// mediaFields are the form fields of the file of the send methods.
+var mediaFields = map[string]string{ //nolint:gochecknoglobals // read-only lookup table
+	"sendDocument":  "document",
+	"sendPhoto":     "photo",
+	"sendAudio":     "audio",
+	"sendVideo":     "video",
+	"sendVoice":     "voice",
+	"sendAnimation": "animation",
+	"sendVideoNote": "video_note",
+}
+
+// Request is a call received by Server.
+type Request struct {
+	// Method is the Bot API method, e.g. "sendDocument"
+	Method string
+	// Fields are the form fields, or the fields of a JSON body with the values that aren't strings as JSON
+	Fields map[string]string
+	// Files are the uploaded files by their form field, e.g. "document"
+	Files map[string]UploadedFile
+}
+
+// UploadedFile is a file part of a multipart request.
+type UploadedFile struct {
+	Name string
+	Data []byte
+}
+
+// Server is a mock Bot API server to run telegram.IBot against, see APIURL and FileURL. It parses the JSON
+// and multipart requests, rejects a send without chat_id or without its file like the Bot API does, and
+// answers with messages that carry a new file_id per upload. getFile and the file URL serve the uploaded
+// files again. Enqueue replaces the next answers of a method, e.g. with FloodResponse.
+// It is safe for concurrent use.
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	requests []Request
+	queued   map[string][]telegram.Response
+	// migrated answers every request to a chat with the migration to the new chat id
+	migrated map[string]int64
+	// files are the contents by file_id
+	files  map[string][]byte
+	lastID int64
+}
+
+// NewServer starts a Server, it must be closed.
+func NewServer() *Server {
+	s := &Server{
+		queued:   make(map[string][]telegram.Response),
+		migrated: make(map[string]int64),
+		files:    make(map[string][]byte),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+
+	return s
+}
+
+// APIURL is the API base for telegram.WithAPIURL.
+func (s *Server) APIURL() string {
+	return s.URL + "/bot"
+}
+
+// FileURL is the file download base for telegram.WithFileURL.
+func (s *Server) FileURL() string {
+	return s.URL + "/file/bot"
+}
+
+// Bot returns a bot talking to the server, opts are applied after its URLs.
+func (s *Server) Bot(opts ...telegram.Option) *telegram.IBot {
+	return telegram.NewBot("token", append([]telegram.Option{
+		telegram.WithAPIURL(s.APIURL()), telegram.WithFileURL(s.FileURL()),
+	}, opts...)...)
+}
+
+// Enqueue answers the next calls of method (e.g. "sendDocument") with responses, one each, before
+// the default answers resume. The calls are recorded all the same.
+func (s *Server) Enqueue(method string, responses ...telegram.Response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.queued[method] = append(s.queued[method], responses...)
+}
+
+// MigrateChat answers every request to chatID with its migration to newChatID,
+// as for a group that was upgraded to a supergroup.
+func (s *Server) MigrateChat(chatID string, newChatID int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.migrated[chatID] = newChatID
+}
+
+// AddFile makes data available by fileID to getFile and the file URL, as if it had been uploaded.
+func (s *Server) AddFile(fileID string, data []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.files[fileID] = data
+}
+
+// Requests returns the received requests of the given methods in order, all of them without methods.
+func (s *Server) Requests(methods ...string) []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var requests []Request
+
+	for _, req := range s.requests {
+		if len(methods) == 0 || slices.Contains(methods, req.Method) {
+			requests = append(requests, req)
+		}
+	}
+
+	return requests
+}
+
+// OK is a successful response with result.
+func OK(result any) telegram.Response {
+	data, err := json.Marshal(result)
+	if err != nil {
+		panic(fmt.Sprintf("telegramtest: marshal result: %v", err))
+	}
+
+	return telegram.Response{Ok: true, Result: data}
+}
+
+// ErrorResponse is a failed response, e.g. ErrorResponse(http.StatusBadRequest, "Bad Request: chat not found").
+func ErrorResponse(code int, description string) telegram.Response {
+	return telegram.Response{ErrorCode: code, Description: description}
+}
+
+// FloodResponse is the response to a request over the flood limit.
+func FloodResponse(retryAfter int) telegram.Response {
+	return telegram.Response{
+		ErrorCode:   http.StatusTooManyRequests,
+		Description: "Too Many Requests: retry after " + strconv.Itoa(retryAfter),
+		Parameters:  &telegram.ResponseParameters{RetryAfter: retryAfter},
+	}
+}
+
+// MigrateResponse is the response to a request to a group that became the supergroup newChatID.
+func MigrateResponse(newChatID int64) telegram.Response {
+	return telegram.Response{
+		ErrorCode:   http.StatusBadRequest,
+		Description: "Bad Request: group chat was upgraded to a supergroup chat",
+		Parameters:  &telegram.ResponseParameters{MigrateToChatID: newChatID},
+	}
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if rest, ok := strings.CutPrefix(r.URL.Path, "/file/bot"); ok {
+		s.serveFile(w, rest)
+
+		return
+	}
+
+	if !strings.HasPrefix(r.URL.Path, "/bot") {
+		http.NotFound(w, r)
+
+		return
+	}
+
+	req, err := parseRequest(path.Base(r.URL.Path), r)
+	if err != nil {
+		writeResponse(w, ErrorResponse(http.StatusBadRequest, "Bad Request: "+err.Error()))
+
+		return
+	}
+
+	writeResponse(w, s.handle(req))
+}
+
+// serveFile serves the file at "<token>/<file_path>", the file_path of getFile is the file_id.
+func (s *Server) serveFile(w http.ResponseWriter, tokenAndPath string) {
+	_, fileID, _ := strings.Cut(tokenAndPath, "/")
+
+	s.mu.Lock()
+	data, ok := s.files[fileID]
+	s.mu.Unlock()
+
+	if !ok {
+		http.NotFound(w, nil)
+
+		return
+	}
+
+	_, _ = w.Write(data)
+}
+
+// handle records req and returns its answer.
+func (s *Server) handle(req Request) telegram.Response {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests = append(s.requests, req)
+
+	if newChatID, ok := s.migrated[req.Fields["chat_id"]]; ok {
+		return MigrateResponse(newChatID)
+	}
+
+	if queued := s.queued[req.Method]; len(queued) > 0 {
+		s.queued[req.Method] = queued[1:]
+
+		return queued[0]
+	}
+
+	if err := validate(req); err != nil {
+		return ErrorResponse(http.StatusBadRequest, "Bad Request: "+err.Error())
+	}
+
+	switch req.Method {
+	case "getMe":
+		return OK(telegram.User{ID: 1, IsBot: true, FirstName: "Mock", Username: "mock_bot"})
+	case "getFile":
+		data, ok := s.files[req.Fields["file_id"]]
+		if !ok {
+			return ErrorResponse(http.StatusBadRequest, "Bad Request: invalid file_id")
+		}
+
+		fileID := req.Fields["file_id"]
+
+		return OK(telegram.File{FileID: fileID, FileUniqueID: fileID, FileSize: int64(len(data)), FilePath: fileID})
+	case "sendMediaGroup":
+		return s.sendMediaGroup(req)
+	}
+
+	if field, ok := mediaFields[req.Method]; ok || req.Method == "sendMessage" {
+		return OK(s.newMessage(req, field))
+	}
+
+	// the edited message, only with the edited text or caption
+	if strings.HasPrefix(req.Method, "editMessage") {
+		chatID, _ := strconv.ParseInt(req.Fields["chat_id"], 10, 64)
+		messageID, _ := strconv.ParseInt(req.Fields["message_id"], 10, 64)
+
+		return OK(telegram.Message{
+			MessageID: messageID,
+			Chat:      telegram.Chat{ID: chatID, Type: "channel"},
+			Text:      req.Fields["text"],
+			Caption:   req.Fields["caption"],
+		})
+	}
+
+	return OK(true)
+}
+
+// sendMediaGroup answers with a message per item of the media field.
+func (s *Server) sendMediaGroup(req Request) telegram.Response {
+	var media []telegram.InputMedia
+	if err := json.Unmarshal([]byte(req.Fields["media"]), &media); err != nil || len(media) == 0 {
+		return ErrorResponse(http.StatusBadRequest, "Bad Request: invalid media")
+	}
+
+	group := strconv.FormatInt(s.lastID+1, 10)
+	msgs := make([]telegram.Message, 0, len(media))
+
+	for _, item := range media {
+		itemReq := Request{
+			Method: "send" + item.Type,
+			Fields: map[string]string{"chat_id": req.Fields["chat_id"], "caption": item.Caption, item.Type: item.Media},
+			Files:  req.Files,
+		}
+
+		if name, ok := strings.CutPrefix(item.Media, "attach://"); ok && len(req.Files[name].Data) == 0 {
+			return ErrorResponse(http.StatusBadRequest, "Bad Request: file of "+item.Media+" not found in the request")
+		}
+
+		msg := s.newMessage(itemReq, item.Type)
+		msg.MediaGroupID = group
+		msgs = append(msgs, msg)
+	}
+
+	return OK(msgs)
+}
+
+// validate rejects the requests the Bot API would reject for their fields.
+func validate(req Request) error {
+	if !strings.HasPrefix(req.Method, "send") {
+		return nil
+	}
+
+	if req.Fields["chat_id"] == "" {
+		return errors.New("chat_id is empty")
+	}
+
+	field, ok := mediaFields[req.Method]
+	if !ok {
+		return nil
+	}
+
+	ref := req.Fields[field]
+	if ref == "" {
+		if _, ok := req.Files[field]; !ok {
+			return fmt.Errorf("there is no %s in the request", field)
+		}
+	}
+
+	// an attach:// reference names the part of the file
+	if name, ok := strings.CutPrefix(ref, "attach://"); ok {
+		if _, ok := req.Files[name]; !ok {
+			return fmt.Errorf("file of %s not found in the request", ref)
+		}
+	}
+
+	for name, file := range req.Files {
+		if len(file.Data) == 0 {
+			return fmt.Errorf("file %s must be non-empty", name)
+		}
+	}
+
+	return nil
+}
+
+// newMessage returns the message sent by req, with the file of field if set. The caller holds mu.
+func (s *Server) newMessage(req Request, field string) telegram.Message {
+	s.lastID++
+
+	chatID, _ := strconv.ParseInt(req.Fields["chat_id"], 10, 64)
+	msg := telegram.Message{
+		MessageID: s.lastID,
+		Chat:      telegram.Chat{ID: chatID, Type: "channel"},
+		Date:      time.Now().Unix(),
+		Text:      req.Fields["text"],
+		Caption:   req.Fields["caption"],
+	}
+
+	if field == "" {
+		return msg
+	}
+
+	fileID, name, size := s.storeFile(req, field)
+
+	switch field {
+	case "photo":
+		msg.Photo = []telegram.PhotoSize{{FileID: fileID, FileUniqueID: fileID, FileSize: size}}
+	case "audio":
+		msg.Audio = &telegram.Audio{FileID: fileID, FileUniqueID: fileID, FileName: name, FileSize: size}
+	case "video":
+		msg.Video = &telegram.Video{FileID: fileID, FileUniqueID: fileID, FileName: name, FileSize: size}
+	case "voice":
+		msg.Voice = &telegram.Voice{FileID: fileID, FileUniqueID: fileID, FileSize: size}
+	case "animation":
+		msg.Animation = &telegram.Animation{FileID: fileID, FileUniqueID: fileID, FileName: name, FileSize: size}
+	case "video_note":
+		msg.VideoNote = &telegram.VideoNote{FileID: fileID, FileUniqueID: fileID, FileSize: size}
+	default:
+		msg.Document = &telegram.Document{FileID: fileID, FileUniqueID: fileID, FileName: name, FileSize: size}
+	}
+
+	return msg
+}
+
+// storeFile keeps the file of field under a new file_id, a file sent by its file_id keeps it.
+// The caller holds mu.
+func (s *Server) storeFile(req Request, field string) (fileID, name string, size int64) {
+	ref := req.Fields[field]
+
+	if part, ok := strings.CutPrefix(ref, "attach://"); ok || ref == "" {
+		if !ok {
+			part = field
+		}
+
+		file := req.Files[part]
+		fileID = "file" + strconv.FormatInt(s.lastID, 10)
+		s.files[fileID] = file.Data
+
+		return fileID, file.Name, int64(len(file.Data))
+	}
+
+	return ref, "", int64(len(s.files[ref]))
+}
+
+// parseRequest reads the fields and files of a JSON, multipart or url-encoded request.
+func parseRequest(method string, r *http.Request) (Request, error) {
+	req := Request{Method: method, Fields: make(map[string]string), Files: make(map[string]UploadedFile)}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+	switch mediaType {
+	case "application/json":
+		var body map[string]json.RawMessage
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			return req, fmt.Errorf("invalid JSON: %w", err)
+		}
+
+		for key, raw := range body {
+			var value string
+			if err := json.Unmarshal(raw, &value); err != nil {
+				value = string(raw)
+			}
+
+			req.Fields[key] = value
+		}
+	case "multipart/form-data":
+		if err := r.ParseMultipartForm(maxMemory); err != nil {
+			return req, fmt.Errorf("invalid multipart form: %w", err)
+		}
+
+		for key, values := range r.MultipartForm.Value {
+			req.Fields[key] = values[0]
+		}
+
+		for key, headers := range r.MultipartForm.File {
+			data, err := readPart(headers[0])
+			if err != nil {
+				return req, err
+			}
+
+			req.Files[key] = UploadedFile{Name: headers[0].Filename, Data: data}
+		}
+	default:
+		if err := r.ParseForm(); err != nil {
+			return req, fmt.Errorf("invalid form: %w", err)
+		}
+
+		for key := range r.Form {
+			req.Fields[key] = r.Form.Get(key)
+		}
+	}
+
+	return req, nil
+}
+
+func readPart(header *multipart.FileHeader) ([]byte, error) {
+	f, err := header.Open()
+	if err != nil {
+		return nil, fmt.Errorf("open file part: %w", err)
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, fmt.Errorf("read file part: %w", err)
+	}
+
+	return data, nil
+}
+
+func writeResponse(w http.ResponseWriter, resp telegram.Response) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if !resp.Ok {
+		w.WriteHeader(resp.ErrorCode)
+	}
+
+	_ = json.NewEncoder(w).Encode(resp)
+}
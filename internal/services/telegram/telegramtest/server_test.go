@@ -0,0 +1,160 @@
+package telegramtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/k0ff1l/tgcloudbot/internal/services/telegram"
+)
+
+func TestServerSendDocument(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+
+	data := []byte("the content of the document")
+
+	path := filepath.Join(t.TempDir(), "report.txt")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	bot := srv.Bot()
+
+	msg, err := bot.SendDocument(t.Context(), "-100", path, "File: report.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if msg.Document == nil || msg.Document.FileID == "" || msg.Document.FileSize != int64(len(data)) ||
+		msg.Chat.ID != -100 {
+		t.Fatalf("unexpected message %+v", msg)
+	}
+
+	reqs := srv.Requests("sendDocument")
+	if len(reqs) != 1 {
+		t.Fatalf("expected one sendDocument, got %+v", srv.Requests())
+	}
+
+	req := reqs[0]
+	if req.Fields["chat_id"] != "-100" || req.Fields["caption"] != "File: report.txt" {
+		t.Errorf("unexpected fields %v", req.Fields)
+	}
+
+	if doc := req.Files["document"]; doc.Name != "report.txt" || !bytes.Equal(doc.Data, data) {
+		t.Errorf("unexpected upload %q: %q", doc.Name, doc.Data)
+	}
+
+	// the upload can be downloaded again
+	info, err := bot.GetFileInfo(t.Context(), msg.Document.FileID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var downloaded bytes.Buffer
+	if err := bot.DownloadFile(t.Context(), info.FilePath, &downloaded); err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(downloaded.Bytes(), data) {
+		t.Errorf("downloaded %q, want %q", downloaded.Bytes(), data)
+	}
+}
+
+func TestServerFloodLimitThenSuccess(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+
+	srv.Enqueue("sendMessage", FloodResponse(1))
+
+	bot := srv.Bot()
+
+	// the bot waits for retry_after and gets the default answer
+	start := time.Now()
+
+	msg, err := bot.SendMessage(t.Context(), "-100", "hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if elapsed := time.Since(start); elapsed < 900*time.Millisecond {
+		t.Errorf("the retry was sent %s after the flood limit of 1s", elapsed)
+	}
+
+	if msg.Text != "hello" || len(srv.Requests("sendMessage")) != 2 {
+		t.Errorf("unexpected message %+v after %+v", msg, srv.Requests())
+	}
+}
+
+func TestServerChatMigration(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+
+	srv.MigrateChat("-42", -100123)
+
+	msg, err := srv.Bot().SendMessage(t.Context(), "-42", "hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reqs := srv.Requests("sendMessage")
+	if len(reqs) != 2 || reqs[0].Fields["chat_id"] != "-42" || reqs[1].Fields["chat_id"] != "-100123" {
+		t.Errorf("expected a retry in the new chat, got %+v", reqs)
+	}
+
+	if msg.Chat.ID != -100123 {
+		t.Errorf("unexpected message %+v", msg)
+	}
+}
+
+func TestServerRejectsInvalidRequests(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+
+	// the bot checks its requests itself, the server is called directly
+	for body, want := range map[string]string{
+		`{"chat_id": "-100"}`:                 "there is no document in the request",
+		`{"document": "file1"}`:               "chat_id is empty",
+		`{"chat_id": -100, "document": "a.b"`: "invalid JSON",
+	} {
+		req, err := http.NewRequestWithContext(t.Context(), http.MethodPost, srv.APIURL()+"token/sendDocument",
+			strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := srv.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var apiResp telegram.Response
+
+		err = json.NewDecoder(resp.Body).Decode(&apiResp)
+		_ = resp.Body.Close()
+
+		if err != nil || resp.StatusCode != http.StatusBadRequest || !strings.Contains(apiResp.Description, want) {
+			t.Errorf("%s: got %d %+v, %v, want %q", body, resp.StatusCode, apiResp, err, want)
+		}
+	}
+
+	bot := srv.Bot()
+
+	if _, err := bot.GetFileInfo(t.Context(), "unknown"); err == nil || !strings.Contains(err.Error(), "invalid file_id") {
+		t.Errorf("expected an unknown file_id to fail, got %v", err)
+	}
+
+	srv.Enqueue("sendMessage", ErrorResponse(http.StatusForbidden, "Forbidden: bot was kicked from the channel chat"))
+
+	if _, err := bot.SendMessage(t.Context(), "-100", "hello"); !errors.Is(err, telegram.ErrForbidden) {
+		t.Errorf("expected the enqueued error, got %v", err)
+	}
+}
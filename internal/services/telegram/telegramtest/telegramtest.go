@@ -0,0 +1,520 @@
+// Package telegramtest provides test doubles of telegram.Bot and a mock Bot API server, see Server.
+package telegramtest
+
+import (
+	"context"
+	"io"
+	"slices"
+	"sync"
+
+	"github.com/k0ff1l/tgcloudbot/internal/services/telegram"
+)
+
+var (
+	_ telegram.Bot = (*FakeClient)(nil)
+	_ telegram.Bot = NoopClient{}
+)
+
+// Call is a recorded call of FakeClient, only the fields of the method are set.
+type Call struct {
+	Method    string
+	ChatID    string
+	Paths     []string
+	Caption   string
+	Text      string
+	MessageID int64
+	FileID    string
+	// FromChatID is the source chat of ForwardMessage and CopyMessage
+	FromChatID string
+	Options    []telegram.SendOption
+}
+
+// FakeClient records every call and answers with a new message id, unless a canned response
+// or an error is set for the method. It is safe for concurrent use.
+type FakeClient struct {
+	mu        sync.Mutex
+	calls     []Call
+	errs      map[string]error
+	responses map[string]telegram.Message
+	files     map[string][]byte
+	lastID    int64
+}
+
+func NewFakeClient() *FakeClient {
+	return &FakeClient{
+		errs:      make(map[string]error),
+		responses: make(map[string]telegram.Message),
+		files:     make(map[string][]byte),
+	}
+}
+
+// FailWith makes every following call of method (e.g. "SendPhoto") return err, nil clears it.
+func (f *FakeClient) FailWith(method string, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err == nil {
+		delete(f.errs, method)
+
+		return
+	}
+
+	f.errs[method] = err
+}
+
+// RespondWith makes every following call of method return msg.
+func (f *FakeClient) RespondWith(method string, msg telegram.Message) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.responses[method] = msg
+}
+
+// ServeFile makes DownloadFile write data for the file with fileID.
+func (f *FakeClient) ServeFile(fileID string, data []byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.files[fileID] = data
+}
+
+// Calls returns the recorded calls in order.
+func (f *FakeClient) Calls() []Call {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return slices.Clone(f.calls)
+}
+
+// CallsTo returns the recorded calls of the given methods in order.
+func (f *FakeClient) CallsTo(methods ...string) []Call {
+	var calls []Call
+
+	for _, call := range f.Calls() {
+		if slices.Contains(methods, call.Method) {
+			calls = append(calls, call)
+		}
+	}
+
+	return calls
+}
+
+// Uploaded returns the paths of all uploaded files in order.
+func (f *FakeClient) Uploaded() []string {
+	var paths []string
+
+	for _, call := range f.Calls() {
+		paths = append(paths, call.Paths...)
+	}
+
+	return paths
+}
+
+// Texts returns the texts of the sent messages in order.
+func (f *FakeClient) Texts() []string {
+	var texts []string
+
+	for _, call := range f.CallsTo("SendMessage") {
+		texts = append(texts, call.Text)
+	}
+
+	return texts
+}
+
+// record stores the call and returns the response of its method.
+func (f *FakeClient) record(call Call) (*telegram.Message, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.calls = append(f.calls, call)
+
+	if err := f.errs[call.Method]; err != nil {
+		return nil, err
+	}
+
+	if msg, ok := f.responses[call.Method]; ok {
+		return &msg, nil
+	}
+
+	f.lastID++
+
+	return &telegram.Message{MessageID: f.lastID, Caption: call.Caption, Text: call.Text}, nil
+}
+
+func (f *FakeClient) sendFile(
+	method, chatID, filePath, caption string, opts []telegram.SendOption,
+) (*telegram.Message, error) {
+	return f.record(Call{Method: method, ChatID: chatID, Paths: []string{filePath}, Caption: caption, Options: opts})
+}
+
+// GetMe answers with a bot user named "fake_bot".
+func (f *FakeClient) GetMe(_ context.Context) (*telegram.User, error) {
+	if _, err := f.record(Call{Method: "GetMe"}); err != nil {
+		return nil, err
+	}
+
+	return &telegram.User{ID: 1, IsBot: true, FirstName: "Fake", Username: "fake_bot"}, nil
+}
+
+func (f *FakeClient) SendDocument(
+	_ context.Context, chatID, filePath, caption string, opts ...telegram.SendOption,
+) (*telegram.Message, error) {
+	return f.sendFile("SendDocument", chatID, filePath, caption, opts)
+}
+
+// SendDocumentReader reads r and records filename as the path of the call.
+func (f *FakeClient) SendDocumentReader(
+	_ context.Context, chatID string, r io.Reader, filename string, _ int64, caption string, opts ...telegram.SendOption,
+) (*telegram.Message, error) {
+	if _, err := io.Copy(io.Discard, r); err != nil {
+		return nil, err
+	}
+
+	return f.sendFile("SendDocumentReader", chatID, filename, caption, opts)
+}
+
+func (f *FakeClient) SendAudio(
+	_ context.Context, chatID, filePath, caption string, opts ...telegram.SendOption,
+) (*telegram.Message, error) {
+	return f.sendFile("SendAudio", chatID, filePath, caption, opts)
+}
+
+func (f *FakeClient) SendPhoto(
+	_ context.Context, chatID, filePath, caption string, opts ...telegram.SendOption,
+) (*telegram.Message, error) {
+	return f.sendFile("SendPhoto", chatID, filePath, caption, opts)
+}
+
+func (f *FakeClient) SendVideo(
+	_ context.Context, chatID, filePath, caption string, opts ...telegram.SendOption,
+) (*telegram.Message, error) {
+	return f.sendFile("SendVideo", chatID, filePath, caption, opts)
+}
+
+func (f *FakeClient) SendVoice(
+	_ context.Context, chatID, filePath, caption string, opts ...telegram.SendOption,
+) (*telegram.Message, error) {
+	return f.sendFile("SendVoice", chatID, filePath, caption, opts)
+}
+
+func (f *FakeClient) SendAnimation(
+	_ context.Context, chatID, filePath, caption string, opts ...telegram.SendOption,
+) (*telegram.Message, error) {
+	return f.sendFile("SendAnimation", chatID, filePath, caption, opts)
+}
+
+func (f *FakeClient) SendVideoNote(
+	_ context.Context, chatID, filePath string, opts ...telegram.SendOption,
+) (*telegram.Message, error) {
+	return f.sendFile("SendVideoNote", chatID, filePath, "", opts)
+}
+
+// sendFileByRef records the ref as the FileID of the call.
+func (f *FakeClient) sendFileByRef(
+	method, chatID, ref, caption string, opts []telegram.SendOption,
+) (*telegram.Message, error) {
+	return f.record(Call{Method: method, ChatID: chatID, FileID: ref, Caption: caption, Options: opts})
+}
+
+func (f *FakeClient) SendDocumentByRef(
+	_ context.Context, chatID, ref, caption string, opts ...telegram.SendOption,
+) (*telegram.Message, error) {
+	return f.sendFileByRef("SendDocumentByRef", chatID, ref, caption, opts)
+}
+
+func (f *FakeClient) SendAudioByRef(
+	_ context.Context, chatID, ref, caption string, opts ...telegram.SendOption,
+) (*telegram.Message, error) {
+	return f.sendFileByRef("SendAudioByRef", chatID, ref, caption, opts)
+}
+
+func (f *FakeClient) SendPhotoByRef(
+	_ context.Context, chatID, ref, caption string, opts ...telegram.SendOption,
+) (*telegram.Message, error) {
+	return f.sendFileByRef("SendPhotoByRef", chatID, ref, caption, opts)
+}
+
+func (f *FakeClient) SendVideoByRef(
+	_ context.Context, chatID, ref, caption string, opts ...telegram.SendOption,
+) (*telegram.Message, error) {
+	return f.sendFileByRef("SendVideoByRef", chatID, ref, caption, opts)
+}
+
+func (f *FakeClient) SendVoiceByRef(
+	_ context.Context, chatID, ref, caption string, opts ...telegram.SendOption,
+) (*telegram.Message, error) {
+	return f.sendFileByRef("SendVoiceByRef", chatID, ref, caption, opts)
+}
+
+func (f *FakeClient) SendAnimationByRef(
+	_ context.Context, chatID, ref, caption string, opts ...telegram.SendOption,
+) (*telegram.Message, error) {
+	return f.sendFileByRef("SendAnimationByRef", chatID, ref, caption, opts)
+}
+
+// SendMediaGroup answers with one message per file.
+func (f *FakeClient) SendMediaGroup(
+	_ context.Context, chatID string, filePaths []string, caption string, opts ...telegram.SendOption,
+) ([]telegram.Message, error) {
+	msg, err := f.record(Call{
+		Method: "SendMediaGroup", ChatID: chatID, Paths: slices.Clone(filePaths), Caption: caption, Options: opts,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	msgs := make([]telegram.Message, len(filePaths))
+	for i := range msgs {
+		msgs[i] = *msg
+	}
+
+	return msgs, nil
+}
+
+func (f *FakeClient) SendMessage(
+	_ context.Context, chatID, text string, opts ...telegram.SendOption,
+) (*telegram.Message, error) {
+	return f.record(Call{Method: "SendMessage", ChatID: chatID, Text: text, Options: opts})
+}
+
+func (f *FakeClient) EditMessageText(
+	_ context.Context, chatID string, messageID int64, text string, opts ...telegram.SendOption,
+) (*telegram.Message, error) {
+	return f.record(Call{Method: "EditMessageText", ChatID: chatID, MessageID: messageID, Text: text, Options: opts})
+}
+
+func (f *FakeClient) EditMessageCaption(
+	_ context.Context, chatID string, messageID int64, caption string,
+) (*telegram.Message, error) {
+	return f.record(Call{Method: "EditMessageCaption", ChatID: chatID, MessageID: messageID, Caption: caption})
+}
+
+// EditMessageMedia records the media type as the text of the call, the message keeps its id.
+func (f *FakeClient) EditMessageMedia(
+	_ context.Context, chatID string, messageID int64, mediaType, filePath, caption string, opts ...telegram.SendOption,
+) (*telegram.Message, error) {
+	msg, err := f.record(Call{
+		Method: "EditMessageMedia", ChatID: chatID, MessageID: messageID, Paths: []string{filePath},
+		Caption: caption, Text: mediaType, Options: opts,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	msg.MessageID = messageID
+
+	return msg, nil
+}
+
+func (f *FakeClient) DeleteMessage(_ context.Context, chatID string, messageID int64) error {
+	_, err := f.record(Call{Method: "DeleteMessage", ChatID: chatID, MessageID: messageID})
+
+	return err
+}
+
+// ForwardMessage records the forwarded message id, the forward gets a new one.
+func (f *FakeClient) ForwardMessage(
+	_ context.Context, chatID, fromChatID string, messageID int64, opts ...telegram.SendOption,
+) (*telegram.Message, error) {
+	return f.record(Call{
+		Method: "ForwardMessage", ChatID: chatID, FromChatID: fromChatID, MessageID: messageID, Options: opts,
+	})
+}
+
+// CopyMessage records the copied message id and answers with the id of a new message.
+func (f *FakeClient) CopyMessage(
+	_ context.Context, chatID, fromChatID string, messageID int64, opts ...telegram.SendOption,
+) (int64, error) {
+	msg, err := f.record(Call{
+		Method: "CopyMessage", ChatID: chatID, FromChatID: fromChatID, MessageID: messageID, Options: opts,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return msg.MessageID, nil
+}
+
+func (f *FakeClient) PinChatMessage(
+	_ context.Context, chatID string, messageID int64, opts ...telegram.SendOption,
+) error {
+	_, err := f.record(Call{Method: "PinChatMessage", ChatID: chatID, MessageID: messageID, Options: opts})
+
+	return err
+}
+
+func (f *FakeClient) UnpinChatMessage(_ context.Context, chatID string, messageID int64) error {
+	_, err := f.record(Call{Method: "UnpinChatMessage", ChatID: chatID, MessageID: messageID})
+
+	return err
+}
+
+// SendChatAction records the action as the text of the call.
+func (f *FakeClient) SendChatAction(_ context.Context, chatID, action string) error {
+	_, err := f.record(Call{Method: "SendChatAction", ChatID: chatID, Text: action})
+
+	return err
+}
+
+// GetFileInfo answers with the file path set to the file id.
+func (f *FakeClient) GetFileInfo(_ context.Context, fileID string) (*telegram.File, error) {
+	if _, err := f.record(Call{Method: "GetFileInfo", FileID: fileID}); err != nil {
+		return nil, err
+	}
+
+	return &telegram.File{FileID: fileID, FilePath: fileID}, nil
+}
+
+// DownloadFile writes the data set by ServeFile, nothing by default.
+func (f *FakeClient) DownloadFile(_ context.Context, filePath string, w io.Writer) error {
+	if _, err := f.record(Call{Method: "DownloadFile", Paths: []string{filePath}}); err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	data := f.files[filePath]
+	f.mu.Unlock()
+
+	_, err := w.Write(data)
+
+	return err
+}
+
+// NoopClient does nothing and answers every call with an empty message.
+type NoopClient struct{}
+
+func (NoopClient) GetMe(_ context.Context) (*telegram.User, error) {
+	return &telegram.User{IsBot: true}, nil
+}
+
+func (NoopClient) SendDocument(_ context.Context, _, _, _ string, _ ...telegram.SendOption) (*telegram.Message, error) {
+	return &telegram.Message{}, nil
+}
+
+func (NoopClient) SendAudio(_ context.Context, _, _, _ string, _ ...telegram.SendOption) (*telegram.Message, error) {
+	return &telegram.Message{}, nil
+}
+
+func (NoopClient) SendPhoto(_ context.Context, _, _, _ string, _ ...telegram.SendOption) (*telegram.Message, error) {
+	return &telegram.Message{}, nil
+}
+
+func (NoopClient) SendVideo(_ context.Context, _, _, _ string, _ ...telegram.SendOption) (*telegram.Message, error) {
+	return &telegram.Message{}, nil
+}
+
+func (NoopClient) SendDocumentReader(
+	_ context.Context, _ string, _ io.Reader, _ string, _ int64, _ string, _ ...telegram.SendOption,
+) (*telegram.Message, error) {
+	return &telegram.Message{}, nil
+}
+
+func (NoopClient) SendVoice(_ context.Context, _, _, _ string, _ ...telegram.SendOption) (*telegram.Message, error) {
+	return &telegram.Message{}, nil
+}
+
+func (NoopClient) SendAnimation(
+	_ context.Context, _, _, _ string, _ ...telegram.SendOption,
+) (*telegram.Message, error) {
+	return &telegram.Message{}, nil
+}
+
+func (NoopClient) SendVideoNote(_ context.Context, _, _ string, _ ...telegram.SendOption) (*telegram.Message, error) {
+	return &telegram.Message{}, nil
+}
+
+func (NoopClient) SendDocumentByRef(
+	_ context.Context, _, _, _ string, _ ...telegram.SendOption,
+) (*telegram.Message, error) {
+	return &telegram.Message{}, nil
+}
+
+func (NoopClient) SendAudioByRef(
+	_ context.Context, _, _, _ string, _ ...telegram.SendOption,
+) (*telegram.Message, error) {
+	return &telegram.Message{}, nil
+}
+
+func (NoopClient) SendPhotoByRef(
+	_ context.Context, _, _, _ string, _ ...telegram.SendOption,
+) (*telegram.Message, error) {
+	return &telegram.Message{}, nil
+}
+
+func (NoopClient) SendVideoByRef(
+	_ context.Context, _, _, _ string, _ ...telegram.SendOption,
+) (*telegram.Message, error) {
+	return &telegram.Message{}, nil
+}
+
+func (NoopClient) SendVoiceByRef(
+	_ context.Context, _, _, _ string, _ ...telegram.SendOption,
+) (*telegram.Message, error) {
+	return &telegram.Message{}, nil
+}
+
+func (NoopClient) SendAnimationByRef(
+	_ context.Context, _, _, _ string, _ ...telegram.SendOption,
+) (*telegram.Message, error) {
+	return &telegram.Message{}, nil
+}
+
+func (NoopClient) SendMediaGroup(
+	_ context.Context, _ string, filePaths []string, _ string, _ ...telegram.SendOption,
+) ([]telegram.Message, error) {
+	return make([]telegram.Message, len(filePaths)), nil
+}
+
+func (NoopClient) SendMessage(_ context.Context, _, _ string, _ ...telegram.SendOption) (*telegram.Message, error) {
+	return &telegram.Message{}, nil
+}
+
+func (NoopClient) EditMessageText(
+	_ context.Context, _ string, _ int64, _ string, _ ...telegram.SendOption,
+) (*telegram.Message, error) {
+	return &telegram.Message{}, nil
+}
+
+func (NoopClient) EditMessageCaption(_ context.Context, _ string, _ int64, _ string) (*telegram.Message, error) {
+	return &telegram.Message{}, nil
+}
+
+func (NoopClient) EditMessageMedia(
+	_ context.Context, _ string, _ int64, _, _, _ string, _ ...telegram.SendOption,
+) (*telegram.Message, error) {
+	return &telegram.Message{}, nil
+}
+
+func (NoopClient) DeleteMessage(_ context.Context, _ string, _ int64) error {
+	return nil
+}
+
+func (NoopClient) ForwardMessage(
+	_ context.Context, _, _ string, _ int64, _ ...telegram.SendOption,
+) (*telegram.Message, error) {
+	return &telegram.Message{}, nil
+}
+
+func (NoopClient) CopyMessage(_ context.Context, _, _ string, _ int64, _ ...telegram.SendOption) (int64, error) {
+	return 0, nil
+}
+
+func (NoopClient) PinChatMessage(_ context.Context, _ string, _ int64, _ ...telegram.SendOption) error {
+	return nil
+}
+
+func (NoopClient) UnpinChatMessage(_ context.Context, _ string, _ int64) error {
+	return nil
+}
+
+func (NoopClient) SendChatAction(_ context.Context, _, _ string) error {
+	return nil
+}
+
+func (NoopClient) GetFileInfo(_ context.Context, fileID string) (*telegram.File, error) {
+	return &telegram.File{FileID: fileID}, nil
+}
+
+func (NoopClient) DownloadFile(_ context.Context, _ string, _ io.Writer) error {
+	return nil
+}
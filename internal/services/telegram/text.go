@@ -0,0 +1,96 @@
+package telegram
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// maxCaptionLength [https://core.telegram.org/bots/api#senddocument]
+	maxCaptionLength = 1024
+	// maxMessageLength [https://core.telegram.org/bots/api#sendmessage]
+	maxMessageLength = 4096
+
+	ellipsis = "…"
+)
+
+// CaptionOverflow is what the send methods do with captions over the 1024 characters limit.
+type CaptionOverflow int
+
+const (
+	// CaptionTruncate cuts the caption and ends it with an ellipsis.
+	CaptionTruncate CaptionOverflow = iota
+	// CaptionSplit sends the rest of the caption as a reply message to the file. A failed reply is only
+	// logged, the file counts as sent.
+	CaptionSplit
+)
+
+// fitCaption returns the caption to send with the file and the rest to send as a message.
+func (b *IBot) fitCaption(caption string) (string, string) {
+	if utf8.RuneCountInString(caption) <= maxCaptionLength {
+		return caption, ""
+	}
+
+	if b.captionOverflow == CaptionSplit {
+		head, rest := splitAt(caption, maxCaptionLength)
+
+		return head, rest
+	}
+
+	return truncateText(caption, maxCaptionLength), ""
+}
+
+// truncateText cuts text to limit characters including the ellipsis.
+func truncateText(text string, limit int) string {
+	if utf8.RuneCountInString(text) <= limit {
+		return text
+	}
+
+	return text[:runeOffset(text, limit-utf8.RuneCountInString(ellipsis))] + ellipsis
+}
+
+// splitText splits text into chunks of at most limit characters, see splitAt.
+func splitText(text string, limit int) []string {
+	var chunks []string
+
+	for text != "" {
+		var chunk string
+
+		chunk, text = splitAt(text, limit)
+		chunks = append(chunks, chunk)
+	}
+
+	return chunks
+}
+
+// splitAt returns the head of text of at most limit characters and the rest,
+// preferably cut at the last line break or space.
+func splitAt(text string, limit int) (string, string) {
+	if utf8.RuneCountInString(text) <= limit {
+		return text, ""
+	}
+
+	cut := runeOffset(text, limit)
+	head := text[:cut]
+
+	for _, sep := range []string{"\n", " "} {
+		if i := strings.LastIndex(head, sep); i > 0 {
+			return head[:i], text[i+len(sep):]
+		}
+	}
+
+	return head, text[cut:]
+}
+
+// runeOffset returns the byte offset of the n-th rune of s.
+func runeOffset(s string, n int) int {
+	for i := range s {
+		if n == 0 {
+			return i
+		}
+
+		n--
+	}
+
+	return len(s)
+}
@@ -0,0 +1,58 @@
+package telegram
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTruncateText(t *testing.T) {
+	exact := strings.Repeat("я", maxCaptionLength)
+	if got := truncateText(exact, maxCaptionLength); got != exact {
+		t.Error("text at the limit must be kept")
+	}
+
+	got := truncateText(exact+"я", maxCaptionLength)
+	if utf8.RuneCountInString(got) != maxCaptionLength || !strings.HasSuffix(got, ellipsis) {
+		t.Errorf("unexpected truncation: %d runes, %q", utf8.RuneCountInString(got), got[len(got)-10:])
+	}
+}
+
+func TestSplitText(t *testing.T) {
+	cases := []struct {
+		name  string
+		text  string
+		limit int
+		want  []string
+	}{
+		{"empty", "", 5, nil},
+		{"at limit", "hello", 5, []string{"hello"}},
+		{"line break", "ab\ncd ef", 6, []string{"ab", "cd ef"}},
+		{"space", "hello world", 8, []string{"hello", "world"}},
+		{"hard cut", "abcdefgh", 3, []string{"abc", "def", "gh"}},
+		{"runes", "ééééé", 2, []string{"éé", "éé", "é"}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := splitText(tc.text, tc.limit)
+			if strings.Join(got, "|") != strings.Join(tc.want, "|") || len(got) != len(tc.want) {
+				t.Errorf("splitText(%q, %d) = %q, want %q", tc.text, tc.limit, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestFitCaption(t *testing.T) {
+	long := strings.Repeat("word ", maxCaptionLength/5+10)
+
+	head, rest := NewBot("token").fitCaption(long)
+	if rest != "" || utf8.RuneCountInString(head) != maxCaptionLength {
+		t.Errorf("truncate: %d runes, rest %q", utf8.RuneCountInString(head), rest)
+	}
+
+	head, rest = NewBot("token", WithCaptionOverflow(CaptionSplit)).fitCaption(long)
+	if utf8.RuneCountInString(head) > maxCaptionLength || head+" "+rest != long {
+		t.Errorf("split lost text: %d + %d", len(head), len(rest))
+	}
+}
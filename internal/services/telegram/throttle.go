@@ -0,0 +1,60 @@
+package telegram
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// throttleChunksPerSec splits reads so the upload is paced smoothly instead of in one-second bursts.
+const throttleChunksPerSec = 10
+
+// uploadThrottle paces the bytes of all uploads of a bot combined.
+type uploadThrottle struct {
+	bytesPerSec int64
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+func newUploadThrottle(bytesPerSec int64) *uploadThrottle {
+	return &uploadThrottle{bytesPerSec: bytesPerSec}
+}
+
+// reserve books n bytes and returns how long to wait before they may be sent.
+func (t *uploadThrottle) reserve(n int) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	if t.next.Before(now) {
+		t.next = now
+	}
+
+	delay := t.next.Sub(now)
+	t.next = t.next.Add(time.Duration(int64(n) * int64(time.Second) / t.bytesPerSec))
+
+	return delay
+}
+
+func (t *uploadThrottle) chunkSize() int {
+	return int(max(t.bytesPerSec/throttleChunksPerSec, 1))
+}
+
+type throttledReader struct {
+	r        io.Reader
+	throttle *uploadThrottle
+}
+
+func (r *throttledReader) Read(p []byte) (int, error) {
+	if chunk := r.throttle.chunkSize(); len(p) > chunk {
+		p = p[:chunk]
+	}
+
+	n, err := r.r.Read(p)
+	if n > 0 {
+		time.Sleep(r.throttle.reserve(n))
+	}
+
+	return n, err
+}
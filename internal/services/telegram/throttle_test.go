@@ -0,0 +1,85 @@
+package telegram
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestThrottledReaderSharedLimit(t *testing.T) {
+	const (
+		rate    = 100 << 10
+		payload = 25 << 10
+	)
+
+	throttle := newUploadThrottle(rate)
+
+	start := time.Now()
+
+	var wg sync.WaitGroup
+
+	// two uploads of 25 KiB share 100 KiB/s, so together they need ~0.5s
+	for range 2 {
+		wg.Go(func() {
+			r := &throttledReader{r: bytes.NewReader(make([]byte, payload)), throttle: throttle}
+
+			n, err := io.Copy(io.Discard, r)
+			if err != nil || n != payload {
+				t.Errorf("copied %d bytes, err %v", n, err)
+			}
+		})
+	}
+
+	wg.Wait()
+
+	if elapsed := time.Since(start); elapsed < 350*time.Millisecond || elapsed > 2*time.Second {
+		t.Errorf("unexpected elapsed time %s for 50 KiB at 100 KiB/s", elapsed)
+	}
+}
+
+func TestThrottledUploadOutlastsTimeout(t *testing.T) {
+	const timeout = 100 * time.Millisecond
+
+	path := filepath.Join(t.TempDir(), "a.bin")
+	if err := os.WriteFile(path, make([]byte, 10<<10), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/bottoken/getMe" {
+			time.Sleep(3 * timeout)
+		}
+
+		_, _ = w.Write([]byte(`{"ok":true,"result":{"message_id":1}}`))
+	}))
+	defer srv.Close()
+
+	transport, err := NewTransport("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	transport.ResponseHeaderTimeout = timeout
+
+	// 10 KiB at 20 KiB/s take 5 timeouts
+	bot := NewBot("token", WithAPIURL(srv.URL+"/bot"), WithHTTPClient(&http.Client{Transport: transport}),
+		WithUploadRateLimit(20<<10))
+	bot.timeout = timeout
+
+	if _, err := bot.SendDocument(t.Context(), "chat", path, ""); err != nil {
+		t.Fatalf("expected the throttled upload to succeed, got %v", err)
+	}
+
+	// requests without a file still time out
+	if _, err := bot.GetMe(t.Context()); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the request to time out, got %v", err)
+	}
+}
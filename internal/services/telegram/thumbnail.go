@@ -0,0 +1,151 @@
+package telegram
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	_ "image/jpeg" // registers the only thumbnail format
+	"mime/multipart"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	// maxThumbnailSize and maxThumbnailSide are the thumbnail limits of the Bot API.
+	maxThumbnailSize = 200 << 10
+	maxThumbnailSide = 320
+
+	// thumbnailPart is the multipart field the thumbnail is uploaded as, see attach://.
+	thumbnailPart = "thumbnail_file"
+)
+
+// ErrInvalidThumbnail is returned for a thumbnail Telegram would reject.
+var ErrInvalidThumbnail = errors.New("invalid thumbnail")
+
+// Thumbnail attaches the JPEG at path as the preview of an uploaded video, document, audio or video note.
+// It must pass ValidateThumbnail. Files sent by reference ignore it.
+func Thumbnail(path string) SendOption {
+	return func(o *sendOptions) {
+		o.thumbnailPath = path
+	}
+}
+
+// VideoInfo sets the width, height and duration of an uploaded video or animation, zero values are left out.
+func VideoInfo(width, height int, duration time.Duration) SendOption {
+	return func(o *sendOptions) {
+		o.width, o.height, o.duration = width, height, duration
+	}
+}
+
+// AudioInfo sets the performer, title and duration of an uploaded audio file, zero values are left out
+// and Telegram reads them from the file's tags.
+func AudioInfo(performer, title string, duration time.Duration) SendOption {
+	return func(o *sendOptions) {
+		o.performer, o.title, o.duration = performer, title, duration
+	}
+}
+
+// SupportsStreaming marks an uploaded video as suitable for streaming, other files ignore it.
+func SupportsStreaming() SendOption {
+	return func(o *sendOptions) {
+		o.supportsStreaming = true
+	}
+}
+
+// ValidateThumbnail checks that path is a JPEG of at most 200 kB and 320x320.
+func ValidateThumbnail(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("open %s: %w", path, err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("stat %s: %w", path, err)
+	}
+
+	if info.Size() > maxThumbnailSize {
+		return fmt.Errorf("%w: %s is %d bytes (max %d)", ErrInvalidThumbnail, path, info.Size(), maxThumbnailSize)
+	}
+
+	cfg, format, err := image.DecodeConfig(file)
+	if err != nil || format != "jpeg" {
+		return fmt.Errorf("%w: %s is not a jpeg", ErrInvalidThumbnail, path)
+	}
+
+	if cfg.Width > maxThumbnailSide || cfg.Height > maxThumbnailSide {
+		return fmt.Errorf("%w: %s is %dx%d (max %dx%d)",
+			ErrInvalidThumbnail, path, cfg.Width, cfg.Height, maxThumbnailSide, maxThumbnailSide)
+	}
+
+	return nil
+}
+
+// writeMediaFields writes the spoiler flag of a photo, video or animation upload, field is the file field,
+// and the video dimensions that are set.
+func (o sendOptions) writeMediaFields(w *multipart.Writer, field string) error {
+	if o.hasSpoiler && hasSpoilerField(field) {
+		if err := w.WriteField("has_spoiler", "true"); err != nil {
+			return err
+		}
+	}
+
+	if o.supportsStreaming && field == mediaTypeVideo {
+		if err := w.WriteField("supports_streaming", "true"); err != nil {
+			return err
+		}
+	}
+
+	if field == mediaTypeAudio {
+		for _, f := range [][2]string{{"performer", o.performer}, {"title", o.title}} {
+			if f[1] == "" {
+				continue
+			}
+
+			if err := w.WriteField(f[0], f[1]); err != nil {
+				return err
+			}
+		}
+	}
+
+	fields := []struct {
+		name  string
+		value int
+	}{
+		{"width", o.width},
+		{"height", o.height},
+		{"duration", int(o.duration / time.Second)},
+	}
+
+	for _, f := range fields {
+		if f.value == 0 {
+			continue
+		}
+
+		if err := w.WriteField(f.name, strconv.Itoa(f.value)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// hasSpoilerField reports whether an upload as field can be blurred with has_spoiler.
+func hasSpoilerField(field string) bool {
+	return field == mediaTypePhoto || field == mediaTypeVideo || field == mediaTypeAnimation
+}
+
+// writeThumbnail uploads the thumbnail, if any, and references it from the thumbnail field.
+func (o sendOptions) writeThumbnail(w *formWriter) error {
+	if o.thumbnailPath == "" {
+		return nil
+	}
+
+	if err := w.WriteField("thumbnail", "attach://"+thumbnailPart); err != nil {
+		return err
+	}
+
+	return writeFilePart(w, thumbnailPart, o.thumbnailPath)
+}
@@ -0,0 +1,196 @@
+package telegram
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+const (
+	// defaultPollTimeout keeps a long poll below the timeout of the requests of the bot
+	defaultPollTimeout = 30 * time.Second
+
+	// pollRetryDelay doubles after every failed poll up to maxPollRetryDelay
+	pollRetryDelay    = time.Second
+	maxPollRetryDelay = time.Minute
+)
+
+// GetUpdates [https://core.telegram.org/bots/api#getupdates]
+//
+// It waits up to timeout for the updates from offset on, ctx cancels the wait.
+// The updates before offset are confirmed and never returned again.
+// It fails while a webhook is set, see SetWebhook.
+func (b *IBot) GetUpdates(
+	ctx context.Context, offset int64, timeout time.Duration, allowedUpdates ...string,
+) ([]Update, error) {
+	req := GetUpdatesRequest{Offset: offset, Timeout: int(timeout / time.Second), AllowedUpdates: allowedUpdates}
+
+	var updates []Update
+
+	if err := b.callJSON(ctx, "getUpdates", req, &updates); err != nil {
+		return nil, err
+	}
+
+	return updates, nil
+}
+
+// Poller receives the updates of the bot by long polling and passes them to a handler,
+// the alternative to a webhook for bots without a public HTTPS endpoint.
+type Poller struct {
+	bot            *IBot
+	handle         func(ctx context.Context, update Update)
+	timeout        time.Duration
+	allowedUpdates []string
+	offset         int64
+}
+
+// PollerOption sets an optional parameter of NewPoller.
+type PollerOption func(p *Poller)
+
+// PollTimeout sets how long a poll waits for updates, 30s by default.
+// It must stay below the timeout of the requests of the bot, 60s.
+func PollTimeout(timeout time.Duration) PollerOption {
+	return func(p *Poller) {
+		if timeout > 0 {
+			p.timeout = timeout
+		}
+	}
+}
+
+// PollAllowedUpdates limits the polled update types, e.g. "message".
+func PollAllowedUpdates(types ...string) PollerOption {
+	return func(p *Poller) {
+		p.allowedUpdates = types
+	}
+}
+
+// NewPoller returns a poller passing the updates of bot to handle, e.g. Dispatcher.Dispatch,
+// with the context of Run.
+func NewPoller(bot *IBot, handle func(ctx context.Context, update Update), opts ...PollerOption) *Poller {
+	p := &Poller{bot: bot, handle: handle, timeout: defaultPollTimeout}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	return p
+}
+
+// Run polls until ctx is done and returns its error. The updates are handled one by one in order,
+// so handle should return quickly. Failed polls are logged and retried with a growing delay,
+// a flood limit is waited for by the bot. An invalid token ends the loop with ErrUnauthorized.
+func (p *Poller) Run(ctx context.Context) error {
+	delay := pollRetryDelay
+
+	for {
+		updates, err := p.bot.GetUpdates(ctx, p.offset, p.timeout, p.allowedUpdates...)
+
+		switch {
+		case ctx.Err() != nil:
+			return ctx.Err()
+		case errors.Is(err, ErrUnauthorized):
+			return err
+		case err != nil:
+			p.bot.logger.Warn("failed to get updates", "error", err, "retry_in", delay)
+
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(delay):
+			}
+
+			delay = min(2*delay, maxPollRetryDelay)
+
+			continue
+		}
+
+		delay = pollRetryDelay
+
+		for _, update := range updates {
+			// confirmed with the next poll
+			p.offset = update.UpdateID + 1
+			p.handle(ctx, update)
+		}
+	}
+}
+
+// CommandHandler handles a bot command, args is the text after it.
+type CommandHandler func(ctx context.Context, msg *Message, args string)
+
+// Dispatcher routes new messages to the handlers of their bot commands, e.g. "/status",
+// and the other messages to a fallback. Its Dispatch is the handler of a Poller or a WebhookHandler.
+type Dispatcher struct {
+	username string
+
+	mu       sync.RWMutex
+	commands map[string]CommandHandler
+	fallback func(ctx context.Context, msg *Message)
+}
+
+// NewDispatcher returns the dispatcher of the bot with username, see GetMe. Commands addressed to
+// another bot of a group, "/status@other_bot", are ignored. An empty username accepts them all.
+func NewDispatcher(username string) *Dispatcher {
+	return &Dispatcher{username: username, commands: make(map[string]CommandHandler)}
+}
+
+// HandleCommand sets the handler of command, given without the slash. Commands are case-insensitive.
+func (d *Dispatcher) HandleCommand(command string, handler CommandHandler) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.commands[strings.ToLower(command)] = handler
+}
+
+// HandleMessage sets the handler of the messages without a known command.
+func (d *Dispatcher) HandleMessage(handler func(ctx context.Context, msg *Message)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.fallback = handler
+}
+
+// Dispatch passes the new message or channel post of update to its handler with ctx, edits are ignored.
+func (d *Dispatcher) Dispatch(ctx context.Context, update Update) {
+	msg := cmp.Or(update.Message, update.ChannelPost)
+	if msg == nil {
+		return
+	}
+
+	command, username, args, isCommand := parseCommand(msg.Text)
+	if isCommand && username != "" && d.username != "" && !strings.EqualFold(username, d.username) {
+		return
+	}
+
+	d.mu.RLock()
+	handler, ok := d.commands[command]
+	fallback := d.fallback
+	d.mu.RUnlock()
+
+	switch {
+	case isCommand && ok:
+		handler(ctx, msg, args)
+	case fallback != nil:
+		fallback(ctx, msg)
+	}
+}
+
+// parseCommand splits text like "/command@username args", it reports false when text is no command.
+func parseCommand(text string) (command, username, args string, ok bool) {
+	rest, ok := strings.CutPrefix(text, "/")
+	if !ok {
+		return "", "", "", false
+	}
+
+	head := rest
+	if i := strings.IndexFunc(rest, unicode.IsSpace); i >= 0 {
+		head, args = rest[:i], strings.TrimSpace(rest[i:])
+	}
+
+	command, username, _ = strings.Cut(head, "@")
+
+	return strings.ToLower(command), username, args, command != ""
+}
@@ -0,0 +1,116 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPollerDispatchesCommands(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var (
+		mu      sync.Mutex
+		offsets []int64
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req GetUpdatesRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error(err)
+		}
+
+		mu.Lock()
+		offsets = append(offsets, req.Offset)
+		mu.Unlock()
+
+		if req.Offset != 0 {
+			// nothing new, the test is done
+			cancel()
+			<-r.Context().Done()
+
+			return
+		}
+
+		_, _ = w.Write([]byte(`{"ok":true,"result":[` +
+			`{"update_id":10,"message":{"message_id":1,"chat":{"id":5},"text":"/Status@my_bot now"}},` +
+			`{"update_id":11,"message":{"message_id":2,"chat":{"id":5},"text":"/status@other_bot"}},` +
+			`{"update_id":12,"edited_message":{"message_id":1,"chat":{"id":5},"text":"/status"}},` +
+			`{"update_id":13,"channel_post":{"message_id":3,"chat":{"id":6},"text":"hello"}}]}`))
+	}))
+	defer srv.Close()
+
+	d := NewDispatcher("my_bot")
+
+	var (
+		commands []string
+		messages []string
+	)
+
+	d.HandleCommand("status", func(_ context.Context, msg *Message, args string) {
+		commands = append(commands, msg.Text+"|"+args)
+	})
+	d.HandleMessage(func(_ context.Context, msg *Message) {
+		messages = append(messages, msg.Text)
+	})
+
+	bot := NewBot("token", WithAPIURL(srv.URL+"/bot"))
+
+	if err := NewPoller(bot, d.Dispatch, PollTimeout(time.Second)).Run(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the poller to stop with ctx, got %v", err)
+	}
+
+	if len(commands) != 1 || commands[0] != "/Status@my_bot now|now" {
+		t.Errorf("expected only the command to this bot, got %q", commands)
+	}
+
+	if len(messages) != 1 || messages[0] != "hello" {
+		t.Errorf("expected the channel post to reach the fallback, got %q", messages)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if len(offsets) != 2 || offsets[1] != 14 {
+		t.Errorf("expected the handled updates to be confirmed, got offsets %v", offsets)
+	}
+}
+
+func TestPollerStopsOnInvalidToken(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"ok":false,"error_code":401,"description":"Unauthorized"}`))
+	}))
+	defer srv.Close()
+
+	bot := NewBot("token", WithAPIURL(srv.URL+"/bot"))
+
+	err := NewPoller(bot, func(context.Context, Update) {}).Run(t.Context())
+	if !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("expected ErrUnauthorized, got %v", err)
+	}
+}
+
+func TestParseCommand(t *testing.T) {
+	for text, want := range map[string][3]string{
+		"/sync":                 {"sync", "", ""},
+		"/delete@bot a.txt b":   {"delete", "bot", "a.txt b"},
+		"/pause\nfor the night": {"pause", "", "for the night"},
+	} {
+		command, username, args, ok := parseCommand(text)
+		if !ok || [3]string{command, username, args} != want {
+			t.Errorf("%q: got %q %q %q, want %q", text, command, username, args, want)
+		}
+	}
+
+	for _, text := range []string{"hello", "", "/", "/@bot"} {
+		if _, _, _, ok := parseCommand(text); ok {
+			t.Errorf("%q must not be a command", text)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package telegram
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+)
+
+const (
+	// secretTokenHeader carries the secret_token of setWebhook in every update request.
+	secretTokenHeader = "X-Telegram-Bot-Api-Secret-Token"
+
+	// maxUpdateSize is the largest update body accepted by WebhookHandler.
+	maxUpdateSize = 1 << 20
+)
+
+// WebhookOption sets an optional parameter of SetWebhook.
+type WebhookOption func(r *SetWebhookRequest)
+
+// SecretToken is sent by Telegram in the X-Telegram-Bot-Api-Secret-Token header of every update,
+// see WebhookHandler. 1-256 characters of A-Z, a-z, 0-9, _ and -.
+func SecretToken(token string) WebhookOption {
+	return func(r *SetWebhookRequest) {
+		r.SecretToken = token
+	}
+}
+
+// AllowedUpdates limits the update types sent to the webhook, e.g. "message".
+func AllowedUpdates(types ...string) WebhookOption {
+	return func(r *SetWebhookRequest) {
+		r.AllowedUpdates = types
+	}
+}
+
+// DropPendingUpdates drops the updates received before the webhook was set.
+func DropPendingUpdates() WebhookOption {
+	return func(r *SetWebhookRequest) {
+		r.DropPendingUpdates = true
+	}
+}
+
+// SetWebhook [https://core.telegram.org/bots/api#setwebhook]
+//
+// Telegram posts the updates to url instead of returning them from getUpdates,
+// the two can't be used at the same time.
+func (b *IBot) SetWebhook(ctx context.Context, url string, opts ...WebhookOption) error {
+	req := SetWebhookRequest{URL: url}
+
+	for _, opt := range opts {
+		opt(&req)
+	}
+
+	return b.callJSON(ctx, "setWebhook", req, nil)
+}
+
+// DeleteWebhook [https://core.telegram.org/bots/api#deletewebhook]
+func (b *IBot) DeleteWebhook(ctx context.Context, dropPendingUpdates bool) error {
+	return b.callJSON(ctx, "deleteWebhook", DeleteWebhookRequest{DropPendingUpdates: dropPendingUpdates}, nil)
+}
+
+// WebhookHandler is the http.Handler of the webhook url, it decodes the posted updates and passes them to handle.
+// Telegram waits for the response and retries on failure, so handle should return quickly.
+type WebhookHandler struct {
+	secretToken string
+	handle      func(Update)
+}
+
+// NewWebhookHandler returns a handler of the updates set up with SetWebhook(url, SecretToken(secretToken)).
+// Requests without that token are rejected, an empty secretToken accepts every request.
+func NewWebhookHandler(secretToken string, handle func(Update)) *WebhookHandler {
+	return &WebhookHandler{secretToken: secretToken, handle: handle}
+}
+
+func (h *WebhookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	token := r.Header.Get(secretTokenHeader)
+	if subtle.ConstantTimeCompare([]byte(token), []byte(h.secretToken)) != 1 {
+		http.Error(w, "forbidden", http.StatusForbidden)
+
+		return
+	}
+
+	var update Update
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxUpdateSize)).Decode(&update); err != nil {
+		http.Error(w, "invalid update", http.StatusBadRequest)
+
+		return
+	}
+
+	h.handle(update)
+
+	w.WriteHeader(http.StatusOK)
+}
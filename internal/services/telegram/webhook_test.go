@@ -0,0 +1,69 @@
+package telegram
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSetWebhook(t *testing.T) {
+	var req SetWebhookRequest
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/bottoken/setWebhook" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatal(err)
+		}
+
+		_, _ = w.Write([]byte(`{"ok":true,"result":true}`))
+	}))
+	defer srv.Close()
+
+	bot := NewBot("token", WithAPIURL(srv.URL+"/bot"))
+
+	err := bot.SetWebhook(t.Context(), "https://example.com/hook", SecretToken("s3cret"), AllowedUpdates("message"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if req.URL != "https://example.com/hook" || req.SecretToken != "s3cret" || len(req.AllowedUpdates) != 1 {
+		t.Errorf("unexpected request %+v", req)
+	}
+}
+
+func TestWebhookHandler(t *testing.T) {
+	var updates []Update
+
+	h := NewWebhookHandler("s3cret", func(u Update) { updates = append(updates, u) })
+
+	body := `{"update_id":7,"message":{"message_id":1,"text":"/status"}}`
+
+	for _, tt := range []struct {
+		method, token string
+		want          int
+	}{
+		{http.MethodPost, "s3cret", http.StatusOK},
+		{http.MethodPost, "spoofed", http.StatusForbidden},
+		{http.MethodPost, "", http.StatusForbidden},
+		{http.MethodGet, "s3cret", http.StatusMethodNotAllowed},
+	} {
+		r := httptest.NewRequest(tt.method, "/hook", strings.NewReader(body))
+		r.Header.Set("X-Telegram-Bot-Api-Secret-Token", tt.token)
+
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, r)
+
+		if rec.Code != tt.want {
+			t.Errorf("%s with token %q: status %d, want %d", tt.method, tt.token, rec.Code, tt.want)
+		}
+	}
+
+	if len(updates) != 1 || updates[0].UpdateID != 7 || updates[0].Message.Text != "/status" {
+		t.Errorf("expected only the authenticated update to be handled, got %+v", updates)
+	}
+}
@@ -0,0 +1,87 @@
+// Package version holds the build information, set at build time with
+//
+//	go build -ldflags "-X github.com/k0ff1l/tgcloudbot/internal/version.Version=v1.2.3 \
+//		-X github.com/k0ff1l/tgcloudbot/internal/version.Commit=abc1234 \
+//		-X github.com/k0ff1l/tgcloudbot/internal/version.Date=2024-01-02T15:04:05Z"
+//
+// see the Makefile. Values that are not set are taken from the module and VCS information
+// go embeds into the binary, e.g. with go install.
+package version
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"sync"
+)
+
+// unknown is the value of the fields that are neither set nor embedded.
+const unknown = "unknown"
+
+// Set with -ldflags -X, read them with Get.
+//
+//nolint:gochecknoglobals // set by the linker
+var (
+	Version = ""
+	Commit  = ""
+	Date    = ""
+)
+
+// Info is the build information of the running binary.
+type Info struct {
+	Version   string
+	Commit    string
+	Date      string
+	GoVersion string
+}
+
+// String formats the info for -version, e.g. "v1.2.3 (commit abc1234, built 2024-01-02T15:04:05Z, go1.25.4)".
+func (i Info) String() string {
+	return fmt.Sprintf("%s (commit %s, built %s, %s)", i.Version, i.Commit, i.Date, i.GoVersion)
+}
+
+//nolint:gochecknoglobals // computed once
+var get = sync.OnceValue(func() Info {
+	bi, _ := debug.ReadBuildInfo()
+
+	return resolve(Version, Commit, Date, bi)
+})
+
+// Get returns the build information.
+func Get() Info {
+	return get()
+}
+
+// resolve fills what isn't set by the linker from bi, which may be nil.
+func resolve(version, commit, date string, bi *debug.BuildInfo) Info {
+	info := Info{Version: version, Commit: commit, Date: date, GoVersion: runtime.Version()}
+
+	if bi != nil {
+		if info.Version == "" && bi.Main.Version != "" && bi.Main.Version != "(devel)" {
+			info.Version = bi.Main.Version
+		}
+
+		for _, s := range bi.Settings {
+			switch {
+			case s.Key == "vcs.revision" && info.Commit == "":
+				info.Commit = s.Value
+			case s.Key == "vcs.time" && info.Date == "":
+				info.Date = s.Value
+			}
+		}
+	}
+
+	if info.Version == "" {
+		info.Version = "dev"
+	}
+
+	if info.Commit == "" {
+		info.Commit = unknown
+	}
+
+	if info.Date == "" {
+		info.Date = unknown
+	}
+
+	return info
+}
@@ -0,0 +1,25 @@
+package version
+
+import (
+	"runtime/debug"
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	bi := &debug.BuildInfo{
+		Main:     debug.Module{Version: "(devel)"},
+		Settings: []debug.BuildSetting{{Key: "vcs.revision", Value: "abc1234"}, {Key: "vcs.time", Value: "2024-01-02"}},
+	}
+
+	if info := resolve("", "", "", bi); info.Version != "dev" || info.Commit != "abc1234" || info.Date != "2024-01-02" {
+		t.Errorf("expected the vcs info to fill the gaps, got %+v", info)
+	}
+
+	if info := resolve("v1.2.3", "def5678", "", bi); info.Version != "v1.2.3" || info.Commit != "def5678" {
+		t.Errorf("the linker values must win, got %+v", info)
+	}
+
+	if info := resolve("", "", "", nil); info.Version != "dev" || info.Commit != unknown || info.Date != unknown {
+		t.Errorf("unexpected info without build info %+v", info)
+	}
+}